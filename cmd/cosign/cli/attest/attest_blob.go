@@ -148,7 +148,17 @@ func (c *AttestBlobCommand) Exec(ctx context.Context, artifactPath string) error
 
 	var rfc3161Timestamp *cbundle.RFC3161Timestamp
 	if c.TSAServerURL != "" {
-		respBytes, err := tsa.GetTimestampedSignature(sig, client.NewTSAClient(c.TSAServerURL))
+		var respBytes []byte
+		if c.TSAClientCACert == "" && c.TSAClientCert == "" { // no mTLS params or custom CA
+			respBytes, err = tsa.GetTimestampedSignature(sig, client.NewTSAClient(c.TSAServerURL))
+		} else {
+			respBytes, err = tsa.GetTimestampedSignature(sig, client.NewTSAClientMTLS(c.TSAServerURL,
+				c.TSAClientCACert,
+				c.TSAClientCert,
+				c.TSAClientKey,
+				c.TSAServerName,
+			))
+		}
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,109 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// TrustPolicy is a declarative verify-blob-attestation configuration. Each
+// field corresponds to the flag of the same name.
+type TrustPolicy struct {
+	CertificateIdentity          string   `json:"certificateIdentity,omitempty"`
+	CertificateIdentityRegexp    string   `json:"certificateIdentityRegexp,omitempty"`
+	CertificateOidcIssuer        string   `json:"certificateOidcIssuer,omitempty"`
+	CertificateOidcIssuerRegexp  string   `json:"certificateOidcIssuerRegexp,omitempty"`
+	PinSPKI                      []string `json:"pinSPKI,omitempty"`
+	RequireCertPolicyOID         string   `json:"requireCertPolicyOID,omitempty"`
+	MaxCertLifetime              string   `json:"maxCertLifetime,omitempty"`
+	RequireKeyID                 string   `json:"requireKeyID,omitempty"`
+	RequireTlogEntryKind         string   `json:"requireTlogEntryKind,omitempty"`
+	PredicateType                string   `json:"predicateType,omitempty"`
+	AllSubjectsMatch             *bool    `json:"allSubjectsMatch,omitempty"`
+	SubjectName                  string   `json:"subjectName,omitempty"`
+	RejectUnknownPredicateFields *bool    `json:"rejectUnknownPredicateFields,omitempty"`
+	PredicateAllowedFields       []string `json:"predicateAllowedFields,omitempty"`
+}
+
+// LoadTrustPolicy reads a trust policy from a YAML or JSON file. Unknown
+// fields are rejected so that a misspelled requirement is never ignored.
+func LoadTrustPolicy(path string) (*TrustPolicy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading trust policy: %w", err)
+	}
+	tp := &TrustPolicy{}
+	if err := yaml.UnmarshalStrict(b, tp); err != nil {
+		return nil, fmt.Errorf("parsing trust policy %s: %w", path, err)
+	}
+	return tp, nil
+}
+
+// ApplyTrustPolicy loads the --trust-policy file, if any, and uses its values
+// for every flag that was not set on the command line.
+func (o *VerifyBlobAttestationOptions) ApplyTrustPolicy(flags *pflag.FlagSet) error {
+	if o.TrustPolicy == "" {
+		return nil
+	}
+	tp, err := LoadTrustPolicy(o.TrustPolicy)
+	if err != nil {
+		return err
+	}
+
+	setString := func(flag string, dst *string, v string) {
+		if v != "" && !flags.Changed(flag) {
+			*dst = v
+		}
+	}
+	setSlice := func(flag string, dst *[]string, v []string) {
+		if len(v) > 0 && !flags.Changed(flag) {
+			*dst = v
+		}
+	}
+	setBool := func(flag string, dst *bool, v *bool) {
+		if v != nil && !flags.Changed(flag) {
+			*dst = *v
+		}
+	}
+
+	setString("certificate-identity", &o.CertVerify.CertIdentity, tp.CertificateIdentity)
+	setString("certificate-identity-regexp", &o.CertVerify.CertIdentityRegexp, tp.CertificateIdentityRegexp)
+	setString("certificate-oidc-issuer", &o.CertVerify.CertOidcIssuer, tp.CertificateOidcIssuer)
+	setString("certificate-oidc-issuer-regexp", &o.CertVerify.CertOidcIssuerRegexp, tp.CertificateOidcIssuerRegexp)
+	setSlice("pin-spki", &o.PinSPKI, tp.PinSPKI)
+	setString("require-cert-policy-oid", &o.RequireCertPolicyOID, tp.RequireCertPolicyOID)
+	setString("require-keyid", &o.RequireKeyID, tp.RequireKeyID)
+	setString("require-tlog-entry-kind", &o.RequireTlogEntryKind, tp.RequireTlogEntryKind)
+	setString("type", &o.PredicateOptions.Type, tp.PredicateType)
+	setBool("all-subjects-match", &o.AllSubjectsMatch, tp.AllSubjectsMatch)
+	setString("subject-name", &o.SubjectName, tp.SubjectName)
+	setBool("reject-unknown-predicate-fields", &o.RejectUnknownPredicateFields, tp.RejectUnknownPredicateFields)
+	setSlice("predicate-allowed-fields", &o.PredicateAllowedFields, tp.PredicateAllowedFields)
+
+	if tp.MaxCertLifetime != "" && !flags.Changed("max-cert-lifetime") {
+		d, err := time.ParseDuration(tp.MaxCertLifetime)
+		if err != nil {
+			return fmt.Errorf("parsing trust policy maxCertLifetime: %w", err)
+		}
+		o.MaxCertLifetime = d
+	}
+	return nil
+}
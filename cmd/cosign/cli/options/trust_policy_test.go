@@ -0,0 +1,111 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const testTrustPolicy = `certificateIdentityRegexp: ^https://github.com/example/.*$
+certificateOidcIssuer: https://token.actions.githubusercontent.com
+predicateType: slsaprovenance1
+maxCertLifetime: 20m
+allSubjectsMatch: true
+pinSPKI:
+- aGVsbG8=
+`
+
+func TestApplyTrustPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(testTrustPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &VerifyBlobAttestationOptions{}
+	cmd := &cobra.Command{}
+	o.AddFlags(cmd)
+	if err := cmd.Flags().Parse([]string{
+		"--trust-policy", path,
+		"--type", "spdxjson",
+		"--max-cert-lifetime", "10m",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.ApplyTrustPolicy(cmd.Flags()); err != nil {
+		t.Fatalf("ApplyTrustPolicy() = %v", err)
+	}
+
+	// Values from the file.
+	if o.CertVerify.CertIdentityRegexp != "^https://github.com/example/.*$" {
+		t.Errorf("CertIdentityRegexp = %q", o.CertVerify.CertIdentityRegexp)
+	}
+	if o.CertVerify.CertOidcIssuer != "https://token.actions.githubusercontent.com" {
+		t.Errorf("CertOidcIssuer = %q", o.CertVerify.CertOidcIssuer)
+	}
+	if !o.AllSubjectsMatch {
+		t.Error("AllSubjectsMatch = false")
+	}
+	if len(o.PinSPKI) != 1 || o.PinSPKI[0] != "aGVsbG8=" {
+		t.Errorf("PinSPKI = %v", o.PinSPKI)
+	}
+	// Flags override the file.
+	if o.PredicateOptions.Type != "spdxjson" {
+		t.Errorf("PredicateOptions.Type = %q, want the flag value", o.PredicateOptions.Type)
+	}
+	if o.MaxCertLifetime != 10*time.Minute {
+		t.Errorf("MaxCertLifetime = %s, want the flag value", o.MaxCertLifetime)
+	}
+}
+
+func TestApplyTrustPolicyErrors(t *testing.T) {
+	tests := []struct {
+		description string
+		policy      string
+		wantErr     string
+	}{
+		{
+			description: "unknown field",
+			policy:      "certificateIdentity: foo\nfreshness: 24h\n",
+			wantErr:     `unknown field "freshness"`,
+		}, {
+			description: "invalid duration",
+			policy:      "maxCertLifetime: forever\n",
+			wantErr:     "parsing trust policy maxCertLifetime",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(test.policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			o := &VerifyBlobAttestationOptions{}
+			cmd := &cobra.Command{}
+			o.AddFlags(cmd)
+			o.TrustPolicy = path
+			err := o.ApplyTrustPolicy(cmd.Flags())
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("ApplyTrustPolicy() = %v, want error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -16,6 +16,8 @@
 package options
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
 
 	"github.com/sigstore/cosign/v2/internal/pkg/cosign"
@@ -204,19 +206,132 @@ func (o *VerifyDockerfileOptions) AddFlags(cmd *cobra.Command) {
 		"only verify the base image (the last FROM image in the Dockerfile)")
 }
 
+// DefaultMaxSignatures is the default bound on the number of signatures of a
+// DSSE envelope verified by verify-blob-attestation.
+const DefaultMaxSignatures = 64
+
+// DefaultCTLogURL is the address of the CT log of the Sigstore public good
+// Fulcio instance, queried by verify-blob-attestation --require-ct-inclusion.
+const DefaultCTLogURL = "https://ctfe.sigstore.dev/2022"
+
 // VerifyBlobAttestationOptions is the top level wrapper for the `verify-blob-attestation` command.
 type VerifyBlobAttestationOptions struct {
-	Key           string
-	SignaturePath string
-	BundlePath    string
+	Key              []string
+	KeyHistory       string
+	KeyHistorySig    string
+	KeyHistoryRoot   string
+	SignaturePath    string
+	EnvelopeJSONPath string
+	PayloadPath      string
+	SignatureArchive string
+	FromImage        string
+	BundlePath       string
+	SaveBundle       string
+	OutputEnvelope   string
+	OutputVSA        string
+	VSAKey           string
+	VSAPolicyURI     string
+	OutputLink       string
+	LinkKey          string
+	LinkStepName     string
+	OutputMaterials  string
+	OutputSPDXGraph  string
+	MintClaim        string
+	MintClaimKey     string
+	MintClaimTTL     time.Duration
+	EmitEdge         string
+	Report           string
+	ReportTime       string
+	TimingJSON       string
+	CertFromJWT      string
+
+	RelaySign            bool
+	RelayKey             string
+	RelayOutputSignature string
+	RelayBundle          string
+	RelayTlogUpload      bool
+	SkipConfirmation     bool
 
 	PredicateOptions
-	CheckClaims bool
+	CheckClaims      bool
+	AllSubjectsMatch bool
+	SubjectName      string
+	SubjectIndex     int
+	DigestEncoding   string
+	RequireKeyID     string
+	MaxSignatures    int
+	VerifyLinked     string
+	RequireSBOM      bool
+	SLSABuilderID    string
+	StatementType    string
+
+	RequireReproducible          bool
+	RequireSubjectURIAndDigest   bool
+	RequireSortedSubjects        bool
+	SubjectNameRegexp            string
+	IdentityPredicateMap         string
+	PredicateVersionConstraint   string
+	PredicateDecrypt             string
+	AgeIdentity                  string
+	RejectUnknownPredicateFields bool
+	PredicateAllowedFields       []string
+	RequirePredicateFields       []string
+	AllowedSignatureAlgorithms   []string
+	MatchComputableDigests       bool
+	ParseStrictness              string
+	RekorWitnessKeys             []string
+	RekorTreeID                  int64
+	RekorURLs                    []string
+	RequireCTInclusion           bool
+	CTLogURL                     string
+	MinTlogEntries               int
+	RekorLocalTree               string
+	AfterCheckpoint              string
+	RequireTlogEntryKind         string
+	Explain                      bool
+	FailOnWarnings               bool
+	BlobSignature                string
+	Provenance                   string
+	AttestationChain             string
+	BlobJSONCanonical            bool
+	Decompress                   string
+	BlobDigest                   string
+	BlobRange                    string
+	BlobParts                    []string
+	CDCDigest                    string
+	StableBlob                   bool
+	TrustCacheDir                string
+	PredicateOnlySignature       bool
+	SubjectDigest                string
+	RefreshTrust                 bool
+	NoNetwork                    bool
+	BlobResolver                 string
+	MatchImageConfig             string
+	MatchAnnotationDigest        string
+	AnnotationImage              string
+	HashAlgorithm                string
+	PinSPKI                      []string
+	RequireCertPolicyOID         string
+	RequireIntermediateSKI       string
+	CertSPIFFEID                 string
+	MaxCertLifetime              time.Duration
+	ClockSkew                    time.Duration
+	RequireSigningTimeInValidity bool
+	TrustPolicy                  string
+	Output                       string
+
+	VerifierPlugin string
+	DSSEPAE        string
 
 	SecurityKey         SecurityKeyOptions
 	CertVerify          CertVerifyOptions
-	Rekor               RekorOptions
 	CommonVerifyOptions CommonVerifyOptions
+	// Registry configures the pulls of the --match-image-config,
+	// --annotation-image and --from-image images.
+	Registry RegistryOptions
+	// Fulcio and OIDC configure the keyless signing of --relay-sign.
+	Fulcio FulcioOptions
+	OIDC   OIDCOptions
 
 	RFC3161TimestampPath string
 }
@@ -227,22 +342,399 @@ var _ Interface = (*VerifyBlobOptions)(nil)
 func (o *VerifyBlobAttestationOptions) AddFlags(cmd *cobra.Command) {
 	o.PredicateOptions.AddFlags(cmd)
 	o.SecurityKey.AddFlags(cmd)
-	o.Rekor.AddFlags(cmd)
 	o.CertVerify.AddFlags(cmd)
 	o.CommonVerifyOptions.AddFlags(cmd)
-
-	cmd.Flags().StringVar(&o.Key, "key", "",
-		"path to the public key file, KMS URI or Kubernetes Secret")
+	o.Fulcio.AddFlags(cmd)
+	o.OIDC.AddFlags(cmd)
+	o.Registry.AddFlags(cmd)
+	cmd.Flags().StringArrayVar(&o.Key, "key", nil,
+		"path to the public key file, KMS URI, Kubernetes Secret, dns://<name> key published in DNS, "+
+			"fido2://<path> FIDO2 credential public key, a CBOR encoded COSE_Key whose signatures are WebAuthn assertions, "+
+			"or jwks://<host>/<path>[#<kid>] key of the JWKS served at https://<host>/<path>, selected by the keyid of the envelope signature if no kid is given. "+
+			"May be repeated to try several keys in order, e.g. during a key rotation: the first key validating the attestation is used and reported")
+
+	cmd.Flags().StringVar(&o.CertFromJWT, "cert-from-jwt", "",
+		"path or URL of a compact JWT whose x5c header delivers the signing certificate and its chain, and whose optional sct claim delivers a base64 detached SCT, "+
+			"used instead of --certificate. The certificate is verified up to the Fulcio roots or --certificate-chain as usual: the x5c chain only provides intermediates, never a trusted root. "+
+			"The JWT must be signed with an asymmetric alg, ES256/384/512, RS256/384/512, PS256/384/512 or EdDSA, and its signature is checked with the certificate key, "+
+			"which doesn't authenticate the JWT issuer. Its other claims, e.g. exp, are ignored")
+
+	cmd.Flags().StringVar(&o.KeyHistory, "key-history", "",
+		"path to a JSON key history, {\"keys\": [{\"key\": <key reference>, \"notBefore\": <RFC3339 time>, \"notAfter\": <RFC3339 time>}]}, used instead of --key. "+
+			"The key whose time range covers the signing time, from the tlog entry of --bundle or from --rfc3161-timestamp, is used and reported. "+
+			"The history must be signed, see --key-history-signature")
+
+	cmd.Flags().StringVar(&o.KeyHistorySig, "key-history-signature", "",
+		"path to the base64-encoded signature of the --key-history file, as written by cosign sign-blob, verified with --key-history-root-key without a tlog entry")
+
+	cmd.Flags().StringVar(&o.KeyHistoryRoot, "key-history-root-key", "",
+		"path to the public key file, or reference, of the root key signing the --key-history. It is the trust anchor of all the keys of the history, "+
+			"so it must be pinned by the verifier like a --key, and not be one of the keys it delegates to")
 
 	cmd.Flags().StringVar(&o.SignaturePath, "signature", "",
-		"path to base64-encoded signature over attestation in DSSE format")
+		"path, or s3://bucket/key or gs://bucket/object reference, to base64-encoded signature over attestation in DSSE format. "+
+			"Object references are fetched with the ambient cloud credentials. "+
+			"env://VAR reads the base64-encoded envelope from the environment variable VAR, whose value is scrubbed from the errors")
+
+	cmd.Flags().StringVar(&o.EnvelopeJSONPath, "envelope-json-path", "",
+		"JSONPath, e.g. $.attestation, of the DSSE envelope within the --signature JSON document. By default the whole document is the envelope")
+
+	cmd.Flags().StringVar(&o.PayloadPath, "payload", "",
+		"path to a gzip-compressed in-toto statement. --signature is then a detached signature over the compressed bytes, "+
+			"which is verified before the statement is decompressed and checked")
+
+	cmd.Flags().StringVar(&o.SignatureArchive, "signature-archive", "",
+		"path to a tar archive of DSSE envelopes. The first envelope of the requested predicate type that verifies is used")
+
+	cmd.Flags().StringVar(&o.FromImage, "from-image", "",
+		"reference to an image whose attestations, found at the tag `cosign triangulate --type attestation` prints, are verified against the blob. "+
+			"The certificate, tlog bundle and timestamp attached to each attestation are used unless given on the command line. The first attestation that verifies is used")
 
 	cmd.Flags().StringVar(&o.BundlePath, "bundle", "",
 		"path to bundle FILE")
 
+	cmd.Flags().StringVar(&o.BlobSignature, "blob-signature", "",
+		"path to a detached signature over the blob, verified with the same key or certificate as the attestation. Both must verify")
+
+	cmd.Flags().StringVar(&o.Provenance, "provenance", "",
+		"path to a SLSA provenance attestation, verified with the same key or certificate as the attestation. "+
+			"The blob must be one of its subjects, i.e. an output of the build. Both must verify")
+
+	cmd.Flags().StringVar(&o.AttestationChain, "attestation-chain", "",
+		"directory of attestations chained to the verified one, e.g. test then scan after build. "+
+			"Each must have the sha256 digest of the envelope of the previous one as a subject, and all must verify with the same key or certificate. "+
+			"The chain order is reported")
+
+	cmd.Flags().StringVar(&o.SaveBundle, "save-bundle", "",
+		"write a bundle of the verified attestation, its certificate and tlog entry to FILE for later offline verification with --bundle")
+
+	cmd.Flags().StringVar(&o.OutputEnvelope, "output-envelope", "",
+		"write the verified DSSE envelope to FILE byte for byte as it was read, after unwrapping it from PEM or --envelope-json-path, so that it can be forwarded and verified again")
+
+	cmd.Flags().StringVar(&o.OutputVSA, "output-vsa", "",
+		"write a SLSA verification summary attestation (VSA) of the verified blob, signed with --vsa-key, to FILE")
+
+	cmd.Flags().StringVar(&o.VSAKey, "vsa-key", "",
+		"path to the private key file, KMS URI or Kubernetes Secret signing the --output-vsa attestation")
+
+	cmd.Flags().StringVar(&o.VSAPolicyURI, "vsa-policy-uri", "",
+		"URI of the policy recorded in the --output-vsa attestation")
+
+	cmd.Flags().StringVar(&o.OutputLink, "output-link", "",
+		"write an in-toto link of the verification step, signed with --link-key, to FILE. The link records the verification command, "+
+			"the verified envelope as its material by digest, and no products, and its subject is the verified blob")
+
+	cmd.Flags().StringVar(&o.LinkKey, "link-key", "",
+		"path to the private key file, KMS URI or Kubernetes Secret signing the --output-link link")
+
+	cmd.Flags().StringVar(&o.LinkStepName, "link-step-name", "verify",
+		"in-toto step name of the --output-link link, as named in the layout")
+
+	cmd.Flags().StringVar(&o.MintClaim, "mint-claim", "",
+		"write a short-lived JWT, signed with --mint-claim-key, asserting the verified blob digest (sub), predicate type (predicateType) "+
+			"and attestation signer (signer) to FILE, for services trusting the key that can't verify the attestation themselves")
+
+	cmd.Flags().StringVar(&o.MintClaimKey, "mint-claim-key", "",
+		"path to the private key file, KMS URI or Kubernetes Secret signing the --mint-claim JWT, an ECDSA P-256, RSA or Ed25519 key")
+
+	cmd.Flags().DurationVar(&o.MintClaimTTL, "mint-claim-ttl", 5*time.Minute,
+		"lifetime of the --mint-claim JWT, from its issuance to its exp claim")
+
+	cmd.Flags().StringVar(&o.EmitEdge, "emit-edge", "",
+		"after a successful verification, append an edge of the supply-chain graph to the JSON Lines FILE, created if needed, "+
+			"for a separate tool to assemble the graph from many verifications. The edge is a line of canonical JSON with the "+
+			"verifier (verifier, verifierVersion), the verified blob digest and name (subject, subjectName), the predicate type (predicateType), "+
+			"the attestation signer (signer) and the RFC 3339 UTC time of the verification (timestamp)")
+
+	cmd.Flags().BoolVar(&o.RelaySign, "relay-sign", false,
+		"after verification, sign the same in-toto statement again with --relay-key, or keyless with the --fulcio-url and --oidc-* options, "+
+			"and write the new DSSE envelope to --relay-output-signature")
+
+	cmd.Flags().StringVar(&o.RelayKey, "relay-key", "",
+		"path to the private key file, KMS URI or Kubernetes Secret signing the --relay-sign attestation. Keyless signing is used if unset")
+
+	cmd.Flags().StringVar(&o.RelayOutputSignature, "relay-output-signature", "",
+		"write the DSSE envelope of the --relay-sign attestation to FILE")
+	_ = cmd.Flags().SetAnnotation("relay-output-signature", cobra.BashCompFilenameExt, []string{})
+
+	cmd.Flags().StringVar(&o.RelayBundle, "relay-bundle", "",
+		"write a bundle of the --relay-sign attestation, with the signing certificate or key and tlog entry, to FILE")
+	_ = cmd.Flags().SetAnnotation("relay-bundle", cobra.BashCompFilenameExt, []string{})
+
+	cmd.Flags().BoolVar(&o.RelayTlogUpload, "relay-tlog-upload", true,
+		"whether to upload the --relay-sign attestation to the transparency log")
+
+	cmd.Flags().BoolVarP(&o.SkipConfirmation, "yes", "y", false,
+		"skip confirmation prompts for non-destructive operations")
+
+	cmd.Flags().StringVar(&o.OutputMaterials, "output-materials", "",
+		"write the materials of a verified SLSA provenance to FILE as a JSON list. "+
+			"Ignored with a warning for other predicate types")
+
+	cmd.Flags().StringVar(&o.OutputSPDXGraph, "output-spdx-graph", "",
+		"write the relationships (e.g. CONTAINS, DEPENDS_ON) of a verified SPDX document to FILE as a JSON graph of nodes and edges, "+
+			"sorted for stable output. Ignored with a warning for other predicate types")
+
+	cmd.Flags().StringVar(&o.Report, "report", "",
+		"write a report of the verification inputs and outcome, passed or the failed checks, to FILE. "+
+			"The report is canonical JSON with sorted keys, byte-identical for identical verifications")
+
+	cmd.Flags().StringVar(&o.ReportTime, "report-time", "",
+		"RFC 3339 time of the verification to record in the --report, e.g. 2023-10-01T12:00:00Z. "+
+			"The report has no time otherwise, so that it is reproducible")
+
+	cmd.Flags().StringVar(&o.TimingJSON, "timing-json", "",
+		"write the durations in seconds of the verification steps to FILE as a JSON object, for local profiling: "+
+			"key-load, envelope-parse, signature-verify, tlog, sct, claim, policy and total. Steps which didn't run are omitted, "+
+			"and the durations of a step run several times, e.g. for each envelope of an archive, are summed")
+
 	cmd.Flags().BoolVar(&o.CheckClaims, "check-claims", true,
 		"if true, verifies the provided blob's sha256 digest exists as an in-toto subject within the attestation. If false, only the DSSE envelope is verified.")
 
+	cmd.Flags().BoolVar(&o.AllSubjectsMatch, "all-subjects-match", false,
+		"if true, every in-toto subject within the attestation must match the provided blob, instead of any one of them")
+
+	cmd.Flags().BoolVar(&o.MatchComputableDigests, "match-computable-digests", false,
+		"if true, a subject matches the blob only if all its digests that cosign can compute (sha256, sha384, sha512, sha3-256 and sha3-512) match, and at least one does. "+
+			"Digests of other algorithms are ignored. By default a subject matches if its digest of the --hash-algorithm does. "+
+			"Applies to each subject checked, with or without --all-subjects-match")
+
+	cmd.Flags().StringVar(&o.BlobDigest, "blob-digest", "",
+		"sha256 digest of the blob, as sha256:<hex> or <hex>, checked against the in-toto subjects instead of a blob file. No blob path is passed with this flag")
+
+	cmd.Flags().BoolVar(&o.StableBlob, "stable-blob", false,
+		"hash the blob from a file kept open until the end of the verification, and fail if the file is modified or replaced in the meantime, "+
+			"as detected by its size, modification time and file identity, so that the verified bytes are those hashed")
+
+	cmd.Flags().StringVar(&o.BlobRange, "blob-range", "",
+		"inclusive byte range of the blob file, as <start>-<end>, whose digest is checked against the in-toto subjects instead of the digest of the whole file, e.g. 0-1023 for its first KiB. "+
+			"The range must be within the file. The attestation must describe the same range: unless --subject-name or --subject-name-regexp is set, "+
+			"the matching subject must be named <name>#bytes=<start>-<end>, e.g. archive.tar#bytes=0-1023")
+
+	cmd.Flags().StringArrayVar(&o.BlobParts, "blob-part", nil,
+		"path to a part of the blob, checked against the in-toto subjects instead of a blob file. May be repeated: the blob is the concatenation of the parts "+
+			"in the order of the flags, which must be that of the parts in the attested blob. The parts are streamed, never joined on disk. No blob path is passed with this flag")
+
+	cmd.Flags().StringVar(&o.CDCDigest, "cdc-digest", "",
+		"check the content-defined chunk tree digest of the blob against the in-toto subjects instead of its sha256 digest, with the chunker fastcdc or "+
+			"fastcdc:min=<bytes>,avg=<bytes>,max=<bytes> (default 16384, 65536 and 262144; avg a power of two, 64 <= min < avg < max <= 268435456). "+
+			"The blob is split with FastCDC normalized chunking (level 2) whose gear table entry of byte b is the first 8 bytes of sha256(b), big-endian; "+
+			"the chunks are the leaves of an RFC 6962 sha256 Merkle tree, and the hex encoded root is matched against the subject digest "+
+			"keyed fastcdc-<min>-<avg>-<max>")
+
+	cmd.Flags().StringVar(&o.BlobResolver, "blob-resolver", "",
+		"command line of a blob resolver plugin fetching the blob checked against the in-toto subjects, whose content ID is passed instead of a blob path. "+
+			"The plugin is run with a resolve argument appended, reads the content ID followed by a newline from stdin and writes the content to stdout, "+
+			"exiting with 0 on success, 2 if the content ID is unknown and another status on failure. The content is verified like a local blob")
+
+	cmd.Flags().StringVar(&o.MatchImageConfig, "match-image-config", "",
+		"reference to an image whose config blob digest, not its manifest digest, is checked against the in-toto subjects instead of a blob file. "+
+			"Indexes are rejected, reference a platform image instead. No blob path is passed with this flag")
+
+	cmd.Flags().StringVar(&o.MatchAnnotationDigest, "match-annotation-digest", "",
+		"key of an annotation of the --annotation-image manifest, e.g. org.opencontainers.image.base.digest, whose sha256 digest value is checked against the in-toto subjects instead of a blob file. "+
+			"No blob path is passed with this flag")
+
+	cmd.Flags().StringVar(&o.AnnotationImage, "annotation-image", "",
+		"reference to the image, or index, whose manifest annotation --match-annotation-digest reads")
+
+	cmd.Flags().StringVar(&o.Decompress, "decompress", "",
+		"compression of the blob (zstd). The blob is decompressed before checking its digest against the in-toto subjects")
+
+	cmd.Flags().BoolVar(&o.BlobJSONCanonical, "blob-json-canonical", false,
+		"if true, the blob must be JSON and its JCS (RFC 8785) canonical form is hashed for the claim check, so formatting and key order don't matter")
+
+	cmd.Flags().BoolVar(&o.RejectUnknownPredicateFields, "reject-unknown-predicate-fields", false,
+		"if true, fail verification when the predicate has top-level fields not listed in --predicate-allowed-fields")
+
+	cmd.Flags().StringSliceVar(&o.PredicateAllowedFields, "predicate-allowed-fields", nil,
+		"top-level predicate fields allowed with --reject-unknown-predicate-fields. May be repeated or comma separated")
+
+	cmd.Flags().StringArrayVar(&o.RequirePredicateFields, "require-predicate-field", nil,
+		"jsonpath=value requirement on a predicate field, e.g. environment=production or $.builder.id=https://ci.example.com. "+
+			"A string field must equal the value, any other the compact JSON value, e.g. true. May be repeated, all must hold")
+
+	cmd.Flags().StringSliceVar(&o.AllowedSignatureAlgorithms, "allowed-signature-algorithms", nil,
+		"signature algorithms the verifying signatures may use, e.g. ecdsa-sha256,ed25519. The algorithm is inferred from the signature itself, "+
+			"and is one of ed25519, ecdsa-<sha256|sha384|sha512> or rsa<bits>-<pkcs1v15|pss>-<sha256|sha384|sha512> such as rsa2048-pkcs1v15-sha256. May be repeated or comma separated")
+
+	cmd.Flags().StringVar(&o.RekorLocalTree, "rekor-local-tree", "",
+		"directory mirroring the Rekor merkle tree, holding a signed \"checkpoint\" and the hex encoded \"leaves\" hashes one per line. "+
+			"The tlog entry of the --bundle is verified to be included in it, without querying Rekor")
+
+	cmd.Flags().StringVar(&o.AfterCheckpoint, "after-checkpoint", "",
+		"path to a pinned Rekor checkpoint, as served by /api/v1/log, which the tlog entry must have been logged after: "+
+			"its log index must be at least the checkpoint tree size and, if the checkpoint has a timestamp, it must not be integrated before it. "+
+			"The checkpoint must be signed by the log of the entry")
+
+	cmd.Flags().StringVar(&o.RequireTlogEntryKind, "require-tlog-entry-kind", "",
+		"kind of the tlog entry required, optionally with its API version, e.g. dsse or intoto:0.0.2. Verification fails if the entry is of another kind")
+
+	cmd.Flags().StringSliceVar(&o.RekorWitnessKeys, "rekor-witness-key", nil,
+		"path to the public key of a witness, KMS URI or Kubernetes Secret. The Rekor checkpoint must be co-signed by at least one of the witness keys. May be repeated")
+
+	cmd.Flags().Int64Var(&o.RekorTreeID, "rekor-tree-id", 0,
+		"ID of the Rekor tree (log shard) the tlog entry must belong to, as named by the origin of its checkpoint. Requires an online tlog lookup")
+
+	cmd.Flags().StringArrayVar(&o.RekorURLs, "rekor-url", []string{DefaultRekorURL},
+		"address of rekor STL server. May be repeated to look the tlog entry up in several logs with --min-tlog-entries, "+
+			"the first one is used for the other tlog checks")
+
+	cmd.Flags().IntVar(&o.MinTlogEntries, "min-tlog-entries", 0,
+		"require the tlog entry of the attestation to be found in at least N distinct transparency logs of --rekor-url, by log ID. "+
+			"Each entry is verified against the trusted Rekor public key of its log, and the logs holding the entry are reported")
+
+	cmd.Flags().BoolVar(&o.RequireCTInclusion, "require-ct-inclusion", false,
+		"require the signing certificate to be included in the CT log of --ct-log-url, beyond the promise of its SCT: an inclusion proof of the certificate "+
+			"in the current tree of the log is fetched and verified against the tree head, signed with the CT log key of the SCT. "+
+			"Requires connectivity to the CT log, and fails if the certificate isn't included yet")
+
+	cmd.Flags().StringVar(&o.CTLogURL, "ct-log-url", DefaultCTLogURL,
+		"address of the CT log queried by --require-ct-inclusion")
+
+	cmd.Flags().StringVar(&o.VerifyLinked, "verify-linked", "",
+		"directory of the documents referenced by digest from the predicate. Every file digest referenced by a SLSA provenance predicate must match a file in it")
+
+	cmd.Flags().BoolVar(&o.RequireSBOM, "require-sbom-attestation", false,
+		"require the attestation to be an SPDX or CycloneDX SBOM, selected with --type, that parses and lists at least one component")
+
+	cmd.Flags().StringVar(&o.SLSABuilderID, "slsa-builder-id", "",
+		"require the attestation to be a SLSA provenance whose builder ID (builder.id in v0.2, runDetails.builder.id in v1) equals this value")
+
+	cmd.Flags().BoolVar(&o.RequireReproducible, "require-reproducible", false,
+		"require the attestation to be a SLSA v0.1 or v0.2 provenance whose metadata.reproducible is true. Other predicate types, including SLSA v1, fail since they don't record reproducibility")
+
+	cmd.Flags().StringVar(&o.StatementType, "statement-type", "",
+		"the only in-toto statement _type to accept, e.g. a vendor variant of https://in-toto.io/Statement/v0.1. By default https://in-toto.io/Statement/v0.1 and https://in-toto.io/Statement/v1 are accepted")
+
+	cmd.Flags().StringVar(&o.PredicateVersionConstraint, "predicate-version-constraint", "",
+		"accept any version of the --type predicate satisfying this constraint rather than its version only, e.g. \">=0.2\" for the SLSA provenance from v0.2 on. "+
+			"The version ends the predicate type URI, as in https://slsa.dev/provenance/v0.2. The constraint is a comma-separated list of comparisons (=, !=, <, <=, >, >=) which must all hold")
+
+	cmd.Flags().StringVar(&o.ParseStrictness, "parse-strictness", "strict",
+		"how strictly the in-toto statement is parsed (strict|lenient|schema). strict decodes it as an in-toto statement and rejects unknown top-level fields. "+
+			"lenient ignores them. schema also validates it against the embedded in-toto statement schema, "+
+			"which requires a non-empty subject list with hex encoded digests and an object predicate. At every level, the _type must be a standard one or --statement-type")
+
+	cmd.Flags().StringVar(&o.IdentityPredicateMap, "identity-predicate-map", "",
+		"path to a JSON map of the predicate types each certificate identity may attest to, "+
+			"{\"identities\": [{\"identity\": <identity>, \"identityRegexp\": <regexp>, \"predicateTypes\": [<type or URI>, ...]}]}, each entry having one of identity or identityRegexp. "+
+			"Verification fails unless an entry matching a SAN of the verified signing certificate lists the predicate type of the statement. Requires verifying against a certificate")
+
+	cmd.Flags().StringVar(&o.PredicateDecrypt, "predicate-decrypt", "",
+		"encryption of the predicate, decrypted after the signature over the ciphertext is verified and before the claims and policies are checked (age). "+
+			"The predicate must be a string holding the armored or base64 encoded ciphertext of a JSON document")
+
+	cmd.Flags().StringVar(&o.AgeIdentity, "age-identity", "",
+		"path to the age identity file decrypting the predicate with --predicate-decrypt age")
+
+	cmd.Flags().StringVar(&o.SubjectName, "subject-name", "",
+		"require the in-toto subject with this name to match the provided blob. Verification fails if no subject has this name, or if it has a different digest")
+
+	cmd.Flags().IntVar(&o.SubjectIndex, "subject-index", -1,
+		"require the in-toto subject at this 0-based position of the statement to match the provided blob, for ordered subjects whose position is meaningful. "+
+			"A subject matching the blob at another position doesn't satisfy it. -1 lets any subject match")
+
+	cmd.Flags().StringVar(&o.SubjectNameRegexp, "subject-name-regexp", "",
+		"require the name of an in-toto subject matching the provided blob to match this regular expression, e.g. ^pkg:. With --all-subjects-match, every subject name must match it. Cannot be combined with --subject-name")
+
+	cmd.Flags().BoolVar(&o.PredicateOnlySignature, "predicate-only-signature", false,
+		"verify an envelope signing only the predicate, with a payload of any type, rather than an in-toto statement. "+
+			"The subject is supplied with --subject-digest and --subject-name, and matched against the provided blob. "+
+			"This is a weaker binding: the signature doesn't cover the subject, so it only proves the signer vouched for the predicate, "+
+			"and anyone may pair the predicate with another artifact. Only use it if the subject digest comes from a trusted source")
+
+	cmd.Flags().StringVar(&o.SubjectDigest, "subject-digest", "",
+		"digest of the subject of a --predicate-only-signature envelope, as <algorithm>:<hex>, e.g. sha256:<hex>")
+
+	cmd.Flags().BoolVar(&o.RequireSubjectURIAndDigest, "require-subject-uri-and-digest", false,
+		"require every in-toto subject matching the blob to have both a uri and a digest")
+
+	cmd.Flags().BoolVar(&o.RequireSortedSubjects, "require-sorted-subjects", false,
+		"require the in-toto subjects to be signed in canonical order, sorted by name then digest, to detect a reordering of the subjects of a multi-subject "+
+			"envelope. Digests are compared as their <algorithm>:<hex> pairs sorted by algorithm and joined by commas. Only for producers committing to this order")
+
+	cmd.Flags().StringVar(&o.RequireKeyID, "require-keyid", "",
+		"require the DSSE signature bearing this keyid to validate, rather than any signature on the envelope")
+
+	cmd.Flags().IntVar(&o.MaxSignatures, "max-signatures", DefaultMaxSignatures,
+		"reject DSSE envelopes carrying more than this number of signatures before verifying any of them")
+
+	cmd.Flags().StringVar(&o.HashAlgorithm, "hash-algorithm", "sha256",
+		"hash algorithm of the blob digest matched against the in-toto subjects (sha256|sha3-256|sha3-512)")
+
+	cmd.Flags().StringVar(&o.DigestEncoding, "digest-encoding", "hex",
+		"encoding of the in-toto subject digests (hex|multihash). multihash digests are hex-encoded multihashes whose algorithm must match the blob digest's")
+
+	cmd.Flags().StringVar(&o.VerifierPlugin, "verifier-plugin", "",
+		"command of an external program the DSSE signature verification is delegated to, instead of --key, --sk or --certificate")
+
+	cmd.Flags().StringVar(&o.DSSEPAE, "dsse-pae", "standard",
+		"name of the DSSE pre-authentication encoding the envelope signatures are verified over. "+
+			"Only standard is available, unless a custom build registers others to interoperate with non-conforming signers. "+
+			"A custom PAE weakens verification if it doesn't encode the payload type and payload unambiguously")
+
+	cmd.Flags().BoolVar(&o.Explain, "explain", false,
+		"print a step by step narrative of the verification, and a suggested fix if it fails")
+
+	cmd.Flags().BoolVar(&o.FailOnWarnings, "fail-on-warnings", false,
+		"fail verification if any warning was emitted, e.g. a skipped check or archive member, listing the warnings as errors")
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "text",
+		"output format for the verification result (json|text)")
+
+	cmd.Flags().StringSliceVar(&o.PinSPKI, "pin-spki", nil,
+		"base64-encoded SHA-256 digest of the SubjectPublicKeyInfo the signing certificate must match. May be repeated. "+
+			"Without --certificate-identity and --certificate-oidc-issuer, the pin replaces the identity verification, which is only meaningful for a long-lived key, "+
+			"not the ephemeral keys of Fulcio certificates")
+
+	cmd.Flags().StringVar(&o.RequireCertPolicyOID, "require-cert-policy-oid", "",
+		"certificate policy OID, in dotted form, the signing certificate must carry")
+
+	cmd.Flags().StringVar(&o.RequireIntermediateSKI, "require-intermediate-ski", "",
+		"hex subject key identifier, optionally colon-separated, of an intermediate CA the chain built for the signing certificate must go through, "+
+			"e.g. to make sure a pinned intermediate issued it rather than any chaining to the roots")
+
+	cmd.Flags().StringVar(&o.CertSPIFFEID, "certificate-spiffe-id", "",
+		"SPIFFE ID a URI SAN of the signing certificate must be exactly, e.g. spiffe://example.org/ci/build. "+
+			"Both are normalized first: the scheme and trust domain are lowercased and a trailing slash is dropped. "+
+			"It can replace --certificate-identity, and is checked along with --certificate-oidc-issuer or --certificate-oidc-issuer-regexp if given")
+
+	cmd.Flags().DurationVar(&o.MaxCertLifetime, "max-cert-lifetime", 0,
+		"maximum validity period (NotAfter - NotBefore) of the signing certificate, e.g. 20m. "+
+			"Longer-lived certificates are rejected. 0 disables the check")
+
+	cmd.Flags().DurationVar(&o.ClockSkew, "clock-skew", 10*time.Second,
+		"tolerated clock skew between signer and verifier. The certificate validity period, the key ranges of "+
+			"--key-history and the time of the --after-checkpoint checkpoint are widened by this amount on both "+
+			"ends when checked against the signing time. Larger values weaken these checks: a certificate is "+
+			"still accepted this long after it expired, or before it was valid, and a key this long after it "+
+			"was rotated out. The validity of the TSA certificate chain is checked against the TSA's own time "+
+			"and isn't widened")
+
+	cmd.Flags().BoolVar(&o.RequireSigningTimeInValidity, "require-signing-time-in-validity", false,
+		"require the tlog integrated time or RFC3161 timestamp to lie within the signing certificate's validity, "+
+			"failing if neither is available instead of checking against the current time")
+
+	cmd.Flags().StringVar(&o.TrustCacheDir, "trust-cache-dir", "",
+		"directory caching the Rekor, CT log and Fulcio keys and certificates fetched from TUF across invocations, keyed by the digest of the TUF root. "+
+			"Each cached key and certificate is checked against the length and digest of its target in the local TUF targets metadata whenever it is read, "+
+			"and the cache is used until the local TUF timestamp or targets metadata expire, then fetched again. "+
+			"Trust material overridden by the SIGSTORE_* environment variables is not cached")
+
+	cmd.Flags().BoolVar(&o.RefreshTrust, "refresh-trust", false,
+		"fetch the trust material from TUF again and replace it in --trust-cache-dir, even if it has not expired")
+
+	cmd.Flags().BoolVar(&o.NoNetwork, "no-network", false,
+		"make all the connections of the command an error, e.g. to Rekor, a CT log, a JWKS or DNS key, a registry or the TUF mirror, "+
+			"rather than silently succeeding or hanging, so that an air-gapped verification fails loudly if it reaches out. "+
+			"Implies --offline. The TUF trust material must be cached and unexpired, and KMS, Kubernetes and GitLab keys, whose clients can't be guarded, are rejected")
+
+	cmd.Flags().StringVar(&o.TrustPolicy, "trust-policy", "",
+		"path to a YAML or JSON trust policy bundling verification requirements. "+
+			"Flags passed on the command line override values from the file")
+	_ = cmd.Flags().SetAnnotation("trust-policy", cobra.BashCompFilenameExt, []string{"yaml", "yml", "json"})
+
 	cmd.Flags().StringVar(&o.RFC3161TimestampPath, "rfc3161-timestamp", "",
 		"path to RFC3161 timestamp FILE")
 }
@@ -0,0 +1,426 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Validate checks that the verify-blob-attestation flags, and the blob path
+// argument, empty if none, can be used together. The values of the flags are
+// parsed, and checked, by the verification.
+func (o *VerifyBlobAttestationOptions) Validate(blobPath string) error {
+	if err := o.validateInputs(); err != nil {
+		return err
+	}
+	if o.PayloadPath != "" {
+		return o.validatePayload(blobPath)
+	}
+	for _, validate := range []func(string) error{
+		o.validateKeys,
+		o.validateClaims,
+		o.validateBlob,
+		o.validateOutputs,
+		o.validateCertificate,
+		o.validateTlog,
+	} {
+		if err := validate(blobPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// key reports whether a key, rather than a certificate, verifies the
+// attestation.
+func (o *VerifyBlobAttestationOptions) key() bool {
+	return len(o.Key) > 0 || o.KeyHistory != "" || o.SecurityKey.Use || o.VerifierPlugin != ""
+}
+
+// validateInputs checks the flags of the attestation to verify.
+func (o *VerifyBlobAttestationOptions) validateInputs() error {
+	switch {
+	case NOf(o.SignaturePath, o.BundlePath, o.SignatureArchive, o.FromImage) == 0:
+		return errors.New("please specify path to the DSSE envelope signature via --signature, --bundle or --signature-archive, or an image with --from-image")
+	case o.SignatureArchive != "" && NOf(o.SignaturePath, o.BundlePath) > 0:
+		return errors.New("--signature-archive cannot be combined with --signature or --bundle")
+	case o.FromImage != "" && NOf(o.SignaturePath, o.BundlePath, o.SignatureArchive) > 0:
+		return errors.New("--from-image cannot be combined with --signature, --bundle or --signature-archive")
+	case o.RelaySign && o.RelayOutputSignature == "":
+		return errors.New("--relay-sign requires --relay-output-signature")
+	case o.NoNetwork && NOf(o.FromImage, o.MatchImageConfig, o.AnnotationImage) > 0:
+		return errors.New("--no-network cannot be combined with --from-image, --match-image-config or --annotation-image, which pull from a registry")
+	case o.EnvelopeJSONPath != "" && o.SignaturePath == "":
+		return errors.New("--envelope-json-path requires --signature")
+	}
+	return nil
+}
+
+// validatePayload checks the flags of the verification of a compressed
+// statement, --payload, most of which don't apply to it.
+func (o *VerifyBlobAttestationOptions) validatePayload(blobPath string) error {
+	switch {
+	case o.SignatureArchive != "":
+		return errors.New("--payload cannot be combined with --signature-archive")
+	case o.FromImage != "":
+		return errors.New("--payload cannot be combined with --from-image")
+	case o.BlobSignature != "":
+		return errors.New("--payload cannot be combined with --blob-signature")
+	case o.Provenance != "":
+		return errors.New("--payload cannot be combined with --provenance")
+	case o.AttestationChain != "":
+		return errors.New("--payload cannot be combined with --attestation-chain")
+	case o.Report != "":
+		return errors.New("--payload cannot be combined with --report")
+	case len(o.Key) > 1:
+		return errors.New("--payload cannot be combined with multiple --key values")
+	case o.OutputEnvelope != "":
+		return errors.New("--payload cannot be combined with --output-envelope, the signature is detached")
+	case o.PredicateDecrypt != "":
+		return errors.New("--payload cannot be combined with --predicate-decrypt")
+	case o.AfterCheckpoint != "":
+		return errors.New("--payload cannot be combined with --after-checkpoint")
+	case o.BlobResolver != "":
+		return errors.New("--payload cannot be combined with --blob-resolver")
+	case o.IdentityPredicateMap != "":
+		return errors.New("--payload cannot be combined with --identity-predicate-map")
+	case o.CertFromJWT != "":
+		return errors.New("--payload cannot be combined with --cert-from-jwt")
+	case o.KeyHistory != "":
+		return errors.New("--payload cannot be combined with --key-history")
+	case o.BlobDigest != "":
+		return errors.New("--payload cannot be combined with --blob-digest")
+	case o.MatchImageConfig != "" || o.MatchAnnotationDigest != "":
+		return errors.New("--payload cannot be combined with --match-image-config or --match-annotation-digest")
+	case o.RelaySign:
+		return errors.New("--payload cannot be combined with --relay-sign")
+	case o.EnvelopeJSONPath != "":
+		return errors.New("--payload cannot be combined with --envelope-json-path, the signature is detached")
+	case o.OutputSPDXGraph != "":
+		return errors.New("--payload cannot be combined with --output-spdx-graph")
+	case o.RequireReproducible:
+		return errors.New("--payload cannot be combined with --require-reproducible")
+	case o.MatchComputableDigests:
+		return errors.New("--payload cannot be combined with --match-computable-digests")
+	case o.BlobRange != "":
+		return errors.New("--payload cannot be combined with --blob-range")
+	case o.PredicateOnlySignature:
+		return errors.New("--payload cannot be combined with --predicate-only-signature, the payload is a statement")
+	case o.TrustCacheDir != "":
+		return errors.New("--payload cannot be combined with --trust-cache-dir, no trust material is fetched")
+	case o.OutputLink != "":
+		return errors.New("--payload cannot be combined with --output-link")
+	case o.MinTlogEntries > 0:
+		return errors.New("--payload cannot be combined with --min-tlog-entries")
+	case o.RequireCTInclusion:
+		return errors.New("--payload cannot be combined with --require-ct-inclusion")
+	case o.StableBlob:
+		return errors.New("--payload cannot be combined with --stable-blob")
+	case len(o.BlobParts) > 0:
+		return errors.New("--payload cannot be combined with --blob-part")
+	case o.CDCDigest != "":
+		return errors.New("--payload cannot be combined with --cdc-digest")
+	case o.MintClaim != "":
+		return errors.New("--payload cannot be combined with --mint-claim")
+	case o.EmitEdge != "":
+		return errors.New("--payload cannot be combined with --emit-edge")
+	case len(o.AllowedSignatureAlgorithms) > 0:
+		return errors.New("--payload cannot be combined with --allowed-signature-algorithms")
+	case o.RequireSubjectURIAndDigest:
+		return errors.New("--payload cannot be combined with --require-subject-uri-and-digest")
+	case o.RequireSortedSubjects:
+		return errors.New("--payload cannot be combined with --require-sorted-subjects")
+	case o.CheckClaims && blobPath == "":
+		return errors.New("a blob is required to check the statement subjects, or use --check-claims=false")
+	}
+	return nil
+}
+
+// validateKeys checks the flags of the key or certificate verifying the
+// attestation.
+func (o *VerifyBlobAttestationOptions) validateKeys(string) error {
+	if o.KeyHistory != "" {
+		switch {
+		case len(o.Key) > 0 || NOf(o.SecurityKey.Use, o.CertVerify.Cert, o.CertFromJWT, o.VerifierPlugin) > 0:
+			return errors.New("--key-history cannot be combined with --key, --sk, --certificate, --cert-from-jwt or --verifier-plugin")
+		case o.SignatureArchive != "" || o.FromImage != "":
+			return errors.New("--key-history cannot be combined with --signature-archive or --from-image")
+		case o.KeyHistorySig == "" || o.KeyHistoryRoot == "":
+			return errors.New("--key-history requires --key-history-signature and --key-history-root-key")
+		}
+		// The key of the history is used as --key.
+		return nil
+	}
+	switch {
+	case o.BlobSignature != "" && len(o.Key) == 0 && !o.SecurityKey.Use && o.CertVerify.Cert == "":
+		return errors.New("--blob-signature requires --key, --sk or --certificate")
+	case !o.key() && NOf(o.CertVerify.Cert, o.CertFromJWT, o.BundlePath) == 0:
+		return errors.New("provide a key with --key or --sk, a verifier plugin with --verifier-plugin, a certificate to verify against with --certificate or --cert-from-jwt, or a bundle with --bundle")
+	case o.CertFromJWT != "" && (o.key() || o.CertVerify.Cert != ""):
+		return errors.New("--cert-from-jwt cannot be combined with --key, --sk, --certificate or --verifier-plugin")
+	case len(o.Key) > 1 && (o.SignatureArchive != "" || o.FromImage != ""):
+		return errors.New("multiple --key values cannot be combined with --signature-archive or --from-image")
+	case len(o.Key) > 0 && o.SecurityKey.Use:
+		return &KeyParseError{}
+	case o.VerifierPlugin != "" && (len(o.Key) > 0 || o.SecurityKey.Use || o.CertVerify.Cert != ""):
+		return errors.New("--verifier-plugin cannot be combined with --key, --sk or --certificate")
+	}
+	return nil
+}
+
+// validateClaims checks the flags of the checks of the statement.
+func (o *VerifyBlobAttestationOptions) validateClaims(string) error {
+	if o.Provenance != "" && !o.CheckClaims {
+		return errors.New("--provenance cannot be used with --check-claims=false, the blob must be a subject of the provenance")
+	}
+	for flag, set := range map[string]bool{
+		"--subject-name":                   o.SubjectName != "",
+		"--subject-index":                  o.SubjectIndex != -1,
+		"--blob-json-canonical":            o.BlobJSONCanonical,
+		"--require-subject-uri-and-digest": o.RequireSubjectURIAndDigest,
+		"--require-sbom-attestation":       o.RequireSBOM,
+	} {
+		if set && !o.CheckClaims {
+			return fmt.Errorf("%s cannot be used with --check-claims=false", flag)
+		}
+	}
+	switch {
+	case o.SubjectIndex < -1:
+		return errors.New("--subject-index must not be negative")
+	case o.MaxSignatures < 0:
+		return fmt.Errorf("--max-signatures must be positive, got %d", o.MaxSignatures)
+	case !o.PredicateOnlySignature && o.SubjectDigest != "":
+		return errors.New("--subject-digest requires --predicate-only-signature")
+	case o.PredicateOnlySignature && o.SubjectDigest == "":
+		return errors.New("--predicate-only-signature requires --subject-digest, the subject of the predicate")
+	case o.PredicateOnlySignature && !o.CheckClaims:
+		return errors.New("--predicate-only-signature cannot be used with --check-claims=false, the subject must be matched against the blob")
+	case o.PredicateOnlySignature && (o.PredicateDecrypt != "" || o.PredicateVersionConstraint != ""):
+		return errors.New("--predicate-only-signature cannot be combined with --predicate-decrypt or --predicate-version-constraint")
+	case o.IdentityPredicateMap != "" && o.key():
+		return errors.New("--identity-predicate-map can only be used when verifying against a certificate")
+	case len(o.PredicateAllowedFields) > 0 && !o.RejectUnknownPredicateFields:
+		return errors.New("--predicate-allowed-fields requires --reject-unknown-predicate-fields")
+	}
+	return nil
+}
+
+// validateBlob checks the flags of how the blob is read, or its digest
+// obtained.
+func (o *VerifyBlobAttestationOptions) validateBlob(blobPath string) error {
+	if blobPath == "" && o.CheckClaims && NOf(o.BlobDigest, o.MatchImageConfig, o.MatchAnnotationDigest) == 0 && len(o.BlobParts) == 0 {
+		return errors.New("no path to blob passed in, run `cosign verify-blob-attestation -h` for more help")
+	}
+	if o.BlobResolver != "" {
+		switch {
+		case blobPath == "":
+			return errors.New("--blob-resolver requires the content ID of the blob as argument")
+		case !o.CheckClaims:
+			return errors.New("--blob-resolver cannot be used with --check-claims=false")
+		case o.BlobSignature != "":
+			return errors.New("--blob-resolver cannot be combined with --blob-signature, which needs a blob file")
+		}
+	}
+	if len(o.BlobParts) > 0 {
+		switch {
+		case blobPath != "":
+			return errors.New("--blob-part cannot be combined with a blob path, the blob is the concatenation of the parts")
+		case !o.CheckClaims:
+			return errors.New("--blob-part cannot be used with --check-claims=false")
+		case slices.Contains(o.BlobParts, ""):
+			return errors.New("--blob-part requires the path of a part")
+		case NOf(o.BlobDigest, o.MatchImageConfig, o.MatchAnnotationDigest) > 0:
+			return errors.New("--blob-part cannot be combined with --blob-digest, --match-image-config or --match-annotation-digest")
+		case o.BlobResolver != "" || o.BlobRange != "" || o.StableBlob || o.BlobSignature != "":
+			return errors.New("--blob-part cannot be combined with --blob-resolver, --blob-range, --stable-blob or --blob-signature, which need a blob file")
+		}
+	}
+	if o.CDCDigest != "" {
+		switch {
+		case !o.CheckClaims:
+			return errors.New("--cdc-digest cannot be used with --check-claims=false")
+		case NOf(o.BlobDigest, o.MatchImageConfig, o.MatchAnnotationDigest) > 0:
+			return errors.New("--cdc-digest cannot be combined with --blob-digest, --match-image-config or --match-annotation-digest, which don't read a blob")
+		case o.StableBlob || o.MatchComputableDigests:
+			return errors.New("--cdc-digest cannot be combined with --stable-blob or --match-computable-digests")
+		case o.HashAlgorithm != "" && o.HashAlgorithm != "sha256":
+			return fmt.Errorf("--cdc-digest cannot be combined with --hash-algorithm %s, the chunk tree is hashed with sha256", o.HashAlgorithm)
+		}
+	}
+	if o.BlobDigest != "" {
+		switch {
+		case blobPath != "":
+			return errors.New("--blob-digest cannot be combined with a blob path")
+		case !o.CheckClaims:
+			return errors.New("--blob-digest cannot be used with --check-claims=false")
+		case o.Decompress != "" || o.BlobJSONCanonical || o.BlobSignature != "":
+			return errors.New("--blob-digest cannot be combined with --decompress, --blob-json-canonical or --blob-signature, which need the blob")
+		case o.HashAlgorithm != "" && o.HashAlgorithm != "sha256":
+			return errors.New("--blob-digest only supports sha256 digests")
+		}
+	}
+	if o.BlobRange != "" {
+		switch {
+		case blobPath == "":
+			return errors.New("--blob-range requires a blob path")
+		case !o.CheckClaims:
+			return errors.New("--blob-range cannot be used with --check-claims=false")
+		case o.BlobResolver != "" || o.Decompress != "" || o.BlobJSONCanonical:
+			return errors.New("--blob-range cannot be combined with --blob-resolver, --decompress or --blob-json-canonical, the range is of the blob file")
+		}
+	}
+	if o.MatchComputableDigests {
+		switch {
+		case !o.CheckClaims:
+			return errors.New("--match-computable-digests cannot be used with --check-claims=false")
+		case NOf(o.BlobDigest, o.MatchImageConfig, o.MatchAnnotationDigest) > 0:
+			return errors.New("--match-computable-digests cannot be combined with --blob-digest, --match-image-config or --match-annotation-digest, which only provide a sha256 digest")
+		}
+	}
+	if (o.MatchAnnotationDigest == "") != (o.AnnotationImage == "") {
+		return errors.New("--match-annotation-digest and --annotation-image must be used together")
+	}
+	if o.MatchImageConfig != "" && o.MatchAnnotationDigest != "" {
+		return errors.New("--match-image-config cannot be combined with --match-annotation-digest")
+	}
+	for flag, set := range map[string]bool{
+		"--match-image-config":      o.MatchImageConfig != "",
+		"--match-annotation-digest": o.MatchAnnotationDigest != "",
+	} {
+		if !set {
+			continue
+		}
+		switch {
+		case blobPath != "" || o.BlobDigest != "":
+			return fmt.Errorf("%s cannot be combined with a blob path or --blob-digest", flag)
+		case !o.CheckClaims:
+			return fmt.Errorf("%s cannot be used with --check-claims=false", flag)
+		case o.Decompress != "" || o.BlobJSONCanonical || o.BlobSignature != "":
+			return fmt.Errorf("%s cannot be combined with --decompress, --blob-json-canonical or --blob-signature, which need a blob", flag)
+		case o.HashAlgorithm != "" && o.HashAlgorithm != "sha256":
+			return fmt.Errorf("%s only supports sha256 digests", flag)
+		}
+	}
+	if o.StableBlob {
+		switch {
+		case !o.CheckClaims || blobPath == "":
+			return errors.New("--stable-blob requires a blob path whose digest is checked against the subjects")
+		case NOf(o.BlobDigest, o.MatchImageConfig, o.MatchAnnotationDigest) > 0:
+			return errors.New("--stable-blob cannot be combined with --blob-digest, --match-image-config or --match-annotation-digest, which don't read a blob")
+		case o.BlobResolver != "" || o.Decompress != "" || o.BlobJSONCanonical || o.BlobRange != "" || o.MatchComputableDigests:
+			return errors.New("--stable-blob cannot be combined with --blob-resolver, --decompress, --blob-json-canonical, --blob-range or --match-computable-digests")
+		}
+	}
+	if o.Decompress != "" && !o.CheckClaims {
+		return errors.New("--decompress cannot be used with --check-claims=false")
+	}
+	return nil
+}
+
+// validateOutputs checks the flags of what is written after a successful
+// verification.
+func (o *VerifyBlobAttestationOptions) validateOutputs(string) error {
+	switch {
+	case o.OutputVSA != "" && o.VSAKey == "":
+		return errors.New("--output-vsa requires --vsa-key to sign the VSA")
+	case o.OutputVSA != "" && !o.CheckClaims:
+		return errors.New("--output-vsa cannot be used with --check-claims=false")
+	case o.ReportTime != "" && o.Report == "":
+		return errors.New("--report-time requires --report")
+	case o.OutputLink != "" && o.LinkKey == "":
+		return errors.New("--output-link requires --link-key to sign the link")
+	case o.OutputLink != "" && !o.CheckClaims:
+		return errors.New("--output-link cannot be used with --check-claims=false")
+	case o.EmitEdge != "" && !o.CheckClaims:
+		return errors.New("--emit-edge cannot be used with --check-claims=false, the edge needs the blob digest")
+	case o.MintClaim != "" && o.MintClaimKey == "":
+		return errors.New("--mint-claim requires --mint-claim-key to sign the claim")
+	case o.MintClaim != "" && !o.CheckClaims:
+		return errors.New("--mint-claim cannot be used with --check-claims=false")
+	case o.MintClaim != "" && o.MintClaimTTL < 0:
+		return errors.New("--mint-claim-ttl must not be negative")
+	case o.RefreshTrust && o.TrustCacheDir == "":
+		return errors.New("--refresh-trust requires --trust-cache-dir")
+	}
+	switch o.Output {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("invalid output format %q, expected json or text", o.Output)
+	}
+	return nil
+}
+
+// validateCertificate checks the flags of the checks of the signing
+// certificate, which don't apply to keys.
+func (o *VerifyBlobAttestationOptions) validateCertificate(string) error {
+	if o.key() {
+		for flag, set := range map[string]bool{
+			"--pin-spki":                         len(o.PinSPKI) > 0,
+			"--require-cert-policy-oid":          o.RequireCertPolicyOID != "",
+			"--require-intermediate-ski":         o.RequireIntermediateSKI != "",
+			"--certificate-spiffe-id":            o.CertSPIFFEID != "",
+			"--require-signing-time-in-validity": o.RequireSigningTimeInValidity,
+			"--max-cert-lifetime":                o.MaxCertLifetime != 0,
+		} {
+			if set {
+				return fmt.Errorf("%s can only be used when verifying against a certificate", flag)
+			}
+		}
+	}
+	switch {
+	case o.ClockSkew < 0:
+		return fmt.Errorf("--clock-skew must not be negative, got %s", o.ClockSkew)
+	case o.MaxCertLifetime < 0:
+		return fmt.Errorf("--max-cert-lifetime must be positive, got %s", o.MaxCertLifetime)
+	}
+	return nil
+}
+
+// validateTlog checks the flags of the checks of the tlog entry and
+// timestamps.
+func (o *VerifyBlobAttestationOptions) validateTlog(string) error {
+	// --no-network implies --offline.
+	ignoreTlog, offline := o.CommonVerifyOptions.IgnoreTlog, o.CommonVerifyOptions.Offline || o.NoNetwork
+	switch {
+	case o.RekorLocalTree != "" && ignoreTlog:
+		return errors.New("--rekor-local-tree cannot be combined with --insecure-ignore-tlog")
+	case o.AfterCheckpoint != "" && ignoreTlog:
+		return errors.New("--after-checkpoint cannot be combined with --insecure-ignore-tlog")
+	case o.RequireTlogEntryKind != "" && ignoreTlog:
+		return errors.New("--require-tlog-entry-kind cannot be combined with --insecure-ignore-tlog")
+	case len(o.RekorWitnessKeys) > 0 && (ignoreTlog || offline):
+		return errors.New("--rekor-witness-key requires an online tlog lookup, it cannot be combined with --insecure-ignore-tlog, --offline or --no-network")
+	case o.MinTlogEntries != 0 && (ignoreTlog || offline):
+		return errors.New("--min-tlog-entries requires online tlog lookups, it cannot be combined with --insecure-ignore-tlog, --offline or --no-network")
+	case o.MinTlogEntries < 0:
+		return fmt.Errorf("--min-tlog-entries must be positive, got %d", o.MinTlogEntries)
+	case o.MinTlogEntries > max(len(o.RekorURLs), 1):
+		return fmt.Errorf("--min-tlog-entries %d exceeds the number of logs, repeat --rekor-url to look the entry up in more logs", o.MinTlogEntries)
+	case o.RequireCTInclusion && offline:
+		return errors.New("--require-ct-inclusion requires connectivity to the CT log, it cannot be combined with --offline or --no-network")
+	case o.RequireCTInclusion && o.CertVerify.IgnoreSCT:
+		return errors.New("--require-ct-inclusion cannot be combined with --insecure-ignore-sct, the inclusion of the SCT is verified")
+	case o.RekorTreeID != 0 && (ignoreTlog || offline):
+		return errors.New("--rekor-tree-id requires an online tlog lookup, it cannot be combined with --insecure-ignore-tlog, --offline or --no-network")
+	case o.RekorTreeID < 0:
+		return fmt.Errorf("--rekor-tree-id must be positive, got %d", o.RekorTreeID)
+	case o.RFC3161TimestampPath != "" && o.CommonVerifyOptions.TSACertChainPath == "":
+		return errors.New("timestamp-cert-chain is required to validate a rfc3161 timestamp bundle")
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyBlobAttestationOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		blobPath string
+		set      func(o *VerifyBlobAttestationOptions)
+		wantErr  string
+	}{{
+		name:     "key",
+		blobPath: "blob",
+		set:      func(*VerifyBlobAttestationOptions) {},
+	}, {
+		name:     "certificate",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.Key = nil
+			o.CertVerify.Cert = "cert.pem"
+			o.CertSPIFFEID = "spiffe://example.org/builder"
+		},
+	}, {
+		name: "no attestation",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.SignaturePath = ""
+		},
+		wantErr: "please specify path to the DSSE envelope signature",
+	}, {
+		name: "signature archive and signature",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.SignatureArchive = "attestations.tar"
+		},
+		wantErr: "--signature-archive cannot be combined with --signature or --bundle",
+	}, {
+		name: "image pulled without network",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.SignaturePath = ""
+			o.FromImage = "example.com/image"
+			o.NoNetwork = true
+		},
+		wantErr: "--no-network cannot be combined with --from-image",
+	}, {
+		name:    "no blob",
+		set:     func(*VerifyBlobAttestationOptions) {},
+		wantErr: "no path to blob passed in",
+	}, {
+		name: "no blob without checking the claims",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.CheckClaims = false
+		},
+	}, {
+		name: "blob parts without a blob path",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.BlobParts = []string{"part0", "part1"}
+		},
+	}, {
+		name:     "blob parts and a blob path",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.BlobParts = []string{"part0"}
+		},
+		wantErr: "--blob-part cannot be combined with a blob path",
+	}, {
+		name: "empty blob part",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.BlobParts = []string{"part0", ""}
+		},
+		wantErr: "--blob-part requires the path of a part",
+	}, {
+		name:     "blob digest and a blob path",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.BlobDigest = "sha256:abc"
+		},
+		wantErr: "--blob-digest cannot be combined with a blob path",
+	}, {
+		name: "blob digest with another hash algorithm",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.BlobDigest = "sha512:abc"
+			o.HashAlgorithm = "sha512"
+		},
+		wantErr: "--blob-digest only supports sha256 digests",
+	}, {
+		name: "blob resolver without a content ID",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.BlobResolver = "https://example.com/{id}"
+			o.BlobParts = []string{"part0"}
+		},
+		wantErr: "--blob-resolver requires the content ID of the blob as argument",
+	}, {
+		name:     "cdc digest with another hash algorithm",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.CDCDigest = "sha256:abc"
+			o.HashAlgorithm = "sha512"
+		},
+		wantErr: "--cdc-digest cannot be combined with --hash-algorithm sha512",
+	}, {
+		name:     "stable blob and a range",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.StableBlob = true
+			o.BlobRange = "0-3"
+		},
+		wantErr: "--stable-blob cannot be combined",
+	}, {
+		name: "annotation digest without an image",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.MatchAnnotationDigest = "org.example.digest"
+		},
+		wantErr: "--match-annotation-digest and --annotation-image must be used together",
+	}, {
+		name:     "image config and a blob path",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.MatchImageConfig = "example.com/image"
+		},
+		wantErr: "--match-image-config cannot be combined with a blob path or --blob-digest",
+	}, {
+		name:     "key history without a signature",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.Key = nil
+			o.KeyHistory = "history.json"
+			o.KeyHistoryRoot = "root.pub"
+		},
+		wantErr: "--key-history requires --key-history-signature and --key-history-root-key",
+	}, {
+		name:     "key history and a key",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.KeyHistory = "history.json"
+		},
+		wantErr: "--key-history cannot be combined with --key",
+	}, {
+		name:     "no key or certificate",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.Key = nil
+		},
+		wantErr: "provide a key with --key or --sk",
+	}, {
+		name:     "certificate from a JWT and a certificate",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.Key = nil
+			o.CertVerify.Cert = "cert.pem"
+			o.CertFromJWT = "token.jwt"
+		},
+		wantErr: "--cert-from-jwt cannot be combined with --key, --sk, --certificate or --verifier-plugin",
+	}, {
+		name:     "verifier plugin and a key",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.VerifierPlugin = "my-verifier"
+		},
+		wantErr: "--verifier-plugin cannot be combined with --key, --sk or --certificate",
+	}, {
+		name:     "blob signature without a key",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.Key = nil
+			o.BundlePath = "bundle.json"
+			o.BlobSignature = "blob.sig"
+		},
+		wantErr: "--blob-signature requires --key, --sk or --certificate",
+	}, {
+		name:     "subject name without checking the claims",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.SubjectName = "blob"
+			o.CheckClaims = false
+		},
+		wantErr: "--subject-name cannot be used with --check-claims=false",
+	}, {
+		name:     "provenance without checking the claims",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.Provenance = "provenance.json"
+			o.CheckClaims = false
+		},
+		wantErr: "--provenance cannot be used with --check-claims=false",
+	}, {
+		name:     "negative max signatures",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.MaxSignatures = -1
+		},
+		wantErr: "--max-signatures must be positive",
+	}, {
+		name:     "subject digest without a predicate-only signature",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.SubjectDigest = "sha256:abc"
+		},
+		wantErr: "--subject-digest requires --predicate-only-signature",
+	}, {
+		name:     "identity predicate map with a key",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.IdentityPredicateMap = "map.yaml"
+		},
+		wantErr: "--identity-predicate-map can only be used when verifying against a certificate",
+	}, {
+		name:     "allowed predicate fields without rejecting the others",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.PredicateAllowedFields = []string{"builder"}
+		},
+		wantErr: "--predicate-allowed-fields requires --reject-unknown-predicate-fields",
+	}, {
+		name:     "report time without a report",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.ReportTime = "2023-01-01T00:00:00Z"
+		},
+		wantErr: "--report-time requires --report",
+	}, {
+		name:     "link without a key",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.OutputLink = "verify.link"
+		},
+		wantErr: "--output-link requires --link-key",
+	}, {
+		name:     "claim without a key",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.MintClaim = "claim.jwt"
+		},
+		wantErr: "--mint-claim requires --mint-claim-key",
+	}, {
+		name: "edge without checking the claims",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.EmitEdge = "edges.jsonl"
+			o.CheckClaims = false
+		},
+		wantErr: "--emit-edge cannot be used with --check-claims=false",
+	}, {
+		name:     "refresh without a trust cache",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.RefreshTrust = true
+		},
+		wantErr: "--refresh-trust requires --trust-cache-dir",
+	}, {
+		name:     "unknown output format",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.Output = "yaml"
+		},
+		wantErr: `invalid output format "yaml"`,
+	}, {
+		name:     "certificate checks with a key",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.RequireIntermediateSKI = "abcd"
+		},
+		wantErr: "--require-intermediate-ski can only be used when verifying against a certificate",
+	}, {
+		name:     "negative clock skew",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.ClockSkew = -time.Minute
+		},
+		wantErr: "--clock-skew must not be negative",
+	}, {
+		name:     "local tree without the tlog",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.RekorLocalTree = "tree"
+			o.CommonVerifyOptions.IgnoreTlog = true
+		},
+		wantErr: "--rekor-local-tree cannot be combined with --insecure-ignore-tlog",
+	}, {
+		name:     "more tlog entries than logs",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.MinTlogEntries = 2
+		},
+		wantErr: "--min-tlog-entries 2 exceeds the number of logs",
+	}, {
+		name:     "tree ID while offline",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.RekorTreeID = 1
+			o.CommonVerifyOptions.Offline = true
+		},
+		wantErr: "--rekor-tree-id requires an online tlog lookup",
+	}, {
+		name:     "CT inclusion without network",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.RequireCTInclusion = true
+			o.NoNetwork = true
+		},
+		wantErr: "--require-ct-inclusion requires connectivity to the CT log",
+	}, {
+		name:     "timestamp without a chain",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.RFC3161TimestampPath = "timestamp.der"
+		},
+		wantErr: "timestamp-cert-chain is required",
+	}, {
+		name:     "payload",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.PayloadPath = "payload.json"
+		},
+	}, {
+		name: "payload without a blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.PayloadPath = "payload.json"
+		},
+		wantErr: "a blob is required to check the statement subjects",
+	}, {
+		name:     "payload and a report",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.PayloadPath = "payload.json"
+			o.Report = "report.json"
+		},
+		wantErr: "--payload cannot be combined with --report",
+	}, {
+		name:     "payload and multiple keys",
+		blobPath: "blob",
+		set: func(o *VerifyBlobAttestationOptions) {
+			o.PayloadPath = "payload.json"
+			o.Key = []string{"a.pub", "b.pub"}
+		},
+		wantErr: "--payload cannot be combined with multiple --key values",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := &VerifyBlobAttestationOptions{
+				Key:           []string{"cosign.pub"},
+				SignaturePath: "attestation.json",
+				CheckClaims:   true,
+				SubjectIndex:  -1,
+			}
+			test.set(o)
+			err := o.Validate(test.blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Validate() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+
+	// A key and a security key is reported as a KeyParseError, as by the
+	// other verify commands.
+	o := &VerifyBlobAttestationOptions{Key: []string{"cosign.pub"}, SignaturePath: "attestation.json", SubjectIndex: -1}
+	o.SecurityKey.Use = true
+	var keyErr *KeyParseError
+	if err := o.Validate(""); !errors.As(err, &keyErr) {
+		t.Errorf("Validate() = %v, expected a KeyParseError", err)
+	}
+}
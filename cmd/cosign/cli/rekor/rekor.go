@@ -15,8 +15,16 @@
 package rekor
 
 import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-openapi/runtime"
+	httptransport "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
 	rekor "github.com/sigstore/rekor/pkg/client"
 	"github.com/sigstore/rekor/pkg/generated/client"
+	"github.com/sigstore/rekor/pkg/util"
 
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
 )
@@ -28,3 +36,51 @@ func NewClient(rekorURL string) (*client.Rekor, error) {
 	}
 	return rekorClient, nil
 }
+
+// NewClientWithHTTPClient returns a Rekor client that sends its requests
+// through httpClient, e.g. to talk to a private Rekor over mTLS or with a
+// custom CA bundle. Unlike NewClient, requests are not retried.
+func NewClientWithHTTPClient(rekorURL string, httpClient *http.Client) (*client.Rekor, error) {
+	if httpClient == nil {
+		return nil, errors.New("no HTTP client provided")
+	}
+	u, err := url.Parse(rekorURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Path == "" {
+		u.Path = client.DefaultBasePath
+	}
+
+	// Copy the client so the caller's transport isn't modified.
+	hc := *httpClient
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	hc.Transport = &userAgentTransport{base: base, userAgent: options.UserAgent()}
+
+	rt := httptransport.NewWithClient(u.Host, u.Path, []string{u.Scheme}, &hc)
+	rt.Consumers["application/json"] = runtime.JSONConsumer()
+	rt.Consumers["application/x-pem-file"] = runtime.TextConsumer()
+	rt.Producers["application/json"] = runtime.JSONProducer()
+
+	// This registers the format in the global strfmt.Default, as
+	// rekor.GetRekorClient does for NewClient. That is safe: Add holds the
+	// registry lock, and registers the same format and validator, so
+	// registering it again leaves the registry unchanged.
+	registry := strfmt.Default
+	registry.Add("signedCheckpoint", &util.SignedNote{}, util.SignedCheckpointValidator)
+	return client.New(rt, registry), nil
+}
+
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
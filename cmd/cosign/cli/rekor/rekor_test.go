@@ -50,3 +50,47 @@ func TestNewClient(t *testing.T) {
 		t.Fatal("no requests were received")
 	}
 }
+
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewClientWithHTTPClient(t *testing.T) {
+	t.Parallel()
+	expectedUserAgent := options.UserAgent()
+	requestReceived := false
+	testServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requestReceived = true
+			got := r.UserAgent()
+			if got != expectedUserAgent {
+				t.Errorf("wanted User-Agent %q, got %q", expectedUserAgent, got)
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte{})
+		}))
+	defer testServer.Close()
+
+	transport := &countingTransport{}
+	client, err := NewClientWithHTTPClient(testServer.URL, &http.Client{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.Tlog.GetLogInfo(nil)
+
+	if !requestReceived {
+		t.Fatal("no requests were received")
+	}
+	if transport.requests != 1 {
+		t.Fatalf("expected the injected transport to be used once, got %d", transport.requests)
+	}
+
+	if _, err := NewClientWithHTTPClient(testServer.URL, nil); err == nil {
+		t.Fatal("expected an error without an HTTP client")
+	}
+}
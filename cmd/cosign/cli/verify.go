@@ -22,6 +22,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/generate"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/verify"
 	"github.com/sigstore/cosign/v2/internal/ui"
@@ -374,20 +375,57 @@ The blob may be specified as a path to a file.`,
 			if o.CommonVerifyOptions.PrivateInfrastructure {
 				o.CommonVerifyOptions.IgnoreTlog = true
 			}
+			if err := o.ApplyTrustPolicy(cmd.Flags()); err != nil {
+				return err
+			}
+			var keyRef string
+			var fallbackKeys []string
+			if len(o.Key) > 0 {
+				keyRef, fallbackKeys = o.Key[0], o.Key[1:]
+			}
+			var rekorURL string
+			if len(o.RekorURLs) > 0 {
+				rekorURL = o.RekorURLs[0]
+			}
 
 			ko := options.KeyOpts{
-				KeyRef:               o.Key,
+				KeyRef:               keyRef,
 				Sk:                   o.SecurityKey.Use,
 				Slot:                 o.SecurityKey.Slot,
-				RekorURL:             o.Rekor.URL,
+				RekorURL:             rekorURL,
 				BundlePath:           o.BundlePath,
 				RFC3161TimestampPath: o.RFC3161TimestampPath,
 				TSACertChainPath:     o.CommonVerifyOptions.TSACertChainPath,
+				// Only used to decrypt the --vsa-key.
+				PassFunc: generate.GetPass,
+			}
+			relayKO := options.KeyOpts{
+				KeyRef:                   o.RelayKey,
+				PassFunc:                 generate.GetPass,
+				FulcioURL:                o.Fulcio.URL,
+				IDToken:                  o.Fulcio.IdentityToken,
+				InsecureSkipFulcioVerify: o.Fulcio.InsecureSkipFulcioVerify,
+				RekorURL:                 rekorURL,
+				OIDCIssuer:               o.OIDC.Issuer,
+				OIDCClientID:             o.OIDC.ClientID,
+				OIDCRedirectURL:          o.OIDC.RedirectURL,
+				OIDCProvider:             o.OIDC.Provider,
+				SkipConfirmation:         o.SkipConfirmation,
+			}
+			if o.RelaySign {
+				oidcClientSecret, err := o.OIDC.ClientSecret()
+				if err != nil {
+					return err
+				}
+				relayKO.OIDCClientSecret = oidcClientSecret
 			}
 			v := verify.VerifyBlobAttestationCommand{
 				KeyOpts:                      ko,
 				PredicateType:                o.PredicateOptions.Type,
 				CheckClaims:                  o.CheckClaims,
+				ClockSkew:                    o.ClockSkew,
+				Explain:                      o.Explain,
+				FailOnWarnings:               o.FailOnWarnings,
 				SignaturePath:                o.SignaturePath,
 				CertVerifyOptions:            o.CertVerify,
 				CertRef:                      o.CertVerify.Cert,
@@ -401,17 +439,130 @@ The blob may be specified as a path to a file.`,
 				SCTRef:                       o.CertVerify.SCT,
 				Offline:                      o.CommonVerifyOptions.Offline,
 				IgnoreTlog:                   o.CommonVerifyOptions.IgnoreTlog,
+				NoNetwork:                    o.NoNetwork,
+				BlobAttestationCertificateChecks: verify.BlobAttestationCertificateChecks{
+					PinSPKI:                      o.PinSPKI,
+					RequireCertPolicyOID:         o.RequireCertPolicyOID,
+					RequireIntermediateSKI:       o.RequireIntermediateSKI,
+					CertSPIFFEID:                 o.CertSPIFFEID,
+					MaxCertLifetime:              o.MaxCertLifetime,
+					RequireSigningTimeInValidity: o.RequireSigningTimeInValidity,
+					IdentityPredicateMap:         o.IdentityPredicateMap,
+					RequireCTInclusion:           o.RequireCTInclusion,
+					CTLogURL:                     o.CTLogURL,
+					CertFromJWT:                  o.CertFromJWT,
+				},
+				BlobAttestationTlogChecks: verify.BlobAttestationTlogChecks{
+					RekorWitnessKeys:     o.RekorWitnessKeys,
+					RekorTreeID:          o.RekorTreeID,
+					RekorURLs:            o.RekorURLs,
+					MinTlogEntries:       o.MinTlogEntries,
+					RekorLocalTree:       o.RekorLocalTree,
+					AfterCheckpoint:      o.AfterCheckpoint,
+					RequireTlogEntryKind: o.RequireTlogEntryKind,
+					TrustCacheDir:        o.TrustCacheDir,
+					RefreshTrust:         o.RefreshTrust,
+				},
+				BlobAttestationSignatureChecks: verify.BlobAttestationSignatureChecks{
+					RequireKeyID:               o.RequireKeyID,
+					MaxSignatures:              o.MaxSignatures,
+					FallbackKeys:               fallbackKeys,
+					KeyHistory:                 o.KeyHistory,
+					KeyHistorySignature:        o.KeyHistorySig,
+					KeyHistoryRootKey:          o.KeyHistoryRoot,
+					PayloadPath:                o.PayloadPath,
+					AllowedSignatureAlgorithms: o.AllowedSignatureAlgorithms,
+					VerifierPlugin:             o.VerifierPlugin,
+					DSSEPAE:                    o.DSSEPAE,
+					EnvelopeJSONPath:           o.EnvelopeJSONPath,
+					SignatureArchive:           o.SignatureArchive,
+					FromImage:                  o.FromImage,
+				},
+				BlobAttestationStatementChecks: verify.BlobAttestationStatementChecks{
+					AllSubjectsMatch:             o.AllSubjectsMatch,
+					SubjectName:                  o.SubjectName,
+					SubjectNameRegexp:            o.SubjectNameRegexp,
+					RequireSubjectURIAndDigest:   o.RequireSubjectURIAndDigest,
+					RequireSortedSubjects:        o.RequireSortedSubjects,
+					DigestEncoding:               o.DigestEncoding,
+					LinkedDir:                    o.VerifyLinked,
+					RequireSBOM:                  o.RequireSBOM,
+					SLSABuilderID:                o.SLSABuilderID,
+					StatementType:                o.StatementType,
+					PredicateVersionConstraint:   o.PredicateVersionConstraint,
+					PredicateDecrypt:             o.PredicateDecrypt,
+					AgeIdentity:                  o.AgeIdentity,
+					RequireReproducible:          o.RequireReproducible,
+					RejectUnknownPredicateFields: o.RejectUnknownPredicateFields,
+					AllowedPredicateFields:       o.PredicateAllowedFields,
+					RequirePredicateFields:       o.RequirePredicateFields,
+					MatchComputableDigests:       o.MatchComputableDigests,
+					ParseStrictness:              o.ParseStrictness,
+					PredicateOnlySignature:       o.PredicateOnlySignature,
+					SubjectDigest:                o.SubjectDigest,
+				},
+				BlobAttestationBlobSource: verify.BlobAttestationBlobSource{
+					BlobJSONCanonical:     o.BlobJSONCanonical,
+					Decompress:            o.Decompress,
+					BlobDigest:            o.BlobDigest,
+					BlobRange:             o.BlobRange,
+					BlobParts:             o.BlobParts,
+					CDCDigest:             o.CDCDigest,
+					StableBlob:            o.StableBlob,
+					BlobResolver:          o.BlobResolver,
+					MatchImageConfig:      o.MatchImageConfig,
+					MatchAnnotationDigest: o.MatchAnnotationDigest,
+					AnnotationImage:       o.AnnotationImage,
+					RegistryOptions:       o.Registry,
+					HashAlgorithm:         o.HashAlgorithm,
+				},
+				BlobAttestationRelatedChecks: verify.BlobAttestationRelatedChecks{
+					BlobSignature:    o.BlobSignature,
+					Provenance:       o.Provenance,
+					AttestationChain: o.AttestationChain,
+				},
+				BlobAttestationOutputs: verify.BlobAttestationOutputs{
+					RelaySign:            o.RelaySign,
+					RelayKeyOpts:         relayKO,
+					RelayOutputSignature: o.RelayOutputSignature,
+					RelayBundlePath:      o.RelayBundle,
+					RelayTlogUpload:      o.RelayTlogUpload,
+					Output:               o.Output,
+					SaveBundle:           o.SaveBundle,
+					OutputEnvelope:       o.OutputEnvelope,
+					OutputVSA:            o.OutputVSA,
+					VSAKey:               o.VSAKey,
+					VSAPolicyURI:         o.VSAPolicyURI,
+					OutputLink:           o.OutputLink,
+					LinkKey:              o.LinkKey,
+					LinkStepName:         o.LinkStepName,
+					OutputMaterials:      o.OutputMaterials,
+					OutputSPDXGraph:      o.OutputSPDXGraph,
+					MintClaim:            o.MintClaim,
+					MintClaimKeyOpts:     options.KeyOpts{KeyRef: o.MintClaimKey, PassFunc: generate.GetPass},
+					MintClaimTTL:         o.MintClaimTTL,
+					EmitEdge:             o.EmitEdge,
+					Report:               o.Report,
+					ReportTime:           o.ReportTime,
+					TimingJSON:           o.TimingJSON,
+				},
 			}
-			// We only use the blob if we are checking claims.
-			if len(args) == 0 && o.CheckClaims {
-				return fmt.Errorf("no path to blob passed in, run `cosign verify-blob-attestation -h` for more help")
+			if o.SubjectIndex != -1 {
+				v.SubjectIndex = &o.SubjectIndex
 			}
 			var path string
 			if len(args) > 0 {
 				path = args[0]
 			}
+			if err := o.Validate(path); err != nil {
+				return err
+			}
 
 			ctx := cmd.Context()
+			if o.FailOnWarnings {
+				// Record the warnings logged before Exec as well.
+				ctx = ui.RecordWarnings(ctx)
+			}
 
 			if o.CommonVerifyOptions.IgnoreTlog && !o.CommonVerifyOptions.PrivateInfrastructure {
 				ui.Warnf(ctx, fmt.Sprintf(ignoreTLogMessage, "blob attestation"))
@@ -16,34 +16,64 @@
 package verify
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/cyberphone/json-canonicalization/go/src/webpki.org/jsoncanonicalizer"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/klauspost/compress/zstd"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/sign"
 	internal "github.com/sigstore/cosign/v2/internal/pkg/cosign"
 	"github.com/sigstore/cosign/v2/internal/pkg/cosign/tsa"
+	"github.com/sigstore/cosign/v2/internal/pkg/netguard"
+	"github.com/sigstore/cosign/v2/internal/pkg/tracing"
+	"github.com/sigstore/cosign/v2/internal/ui"
 	"github.com/sigstore/cosign/v2/pkg/blob"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/blobresolver"
 	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/cosign/git/gitlab"
+	"github.com/sigstore/cosign/v2/pkg/cosign/jwkskey"
+	"github.com/sigstore/cosign/v2/pkg/cosign/kubernetes"
 	"github.com/sigstore/cosign/v2/pkg/cosign/pivkey"
 	"github.com/sigstore/cosign/v2/pkg/cosign/pkcs11key"
+	"github.com/sigstore/cosign/v2/pkg/cosign/verifierplugin"
+	"github.com/sigstore/cosign/v2/pkg/oci"
 	"github.com/sigstore/cosign/v2/pkg/oci/static"
 	"github.com/sigstore/cosign/v2/pkg/policy"
 	sigs "github.com/sigstore/cosign/v2/pkg/signature"
+	"github.com/sigstore/rekor/pkg/generated/client"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/kms"
+	"golang.org/x/crypto/sha3"
+	"golang.org/x/exp/mmap"
 )
 
 // VerifyBlobAttestationCommand verifies an attestation on a supplied blob
@@ -65,38 +95,629 @@ type VerifyBlobAttestationCommand struct {
 	SCTRef     string
 	Offline    bool
 	IgnoreTlog bool
+	// NoNetwork disables the network in the context of the verification, so
+	// that any connection attempted through a transport guarded by netguard
+	// is an error, TUF is only used if its local metadata are unexpired, and
+	// keys of a KMS, Kubernetes or GitLab, whose clients can't be guarded,
+	// are rejected. It implies Offline.
+	NoNetwork bool
 
 	CheckClaims   bool
 	PredicateType string
 	// TODO: Add policies
 
-	SignaturePath string // Path to the signature
+	// ClockSkew widens the time windows on both ends: the certificate
+	// validity period checked against the signing time, the time ranges of
+	// the KeyHistory and the time of the RekorAfterCheckpoint checkpoint.
+	ClockSkew time.Duration
+	// Explain narrates the verification steps and the reason of a failure.
+	Explain bool
+	// FailOnWarnings fails verification if any warning was logged, each
+	// warning becoming one of the VerificationErrors.
+	FailOnWarnings bool
+
+	SignaturePath string // Path or s3:// or gs:// reference to the signature
+
+	// The checks and outputs added to the command are grouped by what they
+	// apply to.
+	BlobAttestationCertificateChecks
+	BlobAttestationTlogChecks
+	BlobAttestationSignatureChecks
+	BlobAttestationStatementChecks
+	BlobAttestationBlobSource
+	BlobAttestationRelatedChecks
+	BlobAttestationOutputs
+}
+
+// BlobAttestationCertificateChecks are the checks of the signing certificate
+// and its chain, beyond its identity.
+type BlobAttestationCertificateChecks struct {
+	// CertFromJWT is the path to a compact JWT delivering the certificate,
+	// chain and SCT in its x5c header and sct claim, see certFromJWT.
+	CertFromJWT string
+	// PinSPKI holds base64-encoded SHA-256 digests of the SubjectPublicKeyInfo
+	// the signing certificate must match. Without an identity or issuer to
+	// check, the pin replaces them, with a warning.
+	PinSPKI []string
+	// RequireCertPolicyOID is a certificate policy OID, in dotted form, the
+	// signing certificate must carry.
+	RequireCertPolicyOID string
+	// RequireIntermediateSKI is the hex Subject Key Identifier of an
+	// intermediate CA the chain built for the signing certificate must go
+	// through.
+	RequireIntermediateSKI string
+	// CertSPIFFEID is the SPIFFE ID a URI SAN of the signing certificate
+	// must be, once normalized. It stands in for the certificate identity,
+	// and the OIDC issuer is only checked if given.
+	CertSPIFFEID string
+	// MaxCertLifetime, if non-zero, rejects signing certificates valid for
+	// longer than this.
+	MaxCertLifetime time.Duration
+	// RequireSigningTimeInValidity requires the tlog integrated time or an
+	// RFC3161 timestamp to lie within the signing certificate's validity.
+	RequireSigningTimeInValidity bool
+	// RequireCTInclusion requires the signing certificate to be included in
+	// the CT log at CTLogURL, options.DefaultCTLogURL if empty, as proven by an
+	// inclusion proof fetched from the log, beyond the promise of its SCT.
+	RequireCTInclusion bool
+	CTLogURL           string
+	// IdentityPredicateMap is the path to an IdentityPredicateMap document
+	// restricting the predicate types each certificate identity may attest
+	// to.
+	IdentityPredicateMap string
+}
+
+// BlobAttestationTlogChecks are the checks of the tlog entry of the
+// attestation, and the trust material they need.
+type BlobAttestationTlogChecks struct {
+	// TrustCacheDir, if set, caches the Rekor, CT log and Fulcio trust
+	// material fetched from TUF in this directory, by the digest of the TUF
+	// root, until the TUF metadata expire. RefreshTrust fetches it again.
+	TrustCacheDir string
+	RefreshTrust  bool
+	// RekorClient, if set, is used for online tlog lookups instead of a
+	// client constructed from RekorURL.
+	RekorClient *client.Rekor
+	// HTTPClient, if set, is used to construct the Rekor client for RekorURL,
+	// e.g. to configure mTLS or a custom CA bundle.
+	HTTPClient *http.Client
+	// MinTlogEntries, if positive, requires the tlog entry of the attestation
+	// to be found in at least this many distinct logs of RekorURLs, RekorURL
+	// if empty.
+	MinTlogEntries int
+	RekorURLs      []string
+	// RekorWitnessKeys are references to witness public keys, one of which
+	// must have co-signed the Rekor checkpoint.
+	RekorWitnessKeys []string
+	// RekorTreeID, if set, is the ID of the Rekor tree the checkpoint must be
+	// for.
+	RekorTreeID int64
+	// RekorLocalTree is a directory mirroring the Rekor tree that bundled
+	// tlog entries are verified against, see cosign.LoadLocalTree.
+	RekorLocalTree string
+	// AfterCheckpoint is the path to a pinned Rekor checkpoint the tlog
+	// entry must have been logged after.
+	AfterCheckpoint string
+	// RequireTlogEntryKind is the kind the tlog entry must be of, optionally
+	// followed by :<apiVersion>, e.g. dsse or intoto:0.0.2.
+	RequireTlogEntryKind string
+}
+
+// BlobAttestationSignatureChecks locate the DSSE envelope and select the
+// keys and algorithms its signatures are verified with.
+type BlobAttestationSignatureChecks struct {
+	// VerifierPlugin is a command line for an external program the DSSE
+	// signature check is delegated to, see the verifierplugin package.
+	VerifierPlugin string
+	// DSSEPAE names the DSSE pre-authentication encoding the envelope
+	// signatures are verified over, one registered with cosign.RegisterPAE.
+	// Empty means cosign.StandardPAE.
+	DSSEPAE string
+	// RequireKeyID, if set, requires a signature bearing this keyid to
+	// validate.
+	RequireKeyID string
+	// AllowedSignatureAlgorithms, if set, are the only algorithms the
+	// verifying signatures may use, see signatureAlgorithm.
+	AllowedSignatureAlgorithms []string
+	// MaxSignatures bounds the number of signatures of an envelope, checked
+	// before verification. If zero, options.DefaultMaxSignatures is used.
+	MaxSignatures int
+	// FallbackKeys are tried in order after KeyRef, e.g. during a key
+	// rotation. The first key validating the envelope signature is used.
+	FallbackKeys []string
+	// KeyHistory is the path to a KeyHistory document. The key valid at the
+	// signing time of the attestation is used instead of KeyRef. The
+	// document must be signed, with KeyHistorySignature, by the pinned
+	// KeyHistoryRootKey.
+	KeyHistory          string
+	KeyHistorySignature string
+	KeyHistoryRootKey   string
+
+	EnvelopeJSONPath string // JSONPath of the DSSE envelope within SignaturePath, if not the whole file
+	PayloadPath      string // Path to a gzip-compressed statement signed with the detached SignaturePath
+	SignatureArchive string // Path to a tar archive of signatures
+	FromImage        string // Reference to an image whose attached attestations are verified, pulled with RegistryOptions
+
+}
+
+// BlobAttestationStatementChecks are the checks of the in-toto statement,
+// its subjects and its predicate.
+type BlobAttestationStatementChecks struct {
+	// PredicateVersionConstraint, if set, accepts any version of the
+	// predicate type satisfying it, see NewPredicateVersionConstraint.
+	PredicateVersionConstraint string
+	// AllSubjectsMatch fails verification if any subject of the statement
+	// doesn't match the blob.
+	AllSubjectsMatch bool
+	// MatchComputableDigests requires every digest of a matching subject
+	// that can be computed to match the blob, see computableDigests.
+	MatchComputableDigests bool
+	// SubjectName requires the subject with this name to match the blob.
+	SubjectName string
+	// SubjectIndex, if set, requires the subject at this 0-based position
+	// of the statement to match the blob.
+	SubjectIndex *int
+	// PredicateOnlySignature verifies an envelope signing only a predicate,
+	// whose subject is supplied out-of-band with SubjectDigest and
+	// SubjectName, and matched against the blob. The signature doesn't bind
+	// the predicate to the subject, so this only proves that the signer
+	// vouched for the predicate, not for which artifact.
+	PredicateOnlySignature bool
+	// SubjectDigest is the <algorithm>:<hex> digest of the subject of a
+	// predicate-only signature.
+	SubjectDigest string
+	// SubjectNameRegexp requires the name of a subject matching the blob to
+	// match this regular expression.
+	SubjectNameRegexp string
+	// DigestEncoding is the encoding of the subject digests (hex|multihash).
+	DigestEncoding string
+	// LinkedDir, if set, is a directory holding the documents referenced by
+	// digest from the predicate.
+	LinkedDir string
+	// RequireSBOM requires the predicate to be an SPDX or CycloneDX document
+	// listing at least one component.
+	RequireSBOM bool
+	// RequireSubjectURIAndDigest requires the subjects matching the blob to
+	// have a uri as well as a digest.
+	RequireSubjectURIAndDigest bool
+	// RequireSortedSubjects requires the subjects of the statement to be
+	// signed in the canonical order, sorted by name then digest.
+	RequireSortedSubjects bool
+	// SLSABuilderID, if set, is the builder ID the SLSA provenance predicate
+	// must record.
+	SLSABuilderID string
+	// RequireReproducible requires the SLSA provenance predicate to claim the
+	// build is reproducible.
+	RequireReproducible bool
+	// StatementType, if set, overrides the accepted in-toto statement _type.
+	StatementType string
+	// ParseStrictness is how strictly the in-toto statement is parsed, one
+	// of ParseStrictnessLevels, ParseStrict if empty.
+	ParseStrictness string
+	// PredicateDecrypt is the encryption of the predicate, decrypted with
+	// AgeIdentity once the signature is verified (age).
+	PredicateDecrypt string
+	AgeIdentity      string
+	// RejectUnknownPredicateFields fails verification if the predicate has
+	// fields outside of AllowedPredicateFields.
+	RejectUnknownPredicateFields bool
+	AllowedPredicateFields       []string
+	// RequirePredicateFields are jsonpath=value requirements on the fields
+	// of the predicate, which must all hold, see
+	// NewPredicateFieldRequirement.
+	RequirePredicateFields []string
+}
+
+// BlobAttestationBlobSource is where the digest checked against the subjects
+// comes from, and how it is computed.
+type BlobAttestationBlobSource struct {
+	// HashAlgorithm is the algorithm of the blob digest matched against the
+	// subjects (sha256|sha3-256|sha3-512).
+	HashAlgorithm string
+	// BlobJSONCanonical canonicalizes the blob, which must be JSON, before
+	// computing the digest checked against the subjects.
+	BlobJSONCanonical bool
+	// Decompress is the compression of the blob (zstd), which is decompressed
+	// before computing the digest checked against the subjects.
+	Decompress string
+	// BlobDigest is the sha256 digest of the blob, checked against the
+	// subjects instead of the digest of a blob file.
+	BlobDigest string
+	// BlobRange is an inclusive byte range <start>-<end> of the blob file,
+	// whose digest is checked against the subjects instead of the digest of
+	// the whole file. Unless SubjectName or SubjectNameRegexp is set, the
+	// matching subject must be named after the range, e.g.
+	// archive.tar#bytes=0-1023, so that it can't be mistaken for the file.
+	BlobRange string
+	// BlobParts are the paths to the parts of the blob, which is their
+	// concatenation in the given order, checked against the subjects instead
+	// of a blob file. The parts are streamed, never joined on disk.
+	BlobParts []string
+	// CDCDigest, if set, is the spec of the content-defined chunker, e.g.
+	// fastcdc or fastcdc:min=16384,avg=65536,max=262144, with which the CDC
+	// digest of the blob is computed and checked against the subjects instead
+	// of its sha256 digest, see cdcAlgorithm.
+	CDCDigest string
+	// StableBlob hashes the blob file from a file kept open until the end of
+	// the verification, and fails the verification if the file is modified
+	// or replaced in the meantime, see pinnedBlob.
+	StableBlob bool
+	// BlobResolver is a command line for a blobresolver plugin fetching the
+	// blob, whose content ID is passed instead of a blob path.
+	BlobResolver string
+	// MatchImageConfig is a reference to an image whose config blob digest,
+	// rather than the manifest digest, is checked against the subjects
+	// instead of a blob. The image is pulled with RegistryOptions.
+	MatchImageConfig string
+	// MatchAnnotationDigest is the key of an annotation of the AnnotationImage
+	// manifest holding the digest checked against the subjects instead of a
+	// blob, e.g. org.opencontainers.image.base.digest.
+	MatchAnnotationDigest string
+	AnnotationImage       string
+	// RegistryOptions configure the pulls of MatchImageConfig,
+	// AnnotationImage and FromImage.
+	RegistryOptions options.RegistryOptions
+}
+
+// BlobAttestationRelatedChecks are the other signed documents verified with
+// the same key or certificate as the attestation.
+type BlobAttestationRelatedChecks struct {
+	// BlobSignature is the path to a detached signature over the blob, to be
+	// verified with the same key or certificate as the attestation.
+	BlobSignature string
+	// Provenance is the path to a SLSA provenance attestation, a DSSE
+	// envelope, to be verified with the same key or certificate as the
+	// attestation. The blob must be one of its subjects.
+	Provenance string
+	// AttestationChain is a directory of attestations, DSSE envelopes,
+	// chained to the verified one: each must have the sha256 digest of the
+	// envelope of the previous one as a subject. They are verified with the
+	// same key or certificate as the attestation.
+	AttestationChain string
 }
 
-// Exec runs the verification command
+// BlobAttestationOutputs are the documents written once the attestation is
+// verified.
+type BlobAttestationOutputs struct {
+	// VSAKey is a reference to the private key signing the verification
+	// summary attestation written to OutputVSA.
+	VSAKey string
+	// VSAPolicyURI identifies the policy recorded in the VSA.
+	VSAPolicyURI string
+	// LinkKey is a reference to the private key signing the in-toto link
+	// of the verification written to OutputLink.
+	LinkKey string
+	// LinkStepName is the step name of the link, DefaultLinkStepName if
+	// empty.
+	LinkStepName string
+	// MintClaimKeyOpts is the key signing the JWT written to MintClaim,
+	// valid for MintClaimTTL, DefaultMintClaimTTL if zero.
+	MintClaimKeyOpts options.KeyOpts
+	MintClaimTTL     time.Duration
+	// RelaySign signs the verified statement again, with RelayKeyOpts, and
+	// writes the new DSSE envelope to RelayOutputSignature.
+	RelaySign            bool
+	RelayKeyOpts         options.KeyOpts
+	RelayOutputSignature string
+	// RelayBundlePath, if set, is where the signing certificate or key and
+	// tlog entry of the relayed attestation are written.
+	RelayBundlePath string
+	RelayTlogUpload bool
+
+	SaveBundle      string // Path to write a bundle of the verified attestation to
+	OutputEnvelope  string // Path to write the verified DSSE envelope to, as read
+	OutputVSA       string // Path to write a signed verification summary attestation to
+	OutputLink      string // Path to write a signed in-toto link of the verification to
+	OutputMaterials string // Path to write the materials of a verified SLSA provenance to
+	OutputSPDXGraph string // Path to write the relationship graph of a verified SPDX document to
+	MintClaim       string // Path to write a signed JWT of the verified blob digest, predicate type and signer to
+	EmitEdge        string // Path to a JSON Lines file to append a provenance graph edge of the verification to
+	Report          string // Path to write a canonical JSON report of the verification inputs and outcome to
+	ReportTime      string // RFC 3339 time of the verification recorded in the Report, none if empty
+	TimingJSON      string // Path to write the durations of the verification steps to, as JSON
+	Output          string // Output format of the verification result (json|text)
+
+	// Metrics, if set, records the result and phase latencies of the
+	// verification.
+	Metrics *Metrics
+}
+
+// Exec runs the verification command. The combinations of flags are checked
+// beforehand, by VerifyBlobAttestationOptions.Validate.
 func (c *VerifyBlobAttestationCommand) Exec(ctx context.Context, artifactPath string) (err error) {
-	if options.NOf(c.SignaturePath, c.BundlePath) == 0 {
-		return fmt.Errorf("please specify path to the DSSE envelope signature via --signature or --bundle")
+	if c.NoNetwork {
+		netguard.Install()
+		ctx = netguard.WithoutNetwork(ctx)
+	}
+	var timings *tracing.Timings
+	if c.TimingJSON != "" {
+		timings = &tracing.Timings{}
+		ctx = tracing.WithTimings(ctx, timings)
+	}
+	ctx, span := tracing.Start(ctx, "verify-blob-attestation")
+	if c.FailOnWarnings {
+		ctx = ui.RecordWarnings(ctx)
+	}
+	phases := tracing.NewPhases(ctx)
+	if c.Metrics != nil {
+		phases.Observe(c.Metrics.observePhase)
+	}
+	defer func() {
+		phases.End(err)
+		tracing.End(span, err)
+		predicateType, ok := options.PredicateTypeMap[c.PredicateType]
+		if !ok {
+			predicateType = c.PredicateType
+		}
+		c.Metrics.observeResult(predicateType, err)
+		if timings != nil {
+			if terr := saveTimings(c.TimingJSON, timings); terr != nil && err == nil {
+				err = terr
+			}
+		}
+	}()
+	ctx = phases.Next("load")
+
+	if netguard.Disabled(ctx) {
+		for _, ref := range append([]string{c.KeyRef, c.KeyHistoryRootKey, c.MintClaimKeyOpts.KeyRef, c.RelayKeyOpts.KeyRef}, c.FallbackKeys...) {
+			if guardlessKeyRef(ref) {
+				return fmt.Errorf("--no-network cannot be combined with the key %s, whose client --no-network doesn't cover", ref)
+			}
+		}
+	}
+	if c.PayloadPath != "" {
+		return c.verifyCompressedStatement(ctx, artifactPath)
+	}
+	if c.KeyHistory != "" {
+		keyRef, err := c.keyHistoryKey(ctx)
+		if err != nil {
+			return err
+		}
+		// Verify with a copy of the command using the historical key.
+		kc := *c
+		kc.KeyRef = keyRef
+		c = &kc
+	}
+
+	vo, err := c.envelopeOptions()
+	if err != nil {
+		return err
+	}
+	ex := &explainer{ctx: ctx, enabled: c.Explain}
+	defer func() { ex.failure(err) }()
+	if vo.CheckOpts, err = c.checkOpts(ctx, ex); err != nil {
+		return err
+	}
+	sig, err := c.loadSignature(ctx, ex, vo.CheckOpts, vo.MaxSignatures)
+	if err != nil {
+		return err
+	}
+	defer sig.Close()
+	vo.SignatureOptions = sig.opts
+
+	ctx = phases.Next("digest")
+	h, pinned, err := c.digest(ctx, ex, vo, artifactPath)
+	if err != nil {
+		return err
+	}
+	if pinned != nil {
+		defer pinned.Close()
+	}
+
+	ctx = phases.Next("verify")
+	verified, err := c.verify(ctx, ex, vo, sig.encodedSig, h)
+	if c.BlobSignature != "" || c.Provenance != "" || c.AttestationChain != "" || c.MinTlogEntries > 0 || c.RequireCTInclusion {
+		err = c.verifyDetached(ctx, vo, verified, err, artifactPath, sig.keyRef, h)
+	}
+	if err == nil && pinned != nil {
+		err = pinned.check()
+	}
+	if err != nil {
+		return c.finish(ctx, artifactPath, h, nil, err)
+	}
+
+	if len(c.FallbackKeys) > 0 || c.KeyHistory != "" {
+		verified.Key = sig.keyRef
+	}
+	switch {
+	case c.MatchImageConfig != "":
+		verified.ImageComponent = ImageComponentConfig
+		verified.ImageDigest = h.String()
+	case c.MatchAnnotationDigest != "":
+		verified.ImageComponent = ImageComponentAnnotation + " " + c.MatchAnnotationDigest
+		verified.ImageDigest = h.String()
+	}
+	ex.step("All checks passed")
+	ctx = phases.Next("output")
+	err = c.writeOutputs(ctx, artifactPath, h, verified, vo.CheckOpts, sig.encodedSig, sig.keyRef)
+	return c.finish(ctx, artifactPath, h, verified, err)
+}
+
+// envelopeOptions parses the flags of the checks of the envelope and its
+// statement. The trust material and the signature options are set by Exec.
+func (c *VerifyBlobAttestationCommand) envelopeOptions() (*VerifyEnvelopeOptions, error) {
+	subjectNameRegexp, err := c.subjectNameRegexp()
+	if err != nil {
+		return nil, err
+	}
+	maxSignatures := c.MaxSignatures
+	if maxSignatures <= 0 {
+		maxSignatures = options.DefaultMaxSignatures
+	}
+	var decrypter func([]byte) ([]byte, error)
+	switch {
+	case c.PredicateDecrypt == "" && c.AgeIdentity != "":
+		return nil, fmt.Errorf("--age-identity requires --predicate-decrypt %s", PredicateDecryptAge)
+	case c.PredicateDecrypt == "":
+	case c.PredicateDecrypt != PredicateDecryptAge:
+		return nil, fmt.Errorf("unsupported --predicate-decrypt %q, expected %s", c.PredicateDecrypt, PredicateDecryptAge)
+	case c.AgeIdentity == "":
+		return nil, fmt.Errorf("--predicate-decrypt %s requires --age-identity", PredicateDecryptAge)
+	default:
+		if decrypter, err = ageDecrypter(c.AgeIdentity); err != nil {
+			return nil, err
+		}
+	}
+	var predicateOnlySubj *in_toto.Subject
+	if c.PredicateOnlySignature {
+		if predicateOnlySubj, err = predicateOnlySubject(c.SubjectDigest, c.SubjectName); err != nil {
+			return nil, err
+		}
+	}
+	var identityMap *IdentityPredicateMap
+	if c.IdentityPredicateMap != "" {
+		if identityMap, err = LoadIdentityPredicateMap(c.IdentityPredicateMap); err != nil {
+			return nil, err
+		}
+	}
+	var versionConstraint *PredicateVersionConstraint
+	if c.PredicateVersionConstraint != "" {
+		if versionConstraint, err = NewPredicateVersionConstraint(c.PredicateType, c.PredicateVersionConstraint); err != nil {
+			return nil, err
+		}
+	}
+	if c.RequireSBOM && !slices.Contains(sbomPredicateTypes, c.PredicateType) {
+		return nil, fmt.Errorf("--require-sbom-attestation requires --type to be one of %s", strings.Join(sbomPredicateTypes, ", "))
+	}
+	if c.CDCDigest != "" {
+		if c.DigestEncoding == DigestEncodingMultihash {
+			return nil, fmt.Errorf("--cdc-digest cannot be combined with --digest-encoding %s, a CDC digest has no multihash code", DigestEncodingMultihash)
+		}
+		if _, err := parseCDCSpec(c.CDCDigest); err != nil {
+			return nil, fmt.Errorf("invalid --cdc-digest: %w", err)
+		}
+	}
+	if c.BlobRange != "" {
+		r, err := parseByteRange(c.BlobRange)
+		if err != nil {
+			return nil, fmt.Errorf("parsing --blob-range: %w", err)
+		}
+		if c.SubjectName == "" && subjectNameRegexp == nil {
+			subjectNameRegexp = r.subjectNameRegexp()
+		}
+	}
+	if c.ReportTime != "" {
+		if _, err := parseReportTime(c.ReportTime); err != nil {
+			return nil, err
+		}
+	}
+	switch c.Decompress {
+	case "":
+	case CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unsupported --decompress %q, expected %s", c.Decompress, CompressionZstd)
+	}
+
+	if c.ParseStrictness != "" && !slices.Contains(ParseStrictnessLevels, c.ParseStrictness) {
+		return nil, fmt.Errorf("unsupported --parse-strictness %q, expected one of %s", c.ParseStrictness, strings.Join(ParseStrictnessLevels, ", "))
+	}
+	if err := checkSignatureAlgorithmNames(c.AllowedSignatureAlgorithms); err != nil {
+		return nil, err
+	}
+	predicateFields, err := c.predicateFieldRequirements()
+	if err != nil {
+		return nil, err
 	}
 
-	// Require a certificate/key OR a local bundle file that has the cert.
-	if options.NOf(c.KeyRef, c.CertRef, c.Sk, c.BundlePath) == 0 {
-		return fmt.Errorf("provide a key with --key or --sk, a certificate to verify against with --certificate, or a bundle with --bundle")
+	switch c.DigestEncoding {
+	case "", DigestEncodingHex, DigestEncodingMultihash:
+	default:
+		return nil, fmt.Errorf("invalid digest encoding %q, expected %s or %s", c.DigestEncoding, DigestEncodingHex, DigestEncodingMultihash)
 	}
 
-	// We can't have both a key and a security key
-	if options.NOf(c.KeyRef, c.Sk) > 1 {
-		return &options.KeyParseError{}
+	if _, ok := blobHashes[c.HashAlgorithm]; !ok && c.HashAlgorithm != "" {
+		return nil, fmt.Errorf("unsupported hash algorithm %q, expected one of %s", c.HashAlgorithm, strings.Join(supportedBlobHashes(), ", "))
+	}
+
+	return &VerifyEnvelopeOptions{
+		CheckClaims:      c.CheckClaims,
+		PredicateType:    c.PredicateType,
+		AllSubjectsMatch: c.AllSubjectsMatch,
+		SubjectName:      c.SubjectName,
+		SubjectIndex:     c.SubjectIndex,
+		DigestEncoding:   c.DigestEncoding,
+		HashAlgorithm:    c.HashAlgorithm,
+		RequireKeyID:     c.RequireKeyID,
+		LinkedDir:        c.LinkedDir,
+		RequireSBOM:      c.RequireSBOM,
+		SLSABuilderID:    c.SLSABuilderID,
+		StatementType:    c.StatementType,
+		ParseStrictness:  c.ParseStrictness,
+
+		RequireReproducible:          c.RequireReproducible,
+		RejectUnknownPredicateFields: c.RejectUnknownPredicateFields,
+		AllowedPredicateFields:       c.AllowedPredicateFields,
+		RequiredPredicateFields:      predicateFields,
+		RequireSubjectURIAndDigest:   c.RequireSubjectURIAndDigest,
+		RequireSortedSubjects:        c.RequireSortedSubjects,
+		SubjectNameRegexp:            subjectNameRegexp,
+		MaxSignatures:                maxSignatures,
+		IdentityPredicateMap:         identityMap,
+		DecryptPredicate:             decrypter,
+		PredicateOnlySubject:         predicateOnlySubj,
+		PredicateVersionConstraint:   versionConstraint,
+		AllowedSignatureAlgorithms:   c.AllowedSignatureAlgorithms,
+		MatchComputableDigests:       c.MatchComputableDigests,
+	}, nil
+}
+
+// checkOpts parses the flags of the checks of the signer, and loads the trust
+// material the signature, certificate and tlog entry are verified with.
+func (c *VerifyBlobAttestationCommand) checkOpts(ctx context.Context, ex *explainer) (*cosign.CheckOpts, error) {
+	spkiPins, err := decodeSPKIPins(c.PinSPKI)
+	if err != nil {
+		return nil, err
+	}
+	if c.RequireCertPolicyOID != "" && !validOID(c.RequireCertPolicyOID) {
+		return nil, fmt.Errorf("invalid --require-cert-policy-oid %q, expected a dotted OID such as 1.3.6.1.4.1.57264.1", c.RequireCertPolicyOID)
+	}
+	var intermediateSKI []byte
+	if c.RequireIntermediateSKI != "" {
+		if intermediateSKI, err = decodeSKI(c.RequireIntermediateSKI); err != nil {
+			return nil, err
+		}
+	}
+	var spiffeID string
+	if c.CertSPIFFEID != "" {
+		if spiffeID, err = cosign.NormalizeSPIFFEID(c.CertSPIFFEID); err != nil {
+			return nil, fmt.Errorf("parsing --certificate-spiffe-id: %w", err)
+		}
 	}
 
 	var identities []cosign.Identity
-	if c.KeyRef == "" {
+	// A pinned public key may stand in for the identity and issuer checks.
+	pinnedOnly := len(spkiPins) > 0 && options.NOf(c.CertIdentity, c.CertIdentityRegexp, c.CertOidcIssuer, c.CertOidcIssuerRegexp) == 0
+	switch {
+	case c.KeyRef != "" || c.VerifierPlugin != "":
+	case pinnedOnly:
+		ui.Warnf(ctx, "The certificate identity and issuer are not checked: --pin-spki without --certificate-identity or --certificate-oidc-issuer only checks the certificate public key")
+	case spiffeID != "" && c.CertIdentity == "" && c.CertIdentityRegexp == "":
+		// The SPIFFE ID stands in for the identity, so only the issuer, if
+		// given, is left to check.
+		if c.CertOidcIssuer != "" || c.CertOidcIssuerRegexp != "" {
+			identities = []cosign.Identity{{Issuer: c.CertOidcIssuer, IssuerRegExp: c.CertOidcIssuerRegexp}}
+		}
+	default:
 		identities, err = c.Identities()
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
+	if c.KeyRef == "" && c.VerifierPlugin == "" {
+		ex.identities(identities)
+	}
+	if spiffeID != "" {
+		ex.step("Requiring the SPIFFE ID %s as a URI SAN of the certificate", spiffeID)
+	}
+
+	if _, err := cosign.LookupPAE(c.DSSEPAE); err != nil {
+		return nil, fmt.Errorf("--dsse-pae: %w", err)
+	}
+	if c.DSSEPAE != "" && c.DSSEPAE != cosign.StandardPAE {
+		ui.Warnf(ctx, "Verifying the envelope signatures over the custom DSSE PAE %q rather than the standard one", c.DSSEPAE)
+	}
+
 	co := &cosign.CheckOpts{
 		Identities:                   identities,
 		CertGithubWorkflowTrigger:    c.CertGithubWorkflowTrigger,
@@ -105,52 +726,64 @@ func (c *VerifyBlobAttestationCommand) Exec(ctx context.Context, artifactPath st
 		CertGithubWorkflowRepository: c.CertGithubWorkflowRepository,
 		CertGithubWorkflowRef:        c.CertGithubWorkflowRef,
 		IgnoreSCT:                    c.IgnoreSCT,
-		Offline:                      c.Offline,
+		Offline:                      c.Offline || c.NoNetwork,
 		IgnoreTlog:                   c.IgnoreTlog,
+		CertSPKIPins:                 spkiPins,
+		CertPolicyOID:                c.RequireCertPolicyOID,
+		CertSPIFFEID:                 spiffeID,
+		IntermediateSKI:              intermediateSKI,
+		DSSEPAE:                      c.DSSEPAE,
+		DecodeDSSESignature:          decodeSignature,
+		MaxCertLifetime:              c.MaxCertLifetime,
+		ClockSkew:                    c.ClockSkew,
+		RequireSigningTime:           c.RequireSigningTimeInValidity,
 	}
-	var h v1.Hash
-	if c.CheckClaims {
-		// Get the actual digest of the blob
-		var payload internal.HashReader
-		f, err := os.Open(filepath.Clean(artifactPath))
+
+	if c.RekorLocalTree != "" {
+		co.RekorLocalTree, err = cosign.LoadLocalTree(c.RekorLocalTree)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		defer f.Close()
-
-		payload = internal.NewHashReader(f, sha256.New())
-		if _, err := io.ReadAll(&payload); err != nil {
-			return err
+	}
+	if c.AfterCheckpoint != "" {
+		co.RekorAfterCheckpoint, err = cosign.LoadPinnedCheckpoint(c.AfterCheckpoint)
+		if err != nil {
+			return nil, err
 		}
-		digest := payload.Sum(nil)
-		h = v1.Hash{
-			Hex:       hex.EncodeToString(digest),
-			Algorithm: "sha256",
+	}
+	if c.RequireTlogEntryKind != "" {
+		co.TlogEntryKind = c.RequireTlogEntryKind
+	}
+
+	if len(c.RekorWitnessKeys) > 0 {
+		for _, ref := range c.RekorWitnessKeys {
+			v, err := sigs.PublicKeyFromKeyRef(ctx, ref)
+			if err != nil {
+				return nil, fmt.Errorf("loading rekor witness key %s: %w", ref, err)
+			}
+			co.RekorWitnessKeys = append(co.RekorWitnessKeys, v)
 		}
-		co.ClaimVerifier = cosign.IntotoSubjectClaimVerifier
 	}
+	co.RekorTreeID = c.RekorTreeID
 
 	// Set up TSA, Fulcio roots and tlog public keys and clients.
-	if c.RFC3161TimestampPath != "" && c.KeyOpts.TSACertChainPath == "" {
-		return fmt.Errorf("timestamp-cert-chain is required to validate a rfc3161 timestamp bundle")
-	}
 	if c.KeyOpts.TSACertChainPath != "" {
 		_, err := os.Stat(c.TSACertChainPath)
 		if err != nil {
-			return fmt.Errorf("unable to open timestamp certificate chain file: %w", err)
+			return nil, fmt.Errorf("unable to open timestamp certificate chain file: %w", err)
 		}
 		// TODO: Add support for TUF certificates.
 		pemBytes, err := os.ReadFile(filepath.Clean(c.TSACertChainPath))
 		if err != nil {
-			return fmt.Errorf("error reading certification chain path file: %w", err)
+			return nil, fmt.Errorf("error reading certification chain path file: %w", err)
 		}
 
 		leaves, intermediates, roots, err := tsa.SplitPEMCertificateChain(pemBytes)
 		if err != nil {
-			return fmt.Errorf("error splitting certificates: %w", err)
+			return nil, fmt.Errorf("error splitting certificates: %w", err)
 		}
 		if len(leaves) > 1 {
-			return fmt.Errorf("certificate chain must contain at most one TSA certificate")
+			return nil, fmt.Errorf("certificate chain must contain at most one TSA certificate")
 		}
 		if len(leaves) == 1 {
 			co.TSACertificate = leaves[0]
@@ -159,90 +792,166 @@ func (c *VerifyBlobAttestationCommand) Exec(ctx context.Context, artifactPath st
 		co.TSARootCertificates = roots
 	}
 
+	// trust is the trust material cached in TrustCacheDir, nil to fetch it
+	// from TUF.
+	var trust *trustMaterial
+	needsFulcio := keylessVerification(c.KeyRef, c.Sk) && c.VerifierPlugin == "" && c.CertChain == ""
+	if c.TrustCacheDir != "" && (!c.IgnoreTlog || needsFulcio || !c.IgnoreSCT || c.KeyRef != "") {
+		if trust, err = loadTrustMaterial(ctx, c.TrustCacheDir, c.RefreshTrust); err != nil {
+			return nil, fmt.Errorf("loading the trust material: %w", err)
+		}
+		ex.step("Loaded the trust material cached in %s, valid until %s", c.TrustCacheDir, trust.expires.Format(time.RFC3339))
+	}
+
 	if !c.IgnoreTlog {
-		if c.RekorURL != "" {
-			rekorClient, err := rekor.NewClient(c.RekorURL)
+		switch {
+		case c.RekorClient != nil:
+			co.RekorClient = c.RekorClient
+		case c.RekorURL != "" && c.httpClient(ctx) != nil:
+			co.RekorClient, err = rekor.NewClientWithHTTPClient(c.RekorURL, c.httpClient(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("creating Rekor client: %w", err)
+			}
+		case c.RekorURL != "":
+			co.RekorClient, err = rekor.NewClient(c.RekorURL)
 			if err != nil {
-				return fmt.Errorf("creating Rekor client: %w", err)
+				return nil, fmt.Errorf("creating Rekor client: %w", err)
 			}
-			co.RekorClient = rekorClient
 		}
 		// This performs an online fetch of the Rekor public keys, but this is needed
 		// for verifying tlog entries (both online and offline).
-		co.RekorPubKeys, err = cosign.GetRekorPubs(ctx)
+		co.RekorPubKeys, err = trust.rekorPubKeys(ctx)
 		if err != nil {
-			return fmt.Errorf("getting Rekor public keys: %w", err)
+			return nil, fmt.Errorf("getting Rekor public keys: %w", err)
 		}
 	}
-	if keylessVerification(c.KeyRef, c.Sk) {
+	if keylessVerification(c.KeyRef, c.Sk) && c.VerifierPlugin == "" {
 		// Use default TUF roots if a cert chain is not provided.
 		// This performs an online fetch of the Fulcio roots. This is needed
 		// for verifying keyless certificates (both online and offline).
 		if c.CertChain == "" {
-			co.RootCerts, err = fulcio.GetRoots()
-			if err != nil {
-				return fmt.Errorf("getting Fulcio roots: %w", err)
-			}
-			co.IntermediateCerts, err = fulcio.GetIntermediates()
+			co.RootCerts, co.IntermediateCerts, err = trust.fulcioCerts(ctx)
 			if err != nil {
-				return fmt.Errorf("getting Fulcio intermediates: %w", err)
+				return nil, err
 			}
 		}
 	}
 	// Ignore Signed Certificate Timestamp if the flag is set or a key is provided
 	if !c.IgnoreSCT || c.KeyRef != "" {
-		co.CTLogPubKeys, err = cosign.GetCTLogPubs(ctx)
+		co.CTLogPubKeys, err = trust.ctLogPubKeys(ctx)
 		if err != nil {
-			return fmt.Errorf("getting ctlog public keys: %w", err)
+			return nil, fmt.Errorf("getting ctlog public keys: %w", err)
 		}
 	}
+	return co, nil
+}
+
+// loadedSignature is the envelope to verify, with the key, or the certificate
+// and chain, that verify it.
+type loadedSignature struct {
+	// encodedSig is the envelope of --signature or --bundle as read.
+	encodedSig []byte
+	// keyRef is the key that verifies the envelope, one of --key and the
+	// fallback keys.
+	keyRef  string
+	opts    []static.Option
+	closers []func()
+}
+
+// Close releases the hardware keys the envelope is verified with.
+func (s *loadedSignature) Close() {
+	for _, closeKey := range s.closers {
+		closeKey()
+	}
+}
+
+// loadSignature reads the envelope to verify, from --signature or --bundle,
+// and loads the key or certificate verifying it into co.
+func (c *VerifyBlobAttestationCommand) loadSignature(ctx context.Context, ex *explainer, co *cosign.CheckOpts, maxSignatures int) (_ *loadedSignature, err error) {
+	var closers []func()
+	defer func() {
+		if err != nil {
+			(&loadedSignature{closers: closers}).Close()
+		}
+	}()
 
 	var encodedSig []byte
 	if c.SignaturePath != "" {
-		encodedSig, err = os.ReadFile(filepath.Clean(c.SignaturePath))
-		if err != nil {
-			return fmt.Errorf("reading %s: %w", c.SignaturePath, err)
+		if encodedSig, err = c.readSignature(ctx); err != nil {
+			return nil, err
 		}
 	}
 
 	// Keys are optional!
 	var cert *x509.Certificate
+	var jwtChain []*x509.Certificate
 	opts := make([]static.Option, 0)
+	keyCtx, keySpan := tracing.Start(ctx, "key-load")
 	switch {
 	case c.KeyRef != "":
-		co.SigVerifier, err = sigs.PublicKeyFromKeyRef(ctx, c.KeyRef)
+		co.SigVerifier, err = sigs.PublicKeyFromKeyRef(keyCtx, c.KeyRef)
 		if err != nil {
-			return fmt.Errorf("loading public key: %w", err)
+			return nil, fmt.Errorf("loading public key: %w", err)
 		}
-		pkcs11Key, ok := co.SigVerifier.(*pkcs11key.Key)
-		if ok {
-			defer pkcs11Key.Close()
+		if pkcs11Key, ok := co.SigVerifier.(*pkcs11key.Key); ok {
+			closers = append(closers, pkcs11Key.Close)
 		}
 	case c.Sk:
 		sk, err := pivkey.GetKeyWithSlot(c.Slot)
 		if err != nil {
-			return fmt.Errorf("opening piv token: %w", err)
+			return nil, fmt.Errorf("opening piv token: %w", err)
 		}
-		defer sk.Close()
+		closers = append(closers, sk.Close)
 		co.SigVerifier, err = sk.Verifier()
 		if err != nil {
-			return fmt.Errorf("loading public key from token: %w", err)
+			return nil, fmt.Errorf("loading public key from token: %w", err)
+		}
+	case c.VerifierPlugin != "":
+		co.SigVerifier, err = verifierplugin.NewExecVerifier(c.VerifierPlugin)
+		if err != nil {
+			return nil, fmt.Errorf("loading verifier plugin: %w", err)
 		}
 	case c.CertRef != "":
 		cert, err = loadCertFromFileOrURL(c.CertRef)
 		if err != nil {
-			return err
+			return nil, err
+		}
+	case c.CertFromJWT != "":
+		jc, err := certFromJWT(c.CertFromJWT)
+		if err != nil {
+			return nil, fmt.Errorf("loading the certificate from %s: %w", c.CertFromJWT, err)
+		}
+		if jc.sct != nil {
+			if c.SCTRef != "" {
+				return nil, fmt.Errorf("--sct cannot be combined with a JWT carrying an sct claim")
+			}
+			co.SCT = jc.sct
 		}
+		cert, jwtChain = jc.cert, jc.chain
+	}
+	tracing.End(keySpan, nil)
+	switch {
+	case c.KeyRef != "":
+		ex.step("Loaded the public key %s", c.KeyRef)
+	case c.Sk:
+		ex.step("Loaded the public key from the security key")
+	case c.VerifierPlugin != "":
+		ex.step("Delegating signature verification to %s", c.VerifierPlugin)
+	case c.CertFromJWT != "":
+		ex.step("Loaded the certificate for %s from the x5c header of %s", sigs.CertSubject(cert), c.CertFromJWT)
+	case cert != nil:
+		ex.step("Loaded the certificate %s for %s", c.CertRef, sigs.CertSubject(cert))
 	}
 	if c.BundlePath != "" {
+		ex.step("Loading the envelope, certificate and tlog entry from the bundle %s", c.BundlePath)
 		b, err := cosign.FetchLocalSignedPayloadFromPath(c.BundlePath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		// A certificate is required in the bundle unless we specified with
 		//  --key, --sk, or --certificate.
 		if b.Cert == "" && co.SigVerifier == nil && cert == nil {
-			return fmt.Errorf("bundle does not contain cert for verification, please provide public key")
+			return nil, fmt.Errorf("bundle does not contain cert for verification, please provide public key")
 		}
 		// We have to condition on this because sign-blob may not output the signing
 		// key to the bundle when there is no tlog upload.
@@ -257,30 +966,59 @@ func (c *VerifyBlobAttestationCommand) Exec(ctx context.Context, artifactPath st
 				// check if cert is actually a public key
 				co.SigVerifier, err = sigs.LoadPublicKeyRaw(certBytes, crypto.SHA256)
 				if err != nil {
-					return fmt.Errorf("loading verifier from bundle: %w", err)
+					return nil, fmt.Errorf("loading verifier from bundle: %w", err)
 				}
 			}
 			// if a cert was passed in, make sure it matches the cert in the bundle
 			if cert != nil && !cert.Equal(bundleCert) {
-				return fmt.Errorf("the cert passed in does not match the cert in the provided bundle")
+				return nil, fmt.Errorf("the cert passed in does not match the cert in the provided bundle")
 			}
 			cert = bundleCert
 		}
 
 		encodedSig, err = base64.StdEncoding.DecodeString(b.Base64Signature)
 		if err != nil {
-			return fmt.Errorf("decoding signature: %w", err)
+			return nil, fmt.Errorf("decoding signature: %w", err)
 		}
 		opts = append(opts, static.WithBundle(b.Bundle))
 	}
+	// The envelope is known at this point, bound its signatures before any
+	// of them is verified, then pick the key that signed it.
+	if encodedSig != nil {
+		if err := checkSignatureCount(encodedSig, maxSignatures); err != nil {
+			return nil, err
+		}
+	}
+	keyRef := c.KeyRef
+	if len(c.FallbackKeys) > 0 {
+		var v signature.Verifier
+		keyRef, v, err = selectKey(ctx, append([]string{c.KeyRef}, c.FallbackKeys...), encodedSig, co)
+		if err != nil {
+			return nil, err
+		}
+		if pkcs11Key, ok := v.(*pkcs11key.Key); ok {
+			closers = append(closers, pkcs11Key.Close)
+		}
+		co.SigVerifier = v
+		ex.step("Selected the public key %s", keyRef)
+	}
+	if ks, ok := co.SigVerifier.(*jwkskey.KeySet); ok && encodedSig != nil {
+		var kid string
+		if kid, co.SigVerifier, err = selectJWK(ks, encodedSig); err != nil {
+			return nil, err
+		}
+		if kid != "" {
+			ex.step("Selected the JWK %s by the keyid of the envelope signature", kid)
+		}
+	}
 	if c.RFC3161TimestampPath != "" {
 		var rfc3161Timestamp bundle.RFC3161Timestamp
 		ts, err := blob.LoadFileOrURL(c.RFC3161TimestampPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if err := json.Unmarshal(ts, &rfc3161Timestamp); err != nil {
-			return err
+			return nil, err
 		}
 		opts = append(opts, static.WithRFC3161Timestamp(&rfc3161Timestamp))
 	}
@@ -288,7 +1026,7 @@ func (c *VerifyBlobAttestationCommand) Exec(ctx context.Context, artifactPath st
 	if c.SCTRef != "" {
 		sct, err := os.ReadFile(filepath.Clean(c.SCTRef))
 		if err != nil {
-			return fmt.Errorf("reading sct from file: %w", err)
+			return nil, fmt.Errorf("reading sct from file: %w", err)
 		}
 		co.SCT = sct
 	}
@@ -297,10 +1035,10 @@ func (c *VerifyBlobAttestationCommand) Exec(ctx context.Context, artifactPath st
 	if c.CertChain != "" {
 		chain, err := loadCertChainFromFileOrURL(c.CertChain)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if chain == nil {
-			return errors.New("expected certificate chain in --certificate-chain")
+			return nil, errors.New("expected certificate chain in --certificate-chain")
 		}
 		// Set the last one in the co.RootCerts. This is trusted, as its passed in
 		// via the CLI.
@@ -308,11 +1046,26 @@ func (c *VerifyBlobAttestationCommand) Exec(ctx context.Context, artifactPath st
 			co.RootCerts = x509.NewCertPool()
 		}
 		co.RootCerts.AddCert(chain[len(chain)-1])
-		// Use the whole as the cert chain in the signature object.
-		// The last one is omitted because it is considered the "root".
-		chainPEM, err = cryptoutils.MarshalCertificatesToPEM(chain)
-		if err != nil {
-			return err
+		if len(jwtChain) > 0 {
+			jwtChain = append(jwtChain, chain[:len(chain)-1]...)
+		} else {
+			// Use the whole as the cert chain in the signature object.
+			// The last one is omitted because it is considered the "root".
+			chainPEM, err = cryptoutils.MarshalCertificatesToPEM(chain)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(jwtChain) > 0 {
+		// The x5c chain provides intermediates only, its last certificate is
+		// never trusted as a root. It isn't set as the chain of the signature,
+		// whose last certificate would be taken for the root and dropped.
+		if co.IntermediateCerts == nil {
+			co.IntermediateCerts = x509.NewCertPool()
+		}
+		for _, c := range jwtChain {
+			co.IntermediateCerts.AddCert(c)
 		}
 	}
 
@@ -322,29 +1075,1386 @@ func (c *VerifyBlobAttestationCommand) Exec(ctx context.Context, artifactPath st
 	if cert != nil {
 		certPEM, err = cryptoutils.MarshalCertificateToPEM(cert)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		opts = append(opts, static.WithCertChain(certPEM, chainPEM))
 	}
+	return &loadedSignature{
+		encodedSig: encodedSig,
+		keyRef:     keyRef,
+		opts:       opts,
+		closers:    closers,
+	}, nil
+}
 
-	signature, err := static.NewAttestation(encodedSig, opts...)
-	if err != nil {
+// digest computes the digest of the blob checked against the subjects of the
+// statement, the zero hash with --check-claims=false. With StableBlob, the
+// blob file is kept open, for pinnedBlob.check, until the returned pinnedBlob
+// is closed.
+func (c *VerifyBlobAttestationCommand) digest(ctx context.Context, ex *explainer, vo *VerifyEnvelopeOptions, artifactPath string) (v1.Hash, *pinnedBlob, error) {
+	var h v1.Hash
+	var pinned *pinnedBlob
+	var err error
+	switch {
+	case c.BlobDigest != "":
+		if h, err = parseBlobDigest(c.BlobDigest); err != nil {
+			return v1.Hash{}, nil, err
+		}
+		ex.step("Using the provided blob digest %s:%s", h.Algorithm, h.Hex)
+	case c.MatchImageConfig != "":
+		if h, err = c.imageConfigDigest(ctx, c.MatchImageConfig); err != nil {
+			return v1.Hash{}, nil, err
+		}
+		ex.step("Fetched the config digest %s of the image %s", h, c.MatchImageConfig)
+	case c.MatchAnnotationDigest != "":
+		if h, err = c.imageAnnotationDigest(ctx, c.AnnotationImage, c.MatchAnnotationDigest); err != nil {
+			return v1.Hash{}, nil, err
+		}
+		ex.step("Fetched the digest %s from the annotation %s of the image %s", h, c.MatchAnnotationDigest, c.AnnotationImage)
+	case c.StableBlob:
+		if pinned, h, err = pinBlob(artifactPath, c.HashAlgorithm); err != nil {
+			return v1.Hash{}, nil, err
+		}
+		ex.step("Computed the blob digest %s:%s from the open blob file", h.Algorithm, h.Hex)
+	case c.CheckClaims && c.MatchComputableDigests:
+		if vo.blobDigests, err = c.artifactDigests(ctx, artifactPath); err != nil {
+			return v1.Hash{}, nil, err
+		}
+		h = computedDigest(vo.blobDigests, c.HashAlgorithm)
+		if c.BlobResolver != "" {
+			ex.step("Resolved the content ID %s with %s", artifactPath, c.BlobResolver)
+		}
+		ex.step("Computed the blob digest %s:%s, and its other computable digests", h.Algorithm, h.Hex)
+	case c.CheckClaims:
+		if h, err = c.artifactDigest(ctx, artifactPath); err != nil {
+			return v1.Hash{}, nil, err
+		}
+		if c.BlobResolver != "" {
+			ex.step("Resolved the content ID %s with %s", artifactPath, c.BlobResolver)
+		}
+		if c.BlobRange != "" {
+			ex.step("Computed the digest %s:%s of the bytes %s of the blob", h.Algorithm, h.Hex, c.BlobRange)
+			break
+		}
+		if len(c.BlobParts) > 0 {
+			ex.step("Computed the digest %s:%s of the concatenation of the %d blob parts", h.Algorithm, h.Hex, len(c.BlobParts))
+			break
+		}
+		if c.CDCDigest != "" {
+			ex.step("Computed the content-defined chunk tree digest %s:%s of the blob", h.Algorithm, h.Hex)
+			break
+		}
+		ex.step("Computed the blob digest %s:%s", h.Algorithm, h.Hex)
+	default:
+		ex.step("Not checking the blob against the attestation subjects (--check-claims=false)")
+	}
+	return h, pinned, nil
+}
+
+// verify verifies the attestation of the blob of digest h, from
+// --signature-archive, --from-image or the envelope encodedSig.
+func (c *VerifyBlobAttestationCommand) verify(ctx context.Context, ex *explainer, vo *VerifyEnvelopeOptions, encodedSig []byte, h v1.Hash) (*VerifiedBlobAttestation, error) {
+	switch {
+	case c.SignatureArchive != "":
+		return verifyArchive(ctx, vo, c.SignatureArchive, h)
+	case c.FromImage != "":
+		ex.step("Verifying the attestations of the image %s", c.FromImage)
+		return c.verifyFromImage(ctx, vo, c.FromImage, h)
+	default:
+		// Make sure the signature is a well-formed DSSE envelope before doing any
+		// further work. The original bytes are kept for verification, since the
+		// tlog entry is computed over the envelope as it was serialized.
+		env := &ssldsse.Envelope{}
+		if err := json.Unmarshal(encodedSig, env); err != nil {
+			return nil, fmt.Errorf("decoding DSSE envelope: %w", err)
+		}
+		ex.subjects(encodedSig)
+		ex.step("Verifying the envelope signature, certificate and tlog entry, then the claims")
+		o := *vo
+		o.blobDigest, o.encodedEnvelope = &h, encodedSig
+		return VerifyParsedEnvelopeAttestation(ctx, &o, env, nil)
+	}
+}
+
+// writeOutputs writes the requested outputs of the verified attestation of the
+// blob at artifactPath, of digest h. The outputs that may be skipped with a
+// warning are prepared first, so that with FailOnWarnings nothing is written
+// if a warning was logged.
+func (c *VerifyBlobAttestationCommand) writeOutputs(ctx context.Context, artifactPath string, h v1.Hash, verified *VerifiedBlobAttestation, co *cosign.CheckOpts, rawEnvelope []byte, keyRef string) error {
+	var materials, graph []byte
+	var err error
+	if c.OutputMaterials != "" {
+		if materials, err = marshalMaterials(ctx, verified); err != nil {
+			return err
+		}
+	}
+	if c.OutputSPDXGraph != "" {
+		if graph, err = marshalSPDXGraph(ctx, verified); err != nil {
+			return err
+		}
+	}
+	if err := c.checkWarnings(ctx); err != nil {
 		return err
 	}
+	if c.SaveBundle != "" {
+		if err := saveBundle(ctx, c.SaveBundle, verified, co); err != nil {
+			return err
+		}
+	}
+	if c.OutputEnvelope != "" {
+		if err := saveEnvelope(c.OutputEnvelope, rawEnvelope, verified); err != nil {
+			return err
+		}
+	}
+	if c.OutputVSA != "" {
+		if err := c.issueVSA(ctx, artifactPath, h, verified); err != nil {
+			return err
+		}
+	}
+	if c.OutputLink != "" {
+		if err := c.issueLink(ctx, artifactPath, h, verified); err != nil {
+			return err
+		}
+	}
+	if c.MintClaim != "" {
+		if err := c.mintClaim(ctx, artifactPath, h, verified, keyRef); err != nil {
+			return err
+		}
+	}
+	if c.EmitEdge != "" {
+		if err := c.emitEdge(artifactPath, h, verified, keyRef); err != nil {
+			return err
+		}
+	}
+	if c.OutputMaterials != "" {
+		if err := saveMaterials(c.OutputMaterials, materials); err != nil {
+			return err
+		}
+	}
+	if c.OutputSPDXGraph != "" {
+		if err := saveSPDXGraph(c.OutputSPDXGraph, graph); err != nil {
+			return err
+		}
+	}
+	if c.RelaySign {
+		if err := c.relaySign(ctx, verified, keyRef); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// TODO: This verifier only supports verification of a single signer/signature on
-	// the envelope. Either have the verifier validate that only one signature exists,
-	// or use a multi-signature verifier.
-	if _, err = cosign.VerifyBlobAttestation(ctx, signature, h, co); err != nil {
+// finish reports the outcome err of the verification of the blob at
+// artifactPath, of digest h. The Report is written last, so that it records
+// the final outcome, including the failure of an output or the warnings
+// promoted to errors. Then verified, or the failed checks, are printed.
+func (c *VerifyBlobAttestationCommand) finish(ctx context.Context, artifactPath string, h v1.Hash, verified *VerifiedBlobAttestation, err error) error {
+	if c.Report != "" {
+		if rerr := c.writeReport(artifactPath, h, verified, err); rerr != nil {
+			if err == nil {
+				err = rerr
+			} else {
+				err = &VerificationErrors{Errs: []error{err, rerr}}
+			}
+		}
+	}
+	if err != nil {
+		if c.Output == "json" {
+			if perr := printVerificationErrors(err); perr != nil {
+				return perr
+			}
+		}
 		return err
 	}
+	fmt.Fprintln(os.Stderr, "Verified OK")
+	return printVerifiedBlobAttestation(ctx, c.Output, verified)
+}
+
+// checkWarnings fails with the warnings logged so far, if FailOnWarnings is
+// set.
+func (c *VerifyBlobAttestationCommand) checkWarnings(ctx context.Context) error {
+	if !c.FailOnWarnings {
+		return nil
+	}
+	warnings := ui.RecordedWarnings(ctx)
+	if len(warnings) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(warnings))
+	for _, w := range warnings {
+		errs = append(errs, fmt.Errorf("warning: %s", w))
+	}
+	return &VerificationErrors{Errs: errs}
+}
 
-	// This checks the predicate type -- if no error is returned and no payload is, then
-	// the attestation is not of the given predicate type.
-	if b, gotPredicateType, err := policy.AttestationToPayloadJSON(ctx, c.PredicateType, signature); b == nil && err == nil {
-		return fmt.Errorf("invalid predicate type, expected %s got %s", c.PredicateType, gotPredicateType)
+// VerifiedBlobAttestation summarizes an attestation that passed verification.
+type VerifiedBlobAttestation struct {
+	// PredicateType is the fully-resolved predicate type URI of the verified
+	// statement, even if a shorthand was requested.
+	PredicateType string `json:"predicateType"`
+	// ArchiveMember is the name of the signature archive member that
+	// satisfied verification, if an archive was given.
+	ArchiveMember string `json:"archiveMember,omitempty"`
+
+	// BlobSignatureVerified is set if a detached blob signature was verified
+	// along with the attestation.
+	BlobSignatureVerified bool `json:"blobSignatureVerified,omitempty"`
+	// ProvenancePredicateType is the predicate type of the SLSA provenance
+	// verified along with the attestation, if any.
+	ProvenancePredicateType string `json:"provenancePredicateType,omitempty"`
+	// Chain are the attestations chained to the verified one, in the chain
+	// order, if --attestation-chain was given.
+	Chain []ChainStep `json:"chain,omitempty"`
+	// TlogEntries are the entries of the attestation found in the logs,
+	// with --min-tlog-entries.
+	TlogEntries []TlogEntry `json:"tlogEntries,omitempty"`
+	// Key is the key reference that validated the attestation, if several
+	// were tried.
+	Key string `json:"key,omitempty"`
+	// AttestationTag and AttestationLayer locate the verified attestation of
+	// the --from-image image.
+	AttestationTag   string `json:"attestationTag,omitempty"`
+	AttestationLayer string `json:"attestationLayer,omitempty"`
+	// ImageComponent is the component of the --match-image-config or
+	// --annotation-image image whose digest, ImageDigest, matched the
+	// subjects, e.g. "config" or "annotation org.opencontainers.image.base.digest".
+	ImageComponent string `json:"imageComponent,omitempty"`
+	ImageDigest    string `json:"imageDigest,omitempty"`
+	// Relay describes the attestation re-signed after verification, if any.
+	Relay *RelayedAttestation `json:"relay,omitempty"`
+
+	// signature is the verified attestation.
+	signature oci.Signature
+}
+
+// verificationErrorMessages returns the messages of the failed checks of err.
+func verificationErrorMessages(err error) []string {
+	var verr *VerificationErrors
+	if !errors.As(err, &verr) {
+		return []string{err.Error()}
 	}
+	msgs := make([]string, 0, len(verr.Errs))
+	for _, e := range verr.Errs {
+		msgs = append(msgs, e.Error())
+	}
+	return msgs
+}
 
-	fmt.Fprintln(os.Stderr, "Verified OK")
-	return nil
+// printVerificationErrors prints the failed checks of err as JSON.
+func printVerificationErrors(err error) error {
+	b, err := json.Marshal(struct {
+		Errors []string `json:"errors"`
+	}{Errors: verificationErrorMessages(err)})
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func printVerifiedBlobAttestation(ctx context.Context, output string, verified *VerifiedBlobAttestation) error {
+	switch output {
+	case "json":
+		b, err := json.Marshal(verified)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	default:
+		ui.Infof(ctx, "Predicate type: %s", verified.PredicateType)
+		if verified.ArchiveMember != "" {
+			ui.Infof(ctx, "Archive member: %s", verified.ArchiveMember)
+		}
+		for i, step := range verified.Chain {
+			ui.Infof(ctx, "Chain step %d: %s (%s), %s", i+1, step.File, step.PredicateType, step.Digest)
+		}
+		for _, e := range verified.TlogEntries {
+			ui.Infof(ctx, "Tlog entry in %s: log %s, index %d", e.RekorURL, e.LogID, e.LogIndex)
+		}
+		if verified.Key != "" {
+			ui.Infof(ctx, "Key: %s", verified.Key)
+		}
+		if verified.AttestationTag != "" {
+			ui.Infof(ctx, "Attestation: %s, layer %s", verified.AttestationTag, verified.AttestationLayer)
+		}
+		if verified.ImageComponent != "" {
+			ui.Infof(ctx, "Matched the image %s: %s", verified.ImageComponent, verified.ImageDigest)
+		}
+		if verified.Relay != nil {
+			ui.Infof(ctx, "Upstream signer: %s", verified.Relay.UpstreamSigner)
+			ui.Infof(ctx, "Relayed by: %s", verified.Relay.Signer)
+		}
+	}
+	return nil
+}
+
+// decodeSPKIPins decodes base64-encoded SHA-256 SubjectPublicKeyInfo digests.
+func decodeSPKIPins(pins []string) ([][]byte, error) {
+	decoded := make([][]byte, 0, len(pins))
+	for _, pin := range pins {
+		b, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil {
+			return nil, fmt.Errorf("decoding SPKI pin %q: %w", pin, err)
+		}
+		if len(b) != sha256.Size {
+			return nil, fmt.Errorf("SPKI pin %q is not a SHA-256 digest", pin)
+		}
+		decoded = append(decoded, b)
+	}
+	return decoded, nil
+}
+
+// decodeSKI decodes a hex Subject Key Identifier, whose bytes may be
+// separated by colons as openssl prints them.
+func decodeSKI(ski string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(ski, ":", ""))
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid --require-intermediate-ski %q, expected a hex subject key identifier", ski)
+	}
+	return b, nil
+}
+
+// readSignature reads the DSSE envelope of SignaturePath, from a file, an
+// object reference or an environment variable. The value of an environment
+// variable is scrubbed from the errors.
+func (c *VerifyBlobAttestationCommand) readSignature(ctx context.Context) ([]byte, error) {
+	var encodedSig []byte
+	var err error
+	switch {
+	case strings.HasPrefix(c.SignaturePath, envSignaturePrefix):
+		envelope, value, err := readEnvSignature(c.SignaturePath)
+		if err != nil {
+			return nil, scrubError(err, value)
+		}
+		encodedSig, err := c.unwrapSignature(envelope)
+		return encodedSig, scrubError(err, value, string(envelope))
+	case blob.IsObjectReference(c.SignaturePath):
+		encodedSig, err = blob.LoadObject(ctx, c.SignaturePath)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		encodedSig, err = os.ReadFile(filepath.Clean(c.SignaturePath))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", c.SignaturePath, err)
+		}
+	}
+	return c.unwrapSignature(encodedSig)
+}
+
+// unwrapSignature unwraps the DSSE envelope read from SignaturePath. Its bytes
+// are left as is, since the tlog entry is computed over the envelope as it was
+// serialized.
+func (c *VerifyBlobAttestationCommand) unwrapSignature(encodedSig []byte) ([]byte, error) {
+	encodedSig, err := unwrapPEMEnvelope(encodedSig)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", c.SignaturePath, err)
+	}
+	if c.EnvelopeJSONPath != "" {
+		encodedSig, err = extractEnvelope(encodedSig, c.EnvelopeJSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", c.SignaturePath, err)
+		}
+	}
+	if err := json.Unmarshal(encodedSig, &ssldsse.Envelope{}); err != nil {
+		return nil, fmt.Errorf("reading %s: decoding DSSE envelope: %w", c.SignaturePath, err)
+	}
+	return encodedSig, nil
+}
+
+// attestationURI identifies where the verified attestation was read from.
+func (c *VerifyBlobAttestationCommand) attestationURI(verified *VerifiedBlobAttestation) string {
+	switch {
+	case c.SignatureArchive != "":
+		return c.SignatureArchive + "#" + verified.ArchiveMember
+	case c.FromImage != "":
+		return verified.AttestationTag + "#" + verified.AttestationLayer
+	case c.SignaturePath == "":
+		return c.BundlePath
+	}
+	return c.SignaturePath
+}
+
+// blobResource names the verified blob, of digest h, in the issued
+// attestations.
+func (c *VerifyBlobAttestationCommand) blobResource(artifactPath string, h v1.Hash) string {
+	if artifactPath == "" {
+		// Only the digest of the blob is known.
+		return h.String()
+	}
+	if c.BlobRange != "" {
+		if r, err := parseByteRange(c.BlobRange); err == nil {
+			return filepath.Base(artifactPath) + r.subjectNameSuffix()
+		}
+	}
+	return filepath.Base(artifactPath)
+}
+
+// issueVSA writes a verification summary attestation of the verified blob to
+// OutputVSA, signed with VSAKey.
+func (c *VerifyBlobAttestationCommand) issueVSA(ctx context.Context, artifactPath string, h v1.Hash, verified *VerifiedBlobAttestation) error {
+	st, err := newVSA(c.blobResource(artifactPath, h), h, verified, c.attestationURI(verified), c.VSAPolicyURI)
+	if err != nil {
+		return err
+	}
+
+	sv, err := sign.SignerFromKeyOpts(ctx, "", "", options.KeyOpts{KeyRef: c.VSAKey, PassFunc: c.PassFunc})
+	if err != nil {
+		return fmt.Errorf("getting VSA signer: %w", err)
+	}
+	defer sv.Close()
+	return saveVSA(ctx, c.OutputVSA, sv, st)
+}
+
+// issueLink writes an in-toto link of the verification of the blob to
+// OutputLink, signed with LinkKey.
+func (c *VerifyBlobAttestationCommand) issueLink(ctx context.Context, artifactPath string, h v1.Hash, verified *VerifiedBlobAttestation) error {
+	stepName := c.LinkStepName
+	if stepName == "" {
+		stepName = DefaultLinkStepName
+	}
+	st, err := newVerificationLink(stepName, c.blobResource(artifactPath, h), h, verified, c.attestationURI(verified), os.Args)
+	if err != nil {
+		return err
+	}
+
+	sv, err := sign.SignerFromKeyOpts(ctx, "", "", options.KeyOpts{KeyRef: c.LinkKey, PassFunc: c.PassFunc})
+	if err != nil {
+		return fmt.Errorf("getting link signer: %w", err)
+	}
+	defer sv.Close()
+	return saveLink(ctx, c.OutputLink, sv, st)
+}
+
+// mintClaim writes a JWT of the verification of the blob to MintClaim,
+// signed with MintClaimKeyOpts.
+func (c *VerifyBlobAttestationCommand) mintClaim(ctx context.Context, artifactPath string, h v1.Hash, verified *VerifiedBlobAttestation, keyRef string) error {
+	ttl := c.MintClaimTTL
+	if ttl == 0 {
+		ttl = DefaultMintClaimTTL
+	}
+	claims, err := newVerificationClaims(c.blobResource(artifactPath, h), h, verified, keyRef, time.Now(), ttl)
+	if err != nil {
+		return err
+	}
+
+	sv, err := sign.SignerFromKeyOpts(ctx, "", "", c.MintClaimKeyOpts)
+	if err != nil {
+		return fmt.Errorf("getting claim signer: %w", err)
+	}
+	defer sv.Close()
+	return saveClaim(ctx, c.MintClaim, sv, claims)
+}
+
+// emitEdge appends the provenance graph edge of the verified blob, of digest
+// h, to EmitEdge.
+func (c *VerifyBlobAttestationCommand) emitEdge(artifactPath string, h v1.Hash, verified *VerifiedBlobAttestation, keyRef string) error {
+	edge, err := newProvenanceEdge(c.blobResource(artifactPath, h), h, verified, keyRef, time.Now())
+	if err != nil {
+		return err
+	}
+	return appendEdge(c.EmitEdge, edge)
+}
+
+// validOID reports whether oid is an object identifier in dotted form.
+func validOID(oid string) bool {
+	arcs := strings.Split(oid, ".")
+	if len(arcs) < 2 {
+		return false
+	}
+	for _, arc := range arcs {
+		if _, err := strconv.ParseUint(arc, 10, 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// selectKey returns the first of refs whose public key validates a signature
+// of the DSSE envelope, with the PAE and signature decoding of co, along with
+// its verifier.
+func selectKey(ctx context.Context, refs []string, envBytes []byte, co *cosign.CheckOpts) (string, signature.Verifier, error) {
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(envBytes, &env); err != nil {
+		return "", nil, fmt.Errorf("decoding DSSE envelope: %w", err)
+	}
+	errs := make([]error, 0, len(refs))
+	for _, ref := range refs {
+		v, err := sigs.PublicKeyFromKeyRef(ctx, ref)
+		if err != nil {
+			return "", nil, fmt.Errorf("loading public key %s: %w", ref, err)
+		}
+		err = cosign.VerifyDSSEEnvelopeSignatures(ctx, v, &env, co)
+		if err == nil {
+			return ref, v, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", ref, err))
+		if pkcs11Key, ok := v.(*pkcs11key.Key); ok {
+			pkcs11Key.Close()
+		}
+	}
+	return "", nil, fmt.Errorf("no --key validates the attestation: %w", &VerificationErrors{Errs: errs})
+}
+
+// predicateFieldRequirements parses RequirePredicateFields.
+func (c *VerifyBlobAttestationCommand) predicateFieldRequirements() ([]*PredicateFieldRequirement, error) {
+	reqs := make([]*PredicateFieldRequirement, 0, len(c.RequirePredicateFields))
+	for _, s := range c.RequirePredicateFields {
+		r, err := NewPredicateFieldRequirement(s)
+		if err != nil {
+			return nil, fmt.Errorf("parsing --require-predicate-field: %w", err)
+		}
+		reqs = append(reqs, r)
+	}
+	return reqs, nil
+}
+
+// subjectNameRegexp compiles SubjectNameRegexp, if set.
+func (c *VerifyBlobAttestationCommand) subjectNameRegexp() (*regexp.Regexp, error) {
+	switch {
+	case c.SubjectNameRegexp == "":
+		return nil, nil
+	case c.SubjectName != "":
+		return nil, fmt.Errorf("--subject-name-regexp cannot be combined with --subject-name")
+	case !c.CheckClaims:
+		return nil, fmt.Errorf("--subject-name-regexp cannot be used with --check-claims=false")
+	}
+	re, err := regexp.Compile(c.SubjectNameRegexp)
+	if err != nil {
+		return nil, fmt.Errorf("compiling --subject-name-regexp: %w", err)
+	}
+	return re, nil
+}
+
+// selectJWK narrows the keys of ks to the one whose kid is the keyid of a
+// signature of the DSSE envelope, returning the kid. If no signature has a
+// keyid, ks is returned as is.
+func selectJWK(ks *jwkskey.KeySet, envBytes []byte) (string, signature.Verifier, error) {
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(envBytes, &env); err != nil {
+		return "", nil, fmt.Errorf("decoding DSSE envelope: %w", err)
+	}
+	var keyIDs []string
+	for _, s := range env.Signatures {
+		if s.KeyID == "" {
+			continue
+		}
+		if v, err := ks.Key(s.KeyID); err == nil {
+			return s.KeyID, v, nil
+		}
+		keyIDs = append(keyIDs, s.KeyID)
+	}
+	if len(keyIDs) > 0 {
+		return "", nil, fmt.Errorf("no key of the JWKS has the keyid of an envelope signature (%s)", strings.Join(keyIDs, ", "))
+	}
+	return "", ks, nil
+}
+
+// verifyDetached runs the checks of the detached blob signature, provenance,
+// attestation chain, tlog entries in several logs and CT inclusion, if any,
+// and records them in verified. attErr is the error of the attestation
+// verification. The chain, tlog entries and CT inclusion are those of the
+// verified attestation, so they are only checked if attErr is nil. All other
+// checks are always run, so that each is reported independently: the status
+// of each check which passed is logged, and each failure is returned.
+func (c *VerifyBlobAttestationCommand) verifyDetached(ctx context.Context, vo *VerifyEnvelopeOptions, verified *VerifiedBlobAttestation, attErr error, artifactPath, keyRef string, h v1.Hash) error {
+	type check struct {
+		name   string
+		err    error
+		detail string
+	}
+	checks := []check{{name: "Attestation", err: attErr}}
+	if c.BlobSignature != "" {
+		err := c.verifyBlobSignature(ctx, artifactPath, keyRef)
+		if err == nil && attErr == nil {
+			verified.BlobSignatureVerified = true
+		}
+		checks = append(checks, check{name: "Blob signature", err: err})
+	}
+	if c.Provenance != "" {
+		provenanceType, err := verifyProvenance(ctx, vo, c.Provenance, h)
+		if err == nil && attErr == nil {
+			verified.ProvenancePredicateType = provenanceType
+		}
+		checks = append(checks, check{name: "Provenance", err: err, detail: provenanceType})
+	}
+	if c.AttestationChain != "" && attErr == nil {
+		chain, err := verifyAttestationChain(ctx, vo, c.AttestationChain, verified.signature)
+		verified.Chain = chain
+		checks = append(checks, check{name: "Attestation chain", err: err})
+	}
+	if c.MinTlogEntries > 0 && attErr == nil {
+		logs, err := c.rekorLogs(ctx)
+		if err == nil {
+			verified.TlogEntries, err = verifyTlogEntries(ctx, vo.CheckOpts, signedAttestation(verified.signature), logs, c.MinTlogEntries)
+		}
+		checks = append(checks, check{name: "Tlog entries", err: err})
+	}
+	if c.RequireCTInclusion && attErr == nil {
+		logURL := c.CTLogURL
+		if logURL == "" {
+			logURL = options.DefaultCTLogURL
+		}
+		err := verifyCTInclusion(ctx, signedAttestation(verified.signature), vo.CheckOpts, logURL, nil)
+		checks = append(checks, check{name: "CT inclusion", err: err})
+	}
+
+	var errs []error
+	for _, ck := range checks {
+		switch {
+		case ck.err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", strings.ToLower(ck.name), ck.err))
+		case ck.detail != "":
+			ui.Infof(ctx, "%s: Verified OK (%s)", ck.name, ck.detail)
+		default:
+			ui.Infof(ctx, "%s: Verified OK", ck.name)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return &VerificationErrors{Errs: errs}
+}
+
+// verifyBlobSignature verifies the detached blob signature with the key or
+// certificate the attestation is verified with.
+func (c *VerifyBlobAttestationCommand) verifyBlobSignature(ctx context.Context, artifactPath, keyRef string) error {
+	return c.detachedSignatureCmd(keyRef, c.BlobSignature).Exec(ctx, artifactPath)
+}
+
+// detachedSignatureCmd returns a verify-blob command checking the detached
+// signature sigRef with keyRef, or the certificate the attestation is verified
+// with.
+func (c *VerifyBlobAttestationCommand) detachedSignatureCmd(keyRef, sigRef string) *VerifyBlobCmd {
+	ko := c.KeyOpts
+	ko.KeyRef = keyRef
+	// The bundle and timestamp belong to the attestation, not to sigRef.
+	ko.BundlePath = ""
+	ko.RFC3161TimestampPath = ""
+	return &VerifyBlobCmd{
+		KeyOpts:                      ko,
+		CertVerifyOptions:            c.CertVerifyOptions,
+		CertRef:                      c.CertRef,
+		CertChain:                    c.CertChain,
+		SigRef:                       sigRef,
+		CertGithubWorkflowTrigger:    c.CertGithubWorkflowTrigger,
+		CertGithubWorkflowSHA:        c.CertGithubWorkflowSHA,
+		CertGithubWorkflowName:       c.CertGithubWorkflowName,
+		CertGithubWorkflowRepository: c.CertGithubWorkflowRepository,
+		CertGithubWorkflowRef:        c.CertGithubWorkflowRef,
+		IgnoreSCT:                    c.IgnoreSCT,
+		SCTRef:                       c.SCTRef,
+		Offline:                      c.Offline || c.NoNetwork,
+		IgnoreTlog:                   c.IgnoreTlog,
+	}
+}
+
+// pemEnvelopeType is the PEM block type of a PEM-wrapped DSSE envelope.
+const pemEnvelopeType = "DSSE ENVELOPE"
+
+// unwrapPEMEnvelope returns the DSSE envelope JSON wrapped in a PEM block, or
+// b unchanged if it isn't PEM encoded.
+func unwrapPEMEnvelope(b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN ")) {
+		return b, nil
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("invalid PEM encoded DSSE envelope")
+	}
+	if block.Type != pemEnvelopeType {
+		return nil, fmt.Errorf("unexpected PEM block type %q, expected %q", block.Type, pemEnvelopeType)
+	}
+	return block.Bytes, nil
+}
+
+// VerifyEnvelopeOptions configures the verification of a parsed DSSE envelope.
+type VerifyEnvelopeOptions struct {
+	// CheckOpts holds the trust material and identity constraints used to
+	// verify the envelope's signature.
+	CheckOpts *cosign.CheckOpts
+	// SignatureOptions are applied to the attestation built from the
+	// envelope, e.g. the signing certificate, a Rekor bundle or an RFC3161
+	// timestamp.
+	SignatureOptions []static.Option
+
+	// CheckClaims verifies that the blob's sha256 digest is a subject of
+	// the in-toto statement.
+	CheckClaims bool
+	// PredicateType is the expected predicate type, either a shorthand
+	// (see options.PredicateTypeMap) or a URI.
+	PredicateType string
+	// PredicateVersionConstraint, if set, accepts any predicate type of the
+	// family of PredicateType whose version satisfies it, rather than
+	// PredicateType only.
+	PredicateVersionConstraint *PredicateVersionConstraint
+
+	// AllSubjectsMatch requires every subject of the statement to match the
+	// blob, rather than any of them.
+	AllSubjectsMatch bool
+	// MatchComputableDigests requires every digest of a subject computed
+	// with one of the computableDigests hash functions to match the blob,
+	// and at least one of them to, for the subject to match. Digests of
+	// other hash functions are ignored. Otherwise a subject matches if its
+	// digest of the blob digest hash function does.
+	MatchComputableDigests bool
+	// SubjectName, if set, requires a subject with this name to exist and to
+	// match the blob. A subject matching the blob under another name doesn't
+	// satisfy it.
+	SubjectName string
+	// SubjectIndex, if set, requires the subject at this position of the
+	// statement to match the blob, and to satisfy SubjectName and
+	// SubjectNameRegexp. A subject matching the blob at another position
+	// doesn't satisfy it.
+	SubjectIndex *int
+	// SubjectNameRegexp, if set, only lets subjects whose name matches it
+	// match the blob.
+	SubjectNameRegexp *regexp.Regexp
+	// DigestEncoding is the encoding of the subject digests, one of
+	// DigestEncodingHex (the default) or DigestEncodingMultihash.
+	DigestEncoding string
+	// HashAlgorithm is the hash function the blob digest is computed with,
+	// sha256 (the default), sha3-256 or sha3-512.
+	HashAlgorithm string
+	// RequireKeyID, if set, requires the envelope signature bearing this
+	// keyid to validate, rather than any of its signatures.
+	RequireKeyID string
+	// AllowedSignatureAlgorithms, if set, are the only algorithms the
+	// envelope signatures verifying with the key or certificate may use,
+	// see checkSignatureAlgorithms.
+	AllowedSignatureAlgorithms []string
+	// MaxSignatures, if positive, rejects envelopes carrying more signatures
+	// before verifying any of them.
+	MaxSignatures int
+	// LinkedDir, if set, is a directory in which every file digest referenced
+	// by the predicate must match a file. Only predicate types with known
+	// references are checked, see verifyLinked.
+	LinkedDir string
+	// RequireSBOM requires the predicate to be an SPDX or CycloneDX document
+	// listing at least one component, see checkSBOM.
+	RequireSBOM bool
+	// RequireSubjectURIAndDigest requires the subjects matching the blob to
+	// have a uri as well as a digest, see checkSubjectURIs. It only applies
+	// when CheckClaims is set.
+	RequireSubjectURIAndDigest bool
+	// RequireSortedSubjects requires the subjects of the statement to be in
+	// the canonical order, see checkSubjectOrder.
+	RequireSortedSubjects bool
+	// SLSABuilderID, if set, is the builder ID the SLSA provenance predicate
+	// must record, see checkBuilderID.
+	SLSABuilderID string
+	// RequireReproducible requires the SLSA provenance predicate to claim the
+	// build is reproducible, see checkReproducible.
+	RequireReproducible bool
+	// StatementType, if set, is the only _type accepted for the in-toto
+	// statement. Otherwise the standard in-toto statement types are accepted.
+	StatementType string
+	// ParseStrictness is how strictly the in-toto statement is parsed, one
+	// of ParseStrict (the default), ParseLenient or ParseSchema.
+	ParseStrictness string
+	// IdentityPredicateMap, if set, restricts the predicate types the
+	// identity of the signing certificate may attest to, see
+	// checkIdentityPredicate.
+	IdentityPredicateMap *IdentityPredicateMap
+	// DecryptPredicate, if set, decrypts the predicate once the signature is
+	// verified, and the claims and policies are checked against the
+	// plaintext, see decryptPredicate.
+	DecryptPredicate func([]byte) ([]byte, error)
+	// PredicateOnlySubject, if set, verifies envelopes signing only a
+	// predicate, of any payload type, and checks the statement made of the
+	// predicate, of type PredicateType, about this subject, see
+	// predicateOnlyStatement.
+	PredicateOnlySubject *in_toto.Subject
+	// RejectUnknownPredicateFields fails verification if the top-level
+	// predicate fields aren't all in AllowedPredicateFields.
+	RejectUnknownPredicateFields bool
+	AllowedPredicateFields       []string
+	// RequiredPredicateFields are requirements on the values of fields of
+	// the predicate, which must all hold.
+	RequiredPredicateFields []*PredicateFieldRequirement
+
+	// blobDigests are the digests of the blob with the computableDigests
+	// hash functions, when MatchComputableDigests is set.
+	blobDigests map[string]string
+	// blobDigest, when set, is the digest of the blob, which isn't read. The
+	// command computes it from a blob file, its parts or an image.
+	blobDigest *v1.Hash
+	// encodedEnvelope, when set, is the encoding the envelope was parsed
+	// from, which is verified instead of re-serializing the envelope, since
+	// the tlog entry is computed over it.
+	encodedEnvelope []byte
+}
+
+// VerifyParsedEnvelope verifies the signature and claims of an already
+// unmarshalled DSSE envelope against the given blob. The blob is only read
+// when opts.CheckClaims is set.
+//
+// The envelope is re-serialized before verification, so a Rekor bundle
+// passed in opts.SignatureOptions only verifies if it was computed over the
+// canonical encoding of the envelope.
+func VerifyParsedEnvelope(ctx context.Context, opts *VerifyEnvelopeOptions, env *ssldsse.Envelope, blob io.Reader) error {
+	_, err := VerifyParsedEnvelopeAttestation(ctx, opts, env, blob)
+	return err
+}
+
+// VerifyParsedEnvelopeAttestation is VerifyParsedEnvelope, returning the
+// verified attestation, whose predicate can be read with its Statement.
+func VerifyParsedEnvelopeAttestation(ctx context.Context, opts *VerifyEnvelopeOptions, env *ssldsse.Envelope, blob io.Reader) (*VerifiedBlobAttestation, error) {
+	if env == nil {
+		return nil, errors.New("no DSSE envelope provided")
+	}
+	if opts != nil && opts.encodedEnvelope != nil {
+		return verifyEnvelope(ctx, opts, opts.encodedEnvelope, blob)
+	}
+	envBytes, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling DSSE envelope: %w", err)
+	}
+	return verifyEnvelope(ctx, opts, envBytes, blob)
+}
+
+func verifyEnvelope(ctx context.Context, opts *VerifyEnvelopeOptions, envBytes []byte, blob io.Reader) (*VerifiedBlobAttestation, error) {
+	if opts == nil || opts.CheckOpts == nil {
+		return nil, errors.New("no check options provided")
+	}
+
+	var h v1.Hash
+	switch {
+	case opts.blobDigest != nil:
+		h = *opts.blobDigest
+	case opts.CheckClaims:
+		if blob == nil {
+			return nil, errors.New("a blob is required to check claims")
+		}
+		var err error
+		if opts.MatchComputableDigests {
+			digests, err := digestBlob(blob)
+			if err != nil {
+				return nil, err
+			}
+			o := *opts
+			o.blobDigests = digests
+			opts = &o
+			h = computedDigest(digests, opts.HashAlgorithm)
+		} else if h, err = hashBlob(blob, opts.HashAlgorithm); err != nil {
+			return nil, err
+		}
+	}
+	return verifyEnvelopeDigest(ctx, opts, envBytes, h)
+}
+
+// blobHashes are the hash functions a blob digest can be computed with, by
+// their in-toto digest set name.
+var blobHashes = map[string]func() hash.Hash{
+	"sha256":   sha256.New,
+	"sha3-256": sha3.New256,
+	"sha3-512": sha3.New512,
+}
+
+func supportedBlobHashes() []string {
+	names := make([]string, 0, len(blobHashes))
+	for name := range blobHashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// computableDigests are the hash functions a blob is digested with to match
+// every digest of its subjects with MatchComputableDigests, by in-toto digest
+// set name.
+var computableDigests = map[string]func() hash.Hash{
+	"sha256":   sha256.New,
+	"sha384":   sha512.New384,
+	"sha512":   sha512.New,
+	"sha3-256": sha3.New256,
+	"sha3-512": sha3.New512,
+}
+
+// digestBlob computes the digests of the blob with all the computable hash
+// functions, in a single pass.
+func digestBlob(blob io.Reader) (map[string]string, error) {
+	hashers := make(map[string]hash.Hash, len(computableDigests))
+	writers := make([]io.Writer, 0, len(computableDigests))
+	for name, newHash := range computableDigests {
+		hashers[name] = newHash()
+		writers = append(writers, hashers[name])
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), blob); err != nil {
+		return nil, err
+	}
+	digests := make(map[string]string, len(hashers))
+	for name, h := range hashers {
+		digests[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return digests, nil
+}
+
+// computedDigest returns the digest of digests, see digestBlob, with the
+// named hash function, sha256 if alg is empty.
+func computedDigest(digests map[string]string, alg string) v1.Hash {
+	if alg == "" {
+		alg = "sha256"
+	}
+	return v1.Hash{Algorithm: alg, Hex: digests[alg]}
+}
+
+// blobDigest computes the sha256 digest of the blob.
+func blobDigest(blob io.Reader) (v1.Hash, error) {
+	return hashBlob(blob, "sha256")
+}
+
+// hashBlob computes the digest of the blob with the named hash function,
+// sha256 if alg is empty.
+func hashBlob(blob io.Reader, alg string) (v1.Hash, error) {
+	if alg == "" {
+		alg = "sha256"
+	}
+	newHash, ok := blobHashes[alg]
+	if !ok {
+		return v1.Hash{}, fmt.Errorf("unsupported hash algorithm %q", alg)
+	}
+	payload := internal.NewHashReader(blob, newHash())
+	if _, err := io.ReadAll(&payload); err != nil {
+		return v1.Hash{}, err
+	}
+	return v1.Hash{
+		Hex:       hex.EncodeToString(payload.Sum(nil)),
+		Algorithm: alg,
+	}, nil
+}
+
+// hashFile computes the digest of the file at path, see hashBlob. A regular
+// file is memory-mapped and streamed through the hash through a buffer of
+// fixed size, rather than read into memory, so that large artifacts don't
+// need as much memory as their size. Other files, such as pipes or
+// /dev/stdin, can't be mapped and are streamed from the file instead.
+func hashFile(path, alg string) (v1.Hash, error) {
+	if alg == "" {
+		alg = "sha256"
+	}
+	newHash, ok := blobHashes[alg]
+	if !ok {
+		return v1.Hash{}, fmt.Errorf("unsupported hash algorithm %q", alg)
+	}
+	path = filepath.Clean(path)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	var blob io.Reader
+	if fi.Mode().IsRegular() {
+		r, err := mmap.Open(path)
+		if err != nil {
+			return v1.Hash{}, err
+		}
+		defer r.Close()
+		blob = io.NewSectionReader(r, 0, int64(r.Len()))
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return v1.Hash{}, err
+		}
+		defer f.Close()
+		blob = f
+	}
+	hasher := newHash()
+	if _, err := io.Copy(hasher, blob); err != nil {
+		return v1.Hash{}, err
+	}
+	return v1.Hash{
+		Hex:       hex.EncodeToString(hasher.Sum(nil)),
+		Algorithm: alg,
+	}, nil
+}
+
+// artifactDigest computes the digest of the artifact at path, after
+// decompressing or canonicalizing it if requested, or its CDC digest.
+func (c *VerifyBlobAttestationCommand) artifactDigest(ctx context.Context, path string) (v1.Hash, error) {
+	if c.BlobResolver == "" && c.Decompress == "" && !c.BlobJSONCanonical && c.BlobRange == "" && len(c.BlobParts) == 0 && c.CDCDigest == "" {
+		return hashFile(path, c.HashAlgorithm)
+	}
+	r, closeArtifact, err := c.openArtifact(ctx, path)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	defer closeArtifact()
+	if c.CDCDigest != "" {
+		p, err := parseCDCSpec(c.CDCDigest)
+		if err != nil {
+			return v1.Hash{}, err
+		}
+		if c.BlobJSONCanonical {
+			canonicalized, err := canonicalizeBlob(r)
+			if err != nil {
+				return v1.Hash{}, err
+			}
+			r = bytes.NewReader(canonicalized)
+		}
+		return cdcDigest(r, p)
+	}
+	if c.BlobJSONCanonical {
+		return canonicalBlobDigest(r, c.HashAlgorithm)
+	}
+	return hashBlob(r, c.HashAlgorithm)
+}
+
+// artifactDigests computes the digests of the artifact at path with all the
+// computable hash functions, see artifactDigest.
+func (c *VerifyBlobAttestationCommand) artifactDigests(ctx context.Context, path string) (map[string]string, error) {
+	r, closeArtifact, err := c.openArtifact(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArtifact()
+	if c.BlobJSONCanonical {
+		canonicalized, err := canonicalizeBlob(r)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(canonicalized)
+	}
+	return digestBlob(r)
+}
+
+// openArtifact returns the content of the artifact at path, or of the
+// BlobParts, resolved with BlobResolver and decompressed if requested, or its
+// BlobRange.
+func (c *VerifyBlobAttestationCommand) openArtifact(ctx context.Context, path string) (io.Reader, func(), error) {
+	if c.BlobRange != "" {
+		r, err := parseByteRange(c.BlobRange)
+		if err != nil {
+			return nil, nil, err
+		}
+		return openByteRange(path, r)
+	}
+	var f io.ReadCloser
+	var err error
+	switch {
+	case len(c.BlobParts) > 0:
+		f, err = openBlobParts(c.BlobParts)
+	case c.BlobResolver != "":
+		f, err = resolveBlob(ctx, c.BlobResolver, path)
+	default:
+		f, err = os.Open(filepath.Clean(path))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Decompress == "" {
+		return f, func() { f.Close() }, nil
+	}
+	zr, err := decompressBlob(f, c.Decompress)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return zr, func() {
+		zr.Close()
+		f.Close()
+	}, nil
+}
+
+// openBlobParts returns the concatenation of the part files, in the order of
+// paths.
+func openBlobParts(paths []string) (io.ReadCloser, error) {
+	p := &blobParts{files: make([]*os.File, 0, len(paths))}
+	for _, path := range paths {
+		f, err := os.Open(filepath.Clean(path))
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.files = append(p.files, f)
+	}
+	return p, nil
+}
+
+// blobParts reads the concatenation of its files, each closed once read.
+type blobParts struct {
+	files []*os.File
+}
+
+func (p *blobParts) Read(b []byte) (int, error) {
+	for len(p.files) > 0 {
+		n, err := p.files[0].Read(b)
+		if errors.Is(err, io.EOF) {
+			p.files[0].Close()
+			p.files = p.files[1:]
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.EOF
+}
+
+func (p *blobParts) Close() error {
+	for _, f := range p.files {
+		f.Close()
+	}
+	p.files = nil
+	return nil
+}
+
+// resolveBlob returns the content of contentID, streamed by the blobresolver
+// plugin command.
+func resolveBlob(ctx context.Context, command, contentID string) (io.ReadCloser, error) {
+	r, err := blobresolver.NewExecResolver(command)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolve(ctx, contentID)
+}
+
+// CompressionZstd is the zstd compression of a blob.
+const CompressionZstd = "zstd"
+
+// zstdMagic is the magic number starting a zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// decompressBlob returns a reader decompressing the blob, after checking it
+// starts with the magic number of the compression.
+func decompressBlob(blob io.Reader, compression string) (io.ReadCloser, error) {
+	if compression != CompressionZstd {
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+	br := bufio.NewReader(blob)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.Equal(magic, zstdMagic) {
+		return nil, errors.New("blob is not zstd compressed")
+	}
+	zr, err := zstd.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing blob: %w", err)
+	}
+	return zr.IOReadCloser(), nil
+}
+
+// parseBlobDigest parses a sha256 digest, given as sha256:<hex> or <hex>.
+func parseBlobDigest(digest string) (v1.Hash, error) {
+	if !strings.Contains(digest, ":") {
+		digest = "sha256:" + digest
+	}
+	h, err := v1.NewHash(strings.ToLower(digest))
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("parsing --blob-digest: %w", err)
+	}
+	return h, nil
+}
+
+// canonicalBlobDigest computes the digest, see hashBlob, of the JCS (RFC 8785)
+// canonicalization of the JSON blob.
+func canonicalBlobDigest(blob io.Reader, alg string) (v1.Hash, error) {
+	canonicalized, err := canonicalizeBlob(blob)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	return hashBlob(bytes.NewReader(canonicalized), alg)
+}
+
+// canonicalizeBlob returns the JCS (RFC 8785) canonicalization of the JSON
+// blob.
+func canonicalizeBlob(blob io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(blob)
+	if err != nil {
+		return nil, err
+	}
+	canonicalized, err := jsoncanonicalizer.Transform(b)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalizing blob, is it valid JSON? %w", err)
+	}
+	return canonicalized, nil
+}
+
+// verifyEnvelopeDigest verifies the envelope, checking its claims against the
+// blob digest h if opts.CheckClaims is set. Once the signature is verified,
+// all the remaining checks are run, and their failures are returned together
+// as a *VerificationErrors.
+func verifyEnvelopeDigest(ctx context.Context, opts *VerifyEnvelopeOptions, envBytes []byte, h v1.Hash) (*VerifiedBlobAttestation, error) {
+	// Work on a copy, claims are checked below along with the other controls.
+	co := *opts.CheckOpts
+	co.ClaimVerifier = nil
+
+	_, span := tracing.Start(ctx, "parse")
+	var err error
+	if opts.MaxSignatures > 0 {
+		err = checkSignatureCount(envBytes, opts.MaxSignatures)
+	}
+	var signature oci.Signature
+	if err == nil {
+		signature, err = static.NewAttestation(envBytes, opts.SignatureOptions...)
+	}
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: This verifier only supports verification of a single signer/signature on
+	// the envelope. Either have the verifier validate that only one signature exists,
+	// or use a multi-signature verifier.
+	verifyEnvelopeSignature := cosign.VerifyBlobAttestation
+	if opts.PredicateOnlySubject != nil {
+		verifyEnvelopeSignature = cosign.VerifyBlobDSSEEnvelope
+	}
+	if _, err = verifyEnvelopeSignature(ctx, signature, h, &co); err != nil {
+		return nil, err
+	}
+	if opts.PredicateOnlySubject != nil {
+		if signature, err = predicateOnlyStatement(signature, opts.PredicateOnlySubject, opts.PredicateType); err != nil {
+			return nil, err
+		}
+	}
+	if opts.DecryptPredicate != nil {
+		if signature, err = decryptPredicate(signature, opts.DecryptPredicate); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	claimCtx, span := tracing.Start(ctx, "claim")
+	if err := checkStatement(signature, opts); err != nil {
+		errs = append(errs, err)
+	}
+	if opts.CheckClaims {
+		if err := subjectClaimVerifier(opts)(signature, h, nil); err != nil {
+			errs = append(errs, err)
+		}
+		if opts.RequireSubjectURIAndDigest {
+			if err := checkSubjectURIs(signature, h, opts); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if opts.RequireSortedSubjects {
+		if err := checkSubjectOrder(signature); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.RequireKeyID != "" {
+		if err := verifyKeyIDSignature(claimCtx, signedAttestation(signature), &co, opts.RequireKeyID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(opts.AllowedSignatureAlgorithms) > 0 {
+		if err := checkSignatureAlgorithms(signedAttestation(signature), &co, opts.AllowedSignatureAlgorithms); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	tracing.End(span, errors.Join(errs...))
+
+	policyCtx, span := tracing.Start(ctx, "policy")
+	policyErrs := len(errs)
+
+	// With a version constraint, the predicate type of the statement is
+	// expected if its version satisfies the constraint.
+	predicateType := opts.PredicateType
+	var versionErr error
+	if opts.PredicateVersionConstraint != nil {
+		if st, err := statementFromAttestation(signature); err == nil {
+			if versionErr = opts.PredicateVersionConstraint.Check(st.PredicateType); versionErr == nil {
+				predicateType = st.PredicateType
+			}
+		}
+	}
+	// This checks the predicate type -- if no error is returned and no payload is, then
+	// the attestation is not of the given predicate type.
+	b, gotPredicateType, err := policy.AttestationToPayloadJSON(policyCtx, predicateType, signature)
+	switch {
+	case versionErr != nil:
+		errs = append(errs, versionErr)
+	case b == nil && err == nil:
+		errs = append(errs, fmt.Errorf("invalid predicate type, expected %s got %s", opts.PredicateType, gotPredicateType))
+	}
+	if opts.LinkedDir != "" {
+		if err := verifyLinked(policyCtx, signature, opts.LinkedDir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.RequireSBOM {
+		if err := checkSBOM(signature); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.SLSABuilderID != "" {
+		if err := checkBuilderID(signature, opts.SLSABuilderID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.RequireReproducible {
+		if err := checkReproducible(signature); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.RejectUnknownPredicateFields {
+		if err := checkPredicateFields(signature, opts.AllowedPredicateFields); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.IdentityPredicateMap != nil {
+		if err := checkIdentityPredicate(signature, opts.IdentityPredicateMap); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(opts.RequiredPredicateFields) > 0 {
+		errs = append(errs, checkPredicateFieldRequirements(signature, opts.RequiredPredicateFields)...)
+	}
+	tracing.End(span, errors.Join(errs[policyErrs:]...))
+	if len(errs) > 0 {
+		return nil, &VerificationErrors{Errs: errs}
+	}
+	return &VerifiedBlobAttestation{PredicateType: gotPredicateType, signature: signature}, nil
+}
+
+// VerificationErrors aggregates the failures of the independent checks run on
+// a signature-verified attestation.
+type VerificationErrors struct {
+	Errs []error
+}
+
+// Error lists the failures, one per line.
+func (e *VerificationErrors) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *VerificationErrors) Unwrap() []error {
+	return e.Errs
+}
+
+// httpClient returns the HTTP client of the Rekor clients: HTTPClient, with
+// its transport guarded by netguard if the network is disabled in ctx, since
+// the Rekor clients have their own transports.
+func (c *VerifyBlobAttestationCommand) httpClient(ctx context.Context) *http.Client {
+	if !netguard.Disabled(ctx) {
+		return c.HTTPClient
+	}
+	hc := &http.Client{}
+	if c.HTTPClient != nil {
+		*hc = *c.HTTPClient
+	}
+	hc.Transport = netguard.Transport(ctx, hc.Transport)
+	return hc
+}
+
+// guardlessKeyRef reports whether the key reference is loaded by a client
+// with its own transport, which netguard doesn't guard: a KMS key, a
+// Kubernetes secret or a GitLab variable.
+func guardlessKeyRef(ref string) bool {
+	for _, prefix := range append(kms.SupportedProviders(), kubernetes.KeyReference, gitlab.ReferenceScheme+"://") {
+		if strings.HasPrefix(ref, prefix) {
+			return true
+		}
+	}
+	return false
 }
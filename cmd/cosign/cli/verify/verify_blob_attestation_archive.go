@@ -0,0 +1,80 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/sigstore/cosign/v2/internal/ui"
+)
+
+// verifyArchive verifies the DSSE envelopes in the tar archive at path, and
+// returns the result for the first one that verifies. Members that are not
+// DSSE envelopes are skipped with a warning.
+func verifyArchive(ctx context.Context, opts *VerifyEnvelopeOptions, path string, h v1.Hash) (*VerifiedBlobAttestation, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var failures []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading signature archive %s: %w", path, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		envBytes, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s from signature archive: %w", hdr.Name, err)
+		}
+		env := &ssldsse.Envelope{}
+		if err := json.Unmarshal(envBytes, env); err != nil || env.PayloadType == "" || len(env.Signatures) == 0 {
+			ui.Warnf(ctx, "skipping %s: not a DSSE envelope", hdr.Name)
+			continue
+		}
+
+		verified, err := verifyEnvelopeDigest(ctx, opts, envBytes, h)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", hdr.Name, err))
+			continue
+		}
+		verified.ArchiveMember = hdr.Name
+		return verified, nil
+	}
+
+	if len(failures) == 0 {
+		return nil, fmt.Errorf("no DSSE envelopes found in signature archive %s", path)
+	}
+	return nil, fmt.Errorf("no attestation in signature archive %s could be verified:\n %s", path, strings.Join(failures, "\n "))
+}
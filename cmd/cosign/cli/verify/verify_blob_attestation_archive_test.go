@@ -0,0 +1,114 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"archive/tar"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+func TestVerifyArchive(t *testing.T) {
+	ctx := context.Background()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := sha256Subject("blob", blobContents)
+
+	writeArchive := func(t *testing.T, members map[string][]byte, order ...string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "sigs.tar")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		tw := tar.NewWriter(f)
+		for _, name := range order {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(members[name]))}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write(members[name]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	members := map[string][]byte{
+		"README":         []byte("not an envelope"),
+		"spdx.intoto":    signTestStatementWith(t, sv, testStatement(in_toto.PredicateSPDX, subject)),
+		"cyclonedx.json": signTestStatementWith(t, sv, testStatement(in_toto.PredicateCycloneDX, subject)),
+	}
+	opts := &VerifyEnvelopeOptions{
+		CheckOpts: &cosign.CheckOpts{
+			SigVerifier: sv,
+			IgnoreTlog:  true,
+		},
+		CheckClaims:   true,
+		PredicateType: "cyclonedx",
+	}
+	h, err := blobDigest(strings.NewReader(blobContents))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reports matching member", func(t *testing.T) {
+		path := writeArchive(t, members, "README", "spdx.intoto", "cyclonedx.json")
+		verified, err := verifyArchive(ctx, opts, path, h)
+		if err != nil {
+			t.Fatalf("verifyArchive() = %v", err)
+		}
+		if verified.ArchiveMember != "cyclonedx.json" {
+			t.Errorf("ArchiveMember = %q, want %q", verified.ArchiveMember, "cyclonedx.json")
+		}
+	})
+
+	t.Run("no matching member", func(t *testing.T) {
+		path := writeArchive(t, members, "README", "spdx.intoto")
+		_, err := verifyArchive(ctx, opts, path, h)
+		if err == nil || !strings.Contains(err.Error(), "spdx.intoto") {
+			t.Fatalf("verifyArchive() = %v, expected failure naming spdx.intoto", err)
+		}
+	})
+
+	t.Run("no envelopes", func(t *testing.T) {
+		path := writeArchive(t, members, "README")
+		_, err := verifyArchive(ctx, opts, path, h)
+		if err == nil || !strings.Contains(err.Error(), "no DSSE envelopes") {
+			t.Fatalf("verifyArchive() = %v, expected no envelopes error", err)
+		}
+	})
+}
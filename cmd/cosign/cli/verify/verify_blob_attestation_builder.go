@@ -0,0 +1,93 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"errors"
+	"fmt"
+
+	slsa01 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.1"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// checkBuilderID verifies that the SLSA provenance predicate of sig was
+// produced by the builder with the given ID. Other predicate types fail the
+// check, since they don't record a builder.
+func checkBuilderID(sig oci.Signature, want string) error {
+	st, err := statementFromAttestation(sig)
+	if err != nil {
+		return err
+	}
+	var got string
+	switch st.PredicateType {
+	case slsa02.PredicateSLSAProvenance:
+		p := slsa02.ProvenancePredicate{}
+		if err := remarshal(st.Predicate, &p); err != nil {
+			return fmt.Errorf("decoding %s predicate: %w", st.PredicateType, err)
+		}
+		got = p.Builder.ID
+	case slsa1.PredicateSLSAProvenance:
+		p := slsa1.ProvenancePredicate{}
+		if err := remarshal(st.Predicate, &p); err != nil {
+			return fmt.Errorf("decoding %s predicate: %w", st.PredicateType, err)
+		}
+		got = p.RunDetails.Builder.ID
+	default:
+		return fmt.Errorf("predicate type %s is not a SLSA provenance, it has no builder ID", st.PredicateType)
+	}
+	if got != want {
+		return fmt.Errorf("builder ID %q does not match the expected %q", got, want)
+	}
+	return nil
+}
+
+// checkReproducible verifies that the SLSA provenance predicate of sig claims
+// the build is reproducible, with metadata.reproducible in v0.1 and v0.2.
+// Other predicate types, including SLSA v1 which dropped the field, fail the
+// check since they carry no reproducibility claim.
+func checkReproducible(sig oci.Signature) error {
+	st, err := statementFromAttestation(sig)
+	if err != nil {
+		return err
+	}
+	var reproducible *bool
+	switch st.PredicateType {
+	case slsa01.PredicateSLSAProvenance:
+		p := slsa01.ProvenancePredicate{}
+		if err := remarshal(st.Predicate, &p); err != nil {
+			return fmt.Errorf("decoding %s predicate: %w", st.PredicateType, err)
+		}
+		if p.Metadata != nil {
+			reproducible = &p.Metadata.Reproducible
+		}
+	case slsa02.PredicateSLSAProvenance:
+		p := slsa02.ProvenancePredicate{}
+		if err := remarshal(st.Predicate, &p); err != nil {
+			return fmt.Errorf("decoding %s predicate: %w", st.PredicateType, err)
+		}
+		if p.Metadata != nil {
+			reproducible = &p.Metadata.Reproducible
+		}
+	default:
+		return fmt.Errorf("predicate type %s does not record whether the build is reproducible", st.PredicateType)
+	}
+	if reproducible == nil || !*reproducible {
+		return errors.New("provenance does not claim the build is reproducible, metadata.reproducible is absent or false")
+	}
+	return nil
+}
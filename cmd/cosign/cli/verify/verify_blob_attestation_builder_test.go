@@ -0,0 +1,174 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa01 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.1"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestVerifyEnvelopeSLSABuilderID(t *testing.T) {
+	ctx := context.Background()
+	const builderID = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@refs/tags/v1.9.0"
+
+	provenance := func(predicateType string, predicate interface{}) in_toto.Statement {
+		st := testStatement(predicateType, sha256Subject("blob", blobContents))
+		st.Predicate = predicate
+		return st
+	}
+
+	tests := []struct {
+		description   string
+		statement     in_toto.Statement
+		predicateType string
+		shouldErr     bool
+	}{
+		{
+			description: "v0.2 builder matches",
+			statement: provenance(slsa02.PredicateSLSAProvenance, slsa02.ProvenancePredicate{
+				Builder: common.ProvenanceBuilder{ID: builderID},
+			}),
+			predicateType: "slsaprovenance02",
+		}, {
+			description: "v1 builder matches",
+			statement: provenance(slsa1.PredicateSLSAProvenance, slsa1.ProvenancePredicate{
+				RunDetails: slsa1.ProvenanceRunDetails{Builder: slsa1.Builder{ID: builderID}},
+			}),
+			predicateType: "slsaprovenance1",
+		}, {
+			description: "v0.2 builder differs",
+			statement: provenance(slsa02.PredicateSLSAProvenance, slsa02.ProvenancePredicate{
+				Builder: common.ProvenanceBuilder{ID: "https://example.com/builder"},
+			}),
+			predicateType: "slsaprovenance02",
+			shouldErr:     true,
+		}, {
+			description: "v1 builder missing",
+			statement: provenance(slsa1.PredicateSLSAProvenance, slsa1.ProvenancePredicate{
+				BuildDefinition: slsa1.ProvenanceBuildDefinition{BuildType: "https://example.com/build"},
+			}),
+			predicateType: "slsaprovenance1",
+			shouldErr:     true,
+		}, {
+			description:   "not a provenance",
+			statement:     testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents)),
+			predicateType: "spdxjson",
+			shouldErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, test.statement)
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: test.predicateType,
+				SLSABuilderID: builderID,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeRequireReproducible(t *testing.T) {
+	ctx := context.Background()
+
+	provenance := func(predicateType string, predicate interface{}) in_toto.Statement {
+		st := testStatement(predicateType, sha256Subject("blob", blobContents))
+		st.Predicate = predicate
+		return st
+	}
+
+	tests := []struct {
+		description   string
+		statement     in_toto.Statement
+		predicateType string
+		shouldErr     bool
+	}{
+		{
+			description: "v0.2 reproducible",
+			statement: provenance(slsa02.PredicateSLSAProvenance, slsa02.ProvenancePredicate{
+				Metadata: &slsa02.ProvenanceMetadata{Reproducible: true},
+			}),
+			predicateType: "slsaprovenance02",
+		}, {
+			description: "v0.1 reproducible",
+			statement: provenance(slsa01.PredicateSLSAProvenance, slsa01.ProvenancePredicate{
+				Metadata: &slsa01.ProvenanceMetadata{Reproducible: true},
+			}),
+			predicateType: slsa01.PredicateSLSAProvenance,
+		}, {
+			description: "v0.2 not reproducible",
+			statement: provenance(slsa02.PredicateSLSAProvenance, slsa02.ProvenancePredicate{
+				Metadata: &slsa02.ProvenanceMetadata{Reproducible: false},
+			}),
+			predicateType: "slsaprovenance02",
+			shouldErr:     true,
+		}, {
+			description: "v0.2 without metadata",
+			statement: provenance(slsa02.PredicateSLSAProvenance, slsa02.ProvenancePredicate{
+				Builder: common.ProvenanceBuilder{ID: "https://example.com/builder"},
+			}),
+			predicateType: "slsaprovenance02",
+			shouldErr:     true,
+		}, {
+			description: "v1 has no reproducibility claim",
+			statement: provenance(slsa1.PredicateSLSAProvenance, slsa1.ProvenancePredicate{
+				RunDetails: slsa1.ProvenanceRunDetails{Builder: slsa1.Builder{ID: "https://example.com/builder"}},
+			}),
+			predicateType: "slsaprovenance1",
+			shouldErr:     true,
+		}, {
+			description:   "not a provenance",
+			statement:     testStatement("customFoo", sha256Subject("blob", blobContents)),
+			predicateType: "customFoo",
+			shouldErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, test.statement)
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:         true,
+				PredicateType:       test.predicateType,
+				RequireReproducible: true,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+)
+
+// saveBundle writes the verified attestation to path in the format read by
+// --bundle. If the attestation wasn't verified against a bundle, its tlog
+// entry is looked up so that the written bundle verifies offline.
+func saveBundle(ctx context.Context, path string, verified *VerifiedBlobAttestation, co *cosign.CheckOpts) error {
+	sig := signedAttestation(verified.signature)
+	if sig == nil {
+		return errors.New("no verified attestation to save")
+	}
+
+	signedPayload := cosign.LocalSignedPayload{}
+	payload, err := sig.Payload()
+	if err != nil {
+		return err
+	}
+	signedPayload.Base64Signature = base64.StdEncoding.EncodeToString(payload)
+
+	cert, err := sig.Cert()
+	if err != nil {
+		return err
+	}
+	var pemBytes []byte
+	switch {
+	case cert != nil:
+		pemBytes, err = cryptoutils.MarshalCertificateToPEM(cert)
+	case co.SigVerifier != nil:
+		pub, pkErr := co.SigVerifier.PublicKey(co.PKOpts...)
+		if pkErr != nil {
+			return pkErr
+		}
+		pemBytes, err = cryptoutils.MarshalPublicKeyToPEM(pub)
+	}
+	if err != nil {
+		return err
+	}
+	if pemBytes != nil {
+		signedPayload.Cert = base64.StdEncoding.EncodeToString(pemBytes)
+	}
+
+	if !co.IgnoreTlog {
+		rekorBundle, err := sig.Bundle()
+		if err != nil {
+			return err
+		}
+		if rekorBundle == nil {
+			entry, err := cosign.FindVerifiedTlogEntry(ctx, sig, co)
+			if err != nil {
+				return fmt.Errorf("finding tlog entry: %w", err)
+			}
+			rekorBundle = cbundle.EntryToBundle(entry)
+		}
+		signedPayload.Bundle = rekorBundle
+	}
+
+	contents, err := json.Marshal(signedPayload)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, contents, 0600); err != nil {
+		return fmt.Errorf("create bundle file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Bundle wrote in the file", path)
+	return nil
+}
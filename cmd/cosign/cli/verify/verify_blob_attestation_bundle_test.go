@@ -0,0 +1,72 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+)
+
+func TestSaveBundle(t *testing.T) {
+	ctx := context.Background()
+
+	env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents)))
+	opts := &VerifyEnvelopeOptions{
+		CheckOpts: &cosign.CheckOpts{
+			SigVerifier: sv,
+			IgnoreTlog:  true,
+		},
+		CheckClaims:   true,
+		PredicateType: "spdx",
+	}
+	verified, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "attestation.bundle")
+	if err := saveBundle(ctx, path, verified, opts.CheckOpts); err != nil {
+		t.Fatalf("saveBundle() = %v", err)
+	}
+
+	b, err := cosign.FetchLocalSignedPayloadFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Bundle != nil {
+		t.Errorf("expected no rekor bundle when the tlog is ignored")
+	}
+	pemBytes, err := base64.StdEncoding.DecodeString(b.Cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cryptoutils.UnmarshalPEMToPublicKey(pemBytes); err != nil {
+		t.Fatalf("decoding saved public key: %v", err)
+	}
+	savedEnv, err := base64.StdEncoding.DecodeString(b.Base64Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyEnvelope(ctx, opts, savedEnv, strings.NewReader(blobContents)); err != nil {
+		t.Fatalf("verifying saved envelope: %v", err)
+	}
+}
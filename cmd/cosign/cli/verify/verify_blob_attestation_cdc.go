@@ -0,0 +1,206 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+// A CDC digest is the root of a Merkle tree over the content-defined chunks
+// of a blob, so that a producer can attest a large blob once and update the
+// attestation cheaply as it changes. It is computed as follows, and must be
+// computed the same way by the producer of the subject:
+//
+//   - The blob is split with FastCDC (normalized chunking, level 2) of the
+//     parameters min, avg and max, in bytes, see cdcParams.cut.
+//   - Each chunk is a leaf of an RFC 6962 Merkle tree, in the order of the
+//     blob: the leaf hash is SHA-256(0x00 || chunk), an interior node
+//     SHA-256(0x01 || left || right), and the root of an empty blob
+//     SHA-256("").
+//   - The digest is the hex encoded root, keyed in the digest set of the
+//     subject by fastcdc-<min>-<avg>-<max>, e.g. fastcdc-16384-65536-262144
+//     for the default parameters.
+const cdcAlgorithm = "fastcdc"
+
+// The default parameters of the FastCDC chunker, and their bounds.
+const (
+	cdcDefaultMin = 16 << 10
+	cdcDefaultAvg = 64 << 10
+	cdcDefaultMax = 256 << 10
+	cdcMinMin     = 64
+	cdcMinAvg     = 256
+	cdcMaxAvg     = 64 << 20
+	cdcMaxMax     = 256 << 20
+)
+
+// cdcGear is the gear table of the rolling hash: cdcGear[b] is the first 8
+// bytes of SHA-256 of the single byte b, read as a big-endian integer.
+var cdcGear = func() (gear [256]uint64) {
+	for i := range gear {
+		sum := sha256.Sum256([]byte{byte(i)})
+		gear[i] = binary.BigEndian.Uint64(sum[:8])
+	}
+	return gear
+}()
+
+// cdcParams are the parameters of the FastCDC chunker.
+type cdcParams struct {
+	min, avg, max int
+	// maskS and maskL are the masks of the hash below and above avg: the
+	// bits+1, respectively bits-1, most significant bits, where avg is
+	// 1<<bits.
+	maskS, maskL uint64
+}
+
+// parseCDCSpec parses the --cdc-digest spec fastcdc, or
+// fastcdc:min=<n>,avg=<n>,max=<n> where parameters that are omitted take
+// their default value. avg must be a power of two between 256 and 64 MiB,
+// with 64 <= min < avg < max <= 256 MiB.
+func parseCDCSpec(spec string) (cdcParams, error) {
+	p := cdcParams{min: cdcDefaultMin, avg: cdcDefaultAvg, max: cdcDefaultMax}
+	alg, args, _ := strings.Cut(spec, ":")
+	if alg != cdcAlgorithm {
+		return cdcParams{}, fmt.Errorf("unsupported CDC chunker %q, expected %s", alg, cdcAlgorithm)
+	}
+	if args != "" {
+		for _, arg := range strings.Split(args, ",") {
+			k, v, ok := strings.Cut(arg, "=")
+			n, err := strconv.Atoi(v)
+			if !ok || err != nil {
+				return cdcParams{}, fmt.Errorf("invalid CDC parameter %q, expected <name>=<bytes>", arg)
+			}
+			switch k {
+			case "min":
+				p.min = n
+			case "avg":
+				p.avg = n
+			case "max":
+				p.max = n
+			default:
+				return cdcParams{}, fmt.Errorf("unknown CDC parameter %q, expected min, avg or max", k)
+			}
+		}
+	}
+	switch {
+	case p.avg < cdcMinAvg || p.avg > cdcMaxAvg || p.avg&(p.avg-1) != 0:
+		return cdcParams{}, fmt.Errorf("the CDC avg %d must be a power of two between %d and %d", p.avg, cdcMinAvg, cdcMaxAvg)
+	case p.min < cdcMinMin || p.min >= p.avg:
+		return cdcParams{}, fmt.Errorf("the CDC min %d must be at least %d and less than avg %d", p.min, cdcMinMin, p.avg)
+	case p.max <= p.avg || p.max > cdcMaxMax:
+		return cdcParams{}, fmt.Errorf("the CDC max %d must be greater than avg %d and at most %d", p.max, p.avg, cdcMaxMax)
+	}
+	b := bits.TrailingZeros(uint(p.avg))
+	p.maskS = ^uint64(0) << (64 - (b + 1))
+	p.maskL = ^uint64(0) << (64 - (b - 1))
+	return p, nil
+}
+
+// algorithm is the key of the CDC digest in the digest set of a subject.
+func (p cdcParams) algorithm() string {
+	return fmt.Sprintf("%s-%d-%d-%d", cdcAlgorithm, p.min, p.avg, p.max)
+}
+
+// cut returns the length of the first chunk of data, which is the rest of the
+// blob or at least its next max bytes.
+//
+// The chunk is the whole of data if it isn't longer than min. Otherwise the
+// hash fp, initially 0, is updated for each byte b from the offset min on as
+// fp = fp<<1 + cdcGear[b], modulo 2^64, and the chunk ends after the first
+// byte for which fp&mask is 0, where mask is maskS before the offset avg and
+// maskL after it. The chunk is max bytes long, or the whole of data if
+// shorter, if there's no such byte.
+func (p cdcParams) cut(data []byte) int {
+	n := len(data)
+	if n <= p.min {
+		return n
+	}
+	if n > p.max {
+		n = p.max
+	}
+	normal := p.avg
+	if n < normal {
+		normal = n
+	}
+	var fp uint64
+	i := p.min
+	for ; i < normal; i++ {
+		fp = fp<<1 + cdcGear[data[i]]
+		if fp&p.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = fp<<1 + cdcGear[data[i]]
+		if fp&p.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// cdcDigest computes the CDC digest of the blob, streaming it through a
+// buffer of max bytes.
+func cdcDigest(blob io.Reader, p cdcParams) (v1.Hash, error) {
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	tree := rf.NewEmptyRange(0)
+	buf := make([]byte, p.max)
+	n := 0
+	eof := false
+	for {
+		if !eof {
+			m, err := io.ReadFull(blob, buf[n:])
+			n += m
+			switch {
+			case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+				eof = true
+			case err != nil:
+				return v1.Hash{}, err
+			}
+		}
+		if n == 0 {
+			break
+		}
+		// Unless at the end of the blob, the buffer is full, so the chunk is
+		// cut as if the whole blob were at hand.
+		c := p.cut(buf[:n])
+		if err := tree.Append(rfc6962.DefaultHasher.HashLeaf(buf[:c]), nil); err != nil {
+			return v1.Hash{}, err
+		}
+		n = copy(buf, buf[c:n])
+	}
+	root, err := tree.GetRootHash(nil)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	if root == nil {
+		root = rfc6962.DefaultHasher.EmptyRoot()
+	}
+	return v1.Hash{
+		Algorithm: p.algorithm(),
+		Hex:       hex.EncodeToString(root),
+	}, nil
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+// cdcTestData returns n pseudo-random bytes, the same for each call.
+func cdcTestData(n int) []byte {
+	data := make([]byte, 0, n+sha256.Size)
+	sum := sha256.Sum256([]byte("cdc"))
+	for len(data) < n {
+		data = append(data, sum[:]...)
+		sum = sha256.Sum256(sum[:])
+	}
+	return data[:n]
+}
+
+// cdcTestRoot computes the RFC 6962 root of the chunks as defined, without
+// a compact range.
+func cdcTestRoot(chunks [][]byte) []byte {
+	switch len(chunks) {
+	case 0:
+		return rfc6962.DefaultHasher.EmptyRoot()
+	case 1:
+		return rfc6962.DefaultHasher.HashLeaf(chunks[0])
+	}
+	k := 1
+	for k*2 < len(chunks) {
+		k *= 2
+	}
+	return rfc6962.DefaultHasher.HashChildren(cdcTestRoot(chunks[:k]), cdcTestRoot(chunks[k:]))
+}
+
+func cdcTestChunks(data []byte, p cdcParams) [][]byte {
+	var chunks [][]byte
+	for len(data) > 0 {
+		n := p.cut(data)
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+	return chunks
+}
+
+func TestParseCDCSpec(t *testing.T) {
+	tests := []struct {
+		spec          string
+		wantAlgorithm string
+		wantErr       string
+	}{
+		{spec: "fastcdc", wantAlgorithm: "fastcdc-16384-65536-262144"},
+		{spec: "fastcdc:min=64,avg=256,max=1024", wantAlgorithm: "fastcdc-64-256-1024"},
+		{spec: "fastcdc:avg=32768", wantAlgorithm: "fastcdc-16384-32768-262144"},
+		{spec: "gear", wantErr: `unsupported CDC chunker "gear"`},
+		{spec: "fastcdc:avg", wantErr: `invalid CDC parameter "avg"`},
+		{spec: "fastcdc:size=4096", wantErr: `unknown CDC parameter "size"`},
+		{spec: "fastcdc:avg=50000", wantErr: "must be a power of two"},
+		{spec: "fastcdc:avg=128,min=64", wantErr: "must be a power of two"},
+		{spec: "fastcdc:min=65536", wantErr: "the CDC min 65536 must be at least 64 and less than avg"},
+		{spec: "fastcdc:min=32", wantErr: "the CDC min 32 must be at least 64"},
+		{spec: "fastcdc:max=65536", wantErr: "the CDC max 65536 must be greater than avg"},
+		{spec: "fastcdc:max=536870912", wantErr: "and at most 268435456"},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			p, err := parseCDCSpec(test.spec)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("parseCDCSpec() = %v, expected %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.algorithm(); got != test.wantAlgorithm {
+				t.Errorf("algorithm() = %s, expected %s", got, test.wantAlgorithm)
+			}
+		})
+	}
+}
+
+func TestCDCDigest(t *testing.T) {
+	p, err := parseCDCSpec("fastcdc:min=64,avg=256,max=1024")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := cdcTestData(64 << 10)
+
+	chunks := cdcTestChunks(data, p)
+	for i, c := range chunks {
+		if len(c) > p.max || (len(c) < p.min && i != len(chunks)-1) {
+			t.Fatalf("chunk %d is %d bytes, expected between %d and %d", i, len(c), p.min, p.max)
+		}
+	}
+	// Content-defined boundaries should average about avg bytes, well below
+	// max.
+	if avg := len(data) / len(chunks); avg < p.min || avg > p.max/2 {
+		t.Errorf("the chunks average %d bytes", avg)
+	}
+
+	want := v1.Hash{Algorithm: "fastcdc-64-256-1024", Hex: hex.EncodeToString(cdcTestRoot(chunks))}
+	for _, r := range []struct {
+		description string
+		blob        io.Reader
+	}{
+		{"whole", bytes.NewReader(data)},
+		{"byte by byte", iotest.OneByteReader(bytes.NewReader(data))},
+		{"half reads", iotest.HalfReader(bytes.NewReader(data))},
+	} {
+		got, err := cdcDigest(r.blob, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("cdcDigest() of the %s blob mismatch (-want +got):\n%s", r.description, diff)
+		}
+	}
+
+	// Inserting a byte changes the chunks around it only.
+	edited := append(append(append([]byte{}, data[:1000]...), 'x'), data[1000:]...)
+	same := map[string]bool{}
+	for _, c := range chunks {
+		same[string(c)] = true
+	}
+	changed := 0
+	for _, c := range cdcTestChunks(edited, p) {
+		if !same[string(c)] {
+			changed++
+		}
+	}
+	if changed > 3 {
+		t.Errorf("inserting a byte changed %d chunks", changed)
+	}
+
+	empty, err := cdcDigest(bytes.NewReader(nil), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Hex != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("cdcDigest() of the empty blob = %s, expected the empty root", empty.Hex)
+	}
+}
+
+func TestVerifyBlobAttestationCDCDigest(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	const spec = "fastcdc:min=64,avg=256,max=1024"
+	p, err := parseCDCSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := cdcTestData(16 << 10)
+	root, err := cdcDigest(bytes.NewReader(data), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobPath := writeBlobFile(t, td, string(data), "blob")
+	edited := append([]byte{}, data...)
+	edited[8000] ^= 1
+	editedPath := writeBlobFile(t, td, string(edited), "edited")
+
+	subj := in_toto.Subject{Name: "blob", Digest: common.DigestSet{"fastcdc-64-256-1024": root.Hex}}
+	att := signTestAttestation(t, td, testStatement("customFoo", subj))
+	keyRef := att.keyPath
+	sigPath := att.sigPath
+
+	tests := []struct {
+		description    string
+		artifactPath   string
+		spec           string
+		hashAlgorithm  string
+		digestEncoding string
+		wantErr        string
+	}{
+		{
+			description:  "matching CDC digest",
+			artifactPath: blobPath,
+			spec:         spec,
+		}, {
+			description:  "edited blob",
+			artifactPath: editedPath,
+			spec:         spec,
+			wantErr:      "no matching subject digest found",
+		}, {
+			description:  "other chunker parameters",
+			artifactPath: blobPath,
+			spec:         "fastcdc:min=64,avg=512,max=1024",
+			wantErr:      "no matching subject digest found",
+		}, {
+			description:  "without --cdc-digest",
+			artifactPath: blobPath,
+			wantErr:      "no matching subject digest found",
+		}, {
+			description:  "invalid spec",
+			artifactPath: blobPath,
+			spec:         "fastcdc:avg=1000",
+			wantErr:      "invalid --cdc-digest",
+		}, {
+			description:    "multihash encoding",
+			artifactPath:   blobPath,
+			spec:           spec,
+			digestEncoding: DigestEncodingMultihash,
+			wantErr:        "--cdc-digest cannot be combined with --digest-encoding multihash",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationStatementChecks: BlobAttestationStatementChecks{
+					DigestEncoding: test.digestEncoding,
+				},
+				BlobAttestationBlobSource: BlobAttestationBlobSource{
+					CDCDigest:     test.spec,
+					HashAlgorithm: test.hashAlgorithm,
+				},
+			}
+			err := cmd.Exec(ctx, test.artifactPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v, expected success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+}
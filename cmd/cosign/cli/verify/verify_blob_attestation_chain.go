@@ -0,0 +1,167 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+)
+
+// ChainStep is an attestation of the chain linked to the verified one, see
+// VerifyBlobAttestationCommand.AttestationChain.
+type ChainStep struct {
+	// File is the name of the envelope in the chain directory.
+	File string `json:"file"`
+	// PredicateType is the predicate type of the step's statement.
+	PredicateType string `json:"predicateType"`
+	// Digest is the sha256 digest of the envelope, the subject of the next
+	// step.
+	Digest string `json:"digest"`
+}
+
+// attestationDigest returns the sha256 digest of the envelope of the verified
+// attestation sig, as signed.
+func attestationDigest(sig oci.Signature) (v1.Hash, error) {
+	envBytes, err := signedAttestation(sig).Payload()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	sum := sha256.Sum256(envBytes)
+	return v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}, nil
+}
+
+// chainLink is an envelope of the chain directory, with the predicate type
+// and the sha256 subject digests of its statement, read before it is
+// verified to order the chain.
+type chainLink struct {
+	file          string
+	envBytes      []byte
+	predicateType string
+	subjects      []string
+}
+
+// readChain reads the envelopes of the regular files of dir, in name order.
+func readChain(dir string) ([]*chainLink, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading the attestation chain: %w", err)
+	}
+	var links []*chainLink
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		envBytes, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading the attestation chain: %w", err)
+		}
+		att, err := static.NewAttestation(envBytes)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", e.Name(), err)
+		}
+		st, err := statementFromAttestation(att)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", e.Name(), err)
+		}
+		link := &chainLink{file: e.Name(), envBytes: envBytes, predicateType: st.PredicateType}
+		for _, s := range st.Subject {
+			if d := s.Digest["sha256"]; d != "" {
+				link.subjects = append(link.subjects, strings.ToLower(d))
+			}
+		}
+		links = append(links, link)
+	}
+	if len(links) == 0 {
+		return nil, fmt.Errorf("no attestation in the chain directory %s", dir)
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].file < links[j].file })
+	return links, nil
+}
+
+// verifyAttestationChain verifies the attestations of dir, DSSE envelopes,
+// as a chain starting from the verified attestation sig: each attestation
+// must have the sha256 digest of the envelope of the previous one as a
+// subject, and every attestation of dir must be in the chain. They are
+// verified with the key or certificate and the trust material of opts, and
+// their claims checked, whatever their predicate type. It returns the steps
+// in the chain order.
+func verifyAttestationChain(ctx context.Context, opts *VerifyEnvelopeOptions, dir string, sig oci.Signature) ([]ChainStep, error) {
+	links, err := readChain(dir)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := attestationDigest(sig)
+	if err != nil {
+		return nil, err
+	}
+	prevName := "the verified attestation"
+
+	var steps []ChainStep
+	for len(links) > 0 {
+		// The statements aren't verified yet, they only order the chain.
+		var next []int
+		for i, link := range links {
+			for _, d := range link.subjects {
+				if d == prev.Hex {
+					next = append(next, i)
+					break
+				}
+			}
+		}
+		switch len(next) {
+		case 0:
+			var files []string
+			for _, link := range links {
+				files = append(files, link.file)
+			}
+			return steps, fmt.Errorf("the attestation chain is broken after %s: no attestation of %s has its digest %s as a subject", prevName, strings.Join(files, ", "), prev)
+		case 1:
+		default:
+			return steps, fmt.Errorf("the attestation chain forks after %s: both %s and %s have its digest as a subject", prevName, links[next[0]].file, links[next[1]].file)
+		}
+
+		link := links[next[0]]
+		verified, err := verifyEnvelopeDigest(ctx, &VerifyEnvelopeOptions{
+			CheckOpts:        opts.CheckOpts,
+			SignatureOptions: opts.SignatureOptions,
+			CheckClaims:      true,
+			PredicateType:    link.predicateType,
+			DigestEncoding:   opts.DigestEncoding,
+			StatementType:    opts.StatementType,
+			MaxSignatures:    opts.MaxSignatures,
+		}, link.envBytes, prev)
+		if err != nil {
+			return steps, fmt.Errorf("%s: %w", link.file, err)
+		}
+		if prev, err = attestationDigest(verified.signature); err != nil {
+			return steps, err
+		}
+		steps = append(steps, ChainStep{File: link.file, PredicateType: verified.PredicateType, Digest: prev.String()})
+		prevName = link.file
+		links = append(links[:next[0]], links[next[0]+1:]...)
+	}
+	return steps, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+func TestVerifyBlobAttestationChain(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := signTestStatementWith(t, sv, testStatement("https://example.com/build", sha256Subject("blob", blobContents)))
+	pub, err := sv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := cryptoutils.MarshalPublicKeyToPEM(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyRef := writeBlobFile(t, td, string(pemBytes), "cosign.pub")
+	sigPath := writeBlobFile(t, td, string(build), "build.dsse.json")
+	// step signs the statement of predicate type pt about the envelope prev.
+	step := func(pt string, signer signature.Signer, prev []byte) []byte {
+		return signTestStatementWith(t, signer, testStatement(pt, sha256Subject("prev", string(prev))))
+	}
+	test := step("https://example.com/test", sv, build)
+	scan := step("https://example.com/scan", sv, test)
+	otherEnv, _ := signTestStatement(t, testStatement("https://example.com/scan", sha256Subject("prev", string(test))))
+
+	tests := []struct {
+		description string
+		files       map[string][]byte
+		wantChain   []string
+		wantErr     string
+	}{
+		{
+			description: "chain in another order than the files",
+			files:       map[string][]byte{"a-scan.json": scan, "b-test.json": test},
+			wantChain:   []string{"b-test.json", "a-scan.json"},
+		}, {
+			description: "single step",
+			files:       map[string][]byte{"test.json": test},
+			wantChain:   []string{"test.json"},
+		}, {
+			description: "broken chain",
+			files:       map[string][]byte{"scan.json": scan},
+			wantErr:     "the attestation chain is broken after the verified attestation",
+		}, {
+			description: "unchained attestation",
+			files:       map[string][]byte{"test.json": test, "scan.json": scan, "other.json": step("https://example.com/other", sv, build[1:])},
+			wantErr:     "the attestation chain is broken after scan.json",
+		}, {
+			description: "fork",
+			files:       map[string][]byte{"test.json": test, "test2.json": step("https://example.com/test2", sv, build)},
+			wantErr:     "the attestation chain forks after the verified attestation",
+		}, {
+			description: "step signed by another key",
+			files:       map[string][]byte{"test.json": test, "scan.json": otherEnv},
+			wantErr:     "attestation chain: scan.json: ",
+		}, {
+			description: "empty",
+			wantErr:     "no attestation in the chain directory",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, env := range test.files {
+				writeBlobFile(t, dir, string(env), name)
+			}
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+				SignaturePath: sigPath,
+				PredicateType: "https://example.com/build",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationRelatedChecks: BlobAttestationRelatedChecks{
+					AttestationChain: dir,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exec() = %v, expected success", err)
+			}
+
+			verified, err := verifyEnvelopeDigest(ctx, &VerifyEnvelopeOptions{
+				CheckOpts:     &cosign.CheckOpts{SigVerifier: sv, IgnoreTlog: true},
+				CheckClaims:   true,
+				PredicateType: "https://example.com/build",
+			}, build, v1.Hash{Algorithm: "sha256", Hex: sha256Subject("", blobContents).Digest["sha256"]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			chain, err := verifyAttestationChain(ctx, &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{SigVerifier: sv, IgnoreTlog: true},
+			}, dir, verified.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, s := range chain {
+				files = append(files, s.File)
+			}
+			if diff := cmp.Diff(test.wantChain, files); diff != "" {
+				t.Errorf("chain mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sigstore/sigstore/pkg/signature"
+	signatureoptions "github.com/sigstore/sigstore/pkg/signature/options"
+)
+
+// DefaultMintClaimTTL is the lifetime of a minted claim, see
+// VerifyBlobAttestationCommand.MintClaim.
+const DefaultMintClaimTTL = 5 * time.Minute
+
+// VerificationClaims are the claims of the JWT minted after a verification:
+// the blob of digest sub was verified to have an attestation of PredicateType
+// signed by Signer.
+type VerificationClaims struct {
+	jwt.Claims
+	// Name is the name of the blob, as in the issued attestations.
+	Name          string `json:"name,omitempty"`
+	PredicateType string `json:"predicateType"`
+	// Signer is the identity of the certificate, or the key reference, the
+	// attestation was verified with.
+	Signer string `json:"signer"`
+}
+
+// newVerificationClaims returns the claims of the verification of the blob
+// named resource, of digest h, valid for ttl from now.
+func newVerificationClaims(resource string, h v1.Hash, verified *VerifiedBlobAttestation, keyRef string, now time.Time, ttl time.Duration) (*VerificationClaims, error) {
+	if h.Hex == "" {
+		return nil, errors.New("a blob digest is required to mint a claim")
+	}
+	if verified.signature == nil {
+		return nil, errors.New("no verified attestation to mint a claim of")
+	}
+	signer, err := signerIdentity(verified.signature, keyRef)
+	if err != nil {
+		return nil, err
+	}
+	return &VerificationClaims{
+		Claims: jwt.Claims{
+			Subject:  h.String(),
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Name:          resource,
+		PredicateType: verified.PredicateType,
+		Signer:        signer,
+	}, nil
+}
+
+// jwtSigner signs JWTs with a sigstore signer, whose signatures are
+// converted to the JWS encoding.
+type jwtSigner struct {
+	ctx context.Context
+	sv  signature.SignerVerifier
+	pub crypto.PublicKey
+	alg jose.SignatureAlgorithm
+}
+
+// newJWTSigner returns the JWS signer of sv. Only the keys whose cosign
+// signatures have a JWS algorithm are supported: ECDSA P-256, RSA and
+// Ed25519.
+func newJWTSigner(ctx context.Context, sv signature.SignerVerifier) (*jwtSigner, error) {
+	pub, err := sv.PublicKey(signatureoptions.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	s := &jwtSigner{ctx: ctx, sv: sv, pub: pub}
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported ECDSA curve %s to sign the claim, expected P-256", k.Curve.Params().Name)
+		}
+		s.alg = jose.ES256
+	case *rsa.PublicKey:
+		s.alg = jose.RS256
+	case ed25519.PublicKey:
+		s.alg = jose.EdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T to sign the claim", pub)
+	}
+	return s, nil
+}
+
+func (s *jwtSigner) Public() *jose.JSONWebKey {
+	return &jose.JSONWebKey{Key: s.pub, Algorithm: string(s.alg)}
+}
+
+func (s *jwtSigner) Algs() []jose.SignatureAlgorithm {
+	return []jose.SignatureAlgorithm{s.alg}
+}
+
+func (s *jwtSigner) SignPayload(payload []byte, _ jose.SignatureAlgorithm) ([]byte, error) {
+	sig, err := s.sv.SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(s.ctx))
+	if err != nil {
+		return nil, err
+	}
+	if s.alg != jose.ES256 {
+		return sig, nil
+	}
+	// JWS ECDSA signatures are the fixed size concatenation of r and s,
+	// not ASN.1.
+	var rs struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) != 0 {
+		return nil, errors.New("decoding the ECDSA signature of the claim")
+	}
+	out := make([]byte, 64)
+	rs.R.FillBytes(out[:32])
+	rs.S.FillBytes(out[32:])
+	return out, nil
+}
+
+// saveClaim signs claims as a compact JWT with sv and writes it to path.
+func saveClaim(ctx context.Context, path string, sv signature.SignerVerifier, claims *VerificationClaims) error {
+	js, err := newJWTSigner(ctx, sv)
+	if err != nil {
+		return err
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: js.alg, Key: js}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return err
+	}
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		return fmt.Errorf("signing claim: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token), 0600); err != nil {
+		return fmt.Errorf("create claim file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Claim written in the file", path)
+	return nil
+}
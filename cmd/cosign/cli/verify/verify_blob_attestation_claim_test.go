@@ -0,0 +1,89 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+)
+
+func TestVerifyBlobAttestationMintClaim(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	claimKeys, err := cosign.GenerateKeyPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	keyRef := att.keyPath
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationOutputs: BlobAttestationOutputs{
+			MintClaim:    filepath.Join(td, "claim.jwt"),
+			MintClaimTTL: time.Minute,
+		},
+	}
+	cmd.MintClaimKeyOpts = options.KeyOpts{KeyRef: writeBlobFile(t, td, string(claimKeys.PrivateBytes), "claim.key")}
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+
+	token, err := os.ReadFile(cmd.MintClaim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jwt.ParseSigned(string(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimPub, err := cryptoutils.UnmarshalPEMToPublicKey(claimKeys.PublicBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got VerificationClaims
+	if err := parsed.Claims(claimPub, &got); err != nil {
+		t.Fatalf("Claims() = %v", err)
+	}
+	if err := got.Validate(jwt.Expected{Subject: "sha256:" + sha256Subject("blob", blobContents).Digest["sha256"]}); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if got.PredicateType != "customFoo" || got.Signer != "key "+keyRef || got.Name != "blob" ||
+		got.Expiry.Time().Sub(got.IssuedAt.Time()) != time.Minute {
+		t.Errorf("unexpected claims %+v", got)
+	}
+	// The claim doesn't verify with the attestation key.
+	pub, err := att.signer.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.Claims(pub, &got); err == nil {
+		t.Error("Claims() with the attestation key succeeded")
+	}
+}
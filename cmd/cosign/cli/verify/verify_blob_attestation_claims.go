@@ -0,0 +1,455 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+const (
+	// DigestEncodingHex is the in-toto default of hex encoded digests.
+	DigestEncodingHex = "hex"
+	// DigestEncodingMultihash is a hex encoded multihash, see
+	// https://multiformats.io/multihash/.
+	DigestEncodingMultihash = "multihash"
+)
+
+// multihashCodes maps the multihash codes of the supported hash functions to
+// their names.
+var multihashCodes = map[uint64]string{
+	0x12: "sha256",
+	0x13: "sha512",
+	0x20: "sha384",
+	0x16: "sha3-256",
+	0x14: "sha3-512",
+}
+
+// digestSetAliases are other digest set names of the hash functions, as
+// spelled by the in-toto attestation specification.
+var digestSetAliases = map[string]string{
+	"sha3-256": "sha3_256",
+	"sha3-512": "sha3_512",
+}
+
+// statementTypeV1 is the _type of in-toto v1 statements.
+const statementTypeV1 = "https://in-toto.io/Statement/v1"
+
+// standardStatementTypes are the statement types accepted unless another one
+// is required with --statement-type.
+var standardStatementTypes = []string{in_toto.StatementInTotoV01, statementTypeV1}
+
+// checkStatementType verifies that the _type of the statement is want or, if
+// want is empty, one of the standard in-toto statement types.
+func checkStatementType(st *in_toto.Statement, want string) error {
+	if want != "" {
+		if st.Type != want {
+			return fmt.Errorf("invalid statement type, expected %s got %q", want, st.Type)
+		}
+		return nil
+	}
+	for _, t := range standardStatementTypes {
+		if st.Type == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid statement type %q, expected one of %s, or use --statement-type", st.Type, strings.Join(standardStatementTypes, ", "))
+}
+
+// statementPayload returns the encoded in-toto statement carried by the DSSE
+// envelope of an attestation.
+func statementPayload(sig oci.Signature) ([]byte, error) {
+	p, err := sig.Payload()
+	if err != nil {
+		return nil, err
+	}
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(p, &env); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(env.Payload)
+}
+
+// statementFromAttestation decodes the in-toto statement carried by the DSSE
+// envelope of an attestation.
+func statementFromAttestation(sig oci.Signature) (*in_toto.Statement, error) {
+	stBytes, err := statementPayload(sig)
+	if err != nil {
+		return nil, err
+	}
+	st := &in_toto.Statement{}
+	if err := json.Unmarshal(stBytes, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// subjectClaimVerifier returns a claim verifier checking that the blob digest
+// is a subject of the in-toto statement, subject to the claim options.
+func subjectClaimVerifier(opts *VerifyEnvelopeOptions) func(oci.Signature, v1.Hash, map[string]interface{}) error {
+	return func(sig oci.Signature, digest v1.Hash, _ map[string]interface{}) error {
+		st, err := statementFromAttestation(sig)
+		if err != nil {
+			return err
+		}
+		return checkSubjects(st, digest, opts)
+	}
+}
+
+// checkSubjects verifies that the blob digest is a subject of the statement,
+// subject to the claim options.
+func checkSubjects(st *in_toto.Statement, digest v1.Hash, opts *VerifyEnvelopeOptions) error {
+	if opts.SubjectIndex != nil {
+		if err := indexedSubjectMatches(st, *opts.SubjectIndex, digest, opts); err != nil {
+			return err
+		}
+		if !opts.AllSubjectsMatch {
+			return nil
+		}
+	}
+	if opts.SubjectName != "" {
+		if err := namedSubjectMatches(st, opts.SubjectName, digest, opts); err != nil {
+			return err
+		}
+		if !opts.AllSubjectsMatch {
+			return nil
+		}
+	}
+
+	matched, digestMatched := false, false
+	for _, subj := range st.Subject {
+		if !matchSubject(subj, digest, opts) {
+			if opts.AllSubjectsMatch {
+				return fmt.Errorf("subject %q does not match the blob digest", subj.Name)
+			}
+			continue
+		}
+		digestMatched = true
+		if opts.SubjectNameRegexp != nil && !opts.SubjectNameRegexp.MatchString(subj.Name) {
+			if opts.AllSubjectsMatch {
+				return fmt.Errorf("subject name %q does not match %s", subj.Name, opts.SubjectNameRegexp)
+			}
+			continue
+		}
+		matched = true
+	}
+	switch {
+	case digestMatched && !matched:
+		return fmt.Errorf("no subject matching the blob digest has a name matching %s", opts.SubjectNameRegexp)
+	case !matched:
+		return errors.New("no matching subject digest found")
+	}
+	return nil
+}
+
+// checkSubjectURIs verifies that every subject of the statement matching the
+// blob digest, and the subject name if any, has both a uri and a digest.
+// in_toto.Subject has no uri, which was added to subjects by the in-toto v1
+// resource descriptor, so the subjects are decoded again here.
+func checkSubjectURIs(sig oci.Signature, digest v1.Hash, opts *VerifyEnvelopeOptions) error {
+	stBytes, err := statementPayload(sig)
+	if err != nil {
+		return err
+	}
+	st := struct {
+		Subject []struct {
+			in_toto.Subject
+			URI string `json:"uri"`
+		} `json:"subject"`
+	}{}
+	if err := json.Unmarshal(stBytes, &st); err != nil {
+		return err
+	}
+	for i, subj := range st.Subject {
+		if opts.SubjectIndex != nil && i != *opts.SubjectIndex {
+			continue
+		}
+		if opts.SubjectName != "" && subj.Name != opts.SubjectName {
+			continue
+		}
+		if opts.SubjectNameRegexp != nil && !opts.SubjectNameRegexp.MatchString(subj.Name) {
+			continue
+		}
+		if !matchSubject(subj.Subject, digest, opts) {
+			continue
+		}
+		if subj.URI == "" || len(subj.Digest) == 0 {
+			return fmt.Errorf("subject %q must have both a uri and a digest", subj.Name)
+		}
+	}
+	return nil
+}
+
+// checkSubjectOrder verifies that the subjects of the statement are in the
+// canonical order, sorted by name then digest, see compareSubjects. Subjects
+// may be equal.
+func checkSubjectOrder(sig oci.Signature) error {
+	st, err := statementFromAttestation(sig)
+	if err != nil {
+		return err
+	}
+	for i := 1; i < len(st.Subject); i++ {
+		if compareSubjects(st.Subject[i-1], st.Subject[i]) > 0 {
+			return fmt.Errorf("the subjects are not in canonical order, subject %d (%q) sorts before subject %d (%q)", i, st.Subject[i].Name, i-1, st.Subject[i-1].Name)
+		}
+	}
+	return nil
+}
+
+// compareSubjects orders subjects by name, then by digest. Digests are
+// compared as their <algorithm>:<hex> pairs sorted by algorithm and joined
+// by commas.
+func compareSubjects(a, b in_toto.Subject) int {
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return strings.Compare(canonicalDigestSet(a.Digest), canonicalDigestSet(b.Digest))
+}
+
+// canonicalDigestSet is the digests as compared by compareSubjects.
+func canonicalDigestSet(digests map[string]string) string {
+	pairs := make([]string, 0, len(digests))
+	for alg, d := range digests {
+		pairs = append(pairs, alg+":"+d)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// indexedSubjectMatches checks that the statement has a subject at index,
+// and that it matches the digest, as well as the subject name and name
+// regular expression of opts if any.
+func indexedSubjectMatches(st *in_toto.Statement, index int, digest v1.Hash, opts *VerifyEnvelopeOptions) error {
+	if index >= len(st.Subject) {
+		return fmt.Errorf("no subject at index %d, the statement has %d subjects", index, len(st.Subject))
+	}
+	subj := st.Subject[index]
+	switch {
+	case opts.SubjectName != "" && subj.Name != opts.SubjectName:
+		return fmt.Errorf("subject %d is named %q, expected %q", index, subj.Name, opts.SubjectName)
+	case opts.SubjectNameRegexp != nil && !opts.SubjectNameRegexp.MatchString(subj.Name):
+		return fmt.Errorf("subject %d name %q does not match %s", index, subj.Name, opts.SubjectNameRegexp)
+	case !matchSubject(subj, digest, opts):
+		return fmt.Errorf("subject %d (%q) does not match the blob digest", index, subj.Name)
+	}
+	return nil
+}
+
+// namedSubjectMatches checks that a subject named name exists in the
+// statement, and that it matches the digest.
+func namedSubjectMatches(st *in_toto.Statement, name string, digest v1.Hash, opts *VerifyEnvelopeOptions) error {
+	found := false
+	for _, subj := range st.Subject {
+		if subj.Name != name {
+			continue
+		}
+		if matchSubject(subj, digest, opts) {
+			return nil
+		}
+		found = true
+	}
+	if found {
+		return fmt.Errorf("subject %q does not match the blob digest", name)
+	}
+	return fmt.Errorf("no subject named %q found", name)
+}
+
+// matchSubject reports whether the subject matches the blob of digest, or
+// of the blob digests with opts.MatchComputableDigests.
+func matchSubject(subj in_toto.Subject, digest v1.Hash, opts *VerifyEnvelopeOptions) bool {
+	if opts.MatchComputableDigests {
+		return computableDigestsMatch(subj, opts.blobDigests, opts.DigestEncoding)
+	}
+	return subjectMatches(subj, digest, opts.DigestEncoding)
+}
+
+// computableDigestsMatch reports whether every digest of the subject that is
+// in blobDigests matches it, and at least one is. The digests of other hash
+// functions, including multihashes which don't decode, are ignored.
+func computableDigestsMatch(subj in_toto.Subject, blobDigests map[string]string, encoding string) bool {
+	matched := false
+	for alg, dgst := range subj.Digest {
+		if encoding == DigestEncodingMultihash {
+			mhAlg, sum, err := decodeMultihash(dgst)
+			if err != nil {
+				continue
+			}
+			alg, dgst = mhAlg, hex.EncodeToString(sum)
+		}
+		for name, alias := range digestSetAliases {
+			if alg == alias {
+				alg = name
+			}
+		}
+		want, ok := blobDigests[alg]
+		if !ok {
+			continue
+		}
+		if !digestsEqual([]byte(dgst), []byte(want)) {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+func subjectMatches(subj in_toto.Subject, digest v1.Hash, encoding string) bool {
+	if encoding != DigestEncodingMultihash {
+		dgst, ok := subj.Digest[digest.Algorithm]
+		if !ok {
+			dgst, ok = subj.Digest[digestSetAliases[digest.Algorithm]]
+		}
+		return ok && digestsEqual([]byte(dgst), []byte(digest.Hex))
+	}
+
+	want, err := hex.DecodeString(digest.Hex)
+	if err != nil {
+		return false
+	}
+	// A multihash is self-describing, so don't rely on the digest set key.
+	for _, dgst := range subj.Digest {
+		alg, sum, err := decodeMultihash(dgst)
+		if err != nil || alg != digest.Algorithm {
+			continue
+		}
+		if digestsEqual(sum, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// digestsEqual compares digests in constant time. Digests aren't secret, but
+// the comparison then doesn't leak how much of an attacker supplied digest
+// matches.
+func digestsEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// decodeMultihash decodes a hex encoded multihash into the name of its hash
+// function and the digest.
+func decodeMultihash(s string) (string, []byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", nil, err
+	}
+	code, n := binary.Uvarint(b)
+	if n <= 0 {
+		return "", nil, errors.New("invalid multihash code")
+	}
+	b = b[n:]
+	length, n := binary.Uvarint(b)
+	if n <= 0 {
+		return "", nil, errors.New("invalid multihash length")
+	}
+	b = b[n:]
+	if uint64(len(b)) != length {
+		return "", nil, fmt.Errorf("multihash length %d does not match digest length %d", length, len(b))
+	}
+	alg, ok := multihashCodes[code]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported multihash code 0x%x", code)
+	}
+	return alg, b, nil
+}
+
+// verifyKeyIDSignature checks that the envelope of sig carries a signature
+// bearing keyID, and that this signature validates. Signatures without a keyid
+// never satisfy a required keyid.
+func verifyKeyIDSignature(ctx context.Context, sig oci.Signature, co *cosign.CheckOpts, keyID string) error {
+	p, err := sig.Payload()
+	if err != nil {
+		return err
+	}
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(p, &env); err != nil {
+		return err
+	}
+	var sigs []ssldsse.Signature
+	for _, s := range env.Signatures {
+		if s.KeyID == keyID {
+			sigs = append(sigs, s)
+		}
+	}
+	if len(sigs) == 0 {
+		return fmt.Errorf("no signature with keyid %q found on the envelope", keyID)
+	}
+	env.Signatures = sigs
+
+	verifier := co.SigVerifier
+	if verifier == nil {
+		cert, err := sig.Cert()
+		if err != nil {
+			return err
+		}
+		if cert == nil {
+			return errors.New("no key or certificate to verify the signature with")
+		}
+		// The certificate has already been validated along with the envelope.
+		if verifier, err = signature.LoadVerifier(cert.PublicKey, crypto.SHA256); err != nil {
+			return err
+		}
+	}
+	// Only the signatures bearing keyID are left in the envelope.
+	if err := cosign.VerifyDSSEEnvelopeSignatures(ctx, verifier, &env, co); err != nil {
+		return fmt.Errorf("signature with keyid %q: %w", keyID, err)
+	}
+	return nil
+}
+
+// checkPredicateFields fails if the predicate of sig has top-level fields
+// that aren't in allowed.
+func checkPredicateFields(sig oci.Signature, allowed []string) error {
+	st, err := statementFromAttestation(sig)
+	if err != nil {
+		return err
+	}
+	fields, ok := st.Predicate.(map[string]interface{})
+	if !ok {
+		return errors.New("predicate is not an object")
+	}
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, f := range allowed {
+		allowedSet[f] = true
+	}
+	var unknown []string
+	for f := range fields {
+		if !allowedSet[f] {
+			unknown = append(unknown, f)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("predicate contains unknown fields: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,820 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+)
+
+func TestSubjectMatches(t *testing.T) {
+	sum := sha256.Sum256([]byte(blobContents))
+	h := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+	multihash := hex.EncodeToString(append([]byte{0x12, 32}, sum[:]...))
+
+	tests := []struct {
+		description string
+		digests     common.DigestSet
+		encoding    string
+		want        bool
+	}{
+		{
+			description: "same digest",
+			digests:     common.DigestSet{"sha256": h.Hex},
+			want:        true,
+		}, {
+			description: "last byte differs",
+			digests:     common.DigestSet{"sha256": h.Hex[:len(h.Hex)-2] + "00"},
+		}, {
+			description: "prefix of the digest",
+			digests:     common.DigestSet{"sha256": h.Hex[:32]},
+		}, {
+			description: "empty digest",
+			digests:     common.DigestSet{"sha256": ""},
+		}, {
+			description: "multihash",
+			digests:     common.DigestSet{"sha256": multihash},
+			encoding:    DigestEncodingMultihash,
+			want:        true,
+		}, {
+			description: "truncated multihash",
+			digests:     common.DigestSet{"sha256": multihash[:len(multihash)-2]},
+			encoding:    DigestEncodingMultihash,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if got := subjectMatches(in_toto.Subject{Name: "blob", Digest: test.digests}, h, test.encoding); got != test.want {
+				t.Errorf("subjectMatches() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeAllSubjectsMatch(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		description      string
+		subjects         []in_toto.Subject
+		allSubjectsMatch bool
+		shouldErr        bool
+	}{
+		{
+			description: "any subject matches",
+			subjects:    []in_toto.Subject{sha256Subject("blob", blobContents), sha256Subject("other", anotherBlobContents)},
+		}, {
+			description:      "extra subject with all-subjects-match",
+			subjects:         []in_toto.Subject{sha256Subject("blob", blobContents), sha256Subject("other", anotherBlobContents)},
+			allSubjectsMatch: true,
+			shouldErr:        true,
+		}, {
+			description:      "single subject with all-subjects-match",
+			subjects:         []in_toto.Subject{sha256Subject("blob", blobContents)},
+			allSubjectsMatch: true,
+		}, {
+			description:      "no matching subject with all-subjects-match",
+			subjects:         []in_toto.Subject{sha256Subject("other", anotherBlobContents)},
+			allSubjectsMatch: true,
+			shouldErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, test.subjects...))
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:      true,
+				PredicateType:    "spdx",
+				AllSubjectsMatch: test.allSubjectsMatch,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeMultihashDigest(t *testing.T) {
+	ctx := context.Background()
+
+	sum := sha256.Sum256([]byte(blobContents))
+	multihash := func(code byte, digest []byte) string {
+		return hex.EncodeToString(append([]byte{code, byte(len(digest))}, digest...))
+	}
+	otherSum := sha256.Sum256([]byte(anotherBlobContents))
+
+	tests := []struct {
+		description string
+		digest      string
+		encoding    string
+		shouldErr   bool
+	}{
+		{
+			description: "multihash sha256",
+			digest:      multihash(0x12, sum[:]),
+			encoding:    DigestEncodingMultihash,
+		}, {
+			description: "multihash with wrong algorithm code",
+			digest:      multihash(0x13, sum[:]),
+			encoding:    DigestEncodingMultihash,
+			shouldErr:   true,
+		}, {
+			description: "multihash of another blob",
+			digest:      multihash(0x12, otherSum[:]),
+			encoding:    DigestEncodingMultihash,
+			shouldErr:   true,
+		}, {
+			description: "truncated multihash",
+			digest:      multihash(0x12, sum[:])[:40],
+			encoding:    DigestEncodingMultihash,
+			shouldErr:   true,
+		}, {
+			description: "multihash without multihash encoding",
+			digest:      multihash(0x12, sum[:]),
+			encoding:    DigestEncodingHex,
+			shouldErr:   true,
+		}, {
+			description: "raw hex with multihash encoding",
+			digest:      hex.EncodeToString(sum[:]),
+			encoding:    DigestEncodingMultihash,
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			subject := in_toto.Subject{Name: "blob", Digest: common.DigestSet{"sha256": test.digest}}
+			env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, subject))
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:    true,
+				PredicateType:  "spdx",
+				DigestEncoding: test.encoding,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeRequireKeyID(t *testing.T) {
+	ctx := context.Background()
+
+	newSigner := func() signature.SignerVerifier {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sv
+	}
+	trusted, untrusted := newSigner(), newSigner()
+
+	payload, err := json.Marshal(testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// envelope returns an envelope with a signature per signer, each bearing
+	// the corresponding keyid.
+	envelope := func(t *testing.T, signers []signature.Signer, keyIDs []string) []byte {
+		t.Helper()
+		env := &ssldsse.Envelope{}
+		for i, s := range signers {
+			es, err := ssldsse.NewEnvelopeSigner(&dsse.SignerAdapter{SignatureSigner: s, PubKeyID: keyIDs[i]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := es.SignPayload(ctx, types.IntotoPayloadType, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env.PayloadType, env.Payload = e.PayloadType, e.Payload
+			env.Signatures = append(env.Signatures, e.Signatures...)
+		}
+		b, err := json.Marshal(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	tests := []struct {
+		description  string
+		signers      []signature.Signer
+		keyIDs       []string
+		requireKeyID string
+		shouldErr    bool
+	}{
+		{
+			description:  "required keyid validates",
+			signers:      []signature.Signer{untrusted, trusted},
+			keyIDs:       []string{"other", "trusted"},
+			requireKeyID: "trusted",
+		}, {
+			description:  "required keyid signed by another key",
+			signers:      []signature.Signer{trusted, untrusted},
+			keyIDs:       []string{"", "trusted"},
+			requireKeyID: "trusted",
+			shouldErr:    true,
+		}, {
+			description:  "empty keyid doesn't satisfy required keyid",
+			signers:      []signature.Signer{trusted},
+			keyIDs:       []string{""},
+			requireKeyID: "trusted",
+			shouldErr:    true,
+		}, {
+			description: "no required keyid",
+			signers:     []signature.Signer{trusted},
+			keyIDs:      []string{""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: trusted,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: "spdx",
+				RequireKeyID:  test.requireKeyID,
+			}
+			env := envelope(t, test.signers, test.keyIDs)
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeRejectUnknownPredicateFields(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		predicate   interface{}
+		allowed     []string
+		shouldErr   bool
+	}{
+		{
+			description: "all fields allowed",
+			predicate:   map[string]interface{}{"builder": "b", "invocation": "i"},
+			allowed:     []string{"builder", "invocation", "metadata"},
+		}, {
+			description: "unknown field",
+			predicate:   map[string]interface{}{"builder": "b", "hidden": "h"},
+			allowed:     []string{"builder"},
+			shouldErr:   true,
+		}, {
+			description: "empty allow list",
+			predicate:   map[string]interface{}{"builder": "b"},
+			shouldErr:   true,
+		}, {
+			description: "predicate not an object",
+			predicate:   "builder",
+			allowed:     []string{"builder"},
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			st := testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents))
+			st.Predicate = test.predicate
+			env, sv := signTestStatement(t, st)
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:                  true,
+				PredicateType:                "spdx",
+				RejectUnknownPredicateFields: true,
+				AllowedPredicateFields:       test.allowed,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeSubjectName(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		description   string
+		subjects      []in_toto.Subject
+		subjectName   string
+		predicateType string
+		wantErr       string
+	}{
+		{
+			description:   "named subject matches",
+			subjects:      []in_toto.Subject{sha256Subject("other", anotherBlobContents), sha256Subject("blob", blobContents)},
+			subjectName:   "blob",
+			predicateType: "spdx",
+		}, {
+			description:   "named subject has another digest",
+			subjects:      []in_toto.Subject{sha256Subject("blob", anotherBlobContents), sha256Subject("other", blobContents)},
+			subjectName:   "blob",
+			predicateType: "spdx",
+			wantErr:       `subject "blob" does not match the blob digest`,
+		}, {
+			description:   "no subject with the name",
+			subjects:      []in_toto.Subject{sha256Subject("other", blobContents)},
+			subjectName:   "blob",
+			predicateType: "spdx",
+			wantErr:       `no subject named "blob" found`,
+		}, {
+			description:   "named subject matches but predicate type doesn't",
+			subjects:      []in_toto.Subject{sha256Subject("blob", blobContents)},
+			subjectName:   "blob",
+			predicateType: "cyclonedx",
+			wantErr:       "invalid predicate type",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, test.subjects...))
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: test.predicateType,
+				SubjectName:   test.subjectName,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyEnvelope() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("verifyEnvelope() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeSubjectIndex(t *testing.T) {
+	ctx := context.Background()
+	blob, other := sha256Subject("blob", blobContents), sha256Subject("other", anotherBlobContents)
+
+	tests := []struct {
+		description      string
+		subjects         []in_toto.Subject
+		index            int
+		subjectName      string
+		allSubjectsMatch bool
+		wantErr          string
+	}{
+		{
+			description: "indexed subject matches",
+			subjects:    []in_toto.Subject{other, blob},
+			index:       1,
+		}, {
+			description: "another subject matches",
+			subjects:    []in_toto.Subject{other, blob},
+			index:       0,
+			wantErr:     `subject 0 ("other") does not match the blob digest`,
+		}, {
+			description: "index out of range",
+			subjects:    []in_toto.Subject{other, blob},
+			index:       2,
+			wantErr:     "no subject at index 2, the statement has 2 subjects",
+		}, {
+			description: "indexed subject has another name",
+			subjects:    []in_toto.Subject{blob, sha256Subject("copy", blobContents)},
+			index:       1,
+			subjectName: "blob",
+			wantErr:     `subject 1 is named "copy", expected "blob"`,
+		}, {
+			description: "indexed subject has the name",
+			subjects:    []in_toto.Subject{other, blob},
+			index:       1,
+			subjectName: "blob",
+		}, {
+			description:      "indexed subject matches but not all do",
+			subjects:         []in_toto.Subject{other, blob},
+			index:            1,
+			allSubjectsMatch: true,
+			wantErr:          `subject "other" does not match the blob digest`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, test.subjects...))
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:      true,
+				PredicateType:    "spdx",
+				SubjectName:      test.subjectName,
+				SubjectIndex:     &test.index,
+				AllSubjectsMatch: test.allSubjectsMatch,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyEnvelope() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("verifyEnvelope() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeSortedSubjects(t *testing.T) {
+	ctx := context.Background()
+	blob, other := sha256Subject("blob", blobContents), sha256Subject("other", anotherBlobContents)
+	// blobSHA512 has the name and sha256 digest of blob, and more digests.
+	blobSHA512 := in_toto.Subject{Name: "blob", Digest: common.DigestSet{"sha256": blob.Digest["sha256"], "sha512": "00"}}
+
+	tests := []struct {
+		description string
+		subjects    []in_toto.Subject
+		noCheck     bool
+		wantErr     string
+	}{
+		{
+			description: "sorted by name",
+			subjects:    []in_toto.Subject{blob, other},
+		}, {
+			description: "reordered",
+			subjects:    []in_toto.Subject{other, blob},
+			wantErr:     `the subjects are not in canonical order, subject 1 ("blob") sorts before subject 0 ("other")`,
+		}, {
+			description: "reordered without the check",
+			subjects:    []in_toto.Subject{other, blob},
+			noCheck:     true,
+		}, {
+			description: "same name sorted by digest",
+			subjects:    []in_toto.Subject{blob, blobSHA512},
+		}, {
+			description: "same name reordered by digest",
+			subjects:    []in_toto.Subject{blobSHA512, blob},
+			wantErr:     `subject 1 ("blob") sorts before subject 0 ("blob")`,
+		}, {
+			description: "equal subjects",
+			subjects:    []in_toto.Subject{blob, blob},
+		}, {
+			description: "single subject",
+			subjects:    []in_toto.Subject{blob},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, test.subjects...))
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:           true,
+				PredicateType:         "spdx",
+				RequireSortedSubjects: !test.noCheck,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyEnvelope() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("verifyEnvelope() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeSubjectNameRegexp(t *testing.T) {
+	ctx := context.Background()
+	re := regexp.MustCompile("^pkg:")
+
+	tests := []struct {
+		description      string
+		subjects         []in_toto.Subject
+		allSubjectsMatch bool
+		wantErr          string
+	}{
+		{
+			description: "matching subject name",
+			subjects:    []in_toto.Subject{sha256Subject("other", anotherBlobContents), sha256Subject("pkg:generic/blob", blobContents)},
+		}, {
+			description: "one of the matching subjects has a matching name",
+			subjects:    []in_toto.Subject{sha256Subject("blob", blobContents), sha256Subject("pkg:generic/blob", blobContents)},
+		}, {
+			description: "matching name on another digest",
+			subjects:    []in_toto.Subject{sha256Subject("pkg:generic/other", anotherBlobContents), sha256Subject("blob", blobContents)},
+			wantErr:     "no subject matching the blob digest has a name matching ^pkg:",
+		}, {
+			description: "no matching digest",
+			subjects:    []in_toto.Subject{sha256Subject("pkg:generic/other", anotherBlobContents)},
+			wantErr:     "no matching subject digest found",
+		}, {
+			description:      "all subjects match",
+			subjects:         []in_toto.Subject{sha256Subject("pkg:generic/blob", blobContents), sha256Subject("pkg:oci/blob", blobContents)},
+			allSubjectsMatch: true,
+		}, {
+			description:      "a subject name doesn't match",
+			subjects:         []in_toto.Subject{sha256Subject("pkg:generic/blob", blobContents), sha256Subject("blob", blobContents)},
+			allSubjectsMatch: true,
+			wantErr:          `subject name "blob" does not match ^pkg:`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, test.subjects...))
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:       true,
+				PredicateType:     "spdx",
+				AllSubjectsMatch:  test.allSubjectsMatch,
+				SubjectNameRegexp: re,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyEnvelope() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("verifyEnvelope() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+
+	for _, cmd := range []VerifyBlobAttestationCommand{
+		{CheckClaims: true, BlobAttestationStatementChecks: BlobAttestationStatementChecks{SubjectName: "blob", SubjectNameRegexp: "^pkg:"}},
+		{BlobAttestationStatementChecks: BlobAttestationStatementChecks{SubjectNameRegexp: "^pkg:"}},
+		{CheckClaims: true, BlobAttestationStatementChecks: BlobAttestationStatementChecks{SubjectNameRegexp: "("}},
+	} {
+		if _, err := cmd.subjectNameRegexp(); err == nil {
+			t.Errorf("subjectNameRegexp() of %+v expected an error", cmd)
+		}
+	}
+}
+
+func TestVerifyEnvelopeRequireSubjectURIAndDigest(t *testing.T) {
+	ctx := context.Background()
+
+	// subject returns an in-toto v1 resource descriptor, with a uri if any.
+	subject := func(name, contents, uri string) map[string]interface{} {
+		s := sha256Subject(name, contents)
+		d := map[string]interface{}{"name": s.Name, "digest": s.Digest}
+		if uri != "" {
+			d["uri"] = uri
+		}
+		return d
+	}
+
+	tests := []struct {
+		description string
+		subjects    []map[string]interface{}
+		subjectName string
+		shouldErr   bool
+	}{
+		{
+			description: "matching subject with a uri",
+			subjects:    []map[string]interface{}{subject("blob", blobContents, "https://example.com/blob")},
+		}, {
+			description: "matching subject without a uri",
+			subjects:    []map[string]interface{}{subject("blob", blobContents, "")},
+			shouldErr:   true,
+		}, {
+			description: "other subject without a uri",
+			subjects: []map[string]interface{}{
+				subject("blob", blobContents, "https://example.com/blob"),
+				subject("other", anotherBlobContents, ""),
+			},
+		}, {
+			description: "same digest under another name without a uri",
+			subjects: []map[string]interface{}{
+				subject("blob", blobContents, "https://example.com/blob"),
+				subject("copy", blobContents, ""),
+			},
+			shouldErr: true,
+		}, {
+			description: "same digest under another name without a uri, with a subject name",
+			subjects: []map[string]interface{}{
+				subject("blob", blobContents, "https://example.com/blob"),
+				subject("copy", blobContents, ""),
+			},
+			subjectName: "blob",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, map[string]interface{}{
+				"_type":         in_toto.StatementInTotoV01,
+				"predicateType": slsa02.PredicateSLSAProvenance,
+				"subject":       test.subjects,
+				"predicate":     map[string]interface{}{},
+			})
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:                true,
+				PredicateType:              "slsaprovenance",
+				SubjectName:                test.subjectName,
+				RequireSubjectURIAndDigest: true,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeStatementType(t *testing.T) {
+	ctx := context.Background()
+	const vendorType = "https://in-toto.io/Statement/v0.1+example"
+
+	withType := func(statementType string) in_toto.Statement {
+		st := testStatement("customFoo", sha256Subject("blob", blobContents))
+		st.Type = statementType
+		return st
+	}
+
+	tests := []struct {
+		description   string
+		statement     in_toto.Statement
+		statementType string
+		shouldErr     bool
+	}{
+		{
+			description: "v0.1 by default",
+			statement:   withType(in_toto.StatementInTotoV01),
+		}, {
+			description: "v1 by default",
+			statement:   withType("https://in-toto.io/Statement/v1"),
+		}, {
+			description: "vendor type by default",
+			statement:   withType(vendorType),
+			shouldErr:   true,
+		}, {
+			description:   "vendor type required",
+			statement:     withType(vendorType),
+			statementType: vendorType,
+		}, {
+			description:   "standard type when the vendor type is required",
+			statement:     withType(in_toto.StatementInTotoV01),
+			statementType: vendorType,
+			shouldErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, test.statement)
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: "customFoo",
+				StatementType: test.statementType,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationMatchComputableDigests(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	digests, err := digestBlob(strings.NewReader(blobContents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := strings.Repeat("0", 128)
+
+	tests := []struct {
+		description string
+		digest      common.DigestSet
+		wantDefault bool
+		want        bool
+	}{
+		{
+			description: "all computable digests match",
+			digest:      common.DigestSet{"sha256": digests["sha256"], "sha512": digests["sha512"]},
+			wantDefault: true,
+			want:        true,
+		}, {
+			description: "a computable digest doesn't match",
+			digest:      common.DigestSet{"sha256": digests["sha256"], "sha512": wrong},
+			wantDefault: true,
+		}, {
+			description: "unknown algorithm ignored",
+			digest:      common.DigestSet{"sha256": digests["sha256"], "blake3": wrong},
+			wantDefault: true,
+			want:        true,
+		}, {
+			description: "no sha256 digest",
+			digest:      common.DigestSet{"sha384": digests["sha384"], "sha3_256": digests["sha3-256"]},
+			want:        true,
+		}, {
+			description: "no computable digest",
+			digest:      common.DigestSet{"blake3": wrong},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			att := signTestAttestation(t, td, testStatement("customFoo", in_toto.Subject{Name: "blob", Digest: test.digest}))
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+				SignaturePath: att.sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+			}
+			if err := cmd.Exec(ctx, blobPath); (err == nil) != test.wantDefault {
+				t.Errorf("Exec() = %v, wanted success %t", err, test.wantDefault)
+			}
+			cmd.MatchComputableDigests = true
+			err = cmd.Exec(ctx, blobPath)
+			if (err == nil) != test.want {
+				t.Errorf("Exec() with --match-computable-digests = %v, wanted success %t", err, test.want)
+			}
+			if err != nil && !strings.Contains(err.Error(), "no matching subject digest found") {
+				t.Errorf("Exec() = %v, expected a subject mismatch", err)
+			}
+		})
+	}
+}
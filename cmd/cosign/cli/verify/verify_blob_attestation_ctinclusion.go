@@ -0,0 +1,163 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	ct "github.com/google/certificate-transparency-go"
+	ctclient "github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/jsonclient"
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/transparency-dev/merkle/proof"
+	"github.com/transparency-dev/merkle/rfc6962"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// verifyCTInclusion verifies that the signing certificate of sig is included
+// in the CT log at logURL, for each of its SCTs, embedded or the detached
+// co.SCT: the inclusion proof of the certificate in the current tree of the
+// log is fetched and verified against the tree head, itself signed with the
+// key of co.CTLogPubKeys that the SCT was issued with.
+func verifyCTInclusion(ctx context.Context, sig oci.Signature, co *cosign.CheckOpts, logURL string, hc *http.Client) error {
+	cert, err := sig.Cert()
+	if err != nil {
+		return err
+	}
+	if cert == nil {
+		return errors.New("the attestation has no signing certificate")
+	}
+	if co.CTLogPubKeys == nil || len(co.CTLogPubKeys.Keys) == 0 {
+		return errors.New("none of the CTFE keys have been found")
+	}
+	leafCert, err := ctx509.ParseCertificate(cert.Raw)
+	if err != nil && ctx509.IsFatal(err) {
+		return err
+	}
+	certPEM, err := cryptoutils.MarshalCertificateToPEM(cert)
+	if err != nil {
+		return err
+	}
+	scts, err := x509util.ParseSCTsFromCertificate(certPEM)
+	if err != nil {
+		return err
+	}
+
+	if len(scts) == 0 {
+		if len(co.SCT) == 0 {
+			return errors.New("no SCT found")
+		}
+		var addChainResp ct.AddChainResponse
+		if err := json.Unmarshal(co.SCT, &addChainResp); err != nil {
+			return fmt.Errorf("unmarshaling the detached SCT: %w", err)
+		}
+		sct, err := addChainResp.ToSignedCertificateTimestamp()
+		if err != nil {
+			return err
+		}
+		leaf, err := ct.MerkleTreeLeafFromChain([]*ctx509.Certificate{leafCert}, ct.X509LogEntryType, sct.Timestamp)
+		if err != nil {
+			return err
+		}
+		return verifyCTLeafInclusion(ctx, leaf, sct, co.CTLogPubKeys, logURL, hc)
+	}
+
+	issuer, err := certIssuer(cert, sig, co)
+	if err != nil {
+		return err
+	}
+	issuerCert, err := ctx509.ParseCertificate(issuer.Raw)
+	if err != nil && ctx509.IsFatal(err) {
+		return err
+	}
+	for _, sct := range scts {
+		leaf, err := ct.MerkleTreeLeafForEmbeddedSCT([]*ctx509.Certificate{leafCert, issuerCert}, sct.Timestamp)
+		if err != nil {
+			return err
+		}
+		if err := verifyCTLeafInclusion(ctx, leaf, sct, co.CTLogPubKeys, logURL, hc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyCTLeafInclusion verifies the inclusion of leaf, logged as promised
+// by sct, in the current tree of the CT log at logURL.
+func verifyCTLeafInclusion(ctx context.Context, leaf *ct.MerkleTreeLeaf, sct *ct.SignedCertificateTimestamp, pubKeys *cosign.TrustedTransparencyLogPubKeys, logURL string, hc *http.Client) error {
+	keyID := hex.EncodeToString(sct.LogID.KeyID[:])
+	pubKey, ok := pubKeys.Keys[keyID]
+	if !ok {
+		return fmt.Errorf("ctfe public key not found for the SCT of log ID %s", keyID)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pubKey.PubKey)
+	if err != nil {
+		return err
+	}
+	lc, err := ctclient.New(logURL, hc, jsonclient.Options{PublicKeyDER: der})
+	if err != nil {
+		return fmt.Errorf("creating CT log client for %s: %w", logURL, err)
+	}
+	// The signature of the tree head is verified with the key of the SCT,
+	// so the tree is that of the log which issued the SCT.
+	sth, err := lc.GetSTH(ctx)
+	if err != nil {
+		return fmt.Errorf("getting the signed tree head of the CT log %s: %w", logURL, err)
+	}
+	leafHash, err := ct.LeafHashForLeaf(leaf)
+	if err != nil {
+		return err
+	}
+	resp, err := lc.GetProofByHash(ctx, leafHash[:], sth.TreeSize)
+	if err != nil {
+		return fmt.Errorf("the certificate is not included in the CT log %s at tree size %d: %w", logURL, sth.TreeSize, err)
+	}
+	if err := proof.VerifyInclusion(rfc6962.DefaultHasher, uint64(resp.LeafIndex), sth.TreeSize, leafHash[:], resp.AuditPath, sth.SHA256RootHash[:]); err != nil {
+		return fmt.Errorf("verifying the inclusion proof of the certificate in the CT log %s: %w", logURL, err)
+	}
+	return nil
+}
+
+// certIssuer returns the certificate that issued cert, from the chain of sig
+// or else from the trusted roots and intermediates of co.
+func certIssuer(cert *x509.Certificate, sig oci.Signature, co *cosign.CheckOpts) (*x509.Certificate, error) {
+	chain, err := sig.Chain()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chain {
+		if !c.Equal(cert) && cert.CheckSignatureFrom(c) == nil {
+			return c, nil
+		}
+	}
+	chains, err := cosign.TrustedCert(cert, co.RootCerts, co.IntermediateCerts)
+	if err != nil {
+		return nil, fmt.Errorf("finding the issuer of the signing certificate: %w", err)
+	}
+	if len(chains) == 0 || len(chains[0]) < 2 {
+		return nil, errors.New("the issuer of the signing certificate wasn't found")
+	}
+	return chains[0][1], nil
+}
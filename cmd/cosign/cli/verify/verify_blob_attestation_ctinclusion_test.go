@@ -0,0 +1,212 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ct "github.com/google/certificate-transparency-go"
+	cttls "github.com/google/certificate-transparency-go/tls"
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/cosign/v2/test"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/tuf"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+func TestVerifyCTInclusion(t *testing.T) {
+	ctx := context.Background()
+	newCTKey := func() (*ecdsa.PrivateKey, [sha256.Size]byte) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(priv.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return priv, sha256.Sum256(der)
+	}
+	ctPriv, logID := newCTKey()
+	otherPriv, otherLogID := newCTKey()
+	ctPEM, err := cryptoutils.MarshalPublicKeyToPEM(ctPriv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctPubKeys := cosign.NewTrustedTransparencyLogPubKeys()
+	if err := ctPubKeys.AddTransparencyLogPubKey(ctPEM, tuf.Active); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _, err := test.GenerateRootCa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, err := cryptoutils.MarshalCertificateToPEM(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := static.NewSignature([]byte("payload"), "", static.WithCertChain(certPEM, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySig, err := static.NewSignature([]byte("payload"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const timestamp = 1700000000000
+	newSCT := func(logID [sha256.Size]byte) []byte {
+		ds, err := cttls.CreateSignature(*ctPriv, cttls.SHA256, []byte("sct"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dsBytes, err := cttls.Marshal(ds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawSCT, err := json.Marshal(ct.AddChainResponse{SCTVersion: ct.V1, ID: logID[:], Timestamp: timestamp, Signature: dsBytes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rawSCT
+	}
+
+	ctCert, err := ctx509.ParseCertificate(cert.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := ct.MerkleTreeLeafFromChain([]*ctx509.Certificate{ctCert}, ct.X509LogEntryType, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafHash, err := ct.LeafHashForLeaf(leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLeafHash := rfc6962.DefaultHasher.HashLeaf([]byte("other"))
+	root := rfc6962.DefaultHasher.HashChildren(leafHash[:], otherLeafHash)
+
+	// newLog serves a CT log of two entries whose tree head, of root, is
+	// signed with sthKey, and which proves the inclusion of the certificate
+	// if included.
+	newLog := func(sthKey *ecdsa.PrivateKey, root []byte, included bool) string {
+		sth := ct.SignedTreeHead{Version: ct.V1, TreeSize: 2, Timestamp: timestamp + 1}
+		copy(sth.SHA256RootHash[:], root)
+		input, err := ct.SerializeSTHSignatureInput(sth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds, err := cttls.CreateSignature(*sthKey, cttls.SHA256, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dsBytes, err := cttls.Marshal(ds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == ct.GetSTHPath:
+				json.NewEncoder(w).Encode(ct.GetSTHResponse{TreeSize: sth.TreeSize, Timestamp: sth.Timestamp, SHA256RootHash: root, TreeHeadSignature: dsBytes})
+			case r.URL.Path == ct.GetProofByHashPath && included && r.URL.Query().Get("hash") == base64.StdEncoding.EncodeToString(leafHash[:]):
+				json.NewEncoder(w).Encode(ct.GetProofByHashResponse{LeafIndex: 0, AuditPath: [][]byte{otherLeafHash}})
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+
+	tests := []struct {
+		description string
+		sig         oci.Signature
+		sct         []byte
+		logURL      string
+		wantErr     string
+	}{
+		{
+			description: "included",
+			sig:         sig,
+			sct:         newSCT(logID),
+			logURL:      newLog(ctPriv, root, true),
+		}, {
+			description: "not included",
+			sig:         sig,
+			sct:         newSCT(logID),
+			logURL:      newLog(ctPriv, root, false),
+			wantErr:     "the certificate is not included in the CT log",
+		}, {
+			description: "proof not matching the tree head",
+			sig:         sig,
+			sct:         newSCT(logID),
+			logURL:      newLog(ctPriv, rfc6962.DefaultHasher.HashChildren(otherLeafHash, otherLeafHash), true),
+			wantErr:     "verifying the inclusion proof",
+		}, {
+			description: "tree head of another log",
+			sig:         sig,
+			sct:         newSCT(logID),
+			logURL:      newLog(otherPriv, root, true),
+			wantErr:     "getting the signed tree head",
+		}, {
+			description: "SCT of an untrusted log",
+			sig:         sig,
+			sct:         newSCT(otherLogID),
+			logURL:      newLog(ctPriv, root, true),
+			wantErr:     "ctfe public key not found",
+		}, {
+			description: "no SCT",
+			sig:         sig,
+			logURL:      newLog(ctPriv, root, true),
+			wantErr:     "no SCT found",
+		}, {
+			description: "no certificate",
+			sig:         keySig,
+			sct:         newSCT(logID),
+			logURL:      newLog(ctPriv, root, true),
+			wantErr:     "the attestation has no signing certificate",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			co := &cosign.CheckOpts{SCT: test.sct, CTLogPubKeys: &ctPubKeys}
+			err := verifyCTInclusion(ctx, test.sig, co, test.logURL, nil)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyCTInclusion() = %v, expected success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("verifyCTInclusion() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+}
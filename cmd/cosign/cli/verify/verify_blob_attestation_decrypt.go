@@ -0,0 +1,157 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// PredicateDecryptAge decrypts predicates encrypted with age
+// (https://age-encryption.org).
+const PredicateDecryptAge = "age"
+
+// maxDecryptedPredicateSize bounds the size of a decrypted predicate.
+const maxDecryptedPredicateSize = 32 << 20
+
+// ageDecrypter returns a function decrypting an age encrypted predicate with
+// the identities of the file at identityPath. The predicate is a JSON string
+// holding the ASCII armored or the base64 encoded binary ciphertext.
+func ageDecrypter(identityPath string) (func([]byte) ([]byte, error), error) {
+	f, err := os.Open(filepath.Clean(identityPath))
+	if err != nil {
+		return nil, fmt.Errorf("reading age identity: %w", err)
+	}
+	defer f.Close()
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing age identity %s: %w", identityPath, err)
+	}
+	return func(predicate []byte) ([]byte, error) {
+		var encoded string
+		if err := json.Unmarshal(predicate, &encoded); err != nil {
+			return nil, errors.New("the predicate is not a string of age ciphertext")
+		}
+		var ciphertext io.Reader
+		if strings.HasPrefix(strings.TrimSpace(encoded), armor.Header) {
+			ciphertext = armor.NewReader(strings.NewReader(strings.TrimSpace(encoded)))
+		} else {
+			b, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("decoding the age ciphertext: %w", err)
+			}
+			ciphertext = bytes.NewReader(b)
+		}
+		r, err := age.Decrypt(ciphertext, identities...)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting the predicate: %w", err)
+		}
+		plaintext, err := io.ReadAll(io.LimitReader(r, maxDecryptedPredicateSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("decrypting the predicate: %w", err)
+		}
+		if len(plaintext) > maxDecryptedPredicateSize {
+			return nil, fmt.Errorf("the decrypted predicate exceeds %d bytes", maxDecryptedPredicateSize)
+		}
+		return plaintext, nil
+	}, nil
+}
+
+// derivedAttestation is a verified attestation whose payload is a DSSE
+// envelope derived from the signed one: the statement with its predicate
+// decrypted, or the statement made of a signed bare predicate, see
+// predicateOnlyStatement. The envelope signatures are those of the signed
+// envelope, so the attestation is only for the checks of the statement, see
+// signedAttestation.
+type derivedAttestation struct {
+	signed
+	payload []byte
+}
+
+// signed is the attestation as signed. It is embedded under this name since
+// oci.Signature has a Signature method.
+type signed = oci.Signature
+
+func (d *derivedAttestation) Payload() ([]byte, error) {
+	return d.payload, nil
+}
+
+// signedAttestation returns the attestation as signed, i.e. with its
+// predicate encrypted if it was decrypted for the checks, or the bare
+// predicate if a statement was made of it.
+func signedAttestation(sig oci.Signature) oci.Signature {
+	if d, ok := sig.(*derivedAttestation); ok {
+		return d.signed
+	}
+	return sig
+}
+
+// decryptPredicate returns the attestation with the predicate of its
+// statement decrypted by decrypt, which must yield JSON. It must only be
+// called once the signature of sig is verified, so that no unauthenticated
+// ciphertext is decrypted.
+func decryptPredicate(sig oci.Signature, decrypt func([]byte) ([]byte, error)) (oci.Signature, error) {
+	p, err := sig.Payload()
+	if err != nil {
+		return nil, err
+	}
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(p, &env); err != nil {
+		return nil, err
+	}
+	stBytes, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the other fields of the statement as they were signed.
+	st := map[string]json.RawMessage{}
+	if err := json.Unmarshal(stBytes, &st); err != nil {
+		return nil, fmt.Errorf("parsing in-toto statement: %w", err)
+	}
+	predicate, ok := st["predicate"]
+	if !ok {
+		return nil, errors.New("the statement has no predicate to decrypt")
+	}
+	plaintext, err := decrypt(predicate)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(plaintext) {
+		return nil, errors.New("the decrypted predicate is not JSON")
+	}
+	st["predicate"] = plaintext
+	if stBytes, err = json.Marshal(st); err != nil {
+		return nil, err
+	}
+	env.Payload = base64.StdEncoding.EncodeToString(stBytes)
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return &derivedAttestation{signed: sig, payload: payload}, nil
+}
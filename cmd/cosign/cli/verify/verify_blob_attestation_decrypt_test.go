@@ -0,0 +1,183 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestVerifyBlobAttestationPredicateDecrypt(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherIdentity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityPath := writeBlobFile(t, td, identity.String()+"\n", "key.txt")
+	otherIdentityPath := writeBlobFile(t, td, otherIdentity.String()+"\n", "other-key.txt")
+
+	// encrypt returns the armored ciphertext of the predicate.
+	encrypt := func(predicate string) string {
+		buf := &bytes.Buffer{}
+		aw := armor.NewWriter(buf)
+		w, err := age.Encrypt(aw, identity.Recipient())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, predicate); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := aw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	key := writeTestKey(t, td)
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	st := testStatement("customFoo", sha256Subject("blob", blobContents))
+	st.Predicate = encrypt(`{"foo": "bar"}`)
+	env := signTestStatementWith(t, key.signer, st)
+	sigPath := writeBlobFile(t, td, string(env), "attestation.json")
+
+	st.Predicate = encrypt(`not JSON`)
+	notJSONPath := writeBlobFile(t, td, string(signTestStatementWith(t, key.signer, st)), "not-json.json")
+
+	// An envelope signed by another key isn't decrypted.
+	otherEnv, _ := signTestStatement(t, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	otherSigPath := writeBlobFile(t, td, string(otherEnv), "other-attestation.json")
+
+	tests := []struct {
+		description string
+		sigPath     string
+		decrypt     string
+		identity    string
+		wantErr     string
+	}{
+		{
+			description: "decrypted predicate",
+			sigPath:     sigPath,
+			decrypt:     PredicateDecryptAge,
+			identity:    identityPath,
+		}, {
+			description: "encrypted predicate",
+			sigPath:     sigPath,
+			wantErr:     "predicate is not an object",
+		}, {
+			description: "wrong identity",
+			sigPath:     sigPath,
+			decrypt:     PredicateDecryptAge,
+			identity:    otherIdentityPath,
+			wantErr:     "decrypting the predicate",
+		}, {
+			description: "decrypted predicate not JSON",
+			sigPath:     notJSONPath,
+			decrypt:     PredicateDecryptAge,
+			identity:    identityPath,
+			wantErr:     "the decrypted predicate is not JSON",
+		}, {
+			description: "invalid signature",
+			sigPath:     otherSigPath,
+			decrypt:     PredicateDecryptAge,
+			identity:    identityPath,
+			wantErr:     "no signature of the envelope verifies",
+		}, {
+			description: "identity without decryption",
+			sigPath:     sigPath,
+			identity:    identityPath,
+			wantErr:     "--age-identity requires --predicate-decrypt age",
+		}, {
+			description: "unsupported decryption",
+			sigPath:     sigPath,
+			decrypt:     "gpg",
+			identity:    identityPath,
+			wantErr:     `unsupported --predicate-decrypt "gpg"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+				SignaturePath: test.sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationStatementChecks: BlobAttestationStatementChecks{
+					RejectUnknownPredicateFields: true,
+					AllowedPredicateFields:       []string{"foo"},
+					PredicateDecrypt:             test.decrypt,
+					AgeIdentity:                  test.identity,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+
+	// The saved bundle holds the envelope as signed, with the ciphertext.
+	bundlePath := filepath.Join(td, "bundle.json")
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+		SignaturePath: sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationStatementChecks: BlobAttestationStatementChecks{
+			PredicateDecrypt: PredicateDecryptAge,
+			AgeIdentity:      identityPath,
+		},
+		BlobAttestationOutputs: BlobAttestationOutputs{
+			SaveBundle: bundlePath,
+		},
+	}
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+	saved, err := cosign.FetchLocalSignedPayloadFromPath(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Base64Signature != base64.StdEncoding.EncodeToString(env) {
+		t.Error("the saved bundle doesn't hold the signed envelope")
+	}
+}
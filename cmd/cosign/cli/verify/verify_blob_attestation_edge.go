@@ -0,0 +1,98 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cyberphone/json-canonicalization/go/src/webpki.org/jsoncanonicalizer"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"sigs.k8s.io/release-utils/version"
+)
+
+// ProvenanceEdge is the edge of a supply-chain graph recorded by a
+// successful verification, see VerifyBlobAttestationCommand.EmitEdge: the
+// subject, of digest Subject, has an attestation of PredicateType signed by
+// Signer, as verified by Verifier at Timestamp.
+type ProvenanceEdge struct {
+	Verifier        string `json:"verifier"`
+	VerifierVersion string `json:"verifierVersion,omitempty"`
+	// Subject is the digest of the verified blob, as <algorithm>:<hex>, and
+	// SubjectName its name, as in the issued attestations.
+	Subject       string `json:"subject"`
+	SubjectName   string `json:"subjectName,omitempty"`
+	PredicateType string `json:"predicateType"`
+	// Signer is the identity of the certificate, or the key reference, the
+	// attestation was verified with.
+	Signer string `json:"signer"`
+	// Timestamp is the time of the verification, in UTC with a precision of
+	// a second.
+	Timestamp string `json:"timestamp"`
+}
+
+// newProvenanceEdge returns the edge of the verification of the blob named
+// resource, of digest h, at now.
+func newProvenanceEdge(resource string, h v1.Hash, verified *VerifiedBlobAttestation, keyRef string, now time.Time) (*ProvenanceEdge, error) {
+	if h.Hex == "" {
+		return nil, errors.New("a blob digest is required to emit an edge")
+	}
+	if verified.signature == nil {
+		return nil, errors.New("no verified attestation to emit an edge of")
+	}
+	signer, err := signerIdentity(verified.signature, keyRef)
+	if err != nil {
+		return nil, err
+	}
+	return &ProvenanceEdge{
+		Verifier:        VSAVerifierID,
+		VerifierVersion: version.GetVersionInfo().GitVersion,
+		Subject:         h.String(),
+		SubjectName:     resource,
+		PredicateType:   verified.PredicateType,
+		Signer:          signer,
+		Timestamp:       now.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// appendEdge appends edge to the JSON Lines file at path, created if needed,
+// as a line of canonical JSON. The line is appended with a single write, so
+// that verifications run concurrently don't interleave their edges.
+func appendEdge(path string, edge *ProvenanceEdge) error {
+	b, err := json.Marshal(edge)
+	if err != nil {
+		return err
+	}
+	if b, err = jsoncanonicalizer.Transform(b); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Clean(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open edge file: %w", err)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("append edge: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("append edge: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Edge appended to the file", path)
+	return nil
+}
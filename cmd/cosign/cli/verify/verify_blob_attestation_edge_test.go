@@ -0,0 +1,114 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestVerifyBlobAttestationEmitEdge(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	otherPath := writeBlobFile(t, td, anotherBlobContents, "other")
+	keyRef := att.keyPath
+	edgePath := filepath.Join(td, "edges.jsonl")
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationOutputs: BlobAttestationOutputs{
+			EmitEdge: edgePath,
+		},
+	}
+	// Each successful verification appends an edge, a failed one none.
+	for i := 0; i < 2; i++ {
+		if err := cmd.Exec(ctx, blobPath); err != nil {
+			t.Fatalf("Exec() = %v", err)
+		}
+	}
+	if err := cmd.Exec(ctx, otherPath); err == nil {
+		t.Fatal("Exec() of another blob succeeded")
+	}
+
+	b, err := os.ReadFile(edgePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("the edge file has %d lines, expected 2:\n%s", len(lines), b)
+	}
+	for _, line := range lines {
+		var got ProvenanceEdge
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := time.Parse(time.RFC3339, got.Timestamp); err != nil || !strings.HasSuffix(got.Timestamp, "Z") {
+			t.Errorf("timestamp %q isn't an RFC 3339 UTC time", got.Timestamp)
+		}
+		got.Timestamp = ""
+		got.VerifierVersion = ""
+		want := ProvenanceEdge{
+			Verifier:      VSAVerifierID,
+			Subject:       "sha256:" + sha256Subject("blob", blobContents).Digest["sha256"],
+			SubjectName:   "blob",
+			PredicateType: "customFoo",
+			Signer:        "key " + keyRef,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("edge mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestAppendEdge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "edges.jsonl")
+	edge := &ProvenanceEdge{
+		Verifier:      VSAVerifierID,
+		Subject:       "sha256:abcd",
+		PredicateType: "https://slsa.dev/provenance/v1",
+		Signer:        "key cosign.pub",
+		Timestamp:     time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC).Format(time.RFC3339),
+	}
+	for i := 0; i < 2; i++ {
+		if err := appendEdge(path, edge); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The keys are sorted, without insignificant whitespace.
+	line := `{"predicateType":"https://slsa.dev/provenance/v1","signer":"key cosign.pub","subject":"sha256:abcd","timestamp":"2023-10-01T12:00:00Z","verifier":"https://github.com/sigstore/cosign"}` + "\n"
+	if diff := cmp.Diff(line+line, string(b)); diff != "" {
+		t.Errorf("edge file mismatch (-want +got):\n%s", diff)
+	}
+}
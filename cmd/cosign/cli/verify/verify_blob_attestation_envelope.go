@@ -0,0 +1,197 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// extractEnvelope returns the DSSE envelope found at the JSONPath path of the
+// JSON document doc.
+//
+// The envelope is returned as it was serialized in doc, rather than decoded
+// and encoded again, since the tlog entry is computed over those bytes.
+func extractEnvelope(doc []byte, path string) ([]byte, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %w", path, err)
+	}
+	v := json.RawMessage(doc)
+	for _, s := range steps {
+		if v, err = s.apply(v); err != nil {
+			return nil, fmt.Errorf("evaluating JSONPath %q: %w", path, err)
+		}
+	}
+	if err := checkEnvelope(v); err != nil {
+		return nil, fmt.Errorf("value at JSONPath %q: %w", path, err)
+	}
+	return v, nil
+}
+
+// checkEnvelope verifies that b is a well-formed DSSE envelope, with a
+// payload type, a base64 payload and at least one signature.
+func checkEnvelope(b []byte) error {
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(b, &env); err != nil {
+		return fmt.Errorf("decoding DSSE envelope: %w", err)
+	}
+	switch {
+	case env.PayloadType == "":
+		return errors.New("DSSE envelope has no payloadType")
+	case env.Payload == "":
+		return errors.New("DSSE envelope has no payload")
+	case len(env.Signatures) == 0:
+		return errors.New("DSSE envelope has no signatures")
+	}
+	if _, err := base64.StdEncoding.DecodeString(env.Payload); err != nil {
+		return fmt.Errorf("decoding DSSE envelope payload: %w", err)
+	}
+	return nil
+}
+
+// checkSignatureCount fails if the DSSE envelope b carries more than limit
+// signatures. The signatures are counted without being decoded.
+func checkSignatureCount(b []byte, limit int) error {
+	env := struct {
+		Signatures []json.RawMessage `json:"signatures"`
+	}{}
+	if err := json.Unmarshal(b, &env); err != nil {
+		return fmt.Errorf("decoding DSSE envelope: %w", err)
+	}
+	if len(env.Signatures) > limit {
+		return fmt.Errorf("DSSE envelope carries %d signatures, more than the maximum of %d", len(env.Signatures), limit)
+	}
+	return nil
+}
+
+// signatureEncodings are the base64 encodings signatures are accepted in.
+// Web-oriented signers commonly emit unpadded base64url.
+var signatureEncodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// decodeSignature decodes a base64 signature in any of signatureEncodings.
+func decodeSignature(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	for _, enc := range signatureEncodings {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.New("signature is not standard or URL-safe base64")
+}
+
+// jsonPathStep selects an object member by name, or an array element by
+// index if name is empty.
+type jsonPathStep struct {
+	name  string
+	index int
+}
+
+func (s jsonPathStep) apply(v json.RawMessage) (json.RawMessage, error) {
+	if s.name != "" {
+		obj := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &obj); err != nil {
+			return nil, fmt.Errorf("selecting %q of a value that isn't an object", s.name)
+		}
+		member, ok := obj[s.name]
+		if !ok {
+			return nil, fmt.Errorf("no member %q", s.name)
+		}
+		return member, nil
+	}
+	var arr []json.RawMessage
+	if err := json.Unmarshal(v, &arr); err != nil {
+		return nil, fmt.Errorf("selecting [%d] of a value that isn't an array", s.index)
+	}
+	if s.index >= len(arr) {
+		return nil, fmt.Errorf("index [%d] out of range of %d elements", s.index, len(arr))
+	}
+	return arr[s.index], nil
+}
+
+// parseJSONPath parses the subset of JSONPath selecting a single value: an
+// optional root $, followed by .name, ['name'] and [index] steps.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	rest := strings.TrimPrefix(path, "$")
+	var steps []jsonPathStep
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" || name == "*" {
+				return nil, fmt.Errorf("unsupported step in %q", rest)
+			}
+			steps = append(steps, jsonPathStep{name: name})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.New("unterminated [")
+			}
+			sel := rest[1:end]
+			if len(sel) > 2 && (sel[0] == '\'' || sel[0] == '"') && sel[len(sel)-1] == sel[0] {
+				steps = append(steps, jsonPathStep{name: sel[1 : len(sel)-1]})
+			} else {
+				i, err := strconv.Atoi(sel)
+				if err != nil || i < 0 {
+					return nil, fmt.Errorf("unsupported selector [%s], only names and non-negative indices are", sel)
+				}
+				steps = append(steps, jsonPathStep{index: i})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+	}
+	return steps, nil
+}
+
+// saveEnvelope writes the verified DSSE envelope to path, as it was read
+// after unwrapping, so that its signatures verify when it is forwarded.
+// envelope is the envelope read from --signature or --bundle, if any, since
+// the verified one may have had its signatures re-encoded.
+func saveEnvelope(path string, envelope []byte, verified *VerifiedBlobAttestation) error {
+	if envelope == nil {
+		sig := signedAttestation(verified.signature)
+		if sig == nil {
+			return errors.New("no verified envelope to save")
+		}
+		var err error
+		if envelope, err = sig.Payload(); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, envelope, 0600); err != nil {
+		return fmt.Errorf("create envelope file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Envelope written in the file", path)
+	return nil
+}
@@ -0,0 +1,313 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	rekor_dsse "github.com/sigstore/rekor/pkg/types/dsse"
+	dsse_v001 "github.com/sigstore/rekor/pkg/types/dsse/v0.0.1"
+)
+
+func TestVerifyEnvelopeMaxSignatures(t *testing.T) {
+	ctx := context.Background()
+
+	env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents)))
+	// Repeat the signature, as a pathological envelope would.
+	e := &ssldsse.Envelope{}
+	if err := json.Unmarshal(env, e); err != nil {
+		t.Fatal(err)
+	}
+	e.Signatures = []ssldsse.Signature{e.Signatures[0], e.Signatures[0], e.Signatures[0]}
+	env, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		maxSignatures int
+		wantErr       bool
+	}{
+		{maxSignatures: 0},
+		{maxSignatures: 3},
+		{maxSignatures: 2, wantErr: true},
+	} {
+		opts := &VerifyEnvelopeOptions{
+			CheckOpts: &cosign.CheckOpts{
+				SigVerifier: sv,
+				IgnoreTlog:  true,
+			},
+			CheckClaims:   true,
+			PredicateType: "spdx",
+			MaxSignatures: test.maxSignatures,
+		}
+		_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+		if test.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "carries 3 signatures, more than the maximum of 2") {
+				t.Errorf("verifyEnvelope() with MaxSignatures %d = %v, expected an error", test.maxSignatures, err)
+			}
+		} else if err != nil {
+			t.Errorf("verifyEnvelope() with MaxSignatures %d = %v", test.maxSignatures, err)
+		}
+	}
+}
+
+func TestExtractEnvelope(t *testing.T) {
+	env, _ := signTestStatement(t, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	doc := fmt.Sprintf(`{"meta": {"source": "ci"}, "attestation": %s, "attestations": [{"dsse": %s}], "meta.v1": %s}`, env, env, env)
+
+	for _, path := range []string{"$.attestation", ".attestation", "$['attestation']", `$.attestations[0].dsse`, `$["meta.v1"]`} {
+		got, err := extractEnvelope([]byte(doc), path)
+		if err != nil {
+			t.Errorf("extractEnvelope(%s) = %v", path, err)
+			continue
+		}
+		if !bytes.Equal(got, env) {
+			t.Errorf("extractEnvelope(%s) = %s, want the envelope as serialized", path, got)
+		}
+	}
+
+	for _, path := range []string{"$.missing", "$.meta", "$.attestations[1]", "$.attestations[*]", "$.attestation.payload", "$..attestation", "attestation", "$['']", "$[-1]"} {
+		if _, err := extractEnvelope([]byte(doc), path); err == nil {
+			t.Errorf("extractEnvelope(%s) expected an error", path)
+		}
+	}
+}
+
+func TestVerifyBlobAttestationEnvelopeJSONPath(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	doc := fmt.Sprintf(`{"attestation": %s, "meta": {"source": "ci"}}`, att.env)
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: writeBlobFile(t, td, doc, "wrapped.json"),
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+			EnvelopeJSONPath: "$.attestation",
+		},
+	}
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+
+	// The whole document isn't an envelope.
+	cmd.EnvelopeJSONPath = ""
+	if err := cmd.Exec(ctx, blobPath); err == nil {
+		t.Fatal("Exec() expected an error without --envelope-json-path")
+	}
+	cmd.EnvelopeJSONPath = "$.meta"
+	if err := cmd.Exec(ctx, blobPath); err == nil || !strings.Contains(err.Error(), "DSSE envelope") {
+		t.Fatalf("Exec() = %v, expected a malformed envelope error", err)
+	}
+}
+
+func TestVerifyBlobAttestationSignatureEncodings(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(att.env, &env); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodings := map[string]string{
+		"standard":          base64.StdEncoding.EncodeToString(sig),
+		"unpadded standard": base64.RawStdEncoding.EncodeToString(sig),
+		"url-safe":          base64.URLEncoding.EncodeToString(sig),
+		"unpadded url-safe": base64.RawURLEncoding.EncodeToString(sig),
+	}
+	for name, encoded := range encodings {
+		t.Run(name, func(t *testing.T) {
+			env := env
+			env.Signatures = []ssldsse.Signature{{KeyID: env.Signatures[0].KeyID, Sig: encoded}}
+			b, err := json.Marshal(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+				SignaturePath: writeBlobFile(t, td, string(b), strings.ReplaceAll(name, " ", "-")+".json"),
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+			}
+			if err := cmd.Exec(ctx, blobPath); err != nil {
+				t.Fatalf("Exec() = %v", err)
+			}
+		})
+	}
+
+	if _, err := decodeSignature("not base64!"); err == nil {
+		t.Error("decodeSignature() of invalid base64 expected an error")
+	}
+}
+
+// TestVerifyBlobAttestationURLSafeBundle verifies the tlog entry of an
+// envelope whose signature is unpadded base64url, which is computed over the
+// envelope as serialized by the signer.
+func TestVerifyBlobAttestationURLSafeBundle(t *testing.T) {
+	keyless := newKeylessStack(t)
+	identity := "hello@foo.com"
+	issuer := "issuer"
+	leafCert, _, leafPemCert, signer := keyless.genLeafCert(t, identity, issuer)
+
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(signTestStatementWith(t, signer, testStatement("customFoo", sha256Subject("blob", blobContents))), &env); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Indent the envelope, so that it doesn't serialize the way the verifier
+	// would re-serialize it.
+	stdBytes, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Signatures[0].Sig = base64.RawURLEncoding.EncodeToString(sig)
+	envBytes, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rekor types only decode standard base64 signatures: the entry is
+	// made from the standard base64 envelope, then bound to the base64url one
+	// by its hash, as a log accepting it would.
+	entryImpl, err := createEntry(context.Background(), rekor_dsse.KIND, "0.0.1", stdBytes, leafPemCert, stdBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envHash := sha256.Sum256(envBytes)
+	*entryImpl.(*dsse_v001.V001Entry).DSSEObj.EnvelopeHash.Value = hex.EncodeToString(envHash[:])
+	canonical, err := entryImpl.Canonicalize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := base64.StdEncoding.EncodeToString(canonical)
+	b := createBundle(t, envBytes, leafPemCert, keyless.rekorLogID, leafCert.NotBefore.Unix()+1, entry)
+	b.Bundle.SignedEntryTimestamp = keyless.rekorSignPayload(t, b.Bundle.Payload)
+	chainPath := writeBlobFile(t, keyless.td, string(keyless.subPemCert)+string(keyless.rootPemCert), "chain.pem")
+	blobPath := writeBlobFile(t, keyless.td, blobContents, "blob")
+
+	cmd := VerifyBlobAttestationCommand{
+		CertVerifyOptions: options.CertVerifyOptions{
+			CertIdentity:   identity,
+			CertOidcIssuer: issuer,
+		},
+		CertChain:     chainPath,
+		KeyOpts:       options.KeyOpts{BundlePath: writeBundleFile(t, keyless.td, b, "bundle.json")},
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		Offline:       true,
+		IgnoreSCT:     true,
+	}
+	if err := cmd.Exec(context.Background(), blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+
+	// The same holds for an envelope passed with --signature.
+	cmd.SignaturePath = writeBlobFile(t, keyless.td, string(envBytes), "attestation.json")
+	if err := cmd.Exec(context.Background(), blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+}
+
+func TestVerifyBlobAttestationOutputEnvelope(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+
+	// Re-encode the signature as URL-safe base64, which the envelope must be
+	// written in as read.
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(att.env, &env); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Signatures[0].Sig = base64.RawURLEncoding.EncodeToString(sig)
+	urlSafeEnv, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := fmt.Sprintf(`{"attestation": %s, "meta": {"source": "ci"}}`, urlSafeEnv)
+
+	outPath := filepath.Join(td, "envelope.json")
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: writeBlobFile(t, td, doc, "wrapped.json"),
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+			EnvelopeJSONPath: "$.attestation",
+		},
+		BlobAttestationOutputs: BlobAttestationOutputs{
+			OutputEnvelope: outPath,
+		},
+	}
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+	got, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, urlSafeEnv) {
+		t.Errorf("written envelope = %s, expected %s", got, urlSafeEnv)
+	}
+
+	// Nothing is written when the verification fails.
+	if err := os.Remove(outPath); err != nil {
+		t.Fatal(err)
+	}
+	cmd.PredicateType = "customBar"
+	if err := cmd.Exec(ctx, blobPath); err == nil {
+		t.Fatal("Exec() expected an error for another predicate type")
+	}
+	if _, err := os.Stat(outPath); !os.IsNotExist(err) {
+		t.Errorf("envelope written despite the failed verification: %v", err)
+	}
+}
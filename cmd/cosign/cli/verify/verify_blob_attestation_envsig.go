@@ -0,0 +1,68 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sigstore/cosign/v2/pkg/blob"
+)
+
+// envSignaturePrefix prefixes a --signature reference naming the environment
+// variable holding the base64 encoded envelope, e.g. env://ATTESTATION.
+const envSignaturePrefix = "env://"
+
+// readEnvSignature returns the DSSE envelope held base64 encoded in the
+// environment variable of ref, an env://VAR reference, and the value of the
+// variable, to be scrubbed from the errors, see scrubError.
+func readEnvSignature(ref string) ([]byte, string, error) {
+	value, err := blob.LoadFileOrURL(ref)
+	if err != nil {
+		return nil, "", err
+	}
+	name := strings.TrimPrefix(ref, envSignaturePrefix)
+	encoded := strings.TrimSpace(string(value))
+	if encoded == "" {
+		return nil, "", fmt.Errorf("the env var $%s is empty", name)
+	}
+	envelope, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// The decoding error only gives the offset of the invalid data.
+		return nil, encoded, fmt.Errorf("decoding the env var $%s: %w", name, err)
+	}
+	return envelope, encoded, nil
+}
+
+// scrubError returns err with every occurrence of the secrets in its message
+// replaced, so that the value of an environment variable isn't leaked in
+// logs. The scrubbed error doesn't wrap err, whose chain holds the secrets.
+func scrubError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	for _, secret := range secrets {
+		if s := strings.TrimSpace(secret); s != "" {
+			msg = strings.ReplaceAll(msg, s, "[REDACTED]")
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestVerifyBlobAttestationEnvSignature(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	keyRef := att.keyPath
+
+	tests := []struct {
+		description string
+		value       string
+		unset       bool
+		wantErr     string
+	}{
+		{
+			description: "base64 envelope",
+			value:       base64.StdEncoding.EncodeToString(att.env),
+		}, {
+			description: "surrounding whitespace",
+			value:       "\n" + base64.StdEncoding.EncodeToString(att.env) + "\n",
+		}, {
+			description: "unset",
+			unset:       true,
+			wantErr:     "env var $COSIGN_TEST_ATTESTATION not found",
+		}, {
+			description: "empty",
+			value:       " ",
+			wantErr:     "env var $COSIGN_TEST_ATTESTATION is empty",
+		}, {
+			description: "not base64",
+			value:       "s3cr3t-t0ken!",
+			wantErr:     "decoding the env var $COSIGN_TEST_ATTESTATION",
+		}, {
+			description: "not an envelope",
+			value:       base64.StdEncoding.EncodeToString([]byte("s3cr3t-t0ken")),
+			wantErr:     "reading env://COSIGN_TEST_ATTESTATION",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if !test.unset {
+				t.Setenv("COSIGN_TEST_ATTESTATION", test.value)
+			}
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+				SignaturePath: "env://COSIGN_TEST_ATTESTATION",
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v, expected success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+			}
+			if strings.Contains(err.Error(), "s3cr3t") {
+				t.Errorf("Exec() = %v, leaks the env var value", err)
+			}
+		})
+	}
+}
+
+func TestScrubError(t *testing.T) {
+	err := scrubError(fmt.Errorf("reading: %w", errors.New(`invalid value "s3cr3t"`)), "s3cr3t", "")
+	if got, want := err.Error(), `reading: invalid value "[REDACTED]"`; got != want {
+		t.Errorf("scrubError() = %q, expected %q", got, want)
+	}
+	if errors.Unwrap(err) != nil {
+		t.Errorf("scrubError() wraps the error holding the secret")
+	}
+	orig := errors.New("no secret")
+	if err := scrubError(orig, "s3cr3t"); err != orig {
+		t.Errorf("scrubError() = %v, expected the error unchanged", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/sigstore/cosign/v2/internal/ui"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+// explainer narrates the steps of a verification for --explain. All of its
+// methods are no-ops if it isn't enabled.
+type explainer struct {
+	ctx     context.Context
+	enabled bool
+	n       int
+}
+
+func (e *explainer) step(format string, args ...any) {
+	if !e.enabled {
+		return
+	}
+	e.n++
+	ui.Infof(e.ctx, "%d. %s", e.n, fmt.Sprintf(format, args...))
+}
+
+// identities narrates the identity constraints asserted on the certificate.
+func (e *explainer) identities(identities []cosign.Identity) {
+	if !e.enabled {
+		return
+	}
+	if len(identities) == 0 {
+		e.step("No certificate identity is asserted")
+		return
+	}
+	for _, id := range identities {
+		subject, issuer := id.Subject, id.Issuer
+		if subject == "" {
+			subject = "matching " + id.SubjectRegExp
+		}
+		if issuer == "" {
+			issuer = "matching " + id.IssuerRegExp
+		}
+		e.step("Asserting the certificate identity is %s, issued by %s", subject, issuer)
+	}
+}
+
+// subjects narrates the subjects claimed by the envelope. The envelope isn't
+// verified yet, so these are only claims.
+func (e *explainer) subjects(envBytes []byte) {
+	if !e.enabled {
+		return
+	}
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(envBytes, &env); err != nil {
+		return
+	}
+	stBytes, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return
+	}
+	st := in_toto.Statement{}
+	if err := json.Unmarshal(stBytes, &st); err != nil {
+		e.step("The envelope payload is not an in-toto statement")
+		return
+	}
+	e.step("The envelope claims predicate type %s and %d subject(s)", st.PredicateType, len(st.Subject))
+	for _, s := range st.Subject {
+		var digests []string
+		for alg, d := range s.Digest {
+			digests = append(digests, alg+":"+d)
+		}
+		e.step("  subject %q with digest %s", s.Name, strings.Join(digests, ", "))
+	}
+}
+
+// failure narrates why verification failed, with a suggested fix for each
+// failed check.
+func (e *explainer) failure(err error) {
+	if !e.enabled || err == nil {
+		return
+	}
+	errs := []error{err}
+	var verr *VerificationErrors
+	if errors.As(err, &verr) {
+		errs = verr.Errs
+	}
+	for _, err := range errs {
+		e.step("Verification failed: %v", err)
+		if hint := explainHint(err); hint != "" {
+			ui.Infof(e.ctx, "   Suggested fix: %s", hint)
+		}
+	}
+}
+
+// explainHint suggests a fix for a verification failure.
+func explainHint(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "subject") && strings.Contains(msg, "digest"):
+		return "make sure the blob is the artifact the attestation was created for, or pass --check-claims=false to only verify the envelope"
+	case strings.Contains(msg, "invalid predicate type"):
+		return "pass the predicate type of the attestation with --type"
+	case strings.Contains(msg, "invalid signature") || strings.Contains(msg, "could not verify envelope"):
+		return "make sure --key or --certificate is the one the attestation was signed with"
+	case strings.Contains(msg, "none of the expected identities matched"):
+		return "check --certificate-identity and --certificate-oidc-issuer against the signing certificate"
+	case strings.Contains(msg, "tlog") || strings.Contains(msg, "rekor"):
+		return "pass the --bundle written at signing time, or --insecure-ignore-tlog if the attestation was never uploaded"
+	case strings.Contains(msg, "certificate"):
+		return "check the certificate chain with --certificate-chain, or the trusted Fulcio roots"
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/internal/ui"
+)
+
+func TestVerifyBlobAttestationExplain(t *testing.T) {
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, anotherBlobContents, "blob")
+	keyRef := writeBlobFile(t, td, pubkey, "cosign.pub")
+	decodedSig, err := base64.StdEncoding.DecodeString(blobSLSAProvenanceSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigRef := writeBlobFile(t, td, string(decodedSig), "signature")
+
+	var execErr error
+	stderr := ui.RunWithTestCtx(func(ctx context.Context, _ ui.WriteFunc) {
+		cmd := VerifyBlobAttestationCommand{
+			KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+			SignaturePath: sigRef,
+			IgnoreTlog:    true,
+			CheckClaims:   true,
+			PredicateType: "slsaprovenance",
+			Explain:       true,
+		}
+		execErr = cmd.Exec(ctx, blobPath)
+	})
+	if execErr == nil {
+		t.Fatal("expected verification of another blob to fail")
+	}
+	for _, want := range []string{
+		"Loaded the public key " + keyRef,
+		"Computed the blob digest sha256:",
+		`subject "blob" with digest sha256:`,
+		"Verification failed: no matching subject digest found",
+		"Suggested fix: make sure the blob is the artifact",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("expected explanation to contain %q, got:\n%s", want, stderr)
+		}
+	}
+}
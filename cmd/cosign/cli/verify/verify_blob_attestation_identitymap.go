@@ -0,0 +1,138 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// IdentityPredicateMap maps certificate identities to the predicate types
+// they may attest to, e.g.
+//
+//	{
+//	  "identities": [
+//	    {"identity": "release@example.com", "predicateTypes": ["slsaprovenance1"]},
+//	    {"identityRegexp": "^https://github.com/example/sbom/", "predicateTypes": ["spdxjson", "cyclonedx"]}
+//	  ]
+//	}
+//
+// A predicate type is a URI or a shorthand of options.PredicateTypeMap.
+type IdentityPredicateMap struct {
+	Identities []IdentityPredicateEntry `json:"identities"`
+
+	regexps []*regexp.Regexp
+}
+
+// IdentityPredicateEntry lets the identities matching Identity, exactly, or
+// IdentityRegexp attest to PredicateTypes.
+type IdentityPredicateEntry struct {
+	Identity       string   `json:"identity,omitempty"`
+	IdentityRegexp string   `json:"identityRegexp,omitempty"`
+	PredicateTypes []string `json:"predicateTypes"`
+}
+
+// LoadIdentityPredicateMap reads and validates the identity predicate map at
+// path, resolving the predicate type shorthands.
+func LoadIdentityPredicateMap(path string) (*IdentityPredicateMap, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading identity predicate map: %w", err)
+	}
+	m := &IdentityPredicateMap{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("parsing identity predicate map %s: %w", path, err)
+	}
+	if len(m.Identities) == 0 {
+		return nil, fmt.Errorf("identity predicate map %s lists no identities", path)
+	}
+	m.regexps = make([]*regexp.Regexp, len(m.Identities))
+	for i, e := range m.Identities {
+		switch {
+		case (e.Identity == "") == (e.IdentityRegexp == ""):
+			return nil, fmt.Errorf("identity predicate map %s: entry %d must have one of identity or identityRegexp", path, i)
+		case len(e.PredicateTypes) == 0:
+			return nil, fmt.Errorf("identity predicate map %s: entry %d has no predicateTypes", path, i)
+		}
+		if e.IdentityRegexp != "" {
+			if m.regexps[i], err = regexp.Compile(e.IdentityRegexp); err != nil {
+				return nil, fmt.Errorf("identity predicate map %s: entry %d: %w", path, i, err)
+			}
+		}
+		for j, t := range e.PredicateTypes {
+			if uri, ok := options.PredicateTypeMap[t]; ok {
+				m.Identities[i].PredicateTypes[j] = uri
+			}
+		}
+	}
+	return m, nil
+}
+
+// Allows reports whether any entry matching one of the identities lets it
+// attest to predicateType.
+func (m *IdentityPredicateMap) Allows(identities []string, predicateType string) bool {
+	for i, e := range m.Identities {
+		for _, id := range identities {
+			if !m.matches(i, id) {
+				continue
+			}
+			for _, t := range e.PredicateTypes {
+				if t == predicateType {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (m *IdentityPredicateMap) matches(i int, identity string) bool {
+	if re := m.regexps[i]; re != nil {
+		return re.MatchString(identity)
+	}
+	return m.Identities[i].Identity == identity
+}
+
+// checkIdentityPredicate fails unless the identity of the signing
+// certificate may attest to the predicate type of the statement, according
+// to m. The certificate was verified along with the signature.
+func checkIdentityPredicate(sig oci.Signature, m *IdentityPredicateMap) error {
+	cert, err := sig.Cert()
+	if err != nil {
+		return err
+	}
+	if cert == nil {
+		return errors.New("the attestation has no signing certificate to check against the identity predicate map")
+	}
+	st, err := statementFromAttestation(sig)
+	if err != nil {
+		return err
+	}
+	identities := cryptoutils.GetSubjectAlternateNames(cert)
+	if !m.Allows(identities, st.PredicateType) {
+		return fmt.Errorf("signer identity %s may not attest to predicate type %s", strings.Join(identities, ", "), st.PredicateType)
+	}
+	return nil
+}
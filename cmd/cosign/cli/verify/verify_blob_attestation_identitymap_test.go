@@ -0,0 +1,102 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestVerifyBlobAttestationIdentityPredicateMap(t *testing.T) {
+	ctx := context.Background()
+	keyless := newKeylessStack(t)
+	identity := "hello@foo.com"
+	issuer := "issuer"
+	_, _, leafPEM, signer := keyless.genLeafCert(t, identity, issuer)
+
+	env := signTestStatementWith(t, signer, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	sigPath := writeBlobFile(t, keyless.td, string(env), "attestation.json")
+	blobPath := writeBlobFile(t, keyless.td, blobContents, "blob")
+	certPath := writeBlobFile(t, keyless.td, string(leafPEM), "cert.pem")
+	chainPath := writeBlobFile(t, keyless.td, string(keyless.subPemCert)+string(keyless.rootPemCert), "chain.pem")
+
+	tests := []struct {
+		description string
+		identityMap string
+		keyRef      string
+		wantErr     string
+	}{
+		{
+			description: "identity allowed",
+			identityMap: `{"identities": [{"identity": "hello@foo.com", "predicateTypes": ["customFoo"]}]}`,
+		}, {
+			description: "identity regexp allowed",
+			identityMap: `{"identities": [{"identity": "other@foo.com", "predicateTypes": ["customFoo"]}, {"identityRegexp": "@foo\\.com$", "predicateTypes": ["slsaprovenance", "customFoo"]}]}`,
+		}, {
+			description: "predicate type not allowed",
+			identityMap: `{"identities": [{"identityRegexp": "^hello@", "predicateTypes": ["slsaprovenance", "custom"]}]}`,
+			wantErr:     "signer identity hello@foo.com may not attest to predicate type customFoo",
+		}, {
+			description: "identity not listed",
+			identityMap: `{"identities": [{"identity": "other@foo.com", "predicateTypes": ["customFoo"]}]}`,
+			wantErr:     "may not attest to predicate type customFoo",
+		}, {
+			description: "entry with identity and regexp",
+			identityMap: `{"identities": [{"identity": "hello@foo.com", "identityRegexp": ".*", "predicateTypes": ["customFoo"]}]}`,
+			wantErr:     "entry 0 must have one of identity or identityRegexp",
+		}, {
+			description: "entry without predicate types",
+			identityMap: `{"identities": [{"identity": "hello@foo.com"}]}`,
+			wantErr:     "entry 0 has no predicateTypes",
+		},
+	}
+	for i, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				CertVerifyOptions: options.CertVerifyOptions{
+					CertIdentity:   identity,
+					CertOidcIssuer: issuer,
+				},
+				CertRef:       certPath,
+				CertChain:     chainPath,
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				IgnoreSCT:     true,
+				BlobAttestationCertificateChecks: BlobAttestationCertificateChecks{
+					IdentityPredicateMap: writeBlobFile(t, keyless.td, test.identityMap, fmt.Sprintf("map-%d.json", i)),
+				},
+			}
+			if test.keyRef != "" {
+				cmd.KeyRef, cmd.CertRef = test.keyRef, ""
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
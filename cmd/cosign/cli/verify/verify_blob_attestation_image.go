@@ -0,0 +1,194 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+)
+
+const (
+	// ImageComponentConfig is the image component matched by
+	// --match-image-config, its config blob.
+	ImageComponentConfig = "config"
+	// ImageComponentAnnotation is the image component matched by
+	// --match-annotation-digest, followed by the annotation key.
+	ImageComponentAnnotation = "annotation"
+)
+
+// imageConfigDigest returns the digest of the config blob of the image ref.
+// An index is rejected rather than resolved to one of its images, since the
+// platform matched would then be implicit.
+func (c *VerifyBlobAttestationCommand) imageConfigDigest(ctx context.Context, ref string) (v1.Hash, error) {
+	r, err := name.ParseReference(ref, c.RegistryOptions.NameOptions()...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("parsing image reference: %w", err)
+	}
+	desc, err := remote.Get(r, c.RegistryOptions.GetRegistryClientOpts(ctx)...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("fetching %s: %w", ref, err)
+	}
+	if desc.MediaType.IsIndex() {
+		return v1.Hash{}, fmt.Errorf("%s is an image index, reference one of its images by digest", ref)
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("fetching %s: %w", ref, err)
+	}
+	h, err := img.ConfigName()
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("getting the config digest of %s: %w", ref, err)
+	}
+	return h, nil
+}
+
+// imageAnnotationDigest returns the sha256 digest held by the annotation key
+// of the manifest of ref. Both image manifests and indexes are read, since
+// the annotation belongs to the manifest referenced rather than to one of its
+// platform images.
+func (c *VerifyBlobAttestationCommand) imageAnnotationDigest(ctx context.Context, ref, key string) (v1.Hash, error) {
+	r, err := name.ParseReference(ref, c.RegistryOptions.NameOptions()...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("parsing image reference: %w", err)
+	}
+	desc, err := remote.Get(r, c.RegistryOptions.GetRegistryClientOpts(ctx)...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("fetching %s: %w", ref, err)
+	}
+	m := struct {
+		Annotations map[string]string `json:"annotations"`
+	}{}
+	if err := json.Unmarshal(desc.Manifest, &m); err != nil {
+		return v1.Hash{}, fmt.Errorf("parsing the manifest of %s: %w", ref, err)
+	}
+	value, ok := m.Annotations[key]
+	if !ok {
+		return v1.Hash{}, fmt.Errorf("the manifest of %s has no annotation %s", ref, key)
+	}
+	h, err := v1.NewHash(value)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("annotation %s of %s is not a digest: %w", key, ref, err)
+	}
+	if h.Algorithm != "sha256" {
+		return v1.Hash{}, fmt.Errorf("annotation %s of %s is a %s digest, only sha256 is supported", key, ref, h.Algorithm)
+	}
+	return h, nil
+}
+
+// verifyFromImage verifies the attestations attached to the image ref, found
+// at its triangulated attestation tag, against the blob digest h. The first
+// attestation that verifies is returned.
+func (c *VerifyBlobAttestationCommand) verifyFromImage(ctx context.Context, opts *VerifyEnvelopeOptions, ref string, h v1.Hash) (*VerifiedBlobAttestation, error) {
+	r, err := name.ParseReference(ref, c.RegistryOptions.NameOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing image reference: %w", err)
+	}
+	ociremoteOpts, err := c.RegistryOptions.ClientOpts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("constructing client options: %w", err)
+	}
+	tag, err := ociremote.AttestationTag(r, ociremoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("triangulating the attestations of %s: %w", ref, err)
+	}
+	atts, err := ociremote.Signatures(tag, ociremoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", tag, err)
+	}
+	sigs, err := atts.Get()
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", tag, err)
+	}
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("no attestations found for %s at %s", ref, tag)
+	}
+
+	var failures []string
+	for _, sig := range sigs {
+		layer, err := sig.Digest()
+		if err != nil {
+			return nil, err
+		}
+		verified, err := verifyAttachedAttestation(ctx, opts, sig, h)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", layer, err))
+			continue
+		}
+		verified.AttestationTag = tag.String()
+		verified.AttestationLayer = layer.String()
+		return verified, nil
+	}
+	return nil, fmt.Errorf("no attestation of %s could be verified:\n %s", ref, strings.Join(failures, "\n "))
+}
+
+// verifyAttachedAttestation verifies an attestation attached to an image
+// with the certificate, chain, tlog bundle and timestamp annotated on it,
+// unless the command line provides them.
+func verifyAttachedAttestation(ctx context.Context, opts *VerifyEnvelopeOptions, sig oci.Signature, h v1.Hash) (*VerifiedBlobAttestation, error) {
+	envBytes, err := sig.Payload()
+	if err != nil {
+		return nil, err
+	}
+	var attached []static.Option
+	cert, err := sig.Cert()
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil {
+		certPEM, err := cryptoutils.MarshalCertificateToPEM(cert)
+		if err != nil {
+			return nil, err
+		}
+		chain, err := sig.Chain()
+		if err != nil {
+			return nil, err
+		}
+		chainPEM, err := cryptoutils.MarshalCertificatesToPEM(chain)
+		if err != nil {
+			return nil, err
+		}
+		attached = append(attached, static.WithCertChain(certPEM, chainPEM))
+	}
+	b, err := sig.Bundle()
+	if err != nil {
+		return nil, err
+	}
+	if b != nil {
+		attached = append(attached, static.WithBundle(b))
+	}
+	ts, err := sig.RFC3161Timestamp()
+	if err != nil {
+		return nil, err
+	}
+	if ts != nil {
+		attached = append(attached, static.WithRFC3161Timestamp(ts))
+	}
+
+	// Options are applied in order, so the command line ones take precedence.
+	o := *opts
+	o.SignatureOptions = append(attached, opts.SignatureOptions...)
+	return verifyEnvelopeDigest(ctx, &o, envBytes, h)
+}
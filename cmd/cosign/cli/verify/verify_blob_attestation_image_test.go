@@ -0,0 +1,353 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	v1mutate "github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+)
+
+func TestVerifyBlobAttestationMatchImageConfig(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	s := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(u.Host + "/image:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := random.Index(1024, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idxRef, err := name.ParseReference(u.Host + "/index:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.WriteIndex(idxRef, idx); err != nil {
+		t.Fatal(err)
+	}
+	configDigest, err := img.ConfigName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDigest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := writeTestKey(t, td)
+
+	// attestationOf writes an attestation whose subject is the image digest h.
+	attestationOf := func(h v1.Hash) string {
+		st := testStatement("customFoo", in_toto.Subject{Name: "image", Digest: common.DigestSet{h.Algorithm: h.Hex}})
+		return writeBlobFile(t, td, string(signTestStatementWith(t, key.signer, st)), h.Hex+".json")
+	}
+
+	tests := []struct {
+		description string
+		signature   string
+		image       string
+		shouldErr   bool
+	}{
+		{
+			description: "subject is the config digest",
+			signature:   attestationOf(configDigest),
+			image:       ref.String(),
+		}, {
+			description: "subject is the manifest digest",
+			signature:   attestationOf(manifestDigest),
+			image:       ref.String(),
+			shouldErr:   true,
+		}, {
+			description: "index",
+			signature:   attestationOf(configDigest),
+			image:       idxRef.String(),
+			shouldErr:   true,
+		}, {
+			description: "missing image",
+			signature:   attestationOf(configDigest),
+			image:       u.Host + "/missing:latest",
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+				SignaturePath: test.signature,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationBlobSource: BlobAttestationBlobSource{
+					MatchImageConfig: test.image,
+				},
+			}
+			err := cmd.Exec(ctx, "")
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("Exec()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+		SignaturePath: attestationOf(configDigest),
+		CheckClaims:   true,
+		BlobAttestationBlobSource: BlobAttestationBlobSource{
+			MatchImageConfig: ref.String(),
+		},
+	}
+	if err := cmd.Exec(ctx, writeBlobFile(t, td, blobContents, "blob")); err == nil {
+		t.Error("Exec() with a blob path and --match-image-config expected an error")
+	}
+}
+
+func TestVerifyBlobAttestationFromImage(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	s := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := writeTestKey(t, td)
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	// pushImage pushes a random image to repo with the given statements
+	// attached as attestations.
+	pushImage := func(repo string, statements ...in_toto.Statement) string {
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := name.ParseReference(u.Host + "/" + repo + ":latest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatal(err)
+		}
+		d, err := img.Digest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		digest := ref.Context().Digest(d.String())
+		se := oci.SignedEntity(ociremote.SignedUnknown(digest))
+		for _, st := range statements {
+			att, err := static.NewAttestation(signTestStatementWith(t, key.signer, st))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if se, err = mutate.AttachAttestationToEntity(se, att); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(statements) > 0 {
+			if err := ociremote.WriteAttestations(digest.Repository, se); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return ref.String()
+	}
+
+	tests := []struct {
+		description string
+		image       string
+		shouldErr   bool
+	}{
+		{
+			description: "matching attestation",
+			image:       pushImage("matching", testStatement("customFoo", sha256Subject("blob", blobContents))),
+		}, {
+			description: "matching attestation after another",
+			image: pushImage("second",
+				testStatement("customFoo", sha256Subject("other", anotherBlobContents)),
+				testStatement("customFoo", sha256Subject("blob", blobContents))),
+		}, {
+			description: "other subject",
+			image:       pushImage("other", testStatement("customFoo", sha256Subject("other", anotherBlobContents))),
+			shouldErr:   true,
+		}, {
+			description: "no attestations",
+			image:       pushImage("unattested"),
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+					FromImage: test.image,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("Exec()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+		SignaturePath: writeBlobFile(t, td, "{}", "envelope.json"),
+		CheckClaims:   true,
+		BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+			FromImage: tests[0].image,
+		},
+	}
+	if err := cmd.Exec(ctx, blobPath); err == nil {
+		t.Error("Exec() with --from-image and --signature expected an error")
+	}
+}
+
+func TestVerifyBlobAttestationMatchAnnotationDigest(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	const key = "org.opencontainers.image.base.digest"
+
+	s := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseDigest, err := base.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pushAnnotated pushes a random image with the annotations to repo.
+	pushAnnotated := func(repo string, annotations map[string]string) string {
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := name.ParseReference(u.Host + "/" + repo + ":latest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := remote.Write(ref, v1mutate.Annotations(img, annotations).(v1.Image)); err != nil {
+			t.Fatal(err)
+		}
+		return ref.String()
+	}
+
+	signingKey := writeTestKey(t, td)
+	st := testStatement("customFoo", in_toto.Subject{Name: "base", Digest: common.DigestSet{"sha256": baseDigest.Hex}})
+	sigPath := writeBlobFile(t, td, string(signTestStatementWith(t, signingKey.signer, st)), "attestation.json")
+
+	tests := []struct {
+		description string
+		image       string
+		wantErr     string
+	}{
+		{
+			description: "annotation matches",
+			image:       pushAnnotated("matching", map[string]string{key: baseDigest.String()}),
+		}, {
+			description: "annotation is another digest",
+			image:       pushAnnotated("other", map[string]string{key: "sha256:" + strings.Repeat("0", 64)}),
+			wantErr:     "no matching subject digest found",
+		}, {
+			description: "annotation missing",
+			image:       pushAnnotated("missing", map[string]string{"org.opencontainers.image.base.name": "docker.io/library/alpine"}),
+			wantErr:     "has no annotation",
+		}, {
+			description: "annotation is not a digest",
+			image:       pushAnnotated("invalid", map[string]string{key: "alpine"}),
+			wantErr:     "is not a digest",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: signingKey.keyPath},
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationBlobSource: BlobAttestationBlobSource{
+					MatchAnnotationDigest: key,
+					AnnotationImage:       test.image,
+				},
+			}
+			err := cmd.Exec(ctx, "")
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: signingKey.keyPath},
+		SignaturePath: sigPath,
+		CheckClaims:   true,
+		BlobAttestationBlobSource: BlobAttestationBlobSource{
+			MatchAnnotationDigest: key,
+		},
+	}
+	if err := cmd.Exec(ctx, ""); err == nil {
+		t.Error("Exec() with --match-annotation-digest and no --annotation-image expected an error")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3"
+
+	"github.com/sigstore/cosign/v2/pkg/blob"
+)
+
+// jwtAlgorithms are the JWT signature algorithms accepted, the asymmetric ones
+// a certificate key may verify. none, and the HMAC algorithms, whose key would
+// be the public key of the certificate, are rejected.
+var jwtAlgorithms = []jose.SignatureAlgorithm{
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.EdDSA,
+}
+
+// jwtCertificate is a certificate delivered in the x5c header of a JWT.
+type jwtCertificate struct {
+	cert  *x509.Certificate
+	chain []*x509.Certificate
+	// sct is the detached SCT of the sct claim, if any.
+	sct []byte
+}
+
+// certFromJWT returns the certificate, the rest of the chain and the SCT
+// delivered in the compact JWT at path.
+//
+// The JWT is only a transport: it adds no trust. The certificate is verified
+// like one passed with --certificate, up to the Fulcio roots or the root of
+// --certificate-chain, and the x5c chain only provides the intermediates, never
+// a root. The JWT must be signed with one of jwtAlgorithms, and its signature
+// is checked with the key of the leaf certificate, which catches a token whose
+// x5c was swapped but doesn't authenticate the issuer of the token. Other
+// claims, e.g. exp, are ignored.
+func certFromJWT(path string) (*jwtCertificate, error) {
+	b, err := blob.LoadFileOrURL(path)
+	if err != nil {
+		return nil, err
+	}
+	token := strings.TrimSpace(string(b))
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%s is not a compact JWT", path)
+	}
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("decoding JWT header: %w", err)
+	}
+	header := struct {
+		Alg string   `json:"alg"`
+		X5C []string `json:"x5c"`
+	}{}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("decoding JWT header: %w", err)
+	}
+	if !allowedJWTAlgorithm(header.Alg) {
+		return nil, fmt.Errorf("JWT alg %q is not allowed, expected one of %s", header.Alg, jwtAlgorithmNames())
+	}
+	if len(header.X5C) == 0 {
+		return nil, errors.New("JWT has no x5c header")
+	}
+	var certs []*x509.Certificate
+	for i, c := range header.X5C {
+		// x5c holds standard base64, not base64url, DER certificates.
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x5c certificate %d: %w", i, err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("parsing x5c certificate %d: %w", i, err)
+		}
+		certs = append(certs, cert)
+	}
+	jc := &jwtCertificate{cert: certs[0], chain: certs[1:]}
+
+	jws, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("parsing JWT: %w", err)
+	}
+	if _, err := jws.Verify(jc.cert.PublicKey); err != nil {
+		return nil, fmt.Errorf("validating the JWT signature with the x5c certificate: %w", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding JWT payload: %w", err)
+	}
+	claims := struct {
+		SCT string `json:"sct"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("decoding JWT claims: %w", err)
+	}
+	if claims.SCT != "" {
+		if jc.sct, err = base64.StdEncoding.DecodeString(claims.SCT); err != nil {
+			return nil, fmt.Errorf("decoding the sct claim: %w", err)
+		}
+	}
+	return jc, nil
+}
+
+func allowedJWTAlgorithm(alg string) bool {
+	for _, a := range jwtAlgorithms {
+		if alg == string(a) {
+			return true
+		}
+	}
+	return false
+}
+
+func jwtAlgorithmNames() string {
+	names := make([]string, 0, len(jwtAlgorithms))
+	for _, a := range jwtAlgorithms {
+		names = append(names, string(a))
+	}
+	return strings.Join(names, ", ")
+}
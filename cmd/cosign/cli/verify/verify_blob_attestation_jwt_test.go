@@ -0,0 +1,174 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/test"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+func TestVerifyBlobAttestationCertFromJWT(t *testing.T) {
+	ctx := context.Background()
+	keyless := newKeylessStack(t)
+	identity := "hello@foo.com"
+	issuer := "issuer"
+	leafCert, leafPriv, _, signer := keyless.genLeafCert(t, identity, issuer)
+
+	env := signTestStatementWith(t, signer, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	sigPath := writeBlobFile(t, keyless.td, string(env), "attestation.json")
+	blobPath := writeBlobFile(t, keyless.td, blobContents, "blob")
+	// Only the root is trusted, the intermediate comes from the x5c header.
+	rootPath := writeBlobFile(t, keyless.td, string(keyless.rootPemCert), "root.pem")
+
+	// jwtAlg returns the path of a JWT delivering certs in x5c, signed with
+	// key unless it is nil, in which case its signature is left empty under
+	// the alg header alg.
+	var n int
+	jwtAlg := func(alg string, key crypto.Signer, certs ...*x509.Certificate) string {
+		n++
+		x5c := make([]string, 0, len(certs))
+		for _, c := range certs {
+			x5c = append(x5c, base64.StdEncoding.EncodeToString(c.Raw))
+		}
+		payload := []byte(`{"sub":"signer"}`)
+		var token string
+		if key == nil {
+			header, err := json.Marshal(map[string]interface{}{"alg": alg, "x5c": x5c})
+			if err != nil {
+				t.Fatal(err)
+			}
+			token = base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+		} else {
+			js, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{}).WithHeader("x5c", x5c))
+			if err != nil {
+				t.Fatal(err)
+			}
+			jws, err := js.Sign(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token, err = jws.CompactSerialize(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return writeBlobFile(t, keyless.td, token, fmt.Sprintf("cert-%d.jwt", n))
+	}
+	// jwt returns the path of a JWT delivering certs in x5c, signed with key
+	// with ES256, or unsigned with the alg none if key is nil.
+	jwt := func(key crypto.Signer, certs ...*x509.Certificate) string {
+		return jwtAlg("none", key, certs...)
+	}
+
+	// A chain of another CA, whose root is delivered in x5c but isn't trusted.
+	otherRoot, otherRootPriv, err := test.GenerateRootCa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLeaf, otherLeafPriv, err := test.GenerateLeafCert(identity, issuer, otherRoot, otherRootPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := signature.LoadECDSASignerVerifier(otherLeafPriv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherEnv := signTestStatementWith(t, otherSigner, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	otherSigPath := writeBlobFile(t, keyless.td, string(otherEnv), "other-attestation.json")
+	wrongKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		jwt         string
+		sigPath     string
+		wantErr     string
+	}{
+		{
+			description: "signed JWT",
+			jwt:         jwt(leafPriv, leafCert, keyless.subCert),
+			sigPath:     sigPath,
+		}, {
+			description: "unsigned JWT",
+			jwt:         jwt(nil, leafCert, keyless.subCert),
+			sigPath:     sigPath,
+			wantErr:     `JWT alg "none" is not allowed`,
+		}, {
+			description: "HMAC JWT",
+			jwt:         jwtAlg("HS256", nil, leafCert, keyless.subCert),
+			sigPath:     sigPath,
+			wantErr:     `JWT alg "HS256" is not allowed`,
+		}, {
+			description: "JWT signed by another key",
+			jwt:         jwt(wrongKey, leafCert, keyless.subCert),
+			sigPath:     sigPath,
+			wantErr:     "validating the JWT signature",
+		}, {
+			description: "untrusted x5c root",
+			jwt:         jwt(otherLeafPriv, otherLeaf, otherRoot),
+			sigPath:     otherSigPath,
+			wantErr:     "certificate signed by unknown authority",
+		}, {
+			description: "no x5c",
+			jwt:         jwt(wrongKey),
+			sigPath:     sigPath,
+			wantErr:     "JWT has no x5c header",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				CertVerifyOptions: options.CertVerifyOptions{
+					CertIdentity:   identity,
+					CertOidcIssuer: issuer,
+				},
+				CertChain:     rootPath,
+				SignaturePath: test.sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				IgnoreSCT:     true,
+				BlobAttestationCertificateChecks: BlobAttestationCertificateChecks{
+					CertFromJWT: test.jwt,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/digitorus/timestamp"
+
+	"github.com/sigstore/cosign/v2/internal/pkg/netguard"
+	"github.com/sigstore/cosign/v2/pkg/blob"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	sigs "github.com/sigstore/cosign/v2/pkg/signature"
+)
+
+// KeyHistory maps the time ranges of a key rotation to the key valid in each,
+// e.g.
+//
+//	{
+//	  "keys": [
+//	    {"key": "cosign-2022.pub", "notBefore": "2022-01-01T00:00:00Z", "notAfter": "2023-01-01T00:00:00Z"},
+//	    {"key": "cosign-2023.pub", "notBefore": "2023-01-01T00:00:00Z"}
+//	  ]
+//	}
+type KeyHistory struct {
+	Keys []KeyHistoryEntry `json:"keys"`
+}
+
+// KeyHistoryEntry is a key reference, as passed to --key, and the time range
+// it signs in, from NotBefore included to NotAfter excluded. A missing
+// NotAfter leaves the range open, e.g. for the current key.
+type KeyHistoryEntry struct {
+	Key       string     `json:"key"`
+	NotBefore time.Time  `json:"notBefore"`
+	NotAfter  *time.Time `json:"notAfter,omitempty"`
+}
+
+// LoadKeyHistory reads and validates the key history at path.
+func LoadKeyHistory(path string) (*KeyHistory, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading key history: %w", err)
+	}
+	return parseKeyHistory(path, b)
+}
+
+// parseKeyHistory parses and validates the key history b read from path.
+func parseKeyHistory(path string, b []byte) (*KeyHistory, error) {
+	h := &KeyHistory{}
+	if err := json.Unmarshal(b, h); err != nil {
+		return nil, fmt.Errorf("parsing key history %s: %w", path, err)
+	}
+	if len(h.Keys) == 0 {
+		return nil, fmt.Errorf("key history %s lists no keys", path)
+	}
+	for i, k := range h.Keys {
+		switch {
+		case k.Key == "":
+			return nil, fmt.Errorf("key history %s: entry %d has no key", path, i)
+		case k.NotBefore.IsZero():
+			return nil, fmt.Errorf("key history %s: entry %d has no notBefore", path, i)
+		case k.NotAfter != nil && !k.NotAfter.After(k.NotBefore):
+			return nil, fmt.Errorf("key history %s: entry %d ends before it starts", path, i)
+		}
+	}
+	return h, nil
+}
+
+// covers reports whether t is in the time range of the key, widened by skew
+// on both ends.
+func (k KeyHistoryEntry) covers(t time.Time, skew time.Duration) bool {
+	return !t.Add(skew).Before(k.NotBefore) && (k.NotAfter == nil || t.Add(-skew).Before(*k.NotAfter))
+}
+
+// KeyAt returns the reference of the first key whose time range, widened by
+// skew on both ends, covers all the signing times. Around a key rotation, the
+// ranges of both keys may then cover a signing time, and the first listed is
+// returned.
+func (h *KeyHistory) KeyAt(skew time.Duration, times ...time.Time) (string, error) {
+	for _, k := range h.Keys {
+		covered := true
+		for _, t := range times {
+			covered = covered && k.covers(t, skew)
+		}
+		if covered {
+			return k.Key, nil
+		}
+	}
+	return "", fmt.Errorf("signing time %s maps to no key in the key history", formatTimes(times))
+}
+
+func formatTimes(times []time.Time) string {
+	s := make([]string, 0, len(times))
+	for _, t := range times {
+		s = append(s, t.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(s, " and ")
+}
+
+// keyHistoryKey returns the key of the key history valid at the signing time
+// of the attestation, once the signature of the key history is verified with
+// the root key.
+//
+// The signing time is read from the tlog entry of the bundle and from the
+// RFC3161 timestamp before they are verified. Both are then verified with the
+// selected key, so a forged time fails verification rather than selecting
+// another key. For the same reason, the tlog entry isn't used when the tlog
+// is ignored.
+func (c *VerifyBlobAttestationCommand) keyHistoryKey(ctx context.Context) (string, error) {
+	// The history is read once, so that the bytes parsed are the bytes
+	// verified.
+	b, err := os.ReadFile(filepath.Clean(c.KeyHistory))
+	if err != nil {
+		return "", fmt.Errorf("reading key history: %w", err)
+	}
+	if err := c.verifyKeyHistory(ctx, b); err != nil {
+		return "", fmt.Errorf("verifying the key history signature: %w", err)
+	}
+	h, err := parseKeyHistory(c.KeyHistory, b)
+	if err != nil {
+		return "", err
+	}
+	var times []time.Time
+	if c.BundlePath != "" && !c.IgnoreTlog {
+		b, err := cosign.FetchLocalSignedPayloadFromPath(c.BundlePath)
+		if err != nil {
+			return "", err
+		}
+		if b.Bundle != nil {
+			times = append(times, time.Unix(b.Bundle.Payload.IntegratedTime, 0))
+		}
+	}
+	if c.RFC3161TimestampPath != "" {
+		b, err := blob.LoadFileOrURL(c.RFC3161TimestampPath)
+		if err != nil {
+			return "", err
+		}
+		ts := bundle.RFC3161Timestamp{}
+		if err := json.Unmarshal(b, &ts); err != nil {
+			return "", err
+		}
+		resp, err := timestamp.ParseResponse(ts.SignedRFC3161Timestamp)
+		if err != nil {
+			return "", fmt.Errorf("parsing RFC3161 timestamp: %w", err)
+		}
+		times = append(times, resp.Time)
+	}
+	if len(times) == 0 {
+		return "", errors.New("--key-history requires a signing time, from the tlog entry of a --bundle or an --rfc3161-timestamp")
+	}
+	ref, err := h.KeyAt(c.ClockSkew, times...)
+	if err == nil && netguard.Disabled(ctx) && guardlessKeyRef(ref) {
+		return "", fmt.Errorf("--no-network cannot be combined with the key %s, whose client --no-network doesn't cover", ref)
+	}
+	return ref, err
+}
+
+// verifyKeyHistory verifies the signature of the key history, as written by
+// cosign sign-blob, with the root key. The root key is pinned by the verifier
+// and is the only trust anchor of the keys of the history. As it signs the
+// history offline, no tlog entry is expected.
+func (c *VerifyBlobAttestationCommand) verifyKeyHistory(ctx context.Context, history []byte) error {
+	b, err := blob.LoadFileOrURL(c.KeyHistorySignature)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", c.KeyHistorySignature, err)
+	}
+	v, err := sigs.PublicKeyFromKeyRef(ctx, c.KeyHistoryRootKey)
+	if err != nil {
+		return fmt.Errorf("loading the root key: %w", err)
+	}
+	return v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(history))
+}
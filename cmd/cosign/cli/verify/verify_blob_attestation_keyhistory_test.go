@@ -0,0 +1,188 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/internal/pkg/cosign/tsa/mock"
+	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+func TestVerifyBlobAttestationKeyHistory(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	newKey := func(name string) (signature.Signer, string) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pemBytes, err := cryptoutils.MarshalPublicKeyToPEM(priv.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sv, writeBlobFile(t, td, string(pemBytes), name)
+	}
+	oldSigner, oldKey := newKey("old.pub")
+	_, newKeyPath := newKey("new.pub")
+
+	history, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"key": oldKey, "notBefore": "2022-01-01T00:00:00Z", "notAfter": "2023-01-01T00:00:00Z"},
+			{"key": newKeyPath, "notBefore": "2023-01-01T00:00:00Z"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	historyPath := writeBlobFile(t, td, string(history), "history.json")
+	rootSigner, rootKey := newKey("root.pub")
+	signHistory := func(name string, b []byte) string {
+		sig, err := rootSigner.SignMessage(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeBlobFile(t, td, base64.StdEncoding.EncodeToString(sig), name)
+	}
+	historySig := signHistory("history.json.sig", history)
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	env := signTestStatementWith(t, oldSigner, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	sigPath := writeBlobFile(t, td, string(env), "attestation.json")
+
+	// timestampAt timestamps the envelope at the given time, returning the
+	// timestamp and TSA certificate chain paths.
+	timestampAt := func(name string, at time.Time) (string, string) {
+		tsaClient, err := mock.NewTSAClient(mock.TSAClientOptions{Time: at, Message: env})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tsr, err := tsaClient.GetTimestampResponse(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chainPEM, err := cryptoutils.MarshalCertificatesToPEM(tsaClient.CertChain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeTimestampFile(t, td, &bundle.RFC3161Timestamp{SignedRFC3161Timestamp: tsr}, name+".json"),
+			writeBlobFile(t, td, string(chainPEM), name+"-chain.pem")
+	}
+
+	tests := []struct {
+		description string
+		signedAt    time.Time
+		skew        time.Duration
+		wantErr     string
+	}{
+		{
+			description: "signed under the old key",
+			signedAt:    time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+		}, {
+			description: "signed after the rotation",
+			signedAt:    time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+			wantErr:     "signature",
+		}, {
+			description: "signed before the history",
+			signedAt:    time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			wantErr:     "maps to no key",
+		}, {
+			description: "signed just before the history, within the skew",
+			signedAt:    time.Date(2021, 12, 31, 23, 59, 55, 0, time.UTC),
+			skew:        10 * time.Second,
+		}, {
+			description: "signed just before the history, beyond the skew",
+			signedAt:    time.Date(2021, 12, 31, 23, 59, 45, 0, time.UTC),
+			skew:        10 * time.Second,
+			wantErr:     "maps to no key",
+		}, {
+			description: "signed just after the rotation, within the skew",
+			signedAt:    time.Date(2023, 1, 1, 0, 0, 5, 0, time.UTC),
+			skew:        10 * time.Second,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tsPath, chainPath := timestampAt(strings.ReplaceAll(test.description, " ", "-"), test.signedAt)
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{TSACertChainPath: chainPath, RFC3161TimestampPath: tsPath},
+				ClockSkew:     test.skew,
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+					KeyHistory:          historyPath,
+					KeyHistorySignature: historySig,
+					KeyHistoryRootKey:   rootKey,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+
+	// The signing time is required.
+	cmd := VerifyBlobAttestationCommand{
+		SignaturePath: sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+			KeyHistory:          historyPath,
+			KeyHistorySignature: historySig,
+			KeyHistoryRootKey:   rootKey,
+		},
+	}
+	if err := cmd.Exec(ctx, blobPath); err == nil || !strings.Contains(err.Error(), "requires a signing time") {
+		t.Errorf("Exec() without a signing time = %v, expected an error", err)
+	}
+
+	// The key history must be signed by the root key.
+	cmd.KeyHistorySignature = signHistory("other.sig", []byte(`{"keys": []}`))
+	if err := cmd.Exec(ctx, blobPath); err == nil || !strings.Contains(err.Error(), "verifying the key history signature") {
+		t.Errorf("Exec() with a signature over another key history = %v, expected an error", err)
+	}
+
+	invalid := writeBlobFile(t, td, `{"keys": [{"key": "k.pub", "notBefore": "2023-01-01T00:00:00Z", "notAfter": "2022-01-01T00:00:00Z"}]}`, "invalid.json")
+	if _, err := LoadKeyHistory(invalid); err == nil {
+		t.Error("LoadKeyHistory() of a range ending before it starts expected an error")
+	}
+}
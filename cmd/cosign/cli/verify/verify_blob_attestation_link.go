@@ -0,0 +1,96 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	signatureoptions "github.com/sigstore/sigstore/pkg/signature/options"
+)
+
+// DefaultLinkStepName is the in-toto step name of the link recording a
+// verification, see VerifyBlobAttestationCommand.OutputLink.
+const DefaultLinkStepName = "verify"
+
+// newVerificationLink returns an in-toto link statement recording that
+// command verified the attestation read from inputURI for the blob named
+// resource, of digest h. The statement's subject is the blob, the verified
+// envelope is the only material of the step, and it has no products.
+func newVerificationLink(stepName, resource string, h v1.Hash, verified *VerifiedBlobAttestation, inputURI string, command []string) (*in_toto.LinkStatement, error) {
+	if h.Hex == "" {
+		return nil, errors.New("a blob digest is required to issue a link")
+	}
+	if verified.signature == nil {
+		return nil, errors.New("no verified envelope to record in the link")
+	}
+	envBytes, err := signedAttestation(verified.signature).Payload()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(envBytes)
+
+	return &in_toto.LinkStatement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV01,
+			PredicateType: in_toto.PredicateLinkV1,
+			Subject: []in_toto.Subject{{
+				Name:   resource,
+				Digest: common.DigestSet{h.Algorithm: h.Hex},
+			}},
+		},
+		Predicate: in_toto.Link{
+			Type: "link",
+			Name: stepName,
+			Materials: map[string]interface{}{
+				inputURI: common.DigestSet{"sha256": hex.EncodeToString(digest[:])},
+			},
+			Products:    map[string]interface{}{},
+			ByProducts:  map[string]interface{}{"return-value": 0},
+			Command:     command,
+			Environment: map[string]interface{}{},
+		},
+	}, nil
+}
+
+// saveLink signs the link statement with signer and writes the DSSE envelope
+// to path.
+func saveLink(ctx context.Context, path string, signer signature.Signer, st *in_toto.LinkStatement) error {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	env, err := dsse.WrapSigner(signer, types.IntotoPayloadType).SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("signing link: %w", err)
+	}
+	if err := os.WriteFile(path, env, 0600); err != nil {
+		return fmt.Errorf("create link file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Link written in the file", path)
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestVerifyBlobAttestationOutputLink(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	linkKeys, err := cosign.GenerateKeyPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationOutputs: BlobAttestationOutputs{
+			OutputLink:   filepath.Join(td, "verify.link"),
+			LinkStepName: "verify-provenance",
+		},
+	}
+	cmd.LinkKey = writeBlobFile(t, td, string(linkKeys.PrivateBytes), "link.key")
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+
+	// The link is an attestation about the blob, signed by the link key.
+	verifyLink := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: writeBlobFile(t, td, string(linkKeys.PublicBytes), "link.pub")},
+		SignaturePath: cmd.OutputLink,
+		PredicateType: "link",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+	}
+	if err := verifyLink.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() of the link = %v", err)
+	}
+
+	linkEnv, err := os.ReadFile(cmd.OutputLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := ssldsse.Envelope{}
+	if err := json.Unmarshal(linkEnv, &e); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got in_toto.LinkStatement
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	envDigest := sha256.Sum256(att.env)
+	material, ok := got.Predicate.Materials[cmd.SignaturePath].(map[string]interface{})
+	if got.Predicate.Type != "link" || got.Predicate.Name != "verify-provenance" ||
+		!ok || material["sha256"] != hex.EncodeToString(envDigest[:]) ||
+		len(got.Predicate.Materials) != 1 || len(got.Predicate.Products) != 0 ||
+		len(got.Predicate.Command) == 0 {
+		t.Errorf("unexpected link %s", payload)
+	}
+}
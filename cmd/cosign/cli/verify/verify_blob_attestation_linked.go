@@ -0,0 +1,175 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+
+	"github.com/sigstore/cosign/v2/internal/ui"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// linkedDigestAlgorithms are the digest algorithms of linked documents that
+// can be checked against files.
+var linkedDigestAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// linkedReference is a document referenced by digest from a predicate.
+type linkedReference struct {
+	name   string
+	uri    string
+	digest common.DigestSet
+}
+
+// label returns how the reference is shown to the user.
+func (r linkedReference) label() string {
+	if r.name != "" {
+		return r.name
+	}
+	return r.uri
+}
+
+// linkedReferences returns the documents referenced by digest from the
+// predicate, and whether the predicate type is recognized.
+func linkedReferences(predicateType string, predicate interface{}) ([]linkedReference, bool, error) {
+	b, err := json.Marshal(predicate)
+	if err != nil {
+		return nil, false, err
+	}
+	var refs []linkedReference
+	switch predicateType {
+	case slsa02.PredicateSLSAProvenance:
+		p := slsa02.ProvenancePredicate{}
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, true, err
+		}
+		for _, m := range p.Materials {
+			refs = append(refs, linkedReference{uri: m.URI, digest: m.Digest})
+		}
+	case slsa1.PredicateSLSAProvenance:
+		p := slsa1.ProvenancePredicate{}
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, true, err
+		}
+		for _, d := range p.BuildDefinition.ResolvedDependencies {
+			refs = append(refs, linkedReference{name: d.Name, uri: d.URI, digest: d.Digest})
+		}
+	default:
+		return nil, false, nil
+	}
+	return refs, true, nil
+}
+
+// verifyLinked checks that every file digest referenced by the predicate of
+// sig matches a file in dir. References without a digest in one of
+// linkedDigestAlgorithms, such as git commits, can't be resolved to a file and
+// are skipped with a warning.
+func verifyLinked(ctx context.Context, sig oci.Signature, dir string) error {
+	st, err := statementFromAttestation(sig)
+	if err != nil {
+		return err
+	}
+	refs, recognized, err := linkedReferences(st.PredicateType, st.Predicate)
+	if err != nil {
+		return fmt.Errorf("decoding %s predicate: %w", st.PredicateType, err)
+	}
+	if !recognized {
+		ui.Warnf(ctx, "predicate type %s has no known linked documents, skipping linked document verification", st.PredicateType)
+		return nil
+	}
+
+	files := &linkedFiles{dir: dir, byAlg: map[string]map[string]bool{}}
+	for _, ref := range refs {
+		checked := false
+		matched := false
+		for alg, want := range ref.digest {
+			if _, ok := linkedDigestAlgorithms[alg]; !ok {
+				continue
+			}
+			checked = true
+			digests, err := files.digests(alg)
+			if err != nil {
+				return err
+			}
+			if digests[strings.ToLower(want)] {
+				matched = true
+				break
+			}
+		}
+		switch {
+		case !checked:
+			ui.Warnf(ctx, "skipping linked document %s: no supported file digest", ref.label())
+		case !matched:
+			return fmt.Errorf("linked document %s does not match any file in %s", ref.label(), dir)
+		}
+	}
+	return nil
+}
+
+// linkedFiles lazily computes the digests of the files in dir.
+type linkedFiles struct {
+	dir string
+	// byAlg holds the set of hex digests of the files per algorithm.
+	byAlg map[string]map[string]bool
+}
+
+func (l *linkedFiles) digests(alg string) (map[string]bool, error) {
+	if d, ok := l.byAlg[alg]; ok {
+		return d, nil
+	}
+	d := map[string]bool{}
+	err := filepath.WalkDir(l.dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !e.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := linkedDigestAlgorithms[alg]()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		d[hex.EncodeToString(h.Sum(nil))] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hashing linked documents: %w", err)
+	}
+	l.byAlg[alg] = d
+	return d, nil
+}
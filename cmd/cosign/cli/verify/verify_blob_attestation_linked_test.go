@@ -0,0 +1,88 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestVerifyEnvelopeLinked(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "source.tar.gz"), []byte(blobContents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	present := sha256Subject("source.tar.gz", blobContents).Digest
+	missing := sha256Subject("missing.tar.gz", anotherBlobContents).Digest
+	gitCommit := common.DigestSet{"gitCommit": "cf6a3e8f5d3c4b1ca3b0b4f5c0d2e1f3a4b5c6d7"}
+
+	provenance := func(materials ...common.DigestSet) in_toto.Statement {
+		st := testStatement(slsa02.PredicateSLSAProvenance, sha256Subject("blob", blobContents))
+		p := slsa02.ProvenancePredicate{}
+		for i, m := range materials {
+			p.Materials = append(p.Materials, common.ProvenanceMaterial{URI: fmt.Sprintf("material-%d", i), Digest: m})
+		}
+		st.Predicate = p
+		return st
+	}
+
+	tests := []struct {
+		description string
+		statement   in_toto.Statement
+		shouldErr   bool
+	}{
+		{
+			description: "linked documents present",
+			statement:   provenance(present, gitCommit),
+		}, {
+			description: "linked document missing",
+			statement:   provenance(present, missing),
+			shouldErr:   true,
+		}, {
+			description: "unrecognized predicate type",
+			statement:   testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, test.statement)
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: test.statement.PredicateType,
+				LinkedDir:     dir,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+
+	"github.com/sigstore/cosign/v2/internal/ui"
+)
+
+// Material is an input of a build, as recorded by the materials of a SLSA
+// v0.2 provenance or the resolved dependencies of a SLSA v1 provenance.
+type Material struct {
+	URI    string           `json:"uri,omitempty"`
+	Name   string           `json:"name,omitempty"`
+	Digest common.DigestSet `json:"digest,omitempty"`
+}
+
+// provenanceMaterials returns the build inputs of the verified attestation,
+// and whether its predicate is a SLSA provenance.
+func provenanceMaterials(verified *VerifiedBlobAttestation) ([]Material, bool, error) {
+	st, err := statementFromAttestation(verified.signature)
+	if err != nil {
+		return nil, false, err
+	}
+	refs, recognized, err := linkedReferences(st.PredicateType, st.Predicate)
+	if err != nil {
+		return nil, recognized, fmt.Errorf("decoding %s predicate: %w", st.PredicateType, err)
+	}
+	materials := make([]Material, 0, len(refs))
+	for _, ref := range refs {
+		materials = append(materials, Material{URI: ref.uri, Name: ref.name, Digest: ref.digest})
+	}
+	return materials, recognized, nil
+}
+
+// marshalMaterials returns the build inputs of the verified provenance as a
+// JSON list. Other predicate types have no materials and are skipped with a
+// warning, nil being returned.
+func marshalMaterials(ctx context.Context, verified *VerifiedBlobAttestation) ([]byte, error) {
+	materials, recognized, err := provenanceMaterials(verified)
+	if err != nil {
+		return nil, err
+	}
+	if !recognized {
+		ui.Warnf(ctx, "predicate type %s is not a SLSA provenance, not writing materials", verified.PredicateType)
+		return nil, nil
+	}
+	return json.MarshalIndent(materials, "", "  ")
+}
+
+// saveMaterials writes the materials b returned by marshalMaterials to path,
+// if any.
+func saveMaterials(path string, b []byte) error {
+	if b == nil {
+		return nil
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		return fmt.Errorf("create materials file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Materials written in the file", path)
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestSaveMaterials(t *testing.T) {
+	ctx := context.Background()
+	source := common.DigestSet{"gitCommit": "cf6a3e8f5d3c4b1ca3b0b4f5c0d2e1f3a4b5c6d7"}
+	dep := sha256Subject("dep.tar.gz", anotherBlobContents).Digest
+
+	slsa02Provenance := testStatement(slsa02.PredicateSLSAProvenance, sha256Subject("blob", blobContents))
+	slsa02Provenance.Predicate = slsa02.ProvenancePredicate{
+		Materials: []common.ProvenanceMaterial{
+			{URI: "git+https://github.com/example/repo", Digest: source},
+			{URI: "https://example.com/dep.tar.gz", Digest: dep},
+		},
+	}
+	slsa1Provenance := testStatement(slsa1.PredicateSLSAProvenance, sha256Subject("blob", blobContents))
+	slsa1Provenance.Predicate = slsa1.ProvenancePredicate{
+		BuildDefinition: slsa1.ProvenanceBuildDefinition{
+			ResolvedDependencies: []slsa1.ResourceDescriptor{
+				{URI: "git+https://github.com/example/repo", Digest: source},
+				{Name: "dep", URI: "https://example.com/dep.tar.gz", Digest: dep},
+			},
+		},
+	}
+
+	tests := []struct {
+		description string
+		statement   in_toto.Statement
+		want        []Material
+	}{
+		{
+			description: "slsa v0.2 materials",
+			statement:   slsa02Provenance,
+			want: []Material{
+				{URI: "git+https://github.com/example/repo", Digest: source},
+				{URI: "https://example.com/dep.tar.gz", Digest: dep},
+			},
+		}, {
+			description: "slsa v1 resolved dependencies",
+			statement:   slsa1Provenance,
+			want: []Material{
+				{URI: "git+https://github.com/example/repo", Digest: source},
+				{Name: "dep", URI: "https://example.com/dep.tar.gz", Digest: dep},
+			},
+		}, {
+			description: "not a provenance",
+			statement:   testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, test.statement)
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: test.statement.PredicateType,
+			}
+			verified, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if err != nil {
+				t.Fatalf("verifyEnvelope() = %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "materials.json")
+			materials, err := marshalMaterials(ctx, verified)
+			if err != nil {
+				t.Fatalf("marshalMaterials() = %v", err)
+			}
+			if err := saveMaterials(path, materials); err != nil {
+				t.Fatalf("saveMaterials() = %v", err)
+			}
+			b, err := os.ReadFile(path)
+			if test.want == nil {
+				if !os.IsNotExist(err) {
+					t.Fatalf("expected no materials file, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []Material
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("materials mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
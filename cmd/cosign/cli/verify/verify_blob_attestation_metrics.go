@@ -0,0 +1,74 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics are the Prometheus metrics of blob attestation verifications, see
+// VerifyBlobAttestationCommand.Metrics. A nil *Metrics records nothing.
+type Metrics struct {
+	verifications *prometheus.CounterVec
+	phaseDuration *prometheus.HistogramVec
+}
+
+// NewMetrics registers the metrics of blob attestation verifications with reg:
+//   - cosign_verify_blob_attestation_total, the verifications by predicate
+//     type and result, success or failure,
+//   - cosign_verify_blob_attestation_phase_duration_seconds, the latency of
+//     the load, digest, verify and output phases of a verification.
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		verifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cosign_verify_blob_attestation_total",
+			Help: "Blob attestation verifications, by predicate type and result.",
+		}, []string{"predicate_type", "result"}),
+		phaseDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cosign_verify_blob_attestation_phase_duration_seconds",
+			Help:    "Latency of the phases of blob attestation verifications.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"phase"}),
+	}
+	for _, c := range []prometheus.Collector{m.verifications, m.phaseDuration} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// observeResult counts a verification of predicateType that failed with err,
+// or succeeded if err is nil.
+func (m *Metrics) observeResult(predicateType string, err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.verifications.WithLabelValues(predicateType, result).Inc()
+}
+
+// observePhase records the duration of a verification phase.
+func (m *Metrics) observePhase(phase string, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.phaseDuration.WithLabelValues(phase).Observe(elapsed.Seconds())
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestVerifyBlobAttestationMetrics(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+
+	reg := prometheus.NewRegistry()
+	metrics, err := NewMetrics(reg)
+	if err != nil {
+		t.Fatalf("NewMetrics() = %v", err)
+	}
+	if _, err := NewMetrics(reg); err == nil {
+		t.Error("NewMetrics() registering the metrics twice expected an error")
+	}
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationOutputs: BlobAttestationOutputs{
+			Metrics: metrics,
+		},
+	}
+	if err := cmd.Exec(ctx, writeBlobFile(t, td, blobContents, "blob")); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+	if err := cmd.Exec(ctx, writeBlobFile(t, td, anotherBlobContents, "other")); err == nil {
+		t.Fatal("Exec() of another blob expected an error")
+	}
+
+	if got := testutil.ToFloat64(metrics.verifications.WithLabelValues("customFoo", "success")); got != 1 {
+		t.Errorf("successes = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.verifications.WithLabelValues("customFoo", "failure")); got != 1 {
+		t.Errorf("failures = %v, want 1", got)
+	}
+	// Both verifications went through all phases but the output of the failed
+	// one.
+	if got := testutil.CollectAndCount(metrics.phaseDuration); got != 4 {
+		t.Errorf("phase histograms = %d, want 4", got)
+	}
+	for phase, want := range map[string]uint64{"load": 2, "digest": 2, "verify": 2, "output": 1} {
+		m := &dto.Metric{}
+		if err := metrics.phaseDuration.WithLabelValues(phase).(prometheus.Histogram).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.GetHistogram().GetSampleCount(); got != want {
+			t.Errorf("%s phase samples = %d, want %d", phase, got, want)
+		}
+	}
+
+	// Without metrics nothing is recorded, nor fails.
+	cmd.Metrics = nil
+	if err := cmd.Exec(ctx, writeBlobFile(t, td, blobContents, "blob")); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+}
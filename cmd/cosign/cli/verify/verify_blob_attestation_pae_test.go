@@ -0,0 +1,125 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/types"
+)
+
+func TestVerifyBlobAttestationDSSEPAE(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	// newlinePAE separates the fields with newlines rather than spaces.
+	newlinePAE := func(payloadType string, payload []byte) []byte {
+		return []byte(fmt.Sprintf("DSSEv1\n%d\n%s\n%d\n%s", len(payloadType), payloadType, len(payload), payload))
+	}
+	if _, err := cosign.LookupPAE("test-newline"); err != nil {
+		cosign.RegisterPAE("test-newline", newlinePAE)
+	}
+
+	key := writeTestKey(t, td)
+	payload, err := json.Marshal(testStatement("customFoo", sha256Subject("blob", blobContents)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(pae cosign.PAEFunc, name string) string {
+		sig, err := key.signer.SignMessage(bytes.NewReader(pae(types.IntotoPayloadType, payload)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err := json.Marshal(ssldsse.Envelope{
+			PayloadType: types.IntotoPayloadType,
+			Payload:     base64.StdEncoding.EncodeToString(payload),
+			Signatures:  []ssldsse.Signature{{Sig: base64.StdEncoding.EncodeToString(sig)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeBlobFile(t, td, string(env), name)
+	}
+	standardSig := sign(ssldsse.PAE, "standard.dsse.json")
+	newlineSig := sign(newlinePAE, "newline.dsse.json")
+
+	tests := []struct {
+		description string
+		sigPath     string
+		pae         string
+		wantErr     string
+	}{
+		{
+			description: "standard PAE by default",
+			sigPath:     standardSig,
+		}, {
+			description: "standard PAE",
+			sigPath:     standardSig,
+			pae:         cosign.StandardPAE,
+		}, {
+			description: "registered PAE",
+			sigPath:     newlineSig,
+			pae:         "test-newline",
+		}, {
+			description: "custom PAE signature by default",
+			sigPath:     newlineSig,
+			wantErr:     "no signature of the envelope verifies",
+		}, {
+			description: "standard PAE signature with a custom PAE",
+			sigPath:     standardSig,
+			pae:         "test-newline",
+			wantErr:     "no signature of the envelope verifies over the message of the custom PAE",
+		}, {
+			description: "unknown PAE",
+			sigPath:     standardSig,
+			pae:         "legacy",
+			wantErr:     `--dsse-pae: unknown DSSE PAE "legacy", expected one of standard`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+				SignaturePath: test.sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+					DSSEPAE: test.pae,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v, expected success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+}
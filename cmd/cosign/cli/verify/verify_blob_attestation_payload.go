@@ -0,0 +1,172 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/in-toto/in-toto-golang/in_toto"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/blob"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+// maxStatementSize bounds the size of a decompressed statement.
+const maxStatementSize = 32 << 20
+
+// verifyCompressedStatement verifies a gzip-compressed in-toto statement,
+// PayloadPath, signed with the detached signature SignaturePath.
+//
+// The signature, and tlog entry or timestamp if any, are verified over the
+// compressed bytes first. Only then is the statement decompressed, and its
+// size bounded, so that unverified data is never decompressed.
+func (c *VerifyBlobAttestationCommand) verifyCompressedStatement(ctx context.Context, artifactPath string) error {
+	// The other flags that don't apply to --payload are rejected by
+	// VerifyBlobAttestationOptions.Validate.
+	switch {
+	case c.DSSEPAE != "" && c.DSSEPAE != cosign.StandardPAE:
+		return errors.New("--payload cannot be combined with --dsse-pae, the signature is not a DSSE envelope")
+	case c.ParseStrictness != "" && c.ParseStrictness != ParseStrict:
+		return errors.New("--payload only supports --parse-strictness strict")
+	}
+
+	predicateFields, err := c.predicateFieldRequirements()
+	if err != nil {
+		return err
+	}
+	var versionConstraint *PredicateVersionConstraint
+	if c.PredicateVersionConstraint != "" {
+		var err error
+		if versionConstraint, err = NewPredicateVersionConstraint(c.PredicateType, c.PredicateVersionConstraint); err != nil {
+			return err
+		}
+	}
+
+	sig, err := blob.LoadFileOrURL(c.SignaturePath)
+	if err != nil {
+		return err
+	}
+	rawSig, err := decodeSignature(string(sig))
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", c.SignaturePath, err)
+	}
+	// The signature is passed on in standard base64, whatever its encoding.
+	verifier := c.detachedSignatureCmd(c.KeyRef, base64.StdEncoding.EncodeToString(rawSig))
+	// The bundle and timestamp cover the compressed statement.
+	verifier.BundlePath = c.BundlePath
+	verifier.RFC3161TimestampPath = c.RFC3161TimestampPath
+	if err := verifier.Exec(ctx, c.PayloadPath); err != nil {
+		return fmt.Errorf("verifying the signature of %s: %w", c.PayloadPath, err)
+	}
+
+	st, err := readCompressedStatement(c.PayloadPath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	predicateURI, ok := options.PredicateTypeMap[c.PredicateType]
+	if !ok {
+		predicateURI = c.PredicateType
+	}
+	if err := checkStatementType(st, c.StatementType); err != nil {
+		errs = append(errs, err)
+	}
+	switch {
+	case versionConstraint != nil:
+		if err := versionConstraint.Check(st.PredicateType); err != nil {
+			errs = append(errs, err)
+		}
+	case st.PredicateType != predicateURI:
+		errs = append(errs, fmt.Errorf("invalid predicate type, expected %s got %s", c.PredicateType, st.PredicateType))
+	}
+	if c.CheckClaims {
+		h, err := hashFile(artifactPath, c.HashAlgorithm)
+		if err != nil {
+			return err
+		}
+		re, err := c.subjectNameRegexp()
+		if err != nil {
+			return err
+		}
+		if err := checkSubjects(st, h, &VerifyEnvelopeOptions{
+			AllSubjectsMatch:  c.AllSubjectsMatch,
+			SubjectName:       c.SubjectName,
+			SubjectIndex:      c.SubjectIndex,
+			SubjectNameRegexp: re,
+			DigestEncoding:    c.DigestEncoding,
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(predicateFields) > 0 {
+		predicate, err := json.Marshal(st.Predicate)
+		if err != nil {
+			return err
+		}
+		for _, r := range predicateFields {
+			if err := r.Check(predicate); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		err = &VerificationErrors{Errs: errs}
+	} else {
+		err = c.checkWarnings(ctx)
+	}
+	// --payload can't be combined with --report, so no report is written.
+	return c.finish(ctx, artifactPath, v1.Hash{}, &VerifiedBlobAttestation{PredicateType: st.PredicateType}, err)
+}
+
+// readCompressedStatement decompresses and parses the gzip-compressed in-toto
+// statement at path.
+func readCompressedStatement(path string) (*in_toto.Statement, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s: %w", path, err)
+	}
+	defer zr.Close()
+	b, err := io.ReadAll(io.LimitReader(zr, maxStatementSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s: %w", path, err)
+	}
+	if len(b) > maxStatementSize {
+		return nil, fmt.Errorf("decompressed statement %s exceeds %d bytes", path, maxStatementSize)
+	}
+	if err := checkStatementFields(b); err != nil {
+		return nil, err
+	}
+	st := &in_toto.Statement{}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("parsing in-toto statement: %w", err)
+	}
+	return st, nil
+}
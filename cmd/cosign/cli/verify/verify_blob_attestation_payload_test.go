@@ -0,0 +1,105 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestVerifyBlobAttestationCompressedPayload(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	key := writeTestKey(t, td)
+
+	// signCompressed gzips the statement and signs the compressed bytes.
+	signCompressed := func(name string, st in_toto.Statement) (string, string) {
+		payload, err := json.Marshal(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		if _, err := zw.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sig, err := key.signer.SignMessage(bytes.NewReader(gz.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeBlobFile(t, td, gz.String(), name+".json.gz"), writeBlobFile(t, td, base64.StdEncoding.EncodeToString(sig), name+".sig")
+	}
+
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	payloadPath, sigPath := signCompressed("statement", testStatement("customFoo", sha256Subject("blob", blobContents)))
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+		SignaturePath: sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+			PayloadPath: payloadPath,
+		},
+	}
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+
+	// The subjects and predicate type are checked after decompression.
+	cmd.PredicateType = "spdxjson"
+	if err := cmd.Exec(ctx, blobPath); err == nil || !strings.Contains(err.Error(), "invalid predicate type") {
+		t.Errorf("Exec() = %v, expected a predicate type mismatch", err)
+	}
+	cmd.PredicateType = "customFoo"
+	if err := cmd.Exec(ctx, writeBlobFile(t, td, anotherBlobContents, "other")); err == nil || !strings.Contains(err.Error(), "no matching subject digest found") {
+		t.Errorf("Exec() = %v, expected a subject mismatch", err)
+	}
+
+	// A base64url signature, as emitted by web-oriented signers, verifies.
+	rawSig, err := os.ReadFile(sigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(string(rawSig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.SignaturePath = writeBlobFile(t, td, base64.RawURLEncoding.EncodeToString(sigBytes)+"\n", "statement.sig.b64url")
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Errorf("Exec() with a base64url signature = %v", err)
+	}
+	cmd.SignaturePath = sigPath
+
+	// Unverified data is never decompressed: a payload that isn't gzip fails
+	// on the signature, not on decompression.
+	cmd.PayloadPath = writeBlobFile(t, td, "not gzip", "tampered.json.gz")
+	if err := cmd.Exec(ctx, blobPath); err == nil || !strings.Contains(err.Error(), "verifying the signature") {
+		t.Errorf("Exec() = %v, expected a signature failure", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// PredicateFieldRequirement requires a field of the predicate to have a
+// value, e.g. environment=production.
+type PredicateFieldRequirement struct {
+	path  string
+	value string
+	steps []jsonPathStep
+}
+
+// NewPredicateFieldRequirement parses a path=value requirement. The path is
+// a JSONPath from the predicate, of the subset of --envelope-json-path, such
+// as $.environment; its leading $. may be omitted. The value is compared
+// with a string field as is, and with the compact JSON encoding of any other
+// field, e.g. true or ["a","b"].
+func NewPredicateFieldRequirement(s string) (*PredicateFieldRequirement, error) {
+	// The path may hold an = in a ['name'] step.
+	depth, sep := 0, -1
+	for i := 0; i < len(s) && sep < 0; i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '=':
+			if depth == 0 {
+				sep = i
+			}
+		}
+	}
+	if sep <= 0 {
+		return nil, fmt.Errorf("invalid predicate field requirement %q, expected jsonpath=value", s)
+	}
+	path, value := s[:sep], s[sep+1:]
+	if !strings.HasPrefix(path, "$") && !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		path = "." + path
+	}
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %w", s[:sep], err)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("invalid predicate field requirement %q, the JSONPath selects no field", s)
+	}
+	return &PredicateFieldRequirement{path: s[:sep], value: value, steps: steps}, nil
+}
+
+// Check verifies that the field of the JSON encoded predicate has the
+// required value.
+func (r *PredicateFieldRequirement) Check(predicate []byte) error {
+	v := json.RawMessage(predicate)
+	var err error
+	for _, s := range r.steps {
+		if v, err = s.apply(v); err != nil {
+			return fmt.Errorf("predicate field %s: %w", r.path, err)
+		}
+	}
+	var str string
+	if err := json.Unmarshal(v, &str); err == nil {
+		if str != r.value {
+			return fmt.Errorf("predicate field %s is %q, expected %q", r.path, str, r.value)
+		}
+		return nil
+	}
+	got, want := compactJSON(v), r.value
+	if json.Valid([]byte(want)) {
+		want = compactJSON([]byte(want))
+	}
+	if got != want {
+		return fmt.Errorf("predicate field %s is %s, expected %s", r.path, got, want)
+	}
+	return nil
+}
+
+// compactJSON returns the JSON value b without insignificant whitespace.
+func compactJSON(b []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+// checkPredicateFieldRequirements verifies that the predicate of sig meets
+// all the requirements, returning the failure of each one that doesn't.
+func checkPredicateFieldRequirements(sig oci.Signature, reqs []*PredicateFieldRequirement) []error {
+	stBytes, err := statementPayload(sig)
+	if err != nil {
+		return []error{err}
+	}
+	st := map[string]json.RawMessage{}
+	if err := json.Unmarshal(stBytes, &st); err != nil {
+		return []error{fmt.Errorf("parsing in-toto statement: %w", err)}
+	}
+	predicate, ok := st["predicate"]
+	if !ok {
+		return []error{errors.New("the statement has no predicate to check the required fields of")}
+	}
+	var errs []error
+	for _, r := range reqs {
+		if err := r.Check(predicate); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
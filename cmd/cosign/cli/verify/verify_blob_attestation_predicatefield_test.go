@@ -0,0 +1,166 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestPredicateFieldRequirement(t *testing.T) {
+	predicate := []byte(`{"environment":"production","builder":{"id":"https://ci.example.com"},"reproducible":true,` +
+		`"tags":["a", "b"],"a=b":"c","count":3}`)
+
+	tests := []struct {
+		description string
+		requirement string
+		wantErr     string
+	}{
+		{
+			description: "string field",
+			requirement: "environment=production",
+		}, {
+			description: "root JSONPath",
+			requirement: "$.builder.id=https://ci.example.com",
+		}, {
+			description: "bracket step with an =",
+			requirement: "['a=b']=c",
+		}, {
+			description: "boolean field",
+			requirement: "reproducible=true",
+		}, {
+			description: "number field",
+			requirement: "count=3",
+		}, {
+			description: "array field",
+			requirement: `tags=[ "a","b" ]`,
+		}, {
+			description: "array element",
+			requirement: "tags[1]=b",
+		}, {
+			description: "other value",
+			requirement: "environment=staging",
+			wantErr:     `predicate field environment is "production", expected "staging"`,
+		}, {
+			description: "string of a boolean",
+			requirement: "environment=true",
+			wantErr:     `predicate field environment is "production", expected "true"`,
+		}, {
+			description: "other boolean",
+			requirement: "reproducible=false",
+			wantErr:     "predicate field reproducible is true, expected false",
+		}, {
+			description: "missing field",
+			requirement: "$.builder.version=1",
+			wantErr:     "predicate field $.builder.version: ",
+		}, {
+			description: "no value separator",
+			requirement: "environment",
+			wantErr:     "expected jsonpath=value",
+		}, {
+			description: "no path",
+			requirement: "=production",
+			wantErr:     "expected jsonpath=value",
+		}, {
+			description: "whole predicate",
+			requirement: "$={}",
+			wantErr:     "the JSONPath selects no field",
+		}, {
+			description: "unsupported JSONPath",
+			requirement: "tags[*]=a",
+			wantErr:     "invalid JSONPath",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			r, err := NewPredicateFieldRequirement(test.requirement)
+			if err == nil {
+				err = r.Check(predicate)
+			}
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Check() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationRequirePredicateField(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	st := testStatement("customFoo", sha256Subject("blob", blobContents))
+	st.Predicate = map[string]interface{}{"environment": "production", "approved": true}
+	att := signTestAttestation(t, td, st)
+	keyRef := att.keyPath
+	sigPath := att.sigPath
+
+	tests := []struct {
+		description  string
+		requirements []string
+		wantErrs     []string
+	}{
+		{
+			description:  "all hold",
+			requirements: []string{"environment=production", "$.approved=true"},
+		}, {
+			description:  "one fails",
+			requirements: []string{"environment=production", "approved=false"},
+			wantErrs:     []string{"predicate field approved is true, expected false"},
+		}, {
+			description:  "each failure is reported",
+			requirements: []string{"environment=staging", "approved=false"},
+			wantErrs:     []string{`predicate field environment is "production", expected "staging"`, "predicate field approved is true, expected false"},
+		}, {
+			description:  "invalid requirement",
+			requirements: []string{"environment"},
+			wantErrs:     []string{"parsing --require-predicate-field"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationStatementChecks: BlobAttestationStatementChecks{
+					RequirePredicateFields: test.requirements,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if len(test.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("Exec() = %v, expected success", err)
+				}
+				return
+			}
+			for _, want := range test.wantErrs {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("Exec() = %v, expected %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/types"
+)
+
+// predicateOnlySubject returns the subject supplied out-of-band for an
+// envelope signing only a predicate, of digest <algorithm>:<hex> and
+// optionally named.
+func predicateOnlySubject(digest, name string) (*in_toto.Subject, error) {
+	alg, value, ok := strings.Cut(digest, ":")
+	if !ok || alg == "" {
+		return nil, fmt.Errorf("invalid subject digest %q, expected <algorithm>:<hex>", digest)
+	}
+	if _, err := hex.DecodeString(value); err != nil || value == "" {
+		return nil, fmt.Errorf("invalid subject digest %q, expected a hex encoded digest", digest)
+	}
+	return &in_toto.Subject{Name: name, Digest: common.DigestSet{alg: strings.ToLower(value)}}, nil
+}
+
+// predicateOnlyStatement returns the attestation with the in-toto statement
+// made of the predicate sig signs, of the predicate type, about subject. It
+// must only be called once the signature of sig is verified.
+//
+// The signature only covers the predicate: nothing binds it to the subject,
+// which the caller vouches for.
+func predicateOnlyStatement(sig oci.Signature, subject *in_toto.Subject, predicateType string) (oci.Signature, error) {
+	p, err := sig.Payload()
+	if err != nil {
+		return nil, err
+	}
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(p, &env); err != nil {
+		return nil, err
+	}
+	predicate, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(predicate) {
+		return nil, errors.New("the signed predicate is not JSON")
+	}
+	if uri, ok := options.PredicateTypeMap[predicateType]; ok {
+		predicateType = uri
+	}
+	stBytes, err := json.Marshal(struct {
+		in_toto.StatementHeader
+		Predicate json.RawMessage `json:"predicate"`
+	}{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV01,
+			PredicateType: predicateType,
+			Subject:       []in_toto.Subject{*subject},
+		},
+		Predicate: predicate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	env.PayloadType = types.IntotoPayloadType
+	env.Payload = base64.StdEncoding.EncodeToString(stBytes)
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return &derivedAttestation{signed: sig, payload: payload}, nil
+}
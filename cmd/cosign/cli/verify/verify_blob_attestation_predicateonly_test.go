@@ -0,0 +1,110 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+)
+
+func TestVerifyBlobAttestationPredicateOnlySignature(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	blobDigest := "sha256:" + sha256Subject("", blobContents).Digest["sha256"]
+
+	key := writeTestKey(t, td)
+	signPredicate := func(predicate string) string {
+		env, err := dsse.WrapSigner(key.signer, "application/json").SignMessage(strings.NewReader(predicate))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeBlobFile(t, td, string(env), "predicate.dsse.json")
+	}
+
+	tests := []struct {
+		description   string
+		predicate     string
+		predicateOnly bool
+		subjectDigest string
+		subjectName   string
+		wantErr       string
+	}{
+		{
+			description:   "subject of the blob",
+			predicate:     `{"builder":"ci"}`,
+			predicateOnly: true,
+			subjectDigest: blobDigest,
+		}, {
+			description:   "named subject",
+			predicate:     `{"builder":"ci"}`,
+			predicateOnly: true,
+			subjectDigest: blobDigest,
+			subjectName:   "blob",
+		}, {
+			description:   "subject of another blob",
+			predicate:     `{"builder":"ci"}`,
+			predicateOnly: true,
+			subjectDigest: "sha256:" + strings.Repeat("0", 64),
+			wantErr:       "no matching subject digest found",
+		}, {
+			description: "not a statement without the flag",
+			predicate:   `{"builder":"ci"}`,
+			wantErr:     "invalid payloadType",
+		}, {
+			description:   "invalid subject digest",
+			predicate:     `{"builder":"ci"}`,
+			predicateOnly: true,
+			subjectDigest: "sha256:xyz",
+			wantErr:       "invalid subject digest",
+		}, {
+			description:   "predicate not JSON",
+			predicate:     "builder=ci",
+			predicateOnly: true,
+			subjectDigest: blobDigest,
+			wantErr:       "the signed predicate is not JSON",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+				SignaturePath: signPredicate(test.predicate),
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationStatementChecks: BlobAttestationStatementChecks{
+					PredicateOnlySignature: test.predicateOnly,
+					SubjectDigest:          test.subjectDigest,
+					SubjectName:            test.subjectName,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v, expected success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+// predicateVersionRE matches the version ending a predicate type URI, e.g.
+// v0.2 in https://slsa.dev/provenance/v0.2 or 2.3 in
+// https://spdx.dev/Document/2.3.
+var predicateVersionRE = regexp.MustCompile(`/v?(\d+(?:\.\d+){0,2})$`)
+
+// constraintVersionRE matches a version of a constraint, e.g. 0.2 or v1.
+var constraintVersionRE = regexp.MustCompile(`^v?(\d+(?:\.\d+){0,2})$`)
+
+// splitPredicateType splits a predicate type URI into the URI of the
+// predicate family and its version, in semver form, "" if it has none.
+func splitPredicateType(uri string) (family, version string) {
+	m := predicateVersionRE.FindStringSubmatchIndex(uri)
+	if m == nil {
+		return uri, ""
+	}
+	return uri[:m[0]], "v" + uri[m[2]:m[3]]
+}
+
+// PredicateVersionConstraint accepts the predicate types of a family whose
+// version is in a range, e.g. the SLSA provenance from v0.2 on.
+type PredicateVersionConstraint struct {
+	family     string
+	constraint string
+	clauses    []versionClause
+}
+
+type versionClause struct {
+	op      string
+	version string
+}
+
+// versionOps are the comparison operators of a constraint, the longer ones
+// first so that they are matched before their prefixes.
+var versionOps = []string{">=", "<=", "!=", ">", "<", "="}
+
+// NewPredicateVersionConstraint returns the constraint accepting the
+// predicate types of the family of predicateType, a shorthand or a URI with
+// or without its version, whose version satisfies constraint. The constraint
+// is a comma-separated list of comparisons which must all hold, e.g.
+// ">=0.2, <2", with the operators =, !=, <, <=, > and >=. A version alone
+// must be equal.
+func NewPredicateVersionConstraint(predicateType, constraint string) (*PredicateVersionConstraint, error) {
+	uri, ok := options.PredicateTypeMap[predicateType]
+	if !ok {
+		uri = predicateType
+	}
+	family, _ := splitPredicateType(uri)
+	c := &PredicateVersionConstraint{family: family, constraint: constraint}
+	for _, s := range strings.Split(constraint, ",") {
+		s = strings.TrimSpace(s)
+		clause := versionClause{op: "="}
+		for _, op := range versionOps {
+			if strings.HasPrefix(s, op) {
+				clause.op = op
+				s = strings.TrimSpace(strings.TrimPrefix(s, op))
+				break
+			}
+		}
+		m := constraintVersionRE.FindStringSubmatch(s)
+		if m == nil {
+			return nil, fmt.Errorf("invalid version %q in predicate version constraint %q", s, constraint)
+		}
+		clause.version = "v" + m[1]
+		c.clauses = append(c.clauses, clause)
+	}
+	return c, nil
+}
+
+// Check verifies that predicateType is of the family of the constraint, with
+// a version satisfying it.
+func (c *PredicateVersionConstraint) Check(predicateType string) error {
+	family, version := splitPredicateType(predicateType)
+	if family != c.family {
+		return fmt.Errorf("invalid predicate type, expected a version of %s got %s", c.family, predicateType)
+	}
+	if version == "" {
+		return fmt.Errorf("predicate type %s has no version to check against %q", predicateType, c.constraint)
+	}
+	for _, clause := range c.clauses {
+		if !clause.holds(version) {
+			return fmt.Errorf("predicate type %s does not satisfy the version constraint %q", predicateType, c.constraint)
+		}
+	}
+	return nil
+}
+
+func (c versionClause) holds(version string) bool {
+	cmp := semver.Compare(version, c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
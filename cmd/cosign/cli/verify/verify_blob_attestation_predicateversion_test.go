@@ -0,0 +1,129 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestPredicateVersionConstraint(t *testing.T) {
+	tests := []struct {
+		description   string
+		predicateType string
+		constraint    string
+		got           string
+		wantErr       string
+	}{
+		{
+			description:   "SLSA v0.2 from v0.2 on",
+			predicateType: "slsaprovenance",
+			constraint:    ">=0.2",
+			got:           "https://slsa.dev/provenance/v0.2",
+		}, {
+			description:   "SLSA v1 from v0.2 on",
+			predicateType: "slsaprovenance",
+			constraint:    ">=0.2",
+			got:           "https://slsa.dev/provenance/v1",
+		}, {
+			description:   "SLSA v0.1 from v0.2 on",
+			predicateType: "slsaprovenance",
+			constraint:    ">=0.2",
+			got:           "https://slsa.dev/provenance/v0.1",
+			wantErr:       "does not satisfy the version constraint",
+		}, {
+			description:   "range",
+			predicateType: "https://slsa.dev/provenance/v1",
+			constraint:    ">= v0.2, <1",
+			got:           "https://slsa.dev/provenance/v1",
+			wantErr:       "does not satisfy the version constraint",
+		}, {
+			description:   "SPDX without a version in the shorthand",
+			predicateType: "spdx",
+			constraint:    "2.3",
+			got:           "https://spdx.dev/Document/v2.3",
+		}, {
+			description:   "predicate type without a version",
+			predicateType: "spdx",
+			constraint:    ">=2",
+			got:           "https://spdx.dev/Document",
+			wantErr:       "has no version",
+		}, {
+			description:   "another family",
+			predicateType: "slsaprovenance",
+			constraint:    ">=0.2",
+			got:           "https://in-toto.io/attestation/vulns/v0.1",
+			wantErr:       "expected a version of https://slsa.dev/provenance",
+		}, {
+			description:   "invalid constraint",
+			predicateType: "slsaprovenance",
+			constraint:    ">=0.2,",
+			wantErr:       "invalid version",
+		}, {
+			description:   "pre-release constraint",
+			predicateType: "slsaprovenance",
+			constraint:    "~1.0-rc",
+			wantErr:       "invalid version",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			c, err := NewPredicateVersionConstraint(test.predicateType, test.constraint)
+			if err == nil {
+				err = c.Check(test.got)
+			}
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Check() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationPredicateVersionConstraint(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	att := signTestAttestation(t, td, testStatement("https://slsa.dev/provenance/v1", sha256Subject("blob", blobContents)))
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: att.sigPath,
+		PredicateType: "slsaprovenance",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+	}
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	// slsaprovenance is SLSA v0.2 only.
+	if err := cmd.Exec(ctx, blobPath); err == nil || !strings.Contains(err.Error(), "invalid predicate type") {
+		t.Fatalf("Exec() = %v, expected an invalid predicate type error", err)
+	}
+
+	cmd.PredicateVersionConstraint = ">=0.2"
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+	cmd.PredicateVersionConstraint = "<1"
+	if err := cmd.Exec(ctx, blobPath); err == nil || !strings.Contains(err.Error(), "does not satisfy the version constraint") {
+		t.Fatalf("Exec() = %v, expected a version constraint error", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	slsa01 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.1"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+)
+
+// slsaProvenanceTypes are the predicate types of the SLSA provenance.
+var slsaProvenanceTypes = []string{
+	slsa01.PredicateSLSAProvenance,
+	slsa02.PredicateSLSAProvenance,
+	slsa1.PredicateSLSAProvenance,
+}
+
+// verifyProvenance verifies the SLSA provenance attestation at path, a DSSE
+// envelope, with the key or certificate and the trust material of opts, and
+// that the blob of digest h is one of its subjects, i.e. an output of the
+// build it describes. It returns the predicate type of the provenance.
+func verifyProvenance(ctx context.Context, opts *VerifyEnvelopeOptions, path string, h v1.Hash) (string, error) {
+	envBytes, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	// The statement isn't verified yet, its predicate type is only read to be
+	// required by the verification.
+	att, err := static.NewAttestation(envBytes)
+	if err != nil {
+		return "", err
+	}
+	st, err := statementFromAttestation(att)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(slsaProvenanceTypes, st.PredicateType) {
+		return "", fmt.Errorf("%s is not a SLSA provenance, its predicate type is %s", path, st.PredicateType)
+	}
+
+	verified, err := verifyEnvelopeDigest(ctx, &VerifyEnvelopeOptions{
+		CheckOpts:        opts.CheckOpts,
+		SignatureOptions: opts.SignatureOptions,
+		CheckClaims:      true,
+		PredicateType:    st.PredicateType,
+		DigestEncoding:   opts.DigestEncoding,
+		HashAlgorithm:    opts.HashAlgorithm,
+		StatementType:    opts.StatementType,
+		MaxSignatures:    opts.MaxSignatures,
+	}, envBytes, h)
+	if err != nil {
+		return "", err
+	}
+	return verified.PredicateType, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestVerifyBlobAttestationProvenance(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	key := writeTestKey(t, td)
+	sigPath := writeBlobFile(t, td, string(signTestStatementWith(t, key.signer, testStatement("customFoo", sha256Subject("blob", blobContents)))), "att.dsse.json")
+	otherEnv, _ := signTestStatement(t, testStatement(slsa02.PredicateSLSAProvenance, sha256Subject("blob", blobContents)))
+
+	tests := []struct {
+		description string
+		provenance  []byte
+		checkClaims bool
+		wantErr     string
+	}{
+		{
+			description: "blob built by the provenance",
+			provenance:  signTestStatementWith(t, key.signer, testStatement(slsa02.PredicateSLSAProvenance, sha256Subject("blob", blobContents))),
+			checkClaims: true,
+		}, {
+			description: "SLSA v1 provenance",
+			provenance:  signTestStatementWith(t, key.signer, testStatement(slsa1.PredicateSLSAProvenance, sha256Subject("blob", blobContents))),
+			checkClaims: true,
+		}, {
+			description: "blob not built by the provenance",
+			provenance:  signTestStatementWith(t, key.signer, testStatement(slsa02.PredicateSLSAProvenance, sha256Subject("other", "other contents"))),
+			checkClaims: true,
+			wantErr:     "provenance: ",
+		}, {
+			description: "not a provenance",
+			provenance:  signTestStatementWith(t, key.signer, testStatement("https://example.com/custom", sha256Subject("blob", blobContents))),
+			checkClaims: true,
+			wantErr:     "is not a SLSA provenance",
+		}, {
+			description: "provenance signed by another key",
+			provenance:  otherEnv,
+			checkClaims: true,
+			wantErr:     "provenance: ",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   test.checkClaims,
+				IgnoreTlog:    true,
+				BlobAttestationRelatedChecks: BlobAttestationRelatedChecks{
+					Provenance: writeBlobFile(t, td, string(test.provenance), "provenance.dsse.json"),
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v, expected success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/mmap"
+)
+
+// byteRange is an inclusive range of bytes of a blob, as in the HTTP Range
+// header: 0-1023 are its first KiB.
+type byteRange struct {
+	start, end int64
+}
+
+// parseByteRange parses a range given as <start>-<end>, both inclusive.
+func parseByteRange(s string) (byteRange, error) {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return byteRange{}, fmt.Errorf("invalid byte range %q, expected <start>-<end>", s)
+	}
+	var r byteRange
+	var err error
+	if r.start, err = strconv.ParseInt(start, 10, 64); err != nil || r.start < 0 {
+		return byteRange{}, fmt.Errorf("invalid start of the byte range %q", s)
+	}
+	if r.end, err = strconv.ParseInt(end, 10, 64); err != nil || r.end < r.start {
+		return byteRange{}, fmt.Errorf("invalid end of the byte range %q, expected a position from its start on", s)
+	}
+	return r, nil
+}
+
+func (r byteRange) String() string {
+	return fmt.Sprintf("%d-%d", r.start, r.end)
+}
+
+// subjectNameSuffix is the suffix naming the range in the subject of an
+// attestation over it, e.g. archive.tar#bytes=0-1023.
+func (r byteRange) subjectNameSuffix() string {
+	return "#bytes=" + r.String()
+}
+
+// subjectNameRegexp matches the names of the subjects of the range, see
+// subjectNameSuffix.
+func (r byteRange) subjectNameRegexp() *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(r.subjectNameSuffix()) + "$")
+}
+
+// openByteRange returns the bytes of the range of the file at path, after
+// checking that the file holds them.
+func openByteRange(path string, r byteRange) (io.Reader, func(), error) {
+	f, err := mmap.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	if size := int64(f.Len()); r.end >= size {
+		f.Close()
+		return nil, nil, fmt.Errorf("byte range %s is out of the %d bytes of %s", r, size, path)
+	}
+	return io.NewSectionReader(f, r.start, r.end-r.start+1), func() { f.Close() }, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    byteRange
+		wantErr bool
+	}{
+		{in: "0-1023", want: byteRange{0, 1023}},
+		{in: "5-5", want: byteRange{5, 5}},
+		{in: "1024", wantErr: true},
+		{in: "-1-3", wantErr: true},
+		{in: "10-2", wantErr: true},
+		{in: "a-b", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := parseByteRange(test.in)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseByteRange() = %v, wanted error %t", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("parseByteRange() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationBlobRange(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	const archive = "header" + blobContents + "trailer"
+	blobPath := writeBlobFile(t, td, archive, "archive.tar")
+	start := len("header")
+	blobRange := fmt.Sprintf("%d-%d", start, start+len(blobContents)-1)
+
+	tests := []struct {
+		description string
+		subjectName string
+		blobRange   string
+		subjectFlag string
+		wantErr     string
+	}{
+		{
+			description: "range subject",
+			subjectName: "archive.tar#bytes=" + blobRange,
+			blobRange:   blobRange,
+		}, {
+			description: "subject not named after the range",
+			subjectName: "archive.tar",
+			blobRange:   blobRange,
+			wantErr:     "has a name matching #bytes=",
+		}, {
+			description: "subject named with --subject-name",
+			subjectName: "payload",
+			blobRange:   blobRange,
+			subjectFlag: "payload",
+		}, {
+			description: "other range",
+			subjectName: "archive.tar#bytes=0-3",
+			blobRange:   "0-3",
+			wantErr:     "no matching subject digest found",
+		}, {
+			description: "range out of the file",
+			subjectName: "archive.tar#bytes=" + blobRange,
+			blobRange:   fmt.Sprintf("%d-%d", start, len(archive)),
+			wantErr:     "is out of the",
+		}, {
+			description: "invalid range",
+			blobRange:   "12",
+			wantErr:     "parsing --blob-range",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			att := signTestAttestation(t, td, testStatement("customFoo", in_toto.Subject{Name: test.subjectName, Digest: sha256Subject("", blobContents).Digest}))
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+				SignaturePath: att.sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationStatementChecks: BlobAttestationStatementChecks{
+					SubjectName: test.subjectFlag,
+				},
+				BlobAttestationBlobSource: BlobAttestationBlobSource{
+					BlobRange: test.blobRange,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v, expected success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/sign"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/rekor/pkg/generated/client"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	signatureoptions "github.com/sigstore/sigstore/pkg/signature/options"
+)
+
+// RelayedAttestation describes the attestation re-signed after verification,
+// see VerifyBlobAttestationCommand.RelaySign.
+type RelayedAttestation struct {
+	// UpstreamSigner is the identity that signed the verified attestation.
+	UpstreamSigner string `json:"upstreamSigner"`
+	// Signer is the identity that signed the relayed attestation.
+	Signer string `json:"signer"`
+	// Signature is the path the relayed DSSE envelope was written to.
+	Signature string `json:"signature"`
+	// TlogIndex is the index of the tlog entry of the relayed attestation, if
+	// it was uploaded.
+	TlogIndex *int64 `json:"tlogIndex,omitempty"`
+}
+
+// relaySign signs the statement of the verified attestation again with
+// RelayKeyOpts, keyless unless a key is given, and writes the new DSSE envelope
+// to RelayOutputSignature. keyRef is the key that verified the attestation, if
+// any.
+func (c *VerifyBlobAttestationCommand) relaySign(ctx context.Context, verified *VerifiedBlobAttestation, keyRef string) error {
+	upstream, err := signerIdentity(verified.signature, keyRef)
+	if err != nil {
+		return err
+	}
+	// The statement is relayed as it was signed, not decoded and encoded again,
+	// nor decrypted.
+	payload, err := statementPayload(signedAttestation(verified.signature))
+	if err != nil {
+		return err
+	}
+
+	sv, err := sign.SignerFromKeyOpts(ctx, "", "", c.RelayKeyOpts)
+	if err != nil {
+		return fmt.Errorf("getting relay signer: %w", err)
+	}
+	defer sv.Close()
+	env, err := dsse.WrapSigner(sv, types.IntotoPayloadType).SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("signing relayed attestation: %w", err)
+	}
+	signerBytes, err := sv.Bytes(ctx)
+	if err != nil {
+		return err
+	}
+	relay := &RelayedAttestation{
+		UpstreamSigner: upstream,
+		Signer:         "key " + c.RelayKeyOpts.KeyRef,
+		Signature:      c.RelayOutputSignature,
+	}
+	if sv.Cert != nil {
+		certs, err := cryptoutils.UnmarshalCertificatesFromPEM(sv.Cert)
+		if err != nil || len(certs) == 0 {
+			return fmt.Errorf("decoding relay signing certificate: %w", err)
+		}
+		relay.Signer = certIdentity(certs[0])
+	}
+
+	signedPayload := cosign.LocalSignedPayload{
+		Base64Signature: base64.StdEncoding.EncodeToString(env),
+		Cert:            base64.StdEncoding.EncodeToString(signerBytes),
+	}
+	shouldUpload, err := sign.ShouldUploadToTlog(ctx, c.RelayKeyOpts, nil, c.RelayTlogUpload)
+	if err != nil {
+		return fmt.Errorf("upload to tlog: %w", err)
+	}
+	if shouldUpload {
+		var rekorClient *client.Rekor
+		if hc := c.httpClient(ctx); hc != nil {
+			rekorClient, err = rekor.NewClientWithHTTPClient(c.RelayKeyOpts.RekorURL, hc)
+		} else {
+			rekorClient, err = rekor.NewClient(c.RelayKeyOpts.RekorURL)
+		}
+		if err != nil {
+			return err
+		}
+		entry, err := cosign.TLogUploadDSSEEnvelope(ctx, rekorClient, env, signerBytes)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "tlog entry of the relayed attestation created with index:", *entry.LogIndex)
+		relay.TlogIndex = entry.LogIndex
+		signedPayload.Bundle = cbundle.EntryToBundle(entry)
+	}
+
+	if err := os.WriteFile(c.RelayOutputSignature, env, 0600); err != nil {
+		return fmt.Errorf("create relayed signature file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Relayed attestation written in the file", c.RelayOutputSignature)
+	if c.RelayBundlePath != "" {
+		contents, err := json.Marshal(signedPayload)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(c.RelayBundlePath, contents, 0600); err != nil {
+			return fmt.Errorf("create relayed bundle file: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "Relayed bundle written in the file", c.RelayBundlePath)
+	}
+	verified.Relay = relay
+	return nil
+}
+
+// signerIdentity describes who signed sig: the identity of its certificate,
+// or else the key reference it was verified with.
+func signerIdentity(sig oci.Signature, keyRef string) (string, error) {
+	cert, err := sig.Cert()
+	if err != nil {
+		return "", err
+	}
+	if cert != nil {
+		return certIdentity(cert), nil
+	}
+	return "key " + keyRef, nil
+}
+
+// certIdentity describes a signing certificate by its subject alternative
+// names and, for Fulcio certificates, OIDC issuer.
+func certIdentity(cert *x509.Certificate) string {
+	id := strings.Join(cryptoutils.GetSubjectAlternateNames(cert), ", ")
+	ce := cosign.CertExtensions{Cert: cert}
+	if issuer := ce.GetIssuer(); issuer != "" {
+		id += " (issuer " + issuer + ")"
+	}
+	return id
+}
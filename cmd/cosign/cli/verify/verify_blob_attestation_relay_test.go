@@ -0,0 +1,125 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+)
+
+func TestVerifyBlobAttestationRelaySign(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	upstreamKey := writeBlobFile(t, td, string(att.pubPEM), "upstream.pub")
+	relayKeys, err := cosign.GenerateKeyPair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayKey := writeBlobFile(t, td, string(relayKeys.PrivateBytes), "relay.key")
+	relayPub := writeBlobFile(t, td, string(relayKeys.PublicBytes), "relay.pub")
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: upstreamKey},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationOutputs: BlobAttestationOutputs{
+			RelaySign:            true,
+			RelayKeyOpts:         options.KeyOpts{KeyRef: relayKey},
+			RelayOutputSignature: filepath.Join(td, "relayed.json"),
+			RelayBundlePath:      filepath.Join(td, "relayed.bundle"),
+		},
+	}
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+
+	// The relayed attestation carries the same statement, signed by the relay
+	// key only.
+	relayed, err := os.ReadFile(cmd.RelayOutputSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreamEnv, relayedEnv := ssldsse.Envelope{}, ssldsse.Envelope{}
+	if err := json.Unmarshal(att.env, &upstreamEnv); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(relayed, &relayedEnv); err != nil {
+		t.Fatal(err)
+	}
+	if relayedEnv.Payload != upstreamEnv.Payload {
+		t.Errorf("relayed payload = %s, want %s", relayedEnv.Payload, upstreamEnv.Payload)
+	}
+	verifyRelayed := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: relayPub},
+		SignaturePath: cmd.RelayOutputSignature,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+	}
+	if err := verifyRelayed.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() of the relayed attestation = %v", err)
+	}
+	verifyRelayed.KeyRef = upstreamKey
+	if err := verifyRelayed.Exec(ctx, blobPath); err == nil {
+		t.Fatal("Exec() of the relayed attestation with the upstream key expected an error")
+	}
+
+	bundleBytes, err := os.ReadFile(cmd.RelayBundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedPayload := cosign.LocalSignedPayload{}
+	if err := json.Unmarshal(bundleBytes, &signedPayload); err != nil {
+		t.Fatal(err)
+	}
+	if signedPayload.Cert != base64.StdEncoding.EncodeToString(relayKeys.PublicBytes) {
+		t.Errorf("relayed bundle cert = %s, want the relay public key", signedPayload.Cert)
+	}
+	if signedPayload.Bundle != nil {
+		t.Error("relayed bundle has a tlog entry, expected none with tlog upload disabled")
+	}
+
+	// Both signers are reported.
+	verified := &VerifiedBlobAttestation{}
+	if verified.signature, err = static.NewAttestation(att.env); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.relaySign(ctx, verified, upstreamKey); err != nil {
+		t.Fatalf("relaySign() = %v", err)
+	}
+	want := &RelayedAttestation{
+		UpstreamSigner: "key " + upstreamKey,
+		Signer:         "key " + relayKey,
+		Signature:      cmd.RelayOutputSignature,
+	}
+	if diff := cmp.Diff(want, verified.Relay); diff != "" {
+		t.Errorf("relaySign() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cyberphone/json-canonicalization/go/src/webpki.org/jsoncanonicalizer"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// VerificationReport records the inputs and the outcome of a verification,
+// see VerifyBlobAttestationCommand.Report. It is written canonicalized, so
+// that identical verifications give byte-identical reports.
+type VerificationReport struct {
+	// Time is the time of the verification given with --report-time, in UTC.
+	// It is omitted otherwise, so that the report is reproducible.
+	Time   string       `json:"time,omitempty"`
+	Inputs ReportInputs `json:"inputs"`
+	// Verified is set if all the checks passed, Result then being the
+	// verified attestation. Otherwise Errors are the failed checks.
+	Verified bool                     `json:"verified"`
+	Result   *VerifiedBlobAttestation `json:"result,omitempty"`
+	Errors   []string                 `json:"errors,omitempty"`
+}
+
+// ReportInputs are the inputs of a verification, as given.
+type ReportInputs struct {
+	Blob                        string `json:"blob,omitempty"`
+	BlobDigest                  string `json:"blobDigest,omitempty"`
+	Attestation                 string `json:"attestation"`
+	PredicateType               string `json:"predicateType"`
+	Key                         string `json:"key,omitempty"`
+	Certificate                 string `json:"certificate,omitempty"`
+	CertificateChain            string `json:"certificateChain,omitempty"`
+	CertificateIdentity         string `json:"certificateIdentity,omitempty"`
+	CertificateIdentityRegexp   string `json:"certificateIdentityRegexp,omitempty"`
+	CertificateOIDCIssuer       string `json:"certificateOidcIssuer,omitempty"`
+	CertificateOIDCIssuerRegexp string `json:"certificateOidcIssuerRegexp,omitempty"`
+	CheckClaims                 bool   `json:"checkClaims"`
+	IgnoreTlog                  bool   `json:"ignoreTlog"`
+	IgnoreSCT                   bool   `json:"ignoreSCT"`
+}
+
+// parseReportTime parses the RFC 3339 --report-time, returned in UTC.
+func parseReportTime(s string) (string, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", fmt.Errorf("parsing --report-time, expected an RFC 3339 time such as 2023-10-01T12:00:00Z: %w", err)
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// writeReport writes the report of the verification of the blob at
+// artifactPath, of digest h, to Report. verified is the verified attestation,
+// or err the failure of the verification.
+func (c *VerifyBlobAttestationCommand) writeReport(artifactPath string, h v1.Hash, verified *VerifiedBlobAttestation, err error) error {
+	report := VerificationReport{
+		Inputs: ReportInputs{
+			Blob:                        artifactPath,
+			Attestation:                 c.attestationSource(),
+			PredicateType:               c.PredicateType,
+			Key:                         c.KeyRef,
+			Certificate:                 c.CertRef,
+			CertificateChain:            c.CertChain,
+			CertificateIdentity:         c.CertIdentity,
+			CertificateIdentityRegexp:   c.CertIdentityRegexp,
+			CertificateOIDCIssuer:       c.CertOidcIssuer,
+			CertificateOIDCIssuerRegexp: c.CertOidcIssuerRegexp,
+			CheckClaims:                 c.CheckClaims,
+			IgnoreTlog:                  c.IgnoreTlog,
+			IgnoreSCT:                   c.IgnoreSCT,
+		},
+	}
+	if h.Hex != "" {
+		report.Inputs.BlobDigest = h.String()
+	}
+	if c.ReportTime != "" {
+		var perr error
+		if report.Time, perr = parseReportTime(c.ReportTime); perr != nil {
+			return perr
+		}
+	}
+	if err != nil {
+		report.Errors = verificationErrorMessages(err)
+	} else {
+		report.Verified = true
+		report.Result = verified
+	}
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	// Sort the keys, and drop the insignificant whitespace.
+	if b, err = jsoncanonicalizer.Transform(b); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.Report, b, 0600); err != nil {
+		return fmt.Errorf("create report file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Report written in the file", c.Report)
+	return nil
+}
+
+// attestationSource names where the attestation under verification is read
+// from.
+func (c *VerifyBlobAttestationCommand) attestationSource() string {
+	switch {
+	case c.SignatureArchive != "":
+		return c.SignatureArchive
+	case c.FromImage != "":
+		return c.FromImage
+	case c.SignaturePath != "":
+		return c.SignaturePath
+	}
+	return c.BundlePath
+}
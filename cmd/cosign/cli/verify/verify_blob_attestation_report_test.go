@@ -0,0 +1,143 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestVerifyBlobAttestationReport(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	keyRef := att.keyPath
+	sigPath := att.sigPath
+	blobDigest := "sha256:" + sha256Subject("", blobContents).Digest["sha256"]
+	missingEnvelope := filepath.Join(td, "missing", "envelope.json")
+
+	tests := []struct {
+		description     string
+		predicate       string
+		reportTime      string
+		outputEnvelope  string
+		outputMaterials bool
+		want            string
+		wantErr         string
+	}{
+		{
+			description: "verified",
+			predicate:   "customFoo",
+			want: `{"inputs":{"attestation":"` + sigPath + `","blob":"` + blobPath + `","blobDigest":"` + blobDigest + `","checkClaims":true,` +
+				`"ignoreSCT":false,"ignoreTlog":true,"key":"` + keyRef + `","predicateType":"customFoo"},` +
+				`"result":{"predicateType":"customFoo"},"verified":true}`,
+		}, {
+			description: "with a time",
+			predicate:   "customFoo",
+			reportTime:  "2023-10-01T14:00:00+02:00",
+			want: `{"inputs":{"attestation":"` + sigPath + `","blob":"` + blobPath + `","blobDigest":"` + blobDigest + `","checkClaims":true,` +
+				`"ignoreSCT":false,"ignoreTlog":true,"key":"` + keyRef + `","predicateType":"customFoo"},` +
+				`"result":{"predicateType":"customFoo"},"time":"2023-10-01T12:00:00Z","verified":true}`,
+		}, {
+			description: "failed",
+			predicate:   "slsaprovenance",
+			want: `{"errors":["invalid predicate type, expected slsaprovenance got customFoo"],` +
+				`"inputs":{"attestation":"` + sigPath + `","blob":"` + blobPath + `","blobDigest":"` + blobDigest + `","checkClaims":true,` +
+				`"ignoreSCT":false,"ignoreTlog":true,"key":"` + keyRef + `","predicateType":"slsaprovenance"},"verified":false}`,
+			wantErr: "invalid predicate type",
+		}, {
+			// The report records the final outcome, after the outputs.
+			description:    "failed output",
+			predicate:      "customFoo",
+			outputEnvelope: missingEnvelope,
+			want: `{"errors":["create envelope file: open ` + missingEnvelope + `: no such file or directory"],` +
+				`"inputs":{"attestation":"` + sigPath + `","blob":"` + blobPath + `","blobDigest":"` + blobDigest + `","checkClaims":true,` +
+				`"ignoreSCT":false,"ignoreTlog":true,"key":"` + keyRef + `","predicateType":"customFoo"},"verified":false}`,
+			wantErr: "create envelope file",
+		}, {
+			description:     "warning promoted to an error",
+			predicate:       "customFoo",
+			outputMaterials: true,
+			want: `{"errors":["warning: predicate type customFoo is not a SLSA provenance, not writing materials"],` +
+				`"inputs":{"attestation":"` + sigPath + `","blob":"` + blobPath + `","blobDigest":"` + blobDigest + `","checkClaims":true,` +
+				`"ignoreSCT":false,"ignoreTlog":true,"key":"` + keyRef + `","predicateType":"customFoo"},"verified":false}`,
+			wantErr: "warning: predicate type customFoo",
+		}, {
+			description: "invalid time",
+			predicate:   "customFoo",
+			reportTime:  "yesterday",
+			wantErr:     "parsing --report-time",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			reportPath := filepath.Join(t.TempDir(), "report.json")
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+				SignaturePath: sigPath,
+				PredicateType: test.predicate,
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationOutputs: BlobAttestationOutputs{
+					Report:         reportPath,
+					ReportTime:     test.reportTime,
+					OutputEnvelope: test.outputEnvelope,
+				},
+			}
+			if test.outputMaterials {
+				cmd.OutputMaterials = filepath.Join(t.TempDir(), "materials.json")
+				cmd.FailOnWarnings = true
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Exec() = %v, expected success", err)
+			}
+			if test.want == "" {
+				if _, err := os.Stat(reportPath); !os.IsNotExist(err) {
+					t.Errorf("report written, expected none")
+				}
+				return
+			}
+			got, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("report = %s, expected %s", got, test.want)
+			}
+
+			// The report of the same verification is byte-identical.
+			_ = cmd.Exec(ctx, blobPath)
+			again, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, again) {
+				t.Errorf("report = %s, then %s", got, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// sbomPredicateTypes are the --type shorthands of the SBOM predicates.
+var sbomPredicateTypes = []string{options.PredicateSPDX, options.PredicateSPDXJSON, options.PredicateCycloneDX}
+
+// checkSBOM verifies that the predicate of sig is an SPDX or CycloneDX
+// document listing at least one package or component.
+func checkSBOM(sig oci.Signature) error {
+	st, err := statementFromAttestation(sig)
+	if err != nil {
+		return err
+	}
+	n, err := sbomComponents(st.PredicateType, st.Predicate)
+	if err != nil {
+		return fmt.Errorf("parsing SBOM: %w", err)
+	}
+	if n == 0 {
+		return errors.New("SBOM lists no components")
+	}
+	return nil
+}
+
+// sbomComponents returns the number of packages of an SPDX document, either
+// JSON or tag-value, or of components of a CycloneDX BOM.
+func sbomComponents(predicateType string, predicate interface{}) (int, error) {
+	switch predicateType {
+	case in_toto.PredicateSPDX:
+		if doc, ok := predicate.(string); ok {
+			return spdxTagValuePackages(doc)
+		}
+		doc := struct {
+			SPDXVersion string            `json:"spdxVersion"`
+			Packages    []json.RawMessage `json:"packages"`
+		}{}
+		if err := remarshal(predicate, &doc); err != nil {
+			return 0, err
+		}
+		if doc.SPDXVersion == "" {
+			return 0, errors.New("not an SPDX document, spdxVersion is missing")
+		}
+		return len(doc.Packages), nil
+	case in_toto.PredicateCycloneDX:
+		bom := struct {
+			BOMFormat  string            `json:"bomFormat"`
+			Components []json.RawMessage `json:"components"`
+		}{}
+		if err := remarshal(predicate, &bom); err != nil {
+			return 0, err
+		}
+		if bom.BOMFormat != "CycloneDX" {
+			return 0, fmt.Errorf("not a CycloneDX BOM, bomFormat is %q", bom.BOMFormat)
+		}
+		return len(bom.Components), nil
+	default:
+		return 0, fmt.Errorf("predicate type %s is not an SBOM", predicateType)
+	}
+}
+
+// spdxTagValuePackages counts the packages of an SPDX tag-value document.
+func spdxTagValuePackages(doc string) (int, error) {
+	n := 0
+	versioned := false
+	for _, line := range strings.Split(doc, "\n") {
+		tag, _, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		switch tag {
+		case "SPDXVersion":
+			versioned = true
+		case "PackageName":
+			n++
+		}
+	}
+	if !versioned {
+		return 0, errors.New("not an SPDX document, SPDXVersion is missing")
+	}
+	return n, nil
+}
+
+// remarshal decodes the JSON encoding of v into out.
+func remarshal(v, out interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
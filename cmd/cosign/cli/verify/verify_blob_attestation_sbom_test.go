@@ -0,0 +1,113 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestVerifyEnvelopeRequireSBOM(t *testing.T) {
+	ctx := context.Background()
+
+	sbom := func(predicateType string, predicate interface{}) in_toto.Statement {
+		st := testStatement(predicateType, sha256Subject("blob", blobContents))
+		st.Predicate = predicate
+		return st
+	}
+
+	tests := []struct {
+		description   string
+		statement     in_toto.Statement
+		predicateType string
+		shouldErr     bool
+	}{
+		{
+			description: "spdx json with packages",
+			statement: sbom(in_toto.PredicateSPDX, map[string]interface{}{
+				"spdxVersion": "SPDX-2.3",
+				"packages":    []interface{}{map[string]interface{}{"name": "foo"}},
+			}),
+			predicateType: "spdxjson",
+		}, {
+			description:   "spdx tag-value with packages",
+			statement:     sbom(in_toto.PredicateSPDX, "SPDXVersion: SPDX-2.3\nDataLicense: CC0-1.0\n\nPackageName: foo\nSPDXID: SPDXRef-foo\n"),
+			predicateType: "spdx",
+		}, {
+			description: "cyclonedx with components",
+			statement: sbom(in_toto.PredicateCycloneDX, map[string]interface{}{
+				"bomFormat":  "CycloneDX",
+				"components": []interface{}{map[string]interface{}{"name": "foo"}},
+			}),
+			predicateType: "cyclonedx",
+		}, {
+			description: "spdx json without packages",
+			statement: sbom(in_toto.PredicateSPDX, map[string]interface{}{
+				"spdxVersion": "SPDX-2.3",
+			}),
+			predicateType: "spdxjson",
+			shouldErr:     true,
+		}, {
+			description:   "spdx tag-value without packages",
+			statement:     sbom(in_toto.PredicateSPDX, "SPDXVersion: SPDX-2.3\nDataLicense: CC0-1.0\n"),
+			predicateType: "spdx",
+			shouldErr:     true,
+		}, {
+			description: "cyclonedx with empty components",
+			statement: sbom(in_toto.PredicateCycloneDX, map[string]interface{}{
+				"bomFormat":  "CycloneDX",
+				"components": []interface{}{},
+			}),
+			predicateType: "cyclonedx",
+			shouldErr:     true,
+		}, {
+			description: "not a cyclonedx bom",
+			statement: sbom(in_toto.PredicateCycloneDX, map[string]interface{}{
+				"components": []interface{}{map[string]interface{}{"name": "foo"}},
+			}),
+			predicateType: "cyclonedx",
+			shouldErr:     true,
+		}, {
+			description:   "not an sbom",
+			statement:     testStatement(slsa02.PredicateSLSAProvenance, sha256Subject("blob", blobContents)),
+			predicateType: "slsaprovenance02",
+			shouldErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, test.statement)
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: test.predicateType,
+				RequireSBOM:   true,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
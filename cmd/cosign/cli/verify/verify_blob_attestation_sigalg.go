@@ -0,0 +1,150 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// signatureAlgorithmRE matches the names of the signature algorithms, see
+// signatureAlgorithm.
+var signatureAlgorithmRE = regexp.MustCompile(`^(ed25519|ecdsa-sha(256|384|512)|rsa\d+-(pkcs1v15|pss)-sha(256|384|512))$`)
+
+// signatureHashes are the hash functions a signature may be computed with,
+// by name.
+var signatureHashes = []struct {
+	name string
+	hash crypto.Hash
+}{
+	{"sha256", crypto.SHA256},
+	{"sha384", crypto.SHA384},
+	{"sha512", crypto.SHA512},
+}
+
+// checkSignatureAlgorithmNames verifies that names are all signature
+// algorithms.
+func checkSignatureAlgorithmNames(names []string) error {
+	for _, name := range names {
+		if !signatureAlgorithmRE.MatchString(name) {
+			return fmt.Errorf("unknown signature algorithm %q, expected ed25519, ecdsa-<hash> or rsa<bits>-<pkcs1v15|pss>-<hash>", name)
+		}
+	}
+	return nil
+}
+
+// signatureAlgorithm returns the algorithm sig, a signature over message by
+// the key pub, was computed with: ed25519, ecdsa-<hash>, or
+// rsa<bits>-<pkcs1v15|pss>-<hash> such as rsa2048-pkcs1v15-sha256. Signatures
+// don't record their hash function, so each is tried in turn. It returns
+// false if sig doesn't verify with any of them.
+func signatureAlgorithm(pub crypto.PublicKey, sig, message []byte) (string, bool) {
+	if k, ok := pub.(ed25519.PublicKey); ok {
+		return "ed25519", ed25519.Verify(k, message, sig)
+	}
+	for _, h := range signatureHashes {
+		hasher := h.hash.New()
+		hasher.Write(message)
+		digest := hasher.Sum(nil)
+		switch k := pub.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest, sig) {
+				return "ecdsa-" + h.name, true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, h.hash, digest, sig) == nil {
+				return fmt.Sprintf("rsa%d-pkcs1v15-%s", k.N.BitLen(), h.name), true
+			}
+			if rsa.VerifyPSS(k, h.hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil {
+				return fmt.Sprintf("rsa%d-pss-%s", k.N.BitLen(), h.name), true
+			}
+		}
+	}
+	return "", false
+}
+
+// checkSignatureAlgorithms verifies that the envelope signatures of sig made
+// by its verified key or certificate all use an algorithm in allowed. The
+// signatures of other keys are ignored.
+func checkSignatureAlgorithms(sig oci.Signature, co *cosign.CheckOpts, allowed []string) error {
+	var pub crypto.PublicKey
+	if co.SigVerifier != nil {
+		var err error
+		if pub, err = co.SigVerifier.PublicKey(co.PKOpts...); err != nil {
+			return fmt.Errorf("getting the public key to check the signature algorithm: %w", err)
+		}
+	} else {
+		cert, err := sig.Cert()
+		if err != nil {
+			return err
+		}
+		if cert == nil {
+			return errors.New("no key or certificate to check the signature algorithm with")
+		}
+		pub = cert.PublicKey
+	}
+
+	p, err := sig.Payload()
+	if err != nil {
+		return err
+	}
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(p, &env); err != nil {
+		return err
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return err
+	}
+	pae, err := cosign.LookupPAE(co.DSSEPAE)
+	if err != nil {
+		return err
+	}
+	message := pae(env.PayloadType, payload)
+
+	var found bool
+	for _, s := range env.Signatures {
+		sigBytes, err := decodeSignature(s.Sig)
+		if err != nil {
+			continue
+		}
+		alg, ok := signatureAlgorithm(pub, sigBytes, message)
+		if !ok {
+			continue
+		}
+		found = true
+		if !slices.Contains(allowed, alg) {
+			return fmt.Errorf("signature algorithm %s is not allowed, expected one of %s", alg, strings.Join(allowed, ", "))
+		}
+	}
+	if !found {
+		return errors.New("no signature of the envelope verifies with a known signature algorithm")
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+func TestSignatureAlgorithm(t *testing.T) {
+	message := []byte("DSSEv1 message")
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecSigner, err := signature.LoadECDSASigner(ecPriv, crypto.SHA384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1Signer, err := signature.LoadRSAPKCS1v15Signer(rsaPriv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pssSigner, err := signature.LoadRSAPSSSigner(rsaPriv, crypto.SHA512, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSigner, err := signature.LoadED25519Signer(edPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		signer      signature.Signer
+		pub         crypto.PublicKey
+		want        string
+	}{
+		{"ecdsa", ecSigner, ecPriv.Public(), "ecdsa-sha384"},
+		{"rsa pkcs1v15", pkcs1Signer, rsaPriv.Public(), "rsa2048-pkcs1v15-sha256"},
+		{"rsa pss", pssSigner, rsaPriv.Public(), "rsa2048-pss-sha512"},
+		{"ed25519", edSigner, edPriv.Public(), "ed25519"},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			sig, err := test.signer.SignMessage(bytes.NewReader(message))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := signatureAlgorithm(test.pub, sig, message)
+			if !ok || got != test.want {
+				t.Errorf("signatureAlgorithm() = %q, %v, expected %q", got, ok, test.want)
+			}
+			if err := checkSignatureAlgorithmNames([]string{got}); err != nil {
+				t.Errorf("checkSignatureAlgorithmNames() = %v", err)
+			}
+			if _, ok := signatureAlgorithm(test.pub, sig, []byte("another message")); ok {
+				t.Error("signatureAlgorithm() of a signature over another message expected to fail")
+			}
+		})
+	}
+	if err := checkSignatureAlgorithmNames([]string{"rsa-sha1"}); err == nil {
+		t.Error("checkSignatureAlgorithmNames() of an unknown algorithm expected an error")
+	}
+}
+
+func TestVerifyBlobAttestationAllowedSignatureAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	tests := []struct {
+		description string
+		allowed     []string
+		wantErr     string
+	}{
+		{
+			description: "allowed",
+			allowed:     []string{"ecdsa-sha256", "ed25519"},
+		}, {
+			description: "not allowed",
+			allowed:     []string{"ed25519", "rsa4096-pss-sha256"},
+			wantErr:     "signature algorithm ecdsa-sha256 is not allowed",
+		}, {
+			description: "unknown algorithm",
+			allowed:     []string{"ecdsa-sha1"},
+			wantErr:     "unknown signature algorithm",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+				SignaturePath: att.sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+					AllowedSignatureAlgorithms: test.allowed,
+				},
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
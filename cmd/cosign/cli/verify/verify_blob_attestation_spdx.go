@@ -0,0 +1,249 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+
+	"github.com/sigstore/cosign/v2/internal/ui"
+)
+
+// SPDXGraph is the relationship graph of an SPDX document, normalized so that
+// the same relationships always serialize the same: nodes are sorted by ID,
+// and edges, without duplicates, by source, type and target.
+type SPDXGraph struct {
+	Nodes []SPDXNode         `json:"nodes"`
+	Edges []SPDXRelationship `json:"edges"`
+}
+
+// SPDXNode is an element of an SPDX document, with the name and version of
+// the package or file it describes, if any.
+type SPDXNode struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// SPDXRelationship is an edge of the graph, e.g. From CONTAINS To.
+type SPDXRelationship struct {
+	From string `json:"from"`
+	Type string `json:"type"`
+	To   string `json:"to"`
+}
+
+// spdxGraph returns the relationship graph of the verified attestation, and
+// whether its predicate is an SPDX document.
+func spdxGraph(verified *VerifiedBlobAttestation) (*SPDXGraph, bool, error) {
+	st, err := statementFromAttestation(verified.signature)
+	if err != nil {
+		return nil, false, err
+	}
+	if st.PredicateType != in_toto.PredicateSPDX {
+		return nil, false, nil
+	}
+	var g *SPDXGraph
+	if doc, ok := st.Predicate.(string); ok {
+		g, err = spdxTagValueGraph(doc)
+	} else {
+		g, err = spdxJSONGraph(st.Predicate)
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("parsing SPDX document: %w", err)
+	}
+	return g, true, nil
+}
+
+// spdxJSONGraph returns the relationship graph of an SPDX JSON document. The
+// documentDescribes shorthand of SPDX 2.2 is read as DESCRIBES relationships
+// of the document.
+func spdxJSONGraph(predicate interface{}) (*SPDXGraph, error) {
+	doc := struct {
+		SPDXVersion string `json:"spdxVersion"`
+		SPDXID      string `json:"SPDXID"`
+		Name        string `json:"name"`
+		Packages    []struct {
+			SPDXID      string `json:"SPDXID"`
+			Name        string `json:"name"`
+			VersionInfo string `json:"versionInfo"`
+		} `json:"packages"`
+		Files []struct {
+			SPDXID   string `json:"SPDXID"`
+			FileName string `json:"fileName"`
+		} `json:"files"`
+		DocumentDescribes []string `json:"documentDescribes"`
+		Relationships     []struct {
+			SPDXElementID      string `json:"spdxElementId"`
+			RelationshipType   string `json:"relationshipType"`
+			RelatedSPDXElement string `json:"relatedSpdxElement"`
+		} `json:"relationships"`
+	}{}
+	if err := remarshal(predicate, &doc); err != nil {
+		return nil, err
+	}
+	if doc.SPDXVersion == "" {
+		return nil, errors.New("not an SPDX document, spdxVersion is missing")
+	}
+	b := newSPDXGraphBuilder()
+	b.node(SPDXNode{ID: doc.SPDXID, Name: doc.Name})
+	for _, p := range doc.Packages {
+		b.node(SPDXNode{ID: p.SPDXID, Name: p.Name, Version: p.VersionInfo})
+	}
+	for _, f := range doc.Files {
+		b.node(SPDXNode{ID: f.SPDXID, Name: f.FileName})
+	}
+	for _, id := range doc.DocumentDescribes {
+		b.edge(SPDXRelationship{From: doc.SPDXID, Type: "DESCRIBES", To: id})
+	}
+	for _, r := range doc.Relationships {
+		b.edge(SPDXRelationship{From: r.SPDXElementID, Type: r.RelationshipType, To: r.RelatedSPDXElement})
+	}
+	return b.graph(), nil
+}
+
+// spdxTagValueGraph returns the relationship graph of an SPDX tag-value
+// document. An SPDXID tag identifies the document, or the package or file
+// whose name precedes it.
+func spdxTagValueGraph(doc string) (*SPDXGraph, error) {
+	b := newSPDXGraphBuilder()
+	versioned := false
+	current := SPDXNode{}
+	for _, line := range strings.Split(doc, "\n") {
+		tag, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch tag {
+		case "SPDXVersion":
+			versioned = true
+		case "DocumentName", "PackageName", "FileName":
+			current = SPDXNode{Name: value}
+		case "PackageVersion":
+			current.Version = value
+			b.node(current)
+		case "SPDXID":
+			current.ID = value
+			b.node(current)
+		case "Relationship":
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("invalid relationship %q", value)
+			}
+			b.edge(SPDXRelationship{From: fields[0], Type: fields[1], To: fields[2]})
+		}
+	}
+	if !versioned {
+		return nil, errors.New("not an SPDX document, SPDXVersion is missing")
+	}
+	return b.graph(), nil
+}
+
+// spdxGraphBuilder collects the nodes and edges of an SPDXGraph. Elements
+// only referenced by relationships, e.g. of external documents, are nodes
+// without a name.
+type spdxGraphBuilder struct {
+	nodes map[string]SPDXNode
+	edges map[SPDXRelationship]bool
+}
+
+func newSPDXGraphBuilder() *spdxGraphBuilder {
+	return &spdxGraphBuilder{nodes: map[string]SPDXNode{}, edges: map[SPDXRelationship]bool{}}
+}
+
+// node adds n, merging it with the node of the same ID, if any.
+func (b *spdxGraphBuilder) node(n SPDXNode) {
+	if n.ID == "" {
+		return
+	}
+	prev := b.nodes[n.ID]
+	if n.Name == "" {
+		n.Name = prev.Name
+	}
+	if n.Version == "" {
+		n.Version = prev.Version
+	}
+	b.nodes[n.ID] = n
+}
+
+func (b *spdxGraphBuilder) edge(e SPDXRelationship) {
+	if e.From == "" || e.To == "" || e.Type == "" {
+		return
+	}
+	e.Type = strings.ToUpper(e.Type)
+	b.edges[e] = true
+	b.node(SPDXNode{ID: e.From})
+	b.node(SPDXNode{ID: e.To})
+}
+
+func (b *spdxGraphBuilder) graph() *SPDXGraph {
+	g := &SPDXGraph{
+		Nodes: make([]SPDXNode, 0, len(b.nodes)),
+		Edges: make([]SPDXRelationship, 0, len(b.edges)),
+	}
+	for _, n := range b.nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	for e := range b.edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.To < b.To
+	})
+	return g
+}
+
+// marshalSPDXGraph returns the relationship graph of the verified SPDX
+// document as JSON. Other predicate types are skipped with a warning, nil
+// being returned.
+func marshalSPDXGraph(ctx context.Context, verified *VerifiedBlobAttestation) ([]byte, error) {
+	g, recognized, err := spdxGraph(verified)
+	if err != nil {
+		return nil, err
+	}
+	if !recognized {
+		ui.Warnf(ctx, "predicate type %s is not an SPDX document, not writing the SPDX graph", verified.PredicateType)
+		return nil, nil
+	}
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// saveSPDXGraph writes the graph b returned by marshalSPDXGraph to path, if
+// any.
+func saveSPDXGraph(path string, b []byte) error {
+	if b == nil {
+		return nil
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		return fmt.Errorf("create SPDX graph file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "SPDX graph written in the file", path)
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestSaveSPDXGraph(t *testing.T) {
+	ctx := context.Background()
+
+	jsonDoc := testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents))
+	jsonDoc.Predicate = map[string]interface{}{
+		"spdxVersion":       "SPDX-2.2",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              "blob",
+		"documentDescribes": []string{"SPDXRef-Package-app"},
+		"packages": []map[string]string{
+			{"SPDXID": "SPDXRef-Package-lib", "name": "lib", "versionInfo": "1.2.0"},
+			{"SPDXID": "SPDXRef-Package-app", "name": "app", "versionInfo": "0.1.0"},
+		},
+		"files": []map[string]string{
+			{"SPDXID": "SPDXRef-File-main", "fileName": "./main.go"},
+		},
+		"relationships": []map[string]string{
+			{"spdxElementId": "SPDXRef-Package-app", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-Package-lib"},
+			{"spdxElementId": "SPDXRef-Package-app", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-File-main"},
+			{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Package-app"},
+		},
+	}
+	tagValueDoc := testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents))
+	tagValueDoc.Predicate = strings.Join([]string{
+		"SPDXVersion: SPDX-2.3",
+		"DocumentName: blob",
+		"SPDXID: SPDXRef-DOCUMENT",
+		"Relationship: SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-app",
+		"PackageName: app",
+		"SPDXID: SPDXRef-Package-app",
+		"PackageVersion: 0.1.0",
+		"Relationship: SPDXRef-Package-app CONTAINS SPDXRef-File-main",
+		"Relationship: SPDXRef-Package-app DEPENDS_ON SPDXRef-Package-lib",
+		"PackageName: lib",
+		"SPDXID: SPDXRef-Package-lib",
+		"PackageVersion: 1.2.0",
+		"FileName: ./main.go",
+		"SPDXID: SPDXRef-File-main",
+	}, "\n")
+
+	want := &SPDXGraph{
+		Nodes: []SPDXNode{
+			{ID: "SPDXRef-DOCUMENT", Name: "blob"},
+			{ID: "SPDXRef-File-main", Name: "./main.go"},
+			{ID: "SPDXRef-Package-app", Name: "app", Version: "0.1.0"},
+			{ID: "SPDXRef-Package-lib", Name: "lib", Version: "1.2.0"},
+		},
+		Edges: []SPDXRelationship{
+			{From: "SPDXRef-DOCUMENT", Type: "DESCRIBES", To: "SPDXRef-Package-app"},
+			{From: "SPDXRef-Package-app", Type: "CONTAINS", To: "SPDXRef-File-main"},
+			{From: "SPDXRef-Package-app", Type: "DEPENDS_ON", To: "SPDXRef-Package-lib"},
+		},
+	}
+
+	tests := []struct {
+		description string
+		statement   in_toto.Statement
+		want        *SPDXGraph
+	}{
+		{
+			description: "spdx json",
+			statement:   jsonDoc,
+			want:        want,
+		}, {
+			description: "spdx tag-value",
+			statement:   tagValueDoc,
+			want:        want,
+		}, {
+			description: "not spdx",
+			statement:   testStatement(in_toto.PredicateCycloneDX, sha256Subject("blob", blobContents)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			env, sv := signTestStatement(t, test.statement)
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: test.statement.PredicateType,
+			}
+			verified, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if err != nil {
+				t.Fatalf("verifyEnvelope() = %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "graph.json")
+			graph, err := marshalSPDXGraph(ctx, verified)
+			if err != nil {
+				t.Fatalf("marshalSPDXGraph() = %v", err)
+			}
+			if err := saveSPDXGraph(path, graph); err != nil {
+				t.Fatalf("saveSPDXGraph() = %v", err)
+			}
+			b, err := os.ReadFile(path)
+			if test.want == nil {
+				if !os.IsNotExist(err) {
+					t.Fatalf("expected no graph file, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &SPDXGraph{}
+			if err := json.Unmarshal(b, got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("graph mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// pinnedBlob is a blob file kept open from its hashing to the end of the
+// verification, with the state it was hashed in, so that a change of the
+// file in between is detected, see VerifyBlobAttestationCommand.StableBlob.
+type pinnedBlob struct {
+	path string
+	f    *os.File
+	info os.FileInfo
+}
+
+// pinBlob opens the blob at path and computes its digest from the open file.
+// The file must not change while it is hashed.
+func pinBlob(path, alg string) (*pinnedBlob, v1.Hash, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, v1.Hash{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, v1.Hash{}, err
+	}
+	if !info.Mode().IsRegular() {
+		f.Close()
+		return nil, v1.Hash{}, fmt.Errorf("%s is not a regular file", path)
+	}
+	p := &pinnedBlob{path: path, f: f, info: info}
+	h, err := hashBlob(f, alg)
+	if err == nil {
+		err = p.check()
+	}
+	if err != nil {
+		f.Close()
+		return nil, v1.Hash{}, err
+	}
+	return p, h, nil
+}
+
+// check verifies that the open file, and the file now at its path, are the
+// file as it was opened, with the same size and modification time. A file
+// rewritten in place with its modification time restored isn't detected.
+func (p *pinnedBlob) check() error {
+	info, err := p.f.Stat()
+	if err != nil {
+		return err
+	}
+	if !sameFileState(p.info, info) {
+		return fmt.Errorf("the blob %s was modified during the verification", p.path)
+	}
+	info, err = os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("the blob %s was removed during the verification: %w", p.path, err)
+	}
+	if !os.SameFile(p.info, info) || !sameFileState(p.info, info) {
+		return fmt.Errorf("the blob %s was replaced during the verification", p.path)
+	}
+	return nil
+}
+
+func (p *pinnedBlob) Close() error {
+	return p.f.Close()
+}
+
+// sameFileState reports whether the file a and b describe has the same size
+// and modification time.
+func sameFileState(a, b os.FileInfo) bool {
+	return a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
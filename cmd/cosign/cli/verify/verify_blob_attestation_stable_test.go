@@ -0,0 +1,115 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestPinnedBlob(t *testing.T) {
+	tests := []struct {
+		description string
+		change      func(t *testing.T, path string)
+		wantErr     string
+	}{
+		{
+			description: "unchanged",
+			change:      func(*testing.T, string) {},
+		}, {
+			description: "appended to",
+			change: func(t *testing.T, path string) {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err := f.WriteString("more"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "was modified during the verification",
+		}, {
+			description: "replaced with the same contents",
+			change: func(t *testing.T, path string) {
+				other := writeBlobFile(t, filepath.Dir(path), blobContents, "other")
+				if err := os.Rename(other, path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "was replaced during the verification",
+		}, {
+			description: "removed",
+			change: func(t *testing.T, path string) {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "was removed during the verification",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			path := writeBlobFile(t, t.TempDir(), blobContents, "blob")
+			p, h, err := pinBlob(path, "sha256")
+			if err != nil {
+				t.Fatalf("pinBlob() = %v", err)
+			}
+			defer p.Close()
+			if want := sha256Subject("blob", blobContents).Digest["sha256"]; h.Hex != want {
+				t.Errorf("pinBlob() digest = %s, want %s", h.Hex, want)
+			}
+			test.change(t, path)
+			err = p.check()
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("check() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("check() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationStableBlob(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationBlobSource: BlobAttestationBlobSource{
+			StableBlob: true,
+		},
+	}
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	_ "embed" // To enable the `go:embed` directive.
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// The in-toto statement parser strictness levels, see
+// VerifyEnvelopeOptions.ParseStrictness. The statement _type is checked at
+// every level.
+const (
+	// ParseStrict decodes the statement as an in-toto statement, and rejects
+	// unknown top-level fields.
+	ParseStrict = "strict"
+	// ParseLenient decodes the statement like ParseStrict, but ignores
+	// unknown fields.
+	ParseLenient = "lenient"
+	// ParseSchema validates the statement against the embedded in-toto
+	// statement schema before decoding it like ParseStrict. The schema
+	// requires a non-empty subject list whose digests are hex encoded, and an
+	// object predicate.
+	ParseSchema = "schema"
+)
+
+// statementFields are the top-level fields of an in-toto statement, decoded
+// as is so that only unknown top-level fields are rejected: subjects may carry
+// the fields of a resource descriptor.
+type statementFields struct {
+	Type          json.RawMessage `json:"_type"`
+	PredicateType json.RawMessage `json:"predicateType"`
+	Subject       json.RawMessage `json:"subject"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// checkStatementFields fails if the encoded statement has unknown top-level
+// fields.
+func checkStatementFields(stBytes []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(stBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&statementFields{}); err != nil {
+		return fmt.Errorf("parsing in-toto statement: %w", err)
+	}
+	return nil
+}
+
+// ParseStrictnessLevels are the supported parser strictness levels.
+var ParseStrictnessLevels = []string{ParseStrict, ParseLenient, ParseSchema}
+
+//go:embed schemas/statement.json
+var statementSchemaJSON []byte
+
+var (
+	statementSchemaOnce sync.Once
+	statementSchema     *spec.Schema
+	statementSchemaErr  error
+)
+
+// validateStatementSchema validates the encoded statement against the
+// embedded in-toto statement schema.
+func validateStatementSchema(stBytes []byte) error {
+	statementSchemaOnce.Do(func() {
+		statementSchema = new(spec.Schema)
+		statementSchemaErr = json.Unmarshal(statementSchemaJSON, statementSchema)
+	})
+	if statementSchemaErr != nil {
+		return fmt.Errorf("loading the in-toto statement schema: %w", statementSchemaErr)
+	}
+	var st interface{}
+	if err := json.Unmarshal(stBytes, &st); err != nil {
+		return fmt.Errorf("parsing in-toto statement: %w", err)
+	}
+	if err := validate.AgainstSchema(statementSchema, st, strfmt.Default); err != nil {
+		return fmt.Errorf("in-toto statement does not match the schema: %w", err)
+	}
+	return nil
+}
+
+// checkStatement parses the in-toto statement of sig with the strictness of
+// opts.ParseStrictness, ParseStrict if empty, and checks its _type.
+func checkStatement(sig oci.Signature, opts *VerifyEnvelopeOptions) error {
+	stBytes, err := statementPayload(sig)
+	if err != nil {
+		return fmt.Errorf("parsing in-toto statement: %w", err)
+	}
+	if opts.ParseStrictness == ParseSchema {
+		if err := validateStatementSchema(stBytes); err != nil {
+			return err
+		}
+	}
+	if opts.ParseStrictness != ParseLenient {
+		if err := checkStatementFields(stBytes); err != nil {
+			return err
+		}
+	}
+	st := &in_toto.Statement{}
+	if err := json.Unmarshal(stBytes, st); err != nil {
+		return fmt.Errorf("parsing in-toto statement: %w", err)
+	}
+	return checkStatementType(st, opts.StatementType)
+}
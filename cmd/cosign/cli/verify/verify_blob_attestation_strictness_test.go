@@ -0,0 +1,219 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestVerifyEnvelopeParseStrictness(t *testing.T) {
+	ctx := context.Background()
+	subject := sha256Subject("blob", blobContents)
+
+	statement := func(modify func(map[string]interface{})) map[string]interface{} {
+		st := map[string]interface{}{
+			"_type":         in_toto.StatementInTotoV01,
+			"predicateType": "customFoo",
+			"subject":       []interface{}{map[string]interface{}{"name": subject.Name, "digest": subject.Digest}},
+			"predicate":     map[string]interface{}{},
+		}
+		if modify != nil {
+			modify(st)
+		}
+		return st
+	}
+
+	tests := []struct {
+		description string
+		statement   map[string]interface{}
+		// wantErr is the expected error of each level, "" for none.
+		wantErr map[string]string
+	}{
+		{
+			description: "standard statement",
+			statement:   statement(nil),
+			wantErr:     map[string]string{},
+		}, {
+			description: "vendor statement type",
+			statement: statement(func(st map[string]interface{}) {
+				st["_type"] = "https://in-toto.io/Statement/v0.1+example"
+			}),
+			wantErr: map[string]string{
+				ParseStrict:  "invalid statement type",
+				ParseLenient: "invalid statement type",
+				ParseSchema:  "invalid statement type",
+			},
+		}, {
+			description: "unknown top-level field",
+			statement: statement(func(st map[string]interface{}) {
+				st["comment"] = "unsigned"
+			}),
+			wantErr: map[string]string{
+				ParseStrict: `unknown field "comment"`,
+				ParseSchema: "does not match the schema",
+			},
+		}, {
+			description: "resource descriptor subject",
+			statement: statement(func(st map[string]interface{}) {
+				st["subject"] = []interface{}{map[string]interface{}{
+					"name":   subject.Name,
+					"uri":    "https://example.com/blob",
+					"digest": subject.Digest,
+				}}
+			}),
+			wantErr: map[string]string{},
+		}, {
+			description: "non-hex digest",
+			statement: statement(func(st map[string]interface{}) {
+				st["subject"] = []interface{}{map[string]interface{}{
+					"name":   subject.Name,
+					"digest": map[string]interface{}{"sha256": subject.Digest["sha256"], "blake3": "not-hex"},
+				}}
+			}),
+			wantErr: map[string]string{
+				ParseSchema: "does not match the schema",
+			},
+		}, {
+			description: "empty subject",
+			statement: statement(func(st map[string]interface{}) {
+				st["subject"] = []interface{}{}
+			}),
+			wantErr: map[string]string{
+				ParseStrict:  "no matching subject digest found",
+				ParseLenient: "no matching subject digest found",
+				ParseSchema:  "does not match the schema",
+			},
+		}, {
+			description: "missing digest",
+			statement: statement(func(st map[string]interface{}) {
+				st["subject"] = []interface{}{map[string]interface{}{"name": "blob", "digest": map[string]interface{}{}}}
+			}),
+			wantErr: map[string]string{
+				ParseStrict:  "no matching subject digest found",
+				ParseLenient: "no matching subject digest found",
+				ParseSchema:  "does not match the schema",
+			},
+		}, {
+			description: "non-object predicate",
+			statement: statement(func(st map[string]interface{}) {
+				st["predicate"] = "text"
+			}),
+			wantErr: map[string]string{
+				ParseSchema: "does not match the schema",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		env, sv := signTestStatement(t, test.statement)
+		for _, level := range ParseStrictnessLevels {
+			t.Run(test.description+"/"+level, func(t *testing.T) {
+				opts := &VerifyEnvelopeOptions{
+					CheckOpts: &cosign.CheckOpts{
+						SigVerifier: sv,
+						IgnoreTlog:  true,
+					},
+					CheckClaims:     true,
+					PredicateType:   "customFoo",
+					ParseStrictness: level,
+				}
+				_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+				wantErr := test.wantErr[level]
+				if wantErr == "" {
+					if err != nil {
+						t.Fatalf("verifyEnvelope() = %v, expected success", err)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), wantErr) {
+					t.Fatalf("verifyEnvelope() = %v, expected %q", err, wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyBlobAttestationParseStrictness(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	decodedSig, err := base64.StdEncoding.DecodeString(dssePredicateEmptySubject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: writeBlobFile(t, td, pubkey, "cosign.pub")},
+		SignaturePath: writeBlobFile(t, td, string(decodedSig), "attestation.json"),
+		PredicateType: "slsaprovenance",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationStatementChecks: BlobAttestationStatementChecks{
+			ParseStrictness: "pedantic",
+		},
+	}
+	err = cmd.Exec(ctx, blobPath)
+	if err == nil || !strings.Contains(err.Error(), "unsupported --parse-strictness") {
+		t.Fatalf("Exec() = %v, expected an unsupported level", err)
+	}
+
+	tests := []struct {
+		description string
+		signature   string
+		// wantErr is the expected error of each level.
+		wantErr map[string]string
+	}{
+		{
+			description: "empty subject",
+			signature:   dssePredicateEmptySubject,
+			wantErr: map[string]string{
+				ParseStrict:  "no matching subject digest found",
+				ParseLenient: "no matching subject digest found",
+				ParseSchema:  "does not match the schema",
+			},
+		}, {
+			description: "missing sha256 digest",
+			signature:   dssePredicateMissingSha256,
+			wantErr: map[string]string{
+				ParseStrict:  "no matching subject digest found",
+				ParseLenient: "no matching subject digest found",
+				ParseSchema:  "does not match the schema",
+			},
+		},
+	}
+	for _, test := range tests {
+		decodedSig, err := base64.StdEncoding.DecodeString(test.signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd.SignaturePath = writeBlobFile(t, td, string(decodedSig), "attestation.json")
+		for _, level := range ParseStrictnessLevels {
+			t.Run(test.description+"/"+level, func(t *testing.T) {
+				cmd.ParseStrictness = level
+				err := cmd.Exec(ctx, blobPath)
+				if err == nil || !strings.Contains(err.Error(), test.wantErr[level]) {
+					t.Fatalf("Exec() = %v, expected %q", err, test.wantErr[level])
+				}
+			})
+		}
+	}
+}
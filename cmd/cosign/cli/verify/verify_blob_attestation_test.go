@@ -15,12 +15,55 @@
 package verify
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-jose/go-jose/v3"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	"github.com/klauspost/compress/zstd"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/internal/pkg/netguard"
+	"github.com/sigstore/cosign/v2/internal/ui"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/jwkskey"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/cosign/v2/test"
+	"github.com/sigstore/rekor/pkg/generated/client"
+	rekor_dsse "github.com/sigstore/rekor/pkg/types/dsse"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/sha3"
 )
 
 const pubkey = `-----BEGIN PUBLIC KEY-----
@@ -179,3 +222,1518 @@ func TestVerifyBlobAttestationNoCheckClaims(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyParsedEnvelope(t *testing.T) {
+	ctx := context.Background()
+
+	verifier, err := cryptoutils.UnmarshalPEMToPublicKey([]byte(pubkey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadVerifier(verifier, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description   string
+		blob          io.Reader
+		signature     string
+		predicateType string
+		checkClaims   bool
+		shouldErr     bool
+	}{
+		{
+			description:   "verify a slsaprovenance predicate",
+			blob:          strings.NewReader(blobContents),
+			signature:     blobSLSAProvenanceSignature,
+			predicateType: "slsaprovenance",
+			checkClaims:   true,
+		}, {
+			description:   "fail with incorrect blob",
+			blob:          strings.NewReader(anotherBlobContents),
+			signature:     blobSLSAProvenanceSignature,
+			predicateType: "slsaprovenance",
+			checkClaims:   true,
+			shouldErr:     true,
+		}, {
+			description:   "fail with incorrect predicate",
+			blob:          strings.NewReader(blobContents),
+			signature:     blobSLSAProvenanceSignature,
+			predicateType: "custom",
+			checkClaims:   true,
+			shouldErr:     true,
+		}, {
+			description:   "fail checking claims without a blob",
+			signature:     blobSLSAProvenanceSignature,
+			predicateType: "slsaprovenance",
+			checkClaims:   true,
+			shouldErr:     true,
+		}, {
+			description:   "no blob needed without checking claims",
+			signature:     blobSLSAProvenanceSignature,
+			predicateType: "slsaprovenance",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			decodedSig, err := base64.StdEncoding.DecodeString(test.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env := &ssldsse.Envelope{}
+			if err := json.Unmarshal(decodedSig, env); err != nil {
+				t.Fatal(err)
+			}
+
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   test.checkClaims,
+				PredicateType: test.predicateType,
+			}
+			err = VerifyParsedEnvelope(ctx, opts, env, test.blob)
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("VerifyParsedEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestDecodeSPKIPins(t *testing.T) {
+	digest := sha256.Sum256([]byte(blobContents))
+	tests := []struct {
+		description string
+		pins        []string
+		shouldErr   bool
+	}{
+		{
+			description: "no pins",
+		}, {
+			description: "valid pin",
+			pins:        []string{base64.StdEncoding.EncodeToString(digest[:])},
+		}, {
+			description: "not base64",
+			pins:        []string{"not base64!"},
+			shouldErr:   true,
+		}, {
+			description: "not a sha256 digest",
+			pins:        []string{base64.StdEncoding.EncodeToString(digest[:16])},
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			pins, err := decodeSPKIPins(test.pins)
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("decodeSPKIPins()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+			if err == nil && len(pins) != len(test.pins) {
+				t.Fatalf("expected %d pins, got %d", len(test.pins), len(pins))
+			}
+		})
+	}
+}
+
+func TestUnwrapPEMEnvelope(t *testing.T) {
+	env := []byte(`{"payloadType":"application/vnd.in-toto+json","payload":"","signatures":[]}`)
+	tests := []struct {
+		description string
+		input       []byte
+		shouldErr   bool
+	}{
+		{
+			description: "raw JSON",
+			input:       env,
+		}, {
+			description: "PEM wrapped",
+			input:       pem.EncodeToMemory(&pem.Block{Type: "DSSE ENVELOPE", Bytes: env}),
+		}, {
+			description: "unexpected block type",
+			input:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: env}),
+			shouldErr:   true,
+		}, {
+			description: "invalid PEM",
+			input:       []byte("-----BEGIN DSSE ENVELOPE-----\nnot base64"),
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := unwrapPEMEnvelope(test.input)
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("unwrapPEMEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+			if err == nil && !bytes.Equal(got, env) {
+				t.Fatalf("unwrapPEMEnvelope()= %s, expected %s", got, env)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeMatchedPredicateType(t *testing.T) {
+	ctx := context.Background()
+
+	verifier, err := cryptoutils.UnmarshalPEMToPublicKey([]byte(pubkey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadVerifier(verifier, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envBytes, err := base64.StdEncoding.DecodeString(blobSLSAProvenanceSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, predicateType := range []string{"slsaprovenance", "slsaprovenance02", "https://slsa.dev/provenance/v0.2"} {
+		t.Run(predicateType, func(t *testing.T) {
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:   true,
+				PredicateType: predicateType,
+			}
+			verified, err := verifyEnvelope(ctx, opts, envBytes, strings.NewReader(blobContents))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verified.PredicateType != "https://slsa.dev/provenance/v0.2" {
+				t.Fatalf("expected the resolved predicate type URI, got %s", verified.PredicateType)
+			}
+		})
+	}
+}
+
+// signTestStatement signs the statement with a fresh key and returns the DSSE
+// envelope along with a verifier for it.
+func signTestStatement(t *testing.T, st interface{}) ([]byte, signature.Verifier) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signTestStatementWith(t, sv, st), sv
+}
+
+// signTestStatementWith signs the statement with sv and returns the DSSE
+// envelope.
+func signTestStatementWith(t *testing.T, sv signature.Signer, st interface{}) []byte {
+	t.Helper()
+	payload, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := dsse.WrapSigner(sv, types.IntotoPayloadType).SignMessage(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+// testStatement returns a statement with the given subjects and predicate type.
+func testStatement(predicateType string, subjects ...in_toto.Subject) in_toto.Statement {
+	return in_toto.Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV01,
+			PredicateType: predicateType,
+			Subject:       subjects,
+		},
+		Predicate: map[string]interface{}{},
+	}
+}
+
+func sha256Subject(name, contents string) in_toto.Subject {
+	h := sha256.Sum256([]byte(contents))
+	return in_toto.Subject{Name: name, Digest: common.DigestSet{"sha256": hex.EncodeToString(h[:])}}
+}
+
+// testKey is a fresh signing key, whose public key is written to keyPath.
+type testKey struct {
+	signer  signature.SignerVerifier
+	pubPEM  []byte
+	keyPath string
+}
+
+// writeTestKey generates a key and writes its public key to dir.
+func writeTestKey(t *testing.T, dir string) testKey {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, err := cryptoutils.MarshalPublicKeyToPEM(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{signer: sv, pubPEM: pubPEM, keyPath: writeBlobFile(t, dir, string(pubPEM), "cosign.pub")}
+}
+
+// testAttestation is an attestation signed with a fresh key, written to
+// sigPath.
+type testAttestation struct {
+	testKey
+	env     []byte
+	sigPath string
+}
+
+// signTestAttestation signs the statement with a fresh key, and writes the
+// envelope and the public key to dir.
+func signTestAttestation(t *testing.T, dir string, st interface{}) *testAttestation {
+	t.Helper()
+	key := writeTestKey(t, dir)
+	env := signTestStatementWith(t, key.signer, st)
+	return &testAttestation{
+		testKey: key,
+		env:     env,
+		sigPath: writeBlobFile(t, dir, string(env), "attestation.json"),
+	}
+}
+
+func TestVerifyEnvelopeAggregatesErrors(t *testing.T) {
+	ctx := context.Background()
+
+	env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, sha256Subject("other", anotherBlobContents)))
+	opts := &VerifyEnvelopeOptions{
+		CheckOpts: &cosign.CheckOpts{
+			SigVerifier: sv,
+			IgnoreTlog:  true,
+		},
+		CheckClaims:   true,
+		PredicateType: "cyclonedx",
+	}
+	_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+	var verr *VerificationErrors
+	if !errors.As(err, &verr) {
+		t.Fatalf("verifyEnvelope() = %v, expected *VerificationErrors", err)
+	}
+	if len(verr.Unwrap()) != 2 {
+		t.Fatalf("expected a claim and a predicate type failure, got %v", verr.Unwrap())
+	}
+	if lines := strings.Split(err.Error(), "\n"); len(lines) != 2 {
+		t.Errorf("expected one failure per line, got %q", err.Error())
+	}
+}
+
+func TestVerifyBlobAttestationBlobSignature(t *testing.T) {
+	td := t.TempDir()
+
+	key := writeTestKey(t, td)
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	env := signTestStatementWith(t, key.signer, testStatement(in_toto.PredicateSPDX, sha256Subject("blob", blobContents)))
+	sigRef := writeBlobFile(t, td, string(env), "attestation.intoto")
+
+	sig, err := key.signer.SignMessage(strings.NewReader(blobContents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobSigRef := writeBlobFile(t, td, base64.StdEncoding.EncodeToString(sig), "blob.sig")
+	otherSig, err := key.signer.SignMessage(strings.NewReader(anotherBlobContents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	badBlobSigRef := writeBlobFile(t, td, base64.StdEncoding.EncodeToString(otherSig), "other.sig")
+
+	tests := []struct {
+		description   string
+		keyRef        string
+		blobSignature string
+		predicateType string
+		shouldErr     bool
+		// wantPassed are the checks reported to have passed.
+		wantPassed []string
+	}{
+		{
+			description:   "attestation and blob signature verify",
+			keyRef:        key.keyPath,
+			blobSignature: blobSigRef,
+			wantPassed:    []string{"Attestation", "Blob signature"},
+		}, {
+			description:   "blob signature doesn't verify",
+			keyRef:        key.keyPath,
+			blobSignature: badBlobSigRef,
+			shouldErr:     true,
+			wantPassed:    []string{"Attestation"},
+		}, {
+			description:   "attestation doesn't verify",
+			keyRef:        key.keyPath,
+			blobSignature: blobSigRef,
+			predicateType: "slsaprovenance",
+			shouldErr:     true,
+			wantPassed:    []string{"Blob signature"},
+		}, {
+			description:   "neither verifies",
+			keyRef:        key.keyPath,
+			blobSignature: badBlobSigRef,
+			predicateType: "slsaprovenance",
+			shouldErr:     true,
+		}, {
+			description:   "blob signature requires a key or certificate",
+			blobSignature: blobSigRef,
+			shouldErr:     true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			predicateType := test.predicateType
+			if predicateType == "" {
+				predicateType = "spdx"
+			}
+			var err error
+			stderr := ui.RunWithTestCtx(func(ctx context.Context, _ ui.WriteFunc) {
+				cmd := VerifyBlobAttestationCommand{
+					KeyOpts:       options.KeyOpts{KeyRef: test.keyRef},
+					SignaturePath: sigRef,
+					IgnoreTlog:    true,
+					CheckClaims:   true,
+					PredicateType: predicateType,
+					BlobAttestationRelatedChecks: BlobAttestationRelatedChecks{
+						BlobSignature: test.blobSignature,
+					},
+				}
+				err = cmd.Exec(ctx, blobPath)
+			})
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("Exec()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+			// Each check is reported independently of the others.
+			for _, name := range []string{"Attestation", "Blob signature"} {
+				passed := strings.Contains(stderr, name+": Verified OK")
+				if want := slices.Contains(test.wantPassed, name); passed != want {
+					t.Errorf("%s reported passed = %t, expected %t:\n%s", name, passed, want, stderr)
+				}
+			}
+		})
+	}
+}
+
+func TestValidOID(t *testing.T) {
+	for oid, want := range map[string]bool{
+		"1.3.6.1.4.1.57264.1": true,
+		"2.5":                 true,
+		"":                    false,
+		"1":                   false,
+		"1..2":                false,
+		"1.3.x":               false,
+		"-1.3":                false,
+	} {
+		if got := validOID(oid); got != want {
+			t.Errorf("validOID(%q) = %v, wanted %v", oid, got, want)
+		}
+	}
+}
+
+func TestCanonicalBlobDigest(t *testing.T) {
+	want, err := canonicalBlobDigest(strings.NewReader(`{"a":1,"b":[true,null]}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := canonicalBlobDigest(strings.NewReader("{\n  \"b\": [ true, null ],\n  \"a\": 1\n}\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("canonicalBlobDigest() = %v, wanted %v", got, want)
+	}
+
+	if _, err := canonicalBlobDigest(strings.NewReader("not json"), ""); err == nil {
+		t.Error("canonicalBlobDigest() expected an error for a blob that isn't JSON")
+	}
+}
+
+func TestDecompressBlob(t *testing.T) {
+	blobContents := "some blob contents"
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := zw.EncodeAll([]byte(blobContents), nil)
+	zw.Close()
+
+	zr, err := decompressBlob(bytes.NewReader(compressed), CompressionZstd)
+	if err != nil {
+		t.Fatalf("decompressBlob() = %v", err)
+	}
+	defer zr.Close()
+	got, err := blobDigest(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := blobDigest(strings.NewReader(blobContents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("digest of the decompressed blob = %v, wanted %v", got, want)
+	}
+
+	for _, blob := range []string{"", blobContents} {
+		if _, err := decompressBlob(strings.NewReader(blob), CompressionZstd); err == nil || !strings.Contains(err.Error(), "not zstd compressed") {
+			t.Errorf("decompressBlob(%q) = %v, wanted a not zstd compressed error", blob, err)
+		}
+	}
+}
+
+// TestVerifyBlobAttestationAirGapped verifies an attestation with nothing but
+// a bundle, the blob digest and local trust material: the certificate chain
+// and the Rekor public key, read from SIGSTORE_REKOR_PUBLIC_KEY.
+func TestVerifyBlobAttestationAirGapped(t *testing.T) {
+	keyless := newKeylessStack(t)
+	identity := "hello@foo.com"
+	issuer := "issuer"
+	leafCert, _, leafPemCert, signer := keyless.genLeafCert(t, identity, issuer)
+
+	blob := "air-gapped blob"
+	env := signTestStatementWith(t, signer, testStatement("customFoo", sha256Subject("blob", blob)))
+	entry := genRekorEntry(t, rekor_dsse.KIND, "0.0.1", env, leafPemCert, env)
+	b := createBundle(t, env, leafPemCert, keyless.rekorLogID, leafCert.NotBefore.Unix()+1, entry)
+	b.Bundle.SignedEntryTimestamp = keyless.rekorSignPayload(t, b.Bundle.Payload)
+	bundlePath := writeBundleFile(t, keyless.td, b, "bundle.json")
+	chainPath := writeBlobFile(t, keyless.td, string(keyless.subPemCert)+string(keyless.rootPemCert), "chain.pem")
+
+	digest := sha256.Sum256([]byte(blob))
+	cmd := VerifyBlobAttestationCommand{
+		CertVerifyOptions: options.CertVerifyOptions{
+			CertIdentity:   identity,
+			CertOidcIssuer: issuer,
+		},
+		CertChain:     chainPath,
+		KeyOpts:       options.KeyOpts{BundlePath: bundlePath},
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		Offline:       true,
+		IgnoreSCT:     true,
+		BlobAttestationBlobSource: BlobAttestationBlobSource{
+			BlobDigest: "sha256:" + hex.EncodeToString(digest[:]),
+		},
+	}
+	if err := cmd.Exec(context.Background(), ""); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+
+	// The subject must match the digest.
+	otherDigest := sha256.Sum256([]byte("another blob"))
+	cmd.BlobDigest = hex.EncodeToString(otherDigest[:])
+	if err := cmd.Exec(context.Background(), ""); err == nil {
+		t.Fatal("Exec() expected an error for a digest that isn't a subject")
+	}
+}
+
+func TestVerifyBlobAttestationNoNetwork(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default transports, once guarded, reject the requests of a context
+	// without network.
+	netguard.Install()
+	for name, hc := range map[string]*http.Client{
+		"net/http":             http.DefaultClient,
+		"go-containerregistry": {Transport: remote.DefaultTransport},
+	} {
+		req, err := http.NewRequestWithContext(netguard.WithoutNetwork(context.Background()), http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := hc.Do(req); !errors.Is(err, netguard.ErrNetworkDisabled) {
+			t.Errorf("%s: Do() = %v, expected %v", name, err, netguard.ErrNetworkDisabled)
+		}
+	}
+
+	td := t.TempDir()
+	env, _ := signTestStatement(t, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	tests := []struct {
+		description string
+		cmd         VerifyBlobAttestationCommand
+		wantErr     string
+	}{
+		{
+			description: "key fetched from the network",
+			cmd:         VerifyBlobAttestationCommand{KeyOpts: options.KeyOpts{KeyRef: "jwks://" + u.Host + "/keys"}},
+			wantErr:     "network access is disabled, refusing to connect to " + u.Host,
+		}, {
+			description: "key of a client that isn't guarded",
+			cmd:         VerifyBlobAttestationCommand{KeyOpts: options.KeyOpts{KeyRef: "k8s://default/cosign"}},
+			wantErr:     "--no-network cannot be combined with the key k8s://default/cosign",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := test.cmd
+			if cmd.FromImage == "" {
+				cmd.SignaturePath = writeBlobFile(t, td, string(env), "attestation.json")
+			}
+			cmd.PredicateType = "customFoo"
+			cmd.CheckClaims = true
+			cmd.IgnoreTlog = true
+			cmd.NoNetwork = true
+			err := cmd.Exec(context.Background(), writeBlobFile(t, td, blobContents, "blob"))
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+
+	// The Rekor clients, which have their own transports, are given a
+	// transport guarded by the context.
+	ctx := netguard.WithoutNetwork(context.Background())
+	if _, err := (&VerifyBlobAttestationCommand{}).httpClient(ctx).Get(srv.URL); !errors.Is(err, netguard.ErrNetworkDisabled) {
+		t.Errorf("Get() = %v, expected %v", err, netguard.ErrNetworkDisabled)
+	}
+
+	// TUF, whose requests carry no context, isn't used if it would update its
+	// expired metadata.
+	writeTUFRoot(t, 1, time.Now().Add(-time.Hour))
+	if _, err := (*trustMaterial)(nil).rekorPubKeys(ctx); !errors.Is(err, netguard.ErrNetworkDisabled) {
+		t.Errorf("rekorPubKeys() = %v, expected %v", err, netguard.ErrNetworkDisabled)
+	}
+	if requests != 0 {
+		t.Errorf("the server got %d requests, expected none", requests)
+	}
+}
+
+func TestParseBlobDigest(t *testing.T) {
+	hexDigest := strings.Repeat("ab", 32)
+	for digest, wantErr := range map[string]string{
+		hexDigest:                              "",
+		"sha256:" + hexDigest:                  "",
+		"SHA256:" + strings.ToUpper(hexDigest): "",
+		"sha512:" + hexDigest + hexDigest:      "unsupported hash",
+		"sha256:abc":                           "parsing --blob-digest",
+		"not a digest":                         "parsing --blob-digest",
+	} {
+		h, err := parseBlobDigest(digest)
+		if wantErr == "" {
+			if err != nil || h.Hex != hexDigest {
+				t.Errorf("parseBlobDigest(%q) = %v, %v", digest, h, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("parseBlobDigest(%q) = %v, wanted %q", digest, err, wantErr)
+		}
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	td := t.TempDir()
+	for _, contents := range []string{blobContents, ""} {
+		path := writeBlobFile(t, td, contents, "blob")
+		for _, alg := range supportedBlobHashes() {
+			want, err := hashBlob(strings.NewReader(contents), alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := hashFile(path, alg)
+			if err != nil {
+				t.Fatalf("hashFile(%s) = %v", alg, err)
+			}
+			if got != want {
+				t.Errorf("hashFile(%s) = %v, want %v", alg, got, want)
+			}
+		}
+	}
+
+	if _, err := hashFile(filepath.Join(td, "missing"), "sha256"); err == nil {
+		t.Error("hashFile() of a missing file expected an error")
+	}
+	if _, err := hashFile(filepath.Join(td, "blob"), "md5"); err == nil {
+		t.Error("hashFile() with an unsupported algorithm expected an error")
+	}
+}
+
+func TestHashFilePipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no /dev/fd on windows")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	go func() {
+		defer w.Close()
+		_, _ = io.WriteString(w, blobContents)
+	}()
+
+	// A pipe, like /dev/stdin or a process substitution, can't be mapped.
+	got, err := hashFile(fmt.Sprintf("/dev/fd/%d", r.Fd()), "sha256")
+	if err != nil {
+		t.Fatalf("hashFile() of a pipe = %v", err)
+	}
+	want, err := hashBlob(strings.NewReader(blobContents), "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("hashFile() of a pipe = %v, want %v", got, want)
+	}
+}
+
+// BenchmarkHashLargeBlob compares the allocations of hashing a large file by
+// reading it, hashBlob, by streaming it from the file, and by memory-mapping
+// it, hashFile.
+func BenchmarkHashLargeBlob(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "blob")
+	if err := os.WriteFile(path, bytes.Repeat([]byte{0xa5}, 64<<20), 0600); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("read", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			f, err := os.Open(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := hashBlob(f, "sha256"); err != nil {
+				b.Fatal(err)
+			}
+			f.Close()
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			f, err := os.Open(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := io.Copy(sha256.New(), f); err != nil {
+				b.Fatal(err)
+			}
+			f.Close()
+		}
+	})
+	b.Run("mmap", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := hashFile(path, "sha256"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestVerifyEnvelopeSHA3Digest(t *testing.T) {
+	ctx := context.Background()
+
+	sum256 := sha3.Sum256([]byte(blobContents))
+	sum512 := sha3.Sum512([]byte(blobContents))
+	otherSum256 := sha3.Sum256([]byte(anotherBlobContents))
+	sha2Sum := sha256.Sum256([]byte(blobContents))
+
+	tests := []struct {
+		description   string
+		digests       common.DigestSet
+		hashAlgorithm string
+		encoding      string
+		shouldErr     bool
+	}{
+		{
+			description:   "sha3-256",
+			digests:       common.DigestSet{"sha3-256": hex.EncodeToString(sum256[:])},
+			hashAlgorithm: "sha3-256",
+		}, {
+			description:   "sha3_256 as spelled by in-toto",
+			digests:       common.DigestSet{"sha3_256": hex.EncodeToString(sum256[:])},
+			hashAlgorithm: "sha3-256",
+		}, {
+			description:   "sha3-512",
+			digests:       common.DigestSet{"sha3-512": hex.EncodeToString(sum512[:])},
+			hashAlgorithm: "sha3-512",
+		}, {
+			description:   "sha3-256 multihash",
+			digests:       common.DigestSet{"sha3-256": hex.EncodeToString(append([]byte{0x16, 32}, sum256[:]...))},
+			hashAlgorithm: "sha3-256",
+			encoding:      DigestEncodingMultihash,
+		}, {
+			description:   "sha3-256 of another blob",
+			digests:       common.DigestSet{"sha3-256": hex.EncodeToString(otherSum256[:])},
+			hashAlgorithm: "sha3-256",
+			shouldErr:     true,
+		}, {
+			description:   "only a sha256 subject",
+			digests:       common.DigestSet{"sha256": hex.EncodeToString(sha2Sum[:])},
+			hashAlgorithm: "sha3-256",
+			shouldErr:     true,
+		}, {
+			description: "only a sha3-256 subject with the default algorithm",
+			digests:     common.DigestSet{"sha3-256": hex.EncodeToString(sum256[:])},
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			subject := in_toto.Subject{Name: "blob", Digest: test.digests}
+			env, sv := signTestStatement(t, testStatement(in_toto.PredicateSPDX, subject))
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts: &cosign.CheckOpts{
+					SigVerifier: sv,
+					IgnoreTlog:  true,
+				},
+				CheckClaims:    true,
+				PredicateType:  "spdx",
+				DigestEncoding: test.encoding,
+				HashAlgorithm:  test.hashAlgorithm,
+			}
+			_, err := verifyEnvelope(ctx, opts, env, strings.NewReader(blobContents))
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("verifyEnvelope()= %s, expected shouldErr=%t ", err, test.shouldErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationRequireSigningTime(t *testing.T) {
+	keyless := newKeylessStack(t)
+	identity := "hello@foo.com"
+	issuer := "issuer"
+	leafCert, _, leafPemCert, signer := keyless.genLeafCert(t, identity, issuer)
+
+	env := signTestStatementWith(t, signer, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	entry := genRekorEntry(t, rekor_dsse.KIND, "0.0.1", env, leafPemCert, env)
+	b := createBundle(t, env, leafPemCert, keyless.rekorLogID, leafCert.NotBefore.Unix()+1, entry)
+	b.Bundle.SignedEntryTimestamp = keyless.rekorSignPayload(t, b.Bundle.Payload)
+	bundlePath := writeBundleFile(t, keyless.td, b, "bundle.json")
+	chainPath := writeBlobFile(t, keyless.td, string(keyless.subPemCert)+string(keyless.rootPemCert), "chain.pem")
+	blobPath := writeBlobFile(t, keyless.td, blobContents, "blob")
+
+	cmd := VerifyBlobAttestationCommand{
+		CertVerifyOptions: options.CertVerifyOptions{
+			CertIdentity:   identity,
+			CertOidcIssuer: issuer,
+		},
+		CertChain:     chainPath,
+		KeyOpts:       options.KeyOpts{BundlePath: bundlePath},
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		Offline:       true,
+		IgnoreSCT:     true,
+		BlobAttestationCertificateChecks: BlobAttestationCertificateChecks{
+			RequireSigningTimeInValidity: true,
+		},
+	}
+	// The bundle's integrated time is a trusted signing time.
+	if err := cmd.Exec(context.Background(), blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+
+	// Without a tlog entry or timestamp there is nothing to check against.
+	cmd.KeyOpts = options.KeyOpts{}
+	cmd.SignaturePath = writeBlobFile(t, keyless.td, string(env), "attestation.json")
+	cmd.CertRef = writeBlobFile(t, keyless.td, string(leafPemCert), "cert.pem")
+	cmd.IgnoreTlog = true
+	if err := cmd.Exec(context.Background(), blobPath); err == nil || !strings.Contains(err.Error(), "no tlog entry or signed timestamp") {
+		t.Fatalf("Exec() = %v, expected a missing signing time", err)
+	}
+	cmd.RequireSigningTimeInValidity = false
+	if err := cmd.Exec(context.Background(), blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+}
+
+func TestVerifyBlobAttestationFallbackKeys(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	writeKey := func(name string, v signature.Verifier) string {
+		pub, err := v.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pemBytes, err := cryptoutils.MarshalPublicKeyToPEM(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeBlobFile(t, td, string(pemBytes), name)
+	}
+
+	st := testStatement("customFoo", sha256Subject("blob", blobContents))
+	env, oldKey := signTestStatement(t, st)
+	_, newKey := signTestStatement(t, st)
+	_, otherKey := signTestStatement(t, st)
+	oldPath := writeKey("old.pub", oldKey)
+	newPath := writeKey("new.pub", newKey)
+	otherPath := writeKey("other.pub", otherKey)
+
+	// The first key validating the envelope is selected.
+	ref, _, err := selectKey(ctx, []string{newPath, oldPath, otherPath}, env, nil)
+	if err != nil {
+		t.Fatalf("selectKey() = %v", err)
+	}
+	if ref != oldPath {
+		t.Errorf("selectKey() = %s, want %s", ref, oldPath)
+	}
+	if _, _, err := selectKey(ctx, []string{newPath, otherPath}, env, nil); err == nil || !strings.Contains(err.Error(), "no --key validates the attestation") {
+		t.Errorf("selectKey() = %v, expected no key to validate", err)
+	}
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: newPath},
+		SignaturePath: writeBlobFile(t, td, string(env), "attestation.json"),
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationSignatureChecks: BlobAttestationSignatureChecks{
+			FallbackKeys: []string{oldPath},
+		},
+	}
+	if err := cmd.Exec(ctx, writeBlobFile(t, td, blobContents, "blob")); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+	cmd.FallbackKeys = []string{otherPath}
+	if err := cmd.Exec(ctx, writeBlobFile(t, td, blobContents, "blob")); err == nil {
+		t.Fatal("Exec() expected an error when no key validates the attestation")
+	}
+}
+
+func TestVerifyBlobAttestationTracing(t *testing.T) {
+	td := t.TempDir()
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, root := tp.Tracer("test").Start(context.Background(), "test")
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+	}
+	if err := cmd.Exec(ctx, writeBlobFile(t, td, blobContents, "blob")); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+	root.End()
+
+	got := map[string]bool{}
+	for _, s := range recorder.Ended() {
+		got[s.Name()] = true
+	}
+	for _, name := range []string{"verify-blob-attestation", "load", "digest", "verify", "parse", "signature", "claim", "policy", "output"} {
+		if !got[name] {
+			t.Errorf("missing span %q, got %v", name, got)
+		}
+	}
+	// The tlog is ignored.
+	if got["tlog"] {
+		t.Error("unexpected tlog span")
+	}
+}
+
+func TestVerifyBlobAttestationFailOnWarnings(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	key := writeTestKey(t, td)
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	env := signTestStatementWith(t, key.signer, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	sigPath := writeBlobFile(t, td, string(env), "attestation.json")
+
+	// Materials aren't written for a predicate other than a SLSA provenance,
+	// with a warning.
+	newCmd := func(failOnWarnings bool) VerifyBlobAttestationCommand {
+		return VerifyBlobAttestationCommand{
+			KeyOpts:        options.KeyOpts{KeyRef: key.keyPath},
+			SignaturePath:  sigPath,
+			PredicateType:  "customFoo",
+			CheckClaims:    true,
+			IgnoreTlog:     true,
+			FailOnWarnings: failOnWarnings,
+			BlobAttestationOutputs: BlobAttestationOutputs{
+				OutputMaterials: filepath.Join(td, "materials.json"),
+			},
+		}
+	}
+	cmd := newCmd(false)
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+	cmd = newCmd(true)
+	cmd.OutputEnvelope = filepath.Join(td, "envelope.json")
+	err := cmd.Exec(ctx, blobPath)
+	var verr *VerificationErrors
+	if !errors.As(err, &verr) || len(verr.Errs) != 1 || !strings.Contains(err.Error(), "warning: predicate type customFoo is not a SLSA provenance") {
+		t.Fatalf("Exec() with --fail-on-warnings = %v, expected the warning as an error", err)
+	}
+	// No output is written by the failed verification.
+	if _, err := os.Stat(cmd.OutputEnvelope); !os.IsNotExist(err) {
+		t.Errorf("the envelope was written despite the warning: %v", err)
+	}
+
+	// Warnings logged before Exec are reported too.
+	wctx := ui.RecordWarnings(ctx)
+	ui.Warnf(wctx, "skipping tlog verification")
+	cmd = newCmd(true)
+	cmd.OutputMaterials = ""
+	if err := cmd.Exec(wctx, blobPath); err == nil || !strings.Contains(err.Error(), "warning: skipping tlog verification") {
+		t.Fatalf("Exec() after a warning = %v, expected the warning as an error", err)
+	}
+}
+
+func TestVerifyBlobAttestationPinSPKIOnly(t *testing.T) {
+	keyless := newKeylessStack(t)
+	leafCert, _, leafPemCert, signer := keyless.genLeafCert(t, "hello@foo.com", "issuer")
+
+	env := signTestStatementWith(t, signer, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	spki := sha256.Sum256(leafCert.RawSubjectPublicKeyInfo)
+	newCmd := func(failOnWarnings bool) VerifyBlobAttestationCommand {
+		return VerifyBlobAttestationCommand{
+			CertRef:        writeBlobFile(t, keyless.td, string(leafPemCert), "cert.pem"),
+			CertChain:      writeBlobFile(t, keyless.td, string(keyless.subPemCert)+string(keyless.rootPemCert), "chain.pem"),
+			SignaturePath:  writeBlobFile(t, keyless.td, string(env), "attestation.json"),
+			PredicateType:  "customFoo",
+			CheckClaims:    true,
+			IgnoreTlog:     true,
+			IgnoreSCT:      true,
+			FailOnWarnings: failOnWarnings,
+			BlobAttestationCertificateChecks: BlobAttestationCertificateChecks{
+				PinSPKI: []string{base64.StdEncoding.EncodeToString(spki[:])},
+			},
+		}
+	}
+	blobPath := writeBlobFile(t, keyless.td, blobContents, "blob")
+
+	// The pin stands in for the identity, with a warning.
+	cmd := newCmd(false)
+	if err := cmd.Exec(context.Background(), blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+	cmd = newCmd(true)
+	if err := cmd.Exec(context.Background(), blobPath); err == nil || !strings.Contains(err.Error(), "identity and issuer are not checked") {
+		t.Fatalf("Exec() with --fail-on-warnings = %v, expected the disabled identity checks as an error", err)
+	}
+}
+
+func TestVerifyBlobAttestationJWKS(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	newKey := func(kid string) (signature.Signer, jose.JSONWebKey) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sv, jose.JSONWebKey{Key: priv.Public(), KeyID: kid, Algorithm: "ES256", Use: "sig"}
+	}
+	oldSigner, oldKey := newKey("old")
+	newSigner, newJWK := newKey("new")
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{oldKey, newJWK}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwks) //nolint:errcheck
+	}))
+	defer s.Close()
+	defer func(c *jwkskey.Cache) { jwkskey.DefaultCache = c }(jwkskey.DefaultCache)
+	jwkskey.DefaultCache = &jwkskey.Cache{Client: s.Client()}
+	keyRef := jwkskey.ReferenceScheme + strings.TrimPrefix(s.URL, "https://") + "/.well-known/jwks.json"
+
+	payload, err := json.Marshal(testStatement("customFoo", sha256Subject("blob", blobContents)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	envelope := func(signer signature.Signer, keyID string) string {
+		n++
+		es, err := ssldsse.NewEnvelopeSigner(&dsse.SignerAdapter{SignatureSigner: signer, PubKeyID: keyID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := es.SignPayload(ctx, types.IntotoPayloadType, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeBlobFile(t, td, string(b), fmt.Sprintf("envelope-%d.json", n))
+	}
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	tests := []struct {
+		description string
+		keyRef      string
+		sigPath     string
+		wantErr     string
+	}{
+		{
+			description: "selected by keyid",
+			keyRef:      keyRef,
+			sigPath:     envelope(newSigner, "new"),
+		}, {
+			description: "selected by kid",
+			keyRef:      keyRef + "#old",
+			sigPath:     envelope(oldSigner, ""),
+		}, {
+			description: "any key without keyid",
+			keyRef:      keyRef,
+			sigPath:     envelope(oldSigner, ""),
+		}, {
+			description: "keyid of another key",
+			keyRef:      keyRef,
+			sigPath:     envelope(oldSigner, "new"),
+			wantErr:     "signature",
+		}, {
+			description: "unknown keyid",
+			keyRef:      keyRef,
+			sigPath:     envelope(oldSigner, "retired"),
+			wantErr:     "no key of the JWKS has the keyid",
+		}, {
+			description: "unknown kid",
+			keyRef:      keyRef + "#retired",
+			sigPath:     envelope(oldSigner, ""),
+			wantErr:     `no key with kid "retired"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: test.keyRef},
+				SignaturePath: test.sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationBlobResolver(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("the test resolver plugin is a shell script")
+	}
+	ctx := context.Background()
+	td := t.TempDir()
+
+	key := writeTestKey(t, td)
+	env := signTestStatementWith(t, key.signer, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	sigPath := writeBlobFile(t, td, string(env), "attestation.json")
+
+	// The plugin serves the content store cas, exiting with 2 for an unknown
+	// content ID.
+	cas := filepath.Join(td, "cas")
+	if err := os.Mkdir(cas, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeBlobFile(t, cas, blobContents, "cid-blob")
+	writeBlobFile(t, cas, anotherBlobContents, "cid-another")
+	plugin := writeBlobFile(t, td, `read id
+[ -f "`+cas+`/$id" ] || { echo "unknown $id" >&2; exit 2; }
+[ "$1" = resolve ] || { echo "unexpected $1" >&2; exit 1; }
+cat "`+cas+`/$id"
+`, "resolver.sh")
+
+	tests := []struct {
+		description string
+		contentID   string
+		wantErr     string
+	}{
+		{
+			description: "resolved content",
+			contentID:   "cid-blob",
+		}, {
+			description: "content not matching the subjects",
+			contentID:   "cid-another",
+			wantErr:     "no matching subject digest found",
+		}, {
+			description: "unknown content ID",
+			contentID:   "cid-missing",
+			wantErr:     "content not found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: key.keyPath},
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationBlobSource: BlobAttestationBlobSource{
+					BlobResolver: "sh " + plugin,
+				},
+			}
+			err := cmd.Exec(ctx, test.contentID)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationBlobParts(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	third := len(blobContents) / 3
+	part1 := writeBlobFile(t, td, blobContents[:third], "blob.part1")
+	part2 := writeBlobFile(t, td, blobContents[third:2*third], "blob.part2")
+	part3 := writeBlobFile(t, td, blobContents[2*third:], "blob.part3")
+	whole := writeBlobFile(t, td, blobContents, "blob")
+
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	keyRef := att.keyPath
+	sigPath := att.sigPath
+
+	tests := []struct {
+		description  string
+		parts        []string
+		artifactPath string
+		wantErr      string
+	}{
+		{
+			description: "parts in order",
+			parts:       []string{part1, part2, part3},
+		}, {
+			description: "single part",
+			parts:       []string{whole},
+		}, {
+			description: "parts out of order",
+			parts:       []string{part2, part1, part3},
+			wantErr:     "no matching subject digest found",
+		}, {
+			description: "missing part",
+			parts:       []string{part1, part3},
+			wantErr:     "no matching subject digest found",
+		}, {
+			description: "nonexistent part",
+			parts:       []string{part1, filepath.Join(td, "blob.part4")},
+			wantErr:     "no such file or directory",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: keyRef},
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				BlobAttestationBlobSource: BlobAttestationBlobSource{
+					BlobParts: test.parts,
+				},
+			}
+			err := cmd.Exec(ctx, test.artifactPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v, expected success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, expected %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationCertSPIFFEID(t *testing.T) {
+	ctx := context.Background()
+	keyless := newKeylessStack(t)
+	issuer := "https://spire.example.org"
+	spiffeURI, err := url.Parse("spiffe://example.org/ci/build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafCert, leafPriv, err := test.GenerateLeafCertWithSubjectAlternateNames(nil, nil, nil, []*url.URL{spiffeURI}, issuer, keyless.subCert, keyless.subPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafPEM, err := cryptoutils.MarshalCertificateToPEM(leafCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := signature.LoadECDSASignerVerifier(leafPriv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := signTestStatementWith(t, signer, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	sigPath := writeBlobFile(t, keyless.td, string(env), "attestation.json")
+	blobPath := writeBlobFile(t, keyless.td, blobContents, "blob")
+	certPath := writeBlobFile(t, keyless.td, string(leafPEM), "cert.pem")
+	chainPath := writeBlobFile(t, keyless.td, string(keyless.subPemCert)+string(keyless.rootPemCert), "chain.pem")
+
+	tests := []struct {
+		description string
+		spiffeID    string
+		issuer      string
+		identity    string
+		keyRef      string
+		wantErr     string
+	}{
+		{
+			description: "exact SPIFFE ID",
+			spiffeID:    "spiffe://example.org/ci/build",
+		}, {
+			description: "normalized SPIFFE ID",
+			spiffeID:    "SPIFFE://Example.org/ci/build/",
+		}, {
+			description: "with the issuer",
+			spiffeID:    "spiffe://example.org/ci/build",
+			issuer:      issuer,
+		}, {
+			description: "with another issuer",
+			spiffeID:    "spiffe://example.org/ci/build",
+			issuer:      "https://accounts.example.com",
+			wantErr:     "none of the expected identities matched",
+		}, {
+			description: "with the identity",
+			spiffeID:    "spiffe://example.org/ci/build",
+			issuer:      issuer,
+			identity:    "spiffe://example.org/ci/build",
+		}, {
+			description: "SPIFFE ID prefix",
+			spiffeID:    "spiffe://example.org/ci",
+			wantErr:     "certificate URI SAN is not the SPIFFE ID spiffe://example.org/ci",
+		}, {
+			description: "other trust domain",
+			spiffeID:    "spiffe://example.com/ci/build",
+			wantErr:     "certificate URI SAN is not the SPIFFE ID",
+		}, {
+			description: "invalid SPIFFE ID",
+			spiffeID:    "https://example.org/ci/build",
+			wantErr:     "parsing --certificate-spiffe-id",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				CertVerifyOptions: options.CertVerifyOptions{
+					CertIdentity:   test.identity,
+					CertOidcIssuer: test.issuer,
+				},
+				CertRef:       certPath,
+				CertChain:     chainPath,
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				IgnoreSCT:     true,
+				BlobAttestationCertificateChecks: BlobAttestationCertificateChecks{
+					CertSPIFFEID: test.spiffeID,
+				},
+			}
+			if test.keyRef != "" {
+				cmd.KeyRef, cmd.CertRef = test.keyRef, ""
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationRequireIntermediateSKI(t *testing.T) {
+	ctx := context.Background()
+	keyless := newKeylessStack(t)
+	identity, issuer := "foo@example.com", "https://accounts.example.com"
+	_, _, leafPEM, signer := keyless.genLeafCert(t, identity, issuer)
+
+	env := signTestStatementWith(t, signer, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	sigPath := writeBlobFile(t, keyless.td, string(env), "attestation.json")
+	blobPath := writeBlobFile(t, keyless.td, blobContents, "blob")
+	certPath := writeBlobFile(t, keyless.td, string(leafPEM), "cert.pem")
+	chainPath := writeBlobFile(t, keyless.td, string(keyless.subPemCert)+string(keyless.rootPemCert), "chain.pem")
+	ski := hex.EncodeToString(keyless.subCert.SubjectKeyId)
+	var colonSKI []string
+	for _, b := range keyless.subCert.SubjectKeyId {
+		colonSKI = append(colonSKI, fmt.Sprintf("%02X", b))
+	}
+
+	tests := []struct {
+		description string
+		ski         string
+		keyRef      string
+		wantErr     string
+	}{
+		{
+			description: "issuing intermediate",
+			ski:         ski,
+		}, {
+			description: "colon-separated",
+			ski:         strings.Join(colonSKI, ":"),
+		}, {
+			description: "other intermediate",
+			ski:         strings.Repeat("ab", 20),
+			wantErr:     "certificate chain does not go through the intermediate CA of subject key identifier " + strings.Repeat("ab", 20),
+		}, {
+			description: "root",
+			ski:         hex.EncodeToString(keyless.rootCert.SubjectKeyId),
+			wantErr:     "certificate chain does not go through the intermediate CA",
+		}, {
+			description: "not hex",
+			ski:         "xyz",
+			wantErr:     "invalid --require-intermediate-ski",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd := VerifyBlobAttestationCommand{
+				CertVerifyOptions: options.CertVerifyOptions{
+					CertIdentity:   identity,
+					CertOidcIssuer: issuer,
+				},
+				CertRef:       certPath,
+				CertChain:     chainPath,
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				IgnoreTlog:    true,
+				IgnoreSCT:     true,
+				BlobAttestationCertificateChecks: BlobAttestationCertificateChecks{
+					RequireIntermediateSKI: test.ski,
+				},
+			}
+			if test.keyRef != "" {
+				cmd.KeyRef, cmd.CertRef = test.keyRef, ""
+			}
+			err := cmd.Exec(ctx, blobPath)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exec() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("Exec() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// recordingTransport records the hosts of the requests it gets and fails
+// them.
+type recordingTransport struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts = append(r.hosts, req.URL.Host)
+	return nil, errors.New("recorded")
+}
+
+func (r *recordingTransport) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.hosts)
+}
+
+func TestVerifyBlobAttestationInjectedClients(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("SIGSTORE_REKOR_PUBLIC_KEY", writeBlobFile(t, td, pubkey, "rekor.pub"))
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	keyPath := att.keyPath
+	sigPath := att.sigPath
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	injected := &recordingTransport{}
+	rekorClient, err := rekor.NewClientWithHTTPClient("https://injected.rekor.example", &http.Client{Transport: injected})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		description string
+		rekorClient *client.Rekor
+		httpClient  *http.Client
+		recorder    *recordingTransport
+		wantHost    string
+	}{
+		{
+			description: "Rekor client",
+			rekorClient: rekorClient,
+			recorder:    injected,
+			wantHost:    "injected.rekor.example",
+		}, {
+			description: "HTTP client",
+			httpClient:  &http.Client{Transport: &recordingTransport{}},
+			wantHost:    "rekor.example",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			recorder := test.recorder
+			if test.httpClient != nil {
+				recorder = test.httpClient.Transport.(*recordingTransport)
+			}
+			cmd := VerifyBlobAttestationCommand{
+				KeyOpts:       options.KeyOpts{KeyRef: keyPath, RekorURL: "https://rekor.example"},
+				SignaturePath: sigPath,
+				PredicateType: "customFoo",
+				CheckClaims:   true,
+				BlobAttestationTlogChecks: BlobAttestationTlogChecks{
+					RekorClient: test.rekorClient,
+					HTTPClient:  test.httpClient,
+				},
+			}
+			if err := cmd.Exec(context.Background(), blobPath); err == nil {
+				t.Fatal("Exec() expected the tlog lookup to fail")
+			}
+			hosts := recorder.recorded()
+			if len(hosts) == 0 {
+				t.Fatal("the injected client got no request")
+			}
+			for _, host := range hosts {
+				if host != test.wantHost {
+					t.Errorf("request to %s, expected %s", host, test.wantHost)
+				}
+			}
+		})
+	}
+}
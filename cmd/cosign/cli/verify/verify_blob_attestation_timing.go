@@ -0,0 +1,56 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sigstore/cosign/v2/internal/pkg/tracing"
+)
+
+// timingSteps names the verification steps in the TimingJSON output, by the
+// name of the span tracing them.
+var timingSteps = map[string]string{
+	"key-load":                "key-load",
+	"parse":                   "envelope-parse",
+	"signature":               "signature-verify",
+	"tlog":                    "tlog",
+	"sct":                     "sct",
+	"claim":                   "claim",
+	"policy":                  "policy",
+	"verify-blob-attestation": "total",
+}
+
+// saveTimings writes the durations of the verification steps recorded in
+// timings to path, as a JSON object of durations in seconds by step name.
+// The steps which didn't run are omitted.
+func saveTimings(path string, timings *tracing.Timings) error {
+	steps := map[string]float64{}
+	for span, d := range timings.Durations() {
+		if step, ok := timingSteps[span]; ok {
+			steps[step] = d.Seconds()
+		}
+	}
+	b, err := json.MarshalIndent(steps, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("create timing file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+)
+
+func TestVerifyBlobAttestationTimingJSON(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationOutputs: BlobAttestationOutputs{
+			TimingJSON: filepath.Join(td, "timing.json"),
+		},
+	}
+	for _, test := range []struct {
+		description string
+		blob        string
+		wantErr     bool
+	}{
+		{description: "verified", blob: blobContents},
+		{description: "failed", blob: anotherBlobContents, wantErr: true},
+	} {
+		t.Run(test.description, func(t *testing.T) {
+			if err := os.WriteFile(blobPath, []byte(test.blob), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.Exec(ctx, blobPath); (err != nil) != test.wantErr {
+				t.Fatalf("Exec() = %v, wantErr %v", err, test.wantErr)
+			}
+			b, err := os.ReadFile(cmd.TimingJSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]float64
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			// Neither the tlog entry nor an SCT is verified with a key and
+			// IgnoreTlog.
+			var steps []string
+			for step, d := range got {
+				if d < 0 {
+					t.Errorf("negative duration %v of %s", d, step)
+				}
+				steps = append(steps, step)
+			}
+			sort.Strings(steps)
+			want := []string{"claim", "envelope-parse", "key-load", "policy", "signature-verify", "total"}
+			if diff := cmp.Diff(want, steps); diff != "" {
+				t.Errorf("timing steps mismatch (-want +got):\n%s", diff)
+			}
+			if got["total"] < got["signature-verify"] {
+				t.Errorf("total %v is less than the signature verification %v", got["total"], got["signature-verify"])
+			}
+		})
+	}
+}
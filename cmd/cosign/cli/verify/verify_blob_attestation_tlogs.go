@@ -0,0 +1,105 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/sigstore/rekor/pkg/generated/client"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+)
+
+// TlogEntry locates the entry of the verified attestation in a transparency
+// log, see VerifyBlobAttestationCommand.MinTlogEntries.
+type TlogEntry struct {
+	RekorURL       string `json:"rekorURL"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	IntegratedTime int64  `json:"integratedTime"`
+}
+
+// rekorLog is a transparency log to look the tlog entry up in.
+type rekorLog struct {
+	url    string
+	client *client.Rekor
+}
+
+// rekorLogs returns the logs of RekorURLs, or of RekorURL if there are
+// none.
+func (c *VerifyBlobAttestationCommand) rekorLogs(ctx context.Context) ([]rekorLog, error) {
+	urls := c.RekorURLs
+	if len(urls) == 0 {
+		urls = []string{c.RekorURL}
+	}
+	logs := make([]rekorLog, 0, len(urls))
+	for _, url := range urls {
+		l := rekorLog{url: url}
+		var err error
+		switch {
+		case url == c.RekorURL && c.RekorClient != nil:
+			l.client = c.RekorClient
+		case c.httpClient(ctx) != nil:
+			l.client, err = rekor.NewClientWithHTTPClient(url, c.httpClient(ctx))
+		default:
+			l.client, err = rekor.NewClient(url)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("creating Rekor client for %s: %w", url, err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+// verifyTlogEntries looks the tlog entry of sig up in each of logs, and
+// requires it to be found in at least minLogs distinct logs, as identified by
+// their log ID. Each entry is verified against the trusted Rekor public key
+// of its log in co. The entries found are returned in the order of logs.
+func verifyTlogEntries(ctx context.Context, co *cosign.CheckOpts, sig oci.Signature, logs []rekorLog, minLogs int) ([]TlogEntry, error) {
+	var entries []TlogEntry
+	var missing []string
+	logIDs := map[string]bool{}
+	for _, l := range logs {
+		lco := *co
+		lco.RekorClient = l.client
+		e, err := cosign.FindVerifiedTlogEntry(ctx, sig, &lco)
+		if err != nil {
+			missing = append(missing, fmt.Sprintf("%s: %v", l.url, err))
+			continue
+		}
+		entry := TlogEntry{
+			RekorURL:       l.url,
+			LogID:          swag.StringValue(e.LogID),
+			LogIndex:       swag.Int64Value(e.LogIndex),
+			IntegratedTime: swag.Int64Value(e.IntegratedTime),
+		}
+		entries = append(entries, entry)
+		logIDs[entry.LogID] = true
+	}
+	if len(logIDs) >= minLogs {
+		return entries, nil
+	}
+	err := fmt.Errorf("the tlog entry is in %d distinct transparency logs, %d required", len(logIDs), minLogs)
+	if len(missing) > 0 {
+		err = fmt.Errorf("%w, not found in %s", err, strings.Join(missing, "; "))
+	}
+	return entries, err
+}
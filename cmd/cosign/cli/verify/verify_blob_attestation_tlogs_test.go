@@ -0,0 +1,169 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/rekor/pkg/generated/models"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/tuf"
+)
+
+func TestVerifyTlogEntries(t *testing.T) {
+	ctx := context.Background()
+
+	env, sv := signTestStatement(t, testStatement("customFoo", sha256Subject("blob", blobContents)))
+	sig, err := static.NewAttestation(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLog := func() (*signature.ECDSASignerVerifier, string) {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logID, err := getLogID(signer.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signer, logID
+	}
+	logA, idA := newLog()
+	logB, idB := newLog()
+	untrusted, _ := newLog()
+	// The lookups don't check the entry body, so the entries are of any
+	// signed artifact.
+	artifactSigner, _ := newLog()
+	artifactSig, err := artifactSigner.SignMessage(bytes.NewReader(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifactPub, err := cryptoutils.MarshalPublicKeyToPEM(artifactSigner.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorPubKeys := cosign.NewTrustedTransparencyLogPubKeys()
+	for _, signer := range []*signature.ECDSASignerVerifier{logA, logB} {
+		p, err := cryptoutils.MarshalPublicKeyToPEM(signer.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rekorPubKeys.AddTransparencyLogPubKey(p, tuf.Active); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// serve returns the URL of a Rekor server holding an entry of the log of
+	// signer, or no entry if signer is nil.
+	serve := func(signer *signature.ECDSASignerVerifier) string {
+		entries := []models.LogEntry{}
+		if signer != nil {
+			entries = append(entries, *makeRekorEntry(t, *signer, env, artifactSig, artifactPub, true))
+		}
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entries) //nolint:errcheck
+		}))
+		t.Cleanup(s.Close)
+		return s.URL
+	}
+	urlA, urlA2, urlB, urlUntrusted, urlEmpty := serve(logA), serve(logA), serve(logB), serve(untrusted), serve(nil)
+
+	tests := []struct {
+		description string
+		urls        []string
+		minLogs     int
+		wantLogIDs  []string
+		wantErr     string
+	}{
+		{
+			description: "two logs",
+			urls:        []string{urlA, urlB},
+			minLogs:     2,
+			wantLogIDs:  []string{idA, idB},
+		}, {
+			description: "the same log twice",
+			urls:        []string{urlA, urlA2},
+			minLogs:     2,
+			wantLogIDs:  []string{idA, idA},
+			wantErr:     "the tlog entry is in 1 distinct transparency logs, 2 required",
+		}, {
+			description: "entry of an untrusted log",
+			urls:        []string{urlA, urlUntrusted},
+			minLogs:     2,
+			wantLogIDs:  []string{idA},
+			wantErr:     "not found in " + urlUntrusted,
+		}, {
+			description: "enough logs with the entry",
+			urls:        []string{urlA, urlEmpty, urlB},
+			minLogs:     2,
+			wantLogIDs:  []string{idA, idB},
+		}, {
+			description: "no log with the entry",
+			urls:        []string{urlEmpty},
+			minLogs:     1,
+			wantErr:     "signature not found in transparency log",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			c := VerifyBlobAttestationCommand{
+				BlobAttestationTlogChecks: BlobAttestationTlogChecks{
+					RekorURLs: test.urls,
+				},
+			}
+			logs, err := c.rekorLogs(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			co := &cosign.CheckOpts{SigVerifier: sv, RekorPubKeys: &rekorPubKeys}
+			entries, err := verifyTlogEntries(ctx, co, sig, logs, test.minLogs)
+			var logIDs []string
+			for _, e := range entries {
+				logIDs = append(logIDs, e.LogID)
+			}
+			if diff := cmp.Diff(test.wantLogIDs, logIDs); diff != "" {
+				t.Errorf("log IDs mismatch (-want +got):\n%s", diff)
+			}
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyTlogEntries() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("verifyTlogEntries() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
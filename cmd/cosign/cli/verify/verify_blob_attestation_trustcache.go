@@ -0,0 +1,426 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/tuf"
+	tufleveldbstore "github.com/theupdateframework/go-tuf/client/leveldbstore"
+	"github.com/theupdateframework/go-tuf/data"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
+	"github.com/sigstore/cosign/v2/internal/pkg/netguard"
+	"github.com/sigstore/cosign/v2/internal/ui"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/env"
+)
+
+// The TUF targets of the trust material, with the names used by the TUF
+// repositories which don't set the usage of their targets.
+var (
+	rekorTargets  = []string{"rekor.pub"}
+	ctfeTargets   = []string{"ctfe.pub"}
+	fulcioTargets = []string{"fulcio.crt.pem", "fulcio_v1.crt.pem", "fulcio_intermediate_v1.crt.pem"}
+)
+
+// trustMaterial is the trust material fetched from TUF, cached on disk by
+// the digest of the TUF root it was fetched with. The cache is only trusted
+// as far as the local TUF metadata go: each target is checked against the
+// targets metadata whenever it is read, and the material expires with the
+// timestamp and targets metadata.
+type trustMaterial struct {
+	Rekor  []cachedTarget `json:"rekor"`
+	CTFE   []cachedTarget `json:"ctfe"`
+	Fulcio []cachedTarget `json:"fulcio"`
+	// expires is when the TUF metadata the material was checked against
+	// expire.
+	expires time.Time
+}
+
+type cachedTarget struct {
+	// Name is the name of the target in the TUF targets metadata.
+	Name   string `json:"name"`
+	Target []byte `json:"target"`
+	// Status is the status of the target in the custom metadata of the
+	// target, not cached.
+	Status tuf.StatusKind `json:"-"`
+}
+
+// tufRootDir is the directory of the local TUF repository, see
+// tuf.TufRootEnv.
+func tufRootDir() string {
+	if dir := os.Getenv(tuf.TufRootEnv); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = ""
+	}
+	return filepath.Join(home, ".sigstore", "root")
+}
+
+// tufState is the trusted metadata of the local TUF repository.
+type tufState struct {
+	// rootDigest is the digest of the trusted TUF root.
+	rootDigest string
+	// expires is when the first of the timestamp and targets metadata
+	// expires.
+	expires time.Time
+	targets data.TargetFiles
+}
+
+// localTUFState reads the trusted metadata of the local TUF repository,
+// which the TUF client verified when it stored them.
+func localTUFState() (*tufState, error) {
+	store, err := tufleveldbstore.FileLocalStore(filepath.Join(tufRootDir(), "tuf.db"))
+	if err != nil {
+		return nil, fmt.Errorf("opening the local TUF repository: %w", err)
+	}
+	defer store.Close()
+	meta, err := store.GetMeta()
+	if err != nil {
+		return nil, fmt.Errorf("reading the local TUF metadata: %w", err)
+	}
+	root, ok := meta["root.json"]
+	if !ok {
+		return nil, errors.New("the local TUF repository has no trusted root")
+	}
+	timestamp := &data.Timestamp{}
+	if err := unmarshalTUFMeta(meta, "timestamp.json", timestamp); err != nil {
+		return nil, err
+	}
+	targets := &data.Targets{}
+	if err := unmarshalTUFMeta(meta, "targets.json", targets); err != nil {
+		return nil, err
+	}
+	expires := timestamp.Expires
+	if targets.Expires.Before(expires) {
+		expires = targets.Expires
+	}
+	digest := sha256.Sum256(root)
+	return &tufState{
+		rootDigest: hex.EncodeToString(digest[:]),
+		expires:    expires,
+		targets:    targets.Targets,
+	}, nil
+}
+
+// unmarshalTUFMeta unmarshals the signed part of the TUF metadata name into
+// v.
+func unmarshalTUFMeta(meta map[string]json.RawMessage, name string, v interface{}) error {
+	md, ok := meta[name]
+	if !ok {
+		return fmt.Errorf("the local TUF repository has no trusted %s", name)
+	}
+	s := &data.Signed{}
+	if err := json.Unmarshal(md, s); err != nil {
+		return fmt.Errorf("parsing the TUF metadata %s: %w", name, err)
+	}
+	if err := json.Unmarshal(s.Signed, v); err != nil {
+		return fmt.Errorf("parsing the TUF metadata %s: %w", name, err)
+	}
+	return nil
+}
+
+// targetName returns the name of the target of the targets metadata whose
+// content is target.
+func (s *tufState) targetName(target []byte) (string, error) {
+	for name := range s.targets {
+		if s.checkTarget(name, target) == nil {
+			return name, nil
+		}
+	}
+	return "", errors.New("the target is not in the TUF targets metadata")
+}
+
+// checkTarget checks target against the length and sha256 digest of the
+// target name of the targets metadata.
+func (s *tufState) checkTarget(name string, target []byte) error {
+	meta, ok := s.targets[name]
+	if !ok {
+		return fmt.Errorf("%s is not in the TUF targets metadata", name)
+	}
+	want, ok := meta.Hashes["sha256"]
+	if !ok {
+		return fmt.Errorf("the TUF targets metadata have no sha256 digest of %s", name)
+	}
+	digest := sha256.Sum256(target)
+	if meta.Length != int64(len(target)) || !bytes.Equal(digest[:], want) {
+		return fmt.Errorf("%s doesn't match the TUF targets metadata", name)
+	}
+	return nil
+}
+
+// targetStatus returns the status of the target name in its custom metadata,
+// tuf.Active if it has none, like tuf.TUF.GetTargetsByMeta.
+func (s *tufState) targetStatus(name string) (tuf.StatusKind, error) {
+	meta := s.targets[name]
+	if meta.Custom == nil {
+		return tuf.Active, nil
+	}
+	var custom struct {
+		Sigstore struct {
+			Status tuf.StatusKind `json:"status"`
+		} `json:"sigstore"`
+	}
+	if err := json.Unmarshal(*meta.Custom, &custom); err != nil {
+		return tuf.UnknownStatus, fmt.Errorf("parsing the custom metadata of %s: %w", name, err)
+	}
+	return custom.Sigstore.Status, nil
+}
+
+// tufCacheDisabled reports whether TUF keeps no local repository to key the
+// trust cache with, see tuf.SigstoreNoCache.
+func tufCacheDisabled() bool {
+	noCache, _ := strconv.ParseBool(os.Getenv(tuf.SigstoreNoCache))
+	return noCache
+}
+
+// checkTUFNetwork returns an error wrapping netguard.ErrNetworkDisabled if
+// the network is disabled in ctx and TUF would update the local TUF
+// repository, missing or expired. TUF creates its own HTTP client, sending
+// requests without a context, so it can't be given a guarded transport.
+func checkTUFNetwork(ctx context.Context) error {
+	if !netguard.Disabled(ctx) {
+		return nil
+	}
+	if tufCacheDisabled() {
+		return fmt.Errorf("%w, TUF can't keep a local repository with %s", netguard.ErrNetworkDisabled, tuf.SigstoreNoCache)
+	}
+	st, err := localTUFState()
+	if err != nil {
+		return fmt.Errorf("%w, TUF would update the local repository: %v", netguard.ErrNetworkDisabled, err)
+	}
+	if !time.Now().Before(st.expires) {
+		return fmt.Errorf("%w, TUF would update the local metadata, expired at %s", netguard.ErrNetworkDisabled, st.expires.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// cachedTrustMaterial returns the trust material cached in dir for the
+// current TUF root, if the TUF metadata have not expired and each of its
+// targets matches them.
+func cachedTrustMaterial(ctx context.Context, dir string) (*trustMaterial, bool) {
+	st, err := localTUFState()
+	if err != nil || !time.Now().Before(st.expires) {
+		return nil, false
+	}
+	path := filepath.Join(dir, st.rootDigest+".json")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	tm := &trustMaterial{expires: st.expires}
+	if err := json.Unmarshal(b, tm); err != nil {
+		return nil, false
+	}
+	for _, targets := range [][]cachedTarget{tm.Rekor, tm.CTFE, tm.Fulcio} {
+		for i := range targets {
+			t := &targets[i]
+			if err := st.checkTarget(t.Name, t.Target); err != nil {
+				ui.Warnf(ctx, "Ignoring the trust cache %s: %v", path, err)
+				return nil, false
+			}
+			if t.Status, err = st.targetStatus(t.Name); err != nil {
+				ui.Warnf(ctx, "Ignoring the trust cache %s: %v", path, err)
+				return nil, false
+			}
+		}
+	}
+	return tm, true
+}
+
+// loadTrustMaterial returns the trust material cached in dir for the current
+// TUF root or, if there is none, it has expired, doesn't match the TUF
+// metadata or refresh is set, fetches it from TUF and caches it. TUF itself
+// updates its metadata once they expire.
+func loadTrustMaterial(ctx context.Context, dir string, refresh bool) (*trustMaterial, error) {
+	if tufCacheDisabled() {
+		return nil, fmt.Errorf("the trust cache needs the local TUF repository, disabled by %s", tuf.SigstoreNoCache)
+	}
+	if !refresh {
+		if tm, ok := cachedTrustMaterial(ctx, dir); ok {
+			return tm, nil
+		}
+	}
+
+	if err := checkTUFNetwork(ctx); err != nil {
+		return nil, err
+	}
+	tufClient, err := tuf.NewFromEnv(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("initializing TUF: %w", err)
+	}
+	st, err := localTUFState()
+	if err != nil {
+		return nil, err
+	}
+	tm := &trustMaterial{expires: st.expires}
+	for _, m := range []struct {
+		usage   tuf.UsageKind
+		targets []string
+		dst     *[]cachedTarget
+	}{
+		{tuf.Rekor, rekorTargets, &tm.Rekor},
+		{tuf.CTFE, ctfeTargets, &tm.CTFE},
+		{tuf.Fulcio, fulcioTargets, &tm.Fulcio},
+	} {
+		targets, err := tufClient.GetTargetsByMeta(m.usage, m.targets)
+		if err != nil {
+			return nil, fmt.Errorf("getting the %s targets: %w", m.usage, err)
+		}
+		for _, t := range targets {
+			name, err := st.targetName(t.Target)
+			if err != nil {
+				return nil, fmt.Errorf("getting the %s targets: %w", m.usage, err)
+			}
+			*m.dst = append(*m.dst, cachedTarget{Name: name, Target: t.Target, Status: t.Status})
+		}
+	}
+
+	if err := saveTrustMaterial(filepath.Join(dir, st.rootDigest+".json"), tm); err != nil {
+		return nil, fmt.Errorf("caching the trust material: %w", err)
+	}
+	return tm, nil
+}
+
+// saveTrustMaterial writes the trust material to path, replacing the file
+// at once so that concurrent invocations never read a partial file.
+func saveTrustMaterial(path string, tm *trustMaterial) error {
+	b, err := json.Marshal(tm)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".trust-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// rekorPubKeys returns the Rekor public keys of the trust material, or
+// fetches them with cosign.GetRekorPubs if tm is nil or they are overridden
+// by the environment. Fetching them from TUF is subject to checkTUFNetwork.
+func (tm *trustMaterial) rekorPubKeys(ctx context.Context) (*cosign.TrustedTransparencyLogPubKeys, error) {
+	if env.Getenv(env.VariableSigstoreRekorPublicKey) != "" {
+		return cosign.GetRekorPubs(ctx)
+	}
+	if tm == nil {
+		if err := checkTUFNetwork(ctx); err != nil {
+			return nil, err
+		}
+		return cosign.GetRekorPubs(ctx)
+	}
+	return transparencyLogPubKeys(tm.Rekor, "Rekor")
+}
+
+// ctLogPubKeys returns the CT log public keys of the trust material, or
+// fetches them with cosign.GetCTLogPubs, see rekorPubKeys.
+func (tm *trustMaterial) ctLogPubKeys(ctx context.Context) (*cosign.TrustedTransparencyLogPubKeys, error) {
+	if env.Getenv(env.VariableSigstoreCTLogPublicKeyFile) != "" {
+		return cosign.GetCTLogPubs(ctx)
+	}
+	if tm == nil {
+		if err := checkTUFNetwork(ctx); err != nil {
+			return nil, err
+		}
+		return cosign.GetCTLogPubs(ctx)
+	}
+	return transparencyLogPubKeys(tm.CTFE, "CTLog")
+}
+
+func transparencyLogPubKeys(targets []cachedTarget, log string) (*cosign.TrustedTransparencyLogPubKeys, error) {
+	publicKeys := cosign.NewTrustedTransparencyLogPubKeys()
+	for _, t := range targets {
+		if err := publicKeys.AddTransparencyLogPubKey(t.Target, t.Status); err != nil {
+			return nil, fmt.Errorf("adding the %s public key: %w", log, err)
+		}
+	}
+	if len(publicKeys.Keys) == 0 {
+		return nil, fmt.Errorf("none of the %s public keys have been found", log)
+	}
+	return &publicKeys, nil
+}
+
+// fulcioCerts returns the Fulcio roots and intermediates of the trust
+// material, or fetches them with fulcio.GetRoots and fulcio.GetIntermediates,
+// see rekorPubKeys.
+func (tm *trustMaterial) fulcioCerts(ctx context.Context) (*x509.CertPool, *x509.CertPool, error) {
+	overridden := env.Getenv(env.VariableSigstoreRootFile) != ""
+	if tm == nil && !overridden {
+		if err := checkTUFNetwork(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	if tm == nil || overridden {
+		roots, err := fulcio.GetRoots()
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting Fulcio roots: %w", err)
+		}
+		intermediates, err := fulcio.GetIntermediates()
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting Fulcio intermediates: %w", err)
+		}
+		return roots, intermediates, nil
+	}
+	if len(tm.Fulcio) == 0 {
+		return nil, nil, errors.New("none of the Fulcio roots have been found")
+	}
+	roots := x509.NewCertPool()
+	// intermediates is nil if there are none, like with fulcio.GetIntermediates.
+	var intermediates *x509.CertPool
+	for _, t := range tm.Fulcio {
+		certs, err := cryptoutils.UnmarshalCertificatesFromPEM(t.Target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing the Fulcio certificates: %w", err)
+		}
+		for _, cert := range certs {
+			// Root certificates are self-signed.
+			if bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+				roots.AddCert(cert)
+				continue
+			}
+			if intermediates == nil {
+				intermediates = x509.NewCertPool()
+			}
+			intermediates.AddCert(cert)
+		}
+	}
+	return roots, intermediates, nil
+}
@@ -0,0 +1,272 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	cosignenv "github.com/sigstore/cosign/v2/pkg/cosign/env"
+	"github.com/sigstore/cosign/v2/test"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/tuf"
+	tufleveldbstore "github.com/theupdateframework/go-tuf/client/leveldbstore"
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+// tufTestTarget is a target of the targets metadata written by writeTUFRoot.
+type tufTestTarget struct {
+	name    string
+	content []byte
+	status  tuf.StatusKind
+}
+
+// writeTUFRoot creates a local TUF repository in a new TUF_ROOT whose
+// timestamp and targets metadata expire at expires, with the given targets,
+// returning the digest of its root.
+func writeTUFRoot(t *testing.T, rootVersion int, expires time.Time, targets ...tufTestTarget) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(tuf.TufRootEnv, dir)
+	store, err := tufleveldbstore.FileLocalStore(filepath.Join(dir, "tuf.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	files := data.TargetFiles{}
+	for _, target := range targets {
+		digest := sha256.Sum256(target.content)
+		custom := json.RawMessage(fmt.Sprintf(`{"sigstore":{"status":%q}}`, target.status))
+		files[target.name] = data.TargetFileMeta{
+			FileMeta: data.FileMeta{Length: int64(len(target.content)), Hashes: data.Hashes{"sha256": digest[:]}},
+			Custom:   &custom,
+		}
+	}
+	var rootDigest string
+	for _, role := range []string{"root", "timestamp", "targets"} {
+		// Only the fields read from the local metadata are set.
+		md := &data.Targets{
+			Type:    role,
+			Version: int64(rootVersion),
+			Expires: expires.UTC().Truncate(time.Second),
+		}
+		if role == "targets" {
+			md.Targets = files
+		}
+		signed, err := json.Marshal(md)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(&data.Signed{Signed: signed, Signatures: []data.Signature{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetMeta(role+".json", b); err != nil {
+			t.Fatal(err)
+		}
+		if role == "root" {
+			digest := sha256.Sum256(b)
+			rootDigest = hex.EncodeToString(digest[:])
+		}
+	}
+	return rootDigest
+}
+
+func TestCachedTrustMaterial(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorPEM, err := cryptoutils.MarshalPublicKeyToPEM(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, _, err := test.GenerateRootCa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootPEM, err := cryptoutils.MarshalCertificateToPEM(rootCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPEM, err := cryptoutils.MarshalPublicKeyToPEM(other.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := []tufTestTarget{
+		{name: "rekor.pub", content: rekorPEM, status: tuf.Active},
+		{name: "ctfe.pub", content: rekorPEM, status: tuf.Expired},
+		{name: "fulcio.crt.pem", content: rootPEM, status: tuf.Active},
+	}
+	material := trustMaterial{
+		Rekor:  []cachedTarget{{Name: "rekor.pub", Target: rekorPEM}},
+		CTFE:   []cachedTarget{{Name: "ctfe.pub", Target: rekorPEM}},
+		Fulcio: []cachedTarget{{Name: "fulcio.crt.pem", Target: rootPEM}},
+	}
+
+	tests := []struct {
+		description string
+		// rootVersion is the version of the TUF root the material is cached
+		// for, 0 for no local TUF repository.
+		rootVersion int
+		tufExpires  time.Time
+		// tamper modifies the cached material.
+		tamper func(tm *trustMaterial)
+		want   bool
+	}{
+		{
+			description: "valid",
+			rootVersion: 1,
+			tufExpires:  time.Now().Add(time.Hour),
+			want:        true,
+		}, {
+			description: "expired TUF metadata",
+			rootVersion: 1,
+			tufExpires:  time.Now().Add(-time.Hour),
+		}, {
+			description: "other root",
+			rootVersion: 2,
+			tufExpires:  time.Now().Add(time.Hour),
+		}, {
+			description: "no TUF repository",
+			tufExpires:  time.Now().Add(time.Hour),
+		}, {
+			description: "tampered target",
+			rootVersion: 1,
+			tufExpires:  time.Now().Add(time.Hour),
+			tamper: func(tm *trustMaterial) {
+				tm.Rekor = []cachedTarget{{Name: "rekor.pub", Target: otherPEM}}
+			},
+		}, {
+			description: "renamed target",
+			rootVersion: 1,
+			tufExpires:  time.Now().Add(time.Hour),
+			tamper: func(tm *trustMaterial) {
+				tm.Fulcio = []cachedTarget{{Name: "rekor.pub", Target: rootPEM}}
+			},
+		}, {
+			description: "target not in the TUF metadata",
+			rootVersion: 1,
+			tufExpires:  time.Now().Add(time.Hour),
+			tamper: func(tm *trustMaterial) {
+				tm.Rekor = append(tm.Rekor, cachedTarget{Name: "other.pub", Target: otherPEM})
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			digest := writeTUFRoot(t, 1, test.tufExpires, targets...)
+			cached := material
+			if test.tamper != nil {
+				test.tamper(&cached)
+			}
+			if err := saveTrustMaterial(filepath.Join(cacheDir, digest+".json"), &cached); err != nil {
+				t.Fatal(err)
+			}
+			switch test.rootVersion {
+			case 0:
+				t.Setenv(tuf.TufRootEnv, t.TempDir())
+			case 1:
+			default:
+				writeTUFRoot(t, test.rootVersion, test.tufExpires, targets...)
+			}
+
+			tm, ok := cachedTrustMaterial(context.Background(), cacheDir)
+			if ok != test.want {
+				t.Fatalf("cachedTrustMaterial() = %t, want %t", ok, test.want)
+			}
+			if !ok {
+				return
+			}
+			rekorKeys, err := transparencyLogPubKeys(tm.Rekor, "Rekor")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctKeys, err := transparencyLogPubKeys(tm.CTFE, "CTLog")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range ctKeys.Keys {
+				if k.Status != tuf.Expired {
+					t.Errorf("CT log key status = %s, want %s", k.Status, tuf.Expired)
+				}
+			}
+			if len(rekorKeys.Keys) != 1 || len(ctKeys.Keys) != 1 {
+				t.Errorf("got %d Rekor and %d CT log keys, want 1 of each", len(rekorKeys.Keys), len(ctKeys.Keys))
+			}
+			roots, intermediates, err := tm.fulcioCerts(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if intermediates != nil {
+				t.Errorf("fulcioCerts() returned intermediates for a root")
+			}
+			if _, err := rootCert.Verify(x509.VerifyOptions{Roots: roots}); err != nil {
+				t.Errorf("the cached Fulcio root is not trusted: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyBlobAttestationTrustCache(t *testing.T) {
+	ctx := context.Background()
+	td := t.TempDir()
+	blobPath := writeBlobFile(t, td, blobContents, "blob")
+	att := signTestAttestation(t, td, testStatement("customFoo", sha256Subject("blob", blobContents)))
+
+	cmd := VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: att.keyPath},
+		SignaturePath: att.sigPath,
+		PredicateType: "customFoo",
+		CheckClaims:   true,
+		IgnoreTlog:    true,
+		BlobAttestationTlogChecks: BlobAttestationTlogChecks{
+			RefreshTrust: true,
+		},
+	}
+	// The cached material is used without fetching it from TUF, which has no
+	// repository to fetch it from here.
+	t.Setenv(string(cosignenv.VariableSigstoreCTLogPublicKeyFile), "")
+	cmd.RefreshTrust = false
+	cmd.TrustCacheDir = filepath.Join(td, "trust")
+	digest := writeTUFRoot(t, 1, time.Now().Add(24*time.Hour),
+		tufTestTarget{name: "rekor.pub", content: att.pubPEM, status: tuf.Active},
+		tufTestTarget{name: "ctfe.pub", content: att.pubPEM, status: tuf.Active})
+	if err := saveTrustMaterial(filepath.Join(cmd.TrustCacheDir, digest+".json"), &trustMaterial{
+		Rekor: []cachedTarget{{Name: "rekor.pub", Target: att.pubPEM}},
+		CTFE:  []cachedTarget{{Name: "ctfe.pub", Target: att.pubPEM}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Exec(ctx, blobPath); err != nil {
+		t.Fatalf("Exec() = %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+// Statement returns the in-toto statement of the verified attestation, whose
+// predicate can be read into a Go struct with AsSLSAProvenance,
+// AsSLSAProvenanceV02 or AsSPDX.
+func (v *VerifiedBlobAttestation) Statement() (*in_toto.Statement, error) {
+	if v == nil || v.signature == nil {
+		return nil, errors.New("no verified attestation")
+	}
+	return statementFromAttestation(v.signature)
+}
+
+// AsSLSAProvenance returns the predicate of st, which must be a SLSA v1
+// provenance.
+func AsSLSAProvenance(st *in_toto.Statement) (*slsa1.ProvenancePredicate, error) {
+	p := &slsa1.ProvenancePredicate{}
+	if err := predicateAs(st, slsa1.PredicateSLSAProvenance, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// AsSLSAProvenanceV02 returns the predicate of st, which must be a SLSA v0.2
+// provenance.
+func AsSLSAProvenanceV02(st *in_toto.Statement) (*slsa02.ProvenancePredicate, error) {
+	p := &slsa02.ProvenancePredicate{}
+	if err := predicateAs(st, slsa02.PredicateSLSAProvenance, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SPDXDocument is the part of an SPDX JSON document read by AsSPDX.
+type SPDXDocument struct {
+	SPDXVersion       string                     `json:"spdxVersion"`
+	DataLicense       string                     `json:"dataLicense,omitempty"`
+	SPDXID            string                     `json:"SPDXID"`
+	Name              string                     `json:"name"`
+	DocumentNamespace string                     `json:"documentNamespace,omitempty"`
+	CreationInfo      SPDXCreationInfo           `json:"creationInfo"`
+	DocumentDescribes []string                   `json:"documentDescribes,omitempty"`
+	Packages          []SPDXPackage              `json:"packages,omitempty"`
+	Relationships     []SPDXDocumentRelationship `json:"relationships,omitempty"`
+}
+
+// SPDXCreationInfo is the creation information of an SPDX document.
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// SPDXPackage is a package of an SPDX document.
+type SPDXPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	Supplier         string            `json:"supplier,omitempty"`
+	DownloadLocation string            `json:"downloadLocation,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded,omitempty"`
+	LicenseDeclared  string            `json:"licenseDeclared,omitempty"`
+	Checksums        []SPDXChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []SPDXExternalRef `json:"externalRefs,omitempty"`
+}
+
+// SPDXChecksum is a checksum of an SPDX package, e.g. of algorithm SHA256.
+type SPDXChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+// SPDXExternalRef is an external reference of an SPDX package, e.g. a purl.
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+// SPDXDocumentRelationship is a relationship of an SPDX document, as
+// written in it, see SPDXGraph for the normalized graph.
+type SPDXDocumentRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// AsSPDX returns the predicate of st, which must be an SPDX JSON document.
+// SPDX tag-value documents aren't supported.
+func AsSPDX(st *in_toto.Statement) (*SPDXDocument, error) {
+	if st != nil {
+		if _, ok := st.Predicate.(string); ok && st.PredicateType == in_toto.PredicateSPDX {
+			return nil, errors.New("the predicate is an SPDX tag-value document, only SPDX JSON documents are supported")
+		}
+	}
+	doc := &SPDXDocument{}
+	if err := predicateAs(st, in_toto.PredicateSPDX, doc); err != nil {
+		return nil, err
+	}
+	if doc.SPDXVersion == "" {
+		return nil, errors.New("not an SPDX document, spdxVersion is missing")
+	}
+	return doc, nil
+}
+
+// predicateAs reads the predicate of st, which must be of predicateType,
+// into v.
+func predicateAs(st *in_toto.Statement, predicateType string, v interface{}) error {
+	if st == nil {
+		return errors.New("no statement provided")
+	}
+	if st.PredicateType != predicateType {
+		return fmt.Errorf("the predicate type is %s, expected %s", st.PredicateType, predicateType)
+	}
+	if err := remarshal(st.Predicate, v); err != nil {
+		return fmt.Errorf("parsing the %s predicate: %w", predicateType, err)
+	}
+	return nil
+}
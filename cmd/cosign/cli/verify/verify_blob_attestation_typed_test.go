@@ -0,0 +1,136 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+func TestTypedPredicates(t *testing.T) {
+	ctx := context.Background()
+	provenance := &slsa1.ProvenancePredicate{
+		BuildDefinition: slsa1.ProvenanceBuildDefinition{BuildType: "https://example.com/build"},
+		RunDetails:      slsa1.ProvenanceRunDetails{Builder: slsa1.Builder{ID: "https://example.com/builder"}},
+	}
+	spdxDoc := &SPDXDocument{
+		SPDXVersion:  "SPDX-2.3",
+		SPDXID:       "SPDXRef-DOCUMENT",
+		Name:         "blob",
+		CreationInfo: SPDXCreationInfo{Created: "2023-01-01T00:00:00Z", Creators: []string{"Tool: test"}},
+		Packages: []SPDXPackage{{
+			SPDXID:      "SPDXRef-pkg",
+			Name:        "pkg",
+			VersionInfo: "1.0",
+			Checksums:   []SPDXChecksum{{Algorithm: "SHA256", ChecksumValue: "abc"}},
+		}},
+		Relationships: []SPDXDocumentRelationship{{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-pkg"}},
+	}
+	asProvenance := func(st *in_toto.Statement) (interface{}, error) { return AsSLSAProvenance(st) }
+	asSPDX := func(st *in_toto.Statement) (interface{}, error) { return AsSPDX(st) }
+
+	tests := []struct {
+		description   string
+		predicateType string
+		predicate     interface{}
+		as            func(*in_toto.Statement) (interface{}, error)
+		want          interface{}
+		wantErr       string
+	}{
+		{
+			description:   "SLSA v1 provenance",
+			predicateType: slsa1.PredicateSLSAProvenance,
+			predicate:     provenance,
+			as:            asProvenance,
+			want:          provenance,
+		}, {
+			description:   "SPDX JSON document",
+			predicateType: in_toto.PredicateSPDX,
+			predicate:     spdxDoc,
+			as:            asSPDX,
+			want:          spdxDoc,
+		}, {
+			description:   "provenance read as SPDX",
+			predicateType: slsa1.PredicateSLSAProvenance,
+			predicate:     provenance,
+			as:            asSPDX,
+			wantErr:       "the predicate type is https://slsa.dev/provenance/v1, expected https://spdx.dev/Document",
+		}, {
+			description:   "SLSA v0.2 provenance read as v1",
+			predicateType: slsa02.PredicateSLSAProvenance,
+			predicate:     map[string]interface{}{},
+			as:            asProvenance,
+			wantErr:       "expected https://slsa.dev/provenance/v1",
+		}, {
+			description:   "SPDX tag-value document",
+			predicateType: in_toto.PredicateSPDX,
+			predicate:     "SPDXVersion: SPDX-2.3",
+			as:            asSPDX,
+			wantErr:       "only SPDX JSON documents are supported",
+		}, {
+			description:   "not an SPDX document",
+			predicateType: in_toto.PredicateSPDX,
+			predicate:     map[string]interface{}{"name": "blob"},
+			as:            asSPDX,
+			wantErr:       "spdxVersion is missing",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			st := testStatement(test.predicateType, sha256Subject("blob", blobContents))
+			st.Predicate = test.predicate
+			envBytes, sv := signTestStatement(t, st)
+			env := &ssldsse.Envelope{}
+			if err := json.Unmarshal(envBytes, env); err != nil {
+				t.Fatal(err)
+			}
+			opts := &VerifyEnvelopeOptions{
+				CheckOpts:     &cosign.CheckOpts{SigVerifier: sv, IgnoreTlog: true},
+				CheckClaims:   true,
+				PredicateType: test.predicateType,
+			}
+			verified, err := VerifyParsedEnvelopeAttestation(ctx, opts, env, strings.NewReader(blobContents))
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifiedSt, err := verified.Statement()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := test.as(verifiedSt)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, expected %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("predicate mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	signatureoptions "github.com/sigstore/sigstore/pkg/signature/options"
+	"sigs.k8s.io/release-utils/version"
+)
+
+const (
+	// PredicateVSA is the predicate type of a SLSA verification summary
+	// attestation.
+	PredicateVSA = "https://slsa.dev/verification_summary/v1"
+	// VSAVerifierID identifies cosign as the verifier of a VSA.
+	VSAVerifierID = "https://github.com/sigstore/cosign"
+)
+
+// vsaPredicate is the predicate of a SLSA verification summary attestation,
+// see https://slsa.dev/spec/v1.0/verification_summary.
+type vsaPredicate struct {
+	Verifier           vsaVerifier             `json:"verifier"`
+	TimeVerified       time.Time               `json:"timeVerified"`
+	ResourceURI        string                  `json:"resourceUri"`
+	Policy             vsaResourceDescriptor   `json:"policy"`
+	InputAttestations  []vsaResourceDescriptor `json:"inputAttestations,omitempty"`
+	VerificationResult string                  `json:"verificationResult"`
+	VerifiedLevels     []string                `json:"verifiedLevels"`
+}
+
+type vsaVerifier struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type vsaResourceDescriptor struct {
+	URI    string           `json:"uri,omitempty"`
+	Digest common.DigestSet `json:"digest,omitempty"`
+}
+
+// newVSA returns a statement that the blob named resource, of digest h, passed
+// the verification of the attestation read from inputURI against policyURI.
+func newVSA(resource string, h v1.Hash, verified *VerifiedBlobAttestation, inputURI, policyURI string) (*in_toto.Statement, error) {
+	if h.Hex == "" {
+		return nil, errors.New("a blob digest is required to issue a VSA")
+	}
+	input := vsaResourceDescriptor{URI: inputURI}
+	if verified.signature != nil {
+		envBytes, err := signedAttestation(verified.signature).Payload()
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(envBytes)
+		input.Digest = common.DigestSet{"sha256": hex.EncodeToString(digest[:])}
+	}
+
+	return &in_toto.Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV01,
+			PredicateType: PredicateVSA,
+			Subject: []in_toto.Subject{{
+				Name:   resource,
+				Digest: common.DigestSet{h.Algorithm: h.Hex},
+			}},
+		},
+		Predicate: vsaPredicate{
+			Verifier: vsaVerifier{
+				ID:      VSAVerifierID,
+				Version: map[string]string{"cosign": version.GetVersionInfo().GitVersion},
+			},
+			TimeVerified:       time.Now().UTC(),
+			ResourceURI:        resource,
+			Policy:             vsaResourceDescriptor{URI: policyURI},
+			InputAttestations:  []vsaResourceDescriptor{input},
+			VerificationResult: "PASSED",
+			VerifiedLevels:     []string{},
+		},
+	}, nil
+}
+
+// saveVSA signs the statement with signer and writes the DSSE envelope to path.
+func saveVSA(ctx context.Context, path string, signer signature.Signer, st *in_toto.Statement) error {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	env, err := dsse.WrapSigner(signer, types.IntotoPayloadType).SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("signing VSA: %w", err)
+	}
+	if err := os.WriteFile(path, env, 0600); err != nil {
+		return fmt.Errorf("create VSA file: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "VSA written in the file", path)
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+)
+
+func TestIssueVSA(t *testing.T) {
+	ctx := context.Background()
+	env, _ := signTestStatement(t, testStatement(in_toto.PredicateCycloneDX, sha256Subject("blob", blobContents)))
+	att, err := static.NewAttestation(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := blobDigest(strings.NewReader(blobContents))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := newVSA("blob", h, &VerifiedBlobAttestation{signature: att}, "att.json", "https://example.com/policy")
+	if err != nil {
+		t.Fatalf("newVSA() = %v", err)
+	}
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vsaPath := filepath.Join(t.TempDir(), "vsa.json")
+	if err := saveVSA(ctx, vsaPath, sv, st); err != nil {
+		t.Fatalf("saveVSA() = %v", err)
+	}
+
+	vsaEnv, err := os.ReadFile(vsaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dsse.WrapVerifier(sv).VerifySignature(bytes.NewReader(vsaEnv), nil); err != nil {
+		t.Fatalf("VSA signature doesn't verify: %v", err)
+	}
+	e := ssldsse.Envelope{}
+	if err := json.Unmarshal(vsaEnv, &e); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		PredicateType string            `json:"predicateType"`
+		Subject       []in_toto.Subject `json:"subject"`
+		Predicate     vsaPredicate      `json:"predicate"`
+	}
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	envDigest := sha256.Sum256(env)
+	if got.PredicateType != PredicateVSA ||
+		len(got.Subject) != 1 || got.Subject[0].Digest["sha256"] != h.Hex ||
+		got.Predicate.VerificationResult != "PASSED" ||
+		got.Predicate.Policy.URI != "https://example.com/policy" ||
+		got.Predicate.Verifier.ID != VSAVerifierID ||
+		len(got.Predicate.InputAttestations) != 1 ||
+		got.Predicate.InputAttestations[0].Digest["sha256"] != hex.EncodeToString(envDigest[:]) {
+		t.Errorf("unexpected VSA %s", payload)
+	}
+
+	if _, err := newVSA("blob", v1.Hash{}, &VerifiedBlobAttestation{signature: att}, "att.json", ""); err == nil {
+		t.Error("newVSA() expected an error without a blob digest")
+	}
+}
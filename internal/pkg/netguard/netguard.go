@@ -0,0 +1,120 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package netguard rejects the network connections made for a context in
+// which the network is disabled, so that an invocation meant to be offline
+// fails loudly rather than reaching out.
+//
+// The network is only disabled in a context returned by WithoutNetwork.
+// Importing the package changes nothing: a command guards the dialers of the
+// default transports of net/http and go-containerregistry by calling Install,
+// which then reject the connections dialed with such a context. The clients
+// that don't pass the context to their requests must be given a Transport
+// bound to it instead, and the clients with their own transports that can't
+// be replaced, e.g. of KMS, must be rejected by the command.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// ErrNetworkDisabled is the error of a connection attempted in a context in
+// which the network is disabled.
+var ErrNetworkDisabled = errors.New("network access is disabled")
+
+type disabledKey struct{}
+
+// WithoutNetwork returns a copy of ctx in which the network is disabled.
+func WithoutNetwork(ctx context.Context) context.Context {
+	return context.WithValue(ctx, disabledKey{}, true)
+}
+
+// Disabled reports whether the network is disabled in ctx.
+func Disabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(disabledKey{}).(bool)
+	return disabled
+}
+
+// Check returns an error wrapping ErrNetworkDisabled if the network is
+// disabled in ctx, for the clients connecting to addr without a guarded
+// dialer.
+func Check(ctx context.Context, addr string) error {
+	if Disabled(ctx) {
+		return fmt.Errorf("%w, refusing to connect to %s", ErrNetworkDisabled, addr)
+	}
+	return nil
+}
+
+// DialFunc is the signature of http.Transport.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Dialer returns dial, or a net.Dialer if nil, rejecting the connections of
+// contexts in which the network is disabled.
+func Dialer(dial DialFunc) DialFunc {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if err := Check(ctx, addr); err != nil {
+			return nil, err
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// Transport returns a transport rejecting all the requests, whatever their
+// context, if the network is disabled in ctx, and passing them to base, or
+// http.DefaultTransport if nil, otherwise. It guards the clients that don't
+// pass the context to their requests.
+func Transport(ctx context.Context, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := Check(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+		return base.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var installOnce sync.Once
+
+// Install guards the dialers of the default transports of net/http and
+// go-containerregistry, and of the transports cloned from them afterwards,
+// so that they reject the connections of contexts in which the network is
+// disabled. It can be called more than once.
+func Install() {
+	installOnce.Do(func() {
+		for _, rt := range []http.RoundTripper{http.DefaultTransport, remote.DefaultTransport} {
+			if t, ok := rt.(*http.Transport); ok {
+				t.DialContext = Dialer(t.DialContext)
+			}
+		}
+	})
+}
@@ -0,0 +1,82 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInstall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	// Importing the package doesn't guard the default transport.
+	req, err := http.NewRequestWithContext(WithoutNetwork(context.Background()), http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do() before Install() = %v", err)
+	}
+	resp.Body.Close()
+
+	// Once installed, only the requests of a context without network are
+	// rejected.
+	Install()
+	srv.CloseClientConnections()
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	if _, err := http.DefaultClient.Do(req); !errors.Is(err, ErrNetworkDisabled) {
+		t.Errorf("Do() after Install() = %v, expected %v", err, ErrNetworkDisabled)
+	}
+	resp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() after Install() = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	// The requests are rejected by the context of the transport, whatever
+	// their own.
+	client := &http.Client{Transport: Transport(WithoutNetwork(context.Background()), nil)}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrNetworkDisabled) {
+		t.Errorf("Get() = %v, expected %v", err, ErrNetworkDisabled)
+	}
+
+	client = &http.Client{Transport: Transport(context.Background(), nil)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(context.Background(), "example.com:53"); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+	if err := Check(WithoutNetwork(context.Background()), "example.com:53"); !errors.Is(err, ErrNetworkDisabled) {
+		t.Errorf("Check() = %v, expected %v", err, ErrNetworkDisabled)
+	}
+}
@@ -0,0 +1,141 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing creates OpenTelemetry spans for the phases of a
+// verification. Spans are created with the tracer provider of the span in the
+// context, so they are no-ops unless the caller started a span with a
+// configured provider.
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/sigstore/cosign/v2"
+
+// Start starts a span named name, a child of the span in ctx. Its duration
+// is added to the Timings of ctx, if any, when it ends.
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	ctx, span := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName).Start(ctx, name)
+	if t, ok := ctx.Value(timingsKey{}).(*Timings); ok {
+		return ctx, &timedSpan{Span: span, timings: t, name: name, start: time.Now()}
+	}
+	return ctx, span
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Phases traces the sequential phases of an operation, each phase's span
+// ending when the next one starts.
+type Phases struct {
+	ctx  context.Context
+	span trace.Span
+
+	name    string
+	start   time.Time
+	observe func(name string, elapsed time.Duration)
+}
+
+// NewPhases returns phases whose spans are children of the span in ctx.
+func NewPhases(ctx context.Context) *Phases {
+	return &Phases{ctx: ctx}
+}
+
+// Observe calls f with the name and duration of each phase when it ends.
+func (p *Phases) Observe(f func(name string, elapsed time.Duration)) {
+	p.observe = f
+}
+
+// Next ends the current phase and starts the named one, returning its
+// context.
+func (p *Phases) Next(name string) context.Context {
+	p.End(nil)
+	var ctx context.Context
+	ctx, p.span = Start(p.ctx, name)
+	p.name, p.start = name, time.Now()
+	return ctx
+}
+
+// End ends the current phase, recording err on it.
+func (p *Phases) End(err error) {
+	if p.span == nil {
+		return
+	}
+	End(p.span, err)
+	p.span = nil
+	if p.observe != nil {
+		p.observe(p.name, time.Since(p.start))
+	}
+}
+
+// Timings sums the durations of the spans started with Start, by name,
+// whether or not the spans are exported.
+type Timings struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+}
+
+type timingsKey struct{}
+
+// WithTimings returns a context in which the durations of the spans started
+// with Start are added to t.
+func WithTimings(ctx context.Context, t *Timings) context.Context {
+	return context.WithValue(ctx, timingsKey{}, t)
+}
+
+// Durations returns the summed durations of the ended spans, by name.
+func (t *Timings) Durations() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	durations := make(map[string]time.Duration, len(t.durations))
+	for name, d := range t.durations {
+		durations[name] = d
+	}
+	return durations
+}
+
+func (t *Timings) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.durations == nil {
+		t.durations = map[string]time.Duration{}
+	}
+	t.durations[name] += d
+}
+
+// timedSpan is a span adding its duration to timings when it ends.
+type timedSpan struct {
+	trace.Span
+	timings *Timings
+	name    string
+	start   time.Time
+}
+
+func (s *timedSpan) End(options ...trace.SpanEndOption) {
+	s.Span.End(options...)
+	s.timings.add(s.name, time.Since(s.start))
+}
@@ -16,6 +16,7 @@ package ui
 import (
 	"context"
 	"fmt"
+	"sync"
 )
 
 func (w *Env) infof(msg string, a ...any) {
@@ -38,4 +39,44 @@ func (w *Env) warnf(msg string, a ...any) {
 // fmt.Printf, except that it always has a trailing newline.
 func Warnf(ctx context.Context, msg string, a ...any) {
 	getEnv(ctx).warnf(msg, a...)
+	if w, ok := ctx.Value(ctxKeyWarnings).(*warningLog); ok {
+		w.mu.Lock()
+		w.msgs = append(w.msgs, fmt.Sprintf(msg, a...))
+		w.mu.Unlock()
+	}
+}
+
+type warningsKey struct{}
+
+func (c warningsKey) String() string {
+	return "cosign/ui:warnings"
+}
+
+var ctxKeyWarnings = warningsKey{}
+
+type warningLog struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+// RecordWarnings returns a context recording the warnings logged with Warnf,
+// in addition to writing them. If ctx already records warnings, it is
+// returned as is.
+func RecordWarnings(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(ctxKeyWarnings).(*warningLog); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKeyWarnings, &warningLog{})
+}
+
+// RecordedWarnings returns the warnings logged so far in ctx, if it records
+// them (see RecordWarnings).
+func RecordedWarnings(ctx context.Context) []string {
+	w, ok := ctx.Value(ctxKeyWarnings).(*warningLog)
+	if !ok {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.msgs...)
 }
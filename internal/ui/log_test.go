@@ -55,3 +55,19 @@ func TestWarnf(t *testing.T) {
 		assert.Equal(t, tc.expected, stderr, "Bad output to STDERR")
 	}
 }
+
+func TestRecordWarnings(t *testing.T) {
+	stderr := ui.RunWithTestCtx(func(ctx context.Context, write ui.WriteFunc) {
+		ui.Warnf(ctx, "not recorded")
+		assert.Empty(t, ui.RecordedWarnings(ctx))
+
+		ctx = ui.RecordWarnings(ctx)
+		ui.Warnf(ctx, "foo: %v", "bar")
+		// Recording again keeps the record.
+		inner := ui.RecordWarnings(ctx)
+		ui.Warnf(inner, "baz")
+		ui.Infof(inner, "not a warning")
+		assert.Equal(t, []string{"foo: bar", "baz"}, ui.RecordedWarnings(ctx))
+	})
+	assert.Equal(t, "WARNING: not recorded\nWARNING: foo: bar\nWARNING: baz\nnot a warning\n", stderr, "Bad output to STDERR")
+}
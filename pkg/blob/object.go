@@ -0,0 +1,131 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+const (
+	// S3Scheme is the scheme of Amazon S3 object references, s3://bucket/key.
+	S3Scheme = "s3://"
+	// GCSScheme is the scheme of Google Cloud Storage object references,
+	// gs://bucket/object.
+	GCSScheme = "gs://"
+)
+
+var (
+	// ErrObjectNotFound is returned when the bucket or object does not exist.
+	ErrObjectNotFound = errors.New("object not found")
+	// ErrObjectAccessDenied is returned when the ambient credentials are not
+	// allowed to read the object.
+	ErrObjectAccessDenied = errors.New("access denied")
+)
+
+// Client options, overridden in tests to talk to a fake server.
+var (
+	s3Options  []func(*s3.Options)
+	gcsOptions []option.ClientOption
+)
+
+// IsObjectReference reports whether ref is an S3 or GCS object reference.
+func IsObjectReference(ref string) bool {
+	return strings.HasPrefix(ref, S3Scheme) || strings.HasPrefix(ref, GCSScheme)
+}
+
+// LoadObject fetches an s3://bucket/key or gs://bucket/object reference with
+// the ambient credentials of the cloud SDKs. Errors wrap ErrObjectNotFound or
+// ErrObjectAccessDenied when the storage service reports them.
+func LoadObject(ctx context.Context, ref string) ([]byte, error) {
+	var load func(context.Context, string, string) ([]byte, error)
+	var rest string
+	switch {
+	case strings.HasPrefix(ref, S3Scheme):
+		load, rest = loadS3Object, strings.TrimPrefix(ref, S3Scheme)
+	case strings.HasPrefix(ref, GCSScheme):
+		load, rest = loadGCSObject, strings.TrimPrefix(ref, GCSScheme)
+	default:
+		return nil, fmt.Errorf("%s is not an s3:// or gs:// reference", ref)
+	}
+	bucket, key, ok := strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid object reference %s, expected <scheme>://<bucket>/<object>", ref)
+	}
+	b, err := load(ctx, bucket, key)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", ref, err)
+	}
+	return b, nil
+}
+
+func loadS3Object(ctx context.Context, bucket, key string) ([]byte, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+	out, err := s3.NewFromConfig(cfg, s3Options...).GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var re *awshttp.ResponseError
+		if errors.As(err, &re) {
+			switch re.HTTPStatusCode() {
+			case http.StatusNotFound:
+				return nil, fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+			case http.StatusForbidden:
+				return nil, fmt.Errorf("%w: %v", ErrObjectAccessDenied, err)
+			}
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func loadGCSObject(ctx context.Context, bucket, object string) ([]byte, error) {
+	client, err := storage.NewClient(ctx, gcsOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("creating GCS client: %w", err)
+	}
+	defer client.Close()
+	r, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		var gerr *googleapi.Error
+		switch {
+		case errors.Is(err, storage.ErrObjectNotExist), errors.Is(err, storage.ErrBucketNotExist):
+			return nil, fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+		case errors.As(err, &gerr) && gerr.Code == http.StatusForbidden:
+			return nil, fmt.Errorf("%w: %v", ErrObjectAccessDenied, err)
+		}
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,97 @@
+//
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"google.golang.org/api/option"
+)
+
+// fakeObjectServer serves "data" for any path ending in /bucket/object, a 403
+// for /bucket/denied, and a 404 otherwise.
+func fakeObjectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/bucket/object"):
+			rw.Write([]byte("data"))
+		case strings.HasSuffix(req.URL.Path, "/bucket/denied"):
+			rw.WriteHeader(http.StatusForbidden)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLoadObject(t *testing.T) {
+	server := fakeObjectServer(t)
+	t.Setenv("AWS_REGION", "us-east-1")
+	s3Options = []func(*s3.Options){func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(server.URL)
+		o.UsePathStyle = true
+		o.Credentials = aws.AnonymousCredentials{}
+	}}
+	gcsOptions = []option.ClientOption{option.WithEndpoint(server.URL), option.WithoutAuthentication()}
+	t.Cleanup(func() {
+		s3Options = nil
+		gcsOptions = nil
+	})
+
+	for _, scheme := range []string{S3Scheme, GCSScheme} {
+		t.Run(scheme, func(t *testing.T) {
+			ctx := context.Background()
+			actual, err := LoadObject(ctx, scheme+"bucket/object")
+			if err != nil {
+				t.Fatalf("LoadObject() = %v", err)
+			}
+			if !bytes.Equal(actual, []byte("data")) {
+				t.Errorf("LoadObject() = '%s'; want 'data'", actual)
+			}
+
+			if _, err := LoadObject(ctx, scheme+"bucket/missing"); !errors.Is(err, ErrObjectNotFound) {
+				t.Errorf("LoadObject(missing) = %v, want ErrObjectNotFound", err)
+			}
+			if _, err := LoadObject(ctx, scheme+"bucket/denied"); !errors.Is(err, ErrObjectAccessDenied) {
+				t.Errorf("LoadObject(denied) = %v, want ErrObjectAccessDenied", err)
+			}
+
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			if _, err := LoadObject(cancelled, scheme+"bucket/object"); !errors.Is(err, context.Canceled) {
+				t.Errorf("LoadObject(cancelled) = %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+func TestLoadObjectInvalidReference(t *testing.T) {
+	for _, ref := range []string{"s3://bucket", "gs:///object", "s3://bucket/", "https://example.com/object"} {
+		if _, err := LoadObject(context.Background(), ref); err == nil {
+			t.Errorf("LoadObject(%q) expected an error", ref)
+		}
+	}
+}
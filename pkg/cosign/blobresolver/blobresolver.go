@@ -0,0 +1,206 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package blobresolver lets cosign fetch the content of a blob from an
+// external program, e.g. out of a content-addressed store, given an
+// identifier of the content.
+//
+// A resolver plugin is an executable that cosign invokes with the following
+// subcommand appended to its arguments:
+//
+//	resolve
+//	    Reads the content ID, followed by a newline, from stdin and writes
+//	    the content to stdout. The plugin must exit with status 0 once the
+//	    whole content is written, with NotFoundExitCode if it doesn't know
+//	    the content ID, and with another non-zero status on any other
+//	    failure. Anything written to stderr is included in the error.
+//
+// The content is streamed: cosign hashes it as it is written, and a plugin
+// exiting with a non-zero status after writing part of it fails the
+// resolution. The content isn't trusted, it is checked against the digests
+// of the attestation like a local blob.
+package blobresolver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// ResolveCommand is the subcommand used to resolve a content ID.
+	ResolveCommand = "resolve"
+	// NotFoundExitCode is the exit status of a plugin not knowing the
+	// content ID.
+	NotFoundExitCode = 2
+)
+
+// ErrNotFound is returned when the resolver doesn't know the content ID.
+var ErrNotFound = errors.New("content not found")
+
+// Resolver returns the content of a content ID.
+type Resolver interface {
+	Resolve(ctx context.Context, contentID string) (io.ReadCloser, error)
+}
+
+// ExecResolver is a Resolver backed by a plugin executable.
+type ExecResolver struct {
+	path string
+	args []string
+}
+
+var _ Resolver = (*ExecResolver)(nil)
+
+// NewExecResolver returns a resolver running the given command line. The
+// command is split on whitespace, the first field being the executable.
+func NewExecResolver(command string) (*ExecResolver, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("empty blob resolver command")
+	}
+	return &ExecResolver{path: fields[0], args: fields[1:]}, nil
+}
+
+// Resolve starts the plugin and returns its stdout. Reading it to the end
+// returns the error of the plugin, if any, rather than io.EOF. Closing it
+// before the end stops the plugin.
+func (r *ExecResolver) Resolve(ctx context.Context, contentID string) (io.ReadCloser, error) {
+	if contentID == "" || strings.ContainsAny(contentID, "\r\n") {
+		return nil, fmt.Errorf("invalid content ID %q", contentID)
+	}
+	args := append(append([]string{}, r.args...), ResolveCommand)
+	cmd := exec.CommandContext(ctx, r.path, args...) // #nosec G204
+	cmd.Stdin = strings.NewReader(contentID + "\n")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	rc := &pluginOutput{cmd: cmd, stdout: stdout, contentID: contentID}
+	cmd.Stderr = &rc.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("blob resolver: %w", err)
+	}
+	return rc, nil
+}
+
+// pluginOutput is the stdout of a running plugin.
+type pluginOutput struct {
+	cmd       *exec.Cmd
+	stdout    io.ReadCloser
+	stderr    bytes.Buffer
+	contentID string
+
+	once    sync.Once
+	waitErr error
+}
+
+func (o *pluginOutput) Read(p []byte) (int, error) {
+	n, err := o.stdout.Read(p)
+	if errors.Is(err, io.EOF) {
+		if werr := o.wait(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (o *pluginOutput) Close() error {
+	o.once.Do(func() {
+		// The content wasn't read to the end, stop the plugin.
+		_ = o.cmd.Process.Kill()
+		_ = o.cmd.Wait()
+	})
+	return nil
+}
+
+// wait waits for the plugin to exit, once its stdout is read to the end.
+func (o *pluginOutput) wait() error {
+	o.once.Do(func() {
+		err := o.cmd.Wait()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+		case errors.As(err, &exitErr) && exitErr.ExitCode() == NotFoundExitCode:
+			o.waitErr = fmt.Errorf("blob resolver: %s: %w", o.contentID, ErrNotFound)
+		case errors.As(err, &exitErr):
+			o.waitErr = fmt.Errorf("blob resolver: %s failed: %s", ResolveCommand, strings.TrimSpace(o.stderr.String()))
+		default:
+			o.waitErr = fmt.Errorf("blob resolver: %w", err)
+		}
+	})
+	return o.waitErr
+}
+
+// DirResolver resolves content IDs to the files of the same name in a
+// directory, e.g. of a content-addressed store.
+type DirResolver string
+
+var _ Resolver = DirResolver("")
+
+// Resolve opens the file named contentID.
+func (d DirResolver) Resolve(_ context.Context, contentID string) (io.ReadCloser, error) {
+	if contentID == "" || contentID != filepath.Base(contentID) || contentID == ".." {
+		return nil, fmt.Errorf("invalid content ID %q", contentID)
+	}
+	f, err := os.Open(filepath.Join(string(d), contentID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", contentID, ErrNotFound)
+	}
+	return f, err
+}
+
+// Serve implements the plugin side of the protocol on top of r, and is the
+// reference implementation of a plugin. A plugin's main function can be as
+// simple as:
+//
+//	if err := blobresolver.Serve(blobresolver.DirResolver("/var/cas"), os.Args[1:], os.Stdin, os.Stdout); err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//		os.Exit(blobresolver.ExitCode(err))
+//	}
+func Serve(r Resolver, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 || args[len(args)-1] != ResolveCommand {
+		return fmt.Errorf("expected %s subcommand", ResolveCommand)
+	}
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading content ID: %w", err)
+	}
+	rc, err := r.Resolve(context.Background(), strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(stdout, rc)
+	return err
+}
+
+// ExitCode is the exit status of a plugin whose Serve returned err.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrNotFound):
+		return NotFoundExitCode
+	default:
+		return 1
+	}
+}
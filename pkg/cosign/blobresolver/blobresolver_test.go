@@ -0,0 +1,112 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobresolver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPluginDirEnv makes the test binary act as a resolver plugin for the
+// content of the given directory.
+const testPluginDirEnv = "COSIGN_TEST_BLOB_RESOLVER_DIR"
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(testPluginDirEnv); dir != "" {
+		if err := Serve(DirResolver(dir), os.Args[1:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(ExitCode(err))
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestExecResolver(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("some content\n"), 10000)
+	if err := os.WriteFile(filepath.Join(dir, "sha256-abc"), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testPluginDirEnv, dir)
+
+	r, err := NewExecResolver(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := r.Resolve(ctx, "sha256-abc")
+	if err != nil {
+		t.Fatalf("Resolve() = %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading resolved content: %v", err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("resolved %d bytes, expected %d", len(got), len(content))
+	}
+
+	rc, err = r.Resolve(ctx, "missing")
+	if err != nil {
+		t.Fatalf("Resolve() = %v", err)
+	}
+	if _, err := io.ReadAll(rc); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reading missing content = %v, expected ErrNotFound", err)
+	}
+	rc.Close()
+
+	rc, err = r.Resolve(ctx, "../sha256-abc")
+	if err != nil {
+		t.Fatalf("Resolve() = %v", err)
+	}
+	if _, err := io.ReadAll(rc); err == nil || !strings.Contains(err.Error(), "invalid content ID") {
+		t.Errorf("reading content outside the directory = %v, expected the plugin error", err)
+	}
+	rc.Close()
+
+	// Closing before the end stops the plugin.
+	rc, err = r.Resolve(ctx, "sha256-abc")
+	if err != nil {
+		t.Fatalf("Resolve() = %v", err)
+	}
+	if _, err := rc.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	if _, err := r.Resolve(ctx, "two\nlines"); err == nil {
+		t.Error("Resolve() of a content ID with a newline expected an error")
+	}
+}
+
+func TestNewExecResolverEmpty(t *testing.T) {
+	if _, err := NewExecResolver("  "); err == nil {
+		t.Fatal("expected an error for an empty command")
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dnskey loads public keys published in DNS.
+//
+// A key reference has the form
+//
+//	dns://<name>[?type=txt|tlsa][&dnssec=optional|require][&server=<host:port>]
+//
+// With type=txt (the default) the TXT record of name holds the base64
+// encoded DER SubjectPublicKeyInfo, possibly split across several strings.
+// With type=tlsa a TLSA record of name with selector 1 (SubjectPublicKeyInfo)
+// and matching type 0 (full) holds the key.
+//
+// Queries are sent to server, or to the first resolver of /etc/resolv.conf,
+// with the DNSSEC OK bit set. DNSSEC validation is left to that resolver,
+// which reports it with the Authenticated Data bit: with dnssec=require an
+// unauthenticated answer is an error, with dnssec=optional it is a warning.
+// No query is sent in a context in which the network is disabled, see
+// netguard.
+package dnskey
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/sigstore/cosign/v2/internal/pkg/netguard"
+	"github.com/sigstore/cosign/v2/internal/ui"
+)
+
+const (
+	// ReferenceScheme is the prefix of DNS key references.
+	ReferenceScheme = "dns://"
+
+	// RecordTypeTXT looks the key up in a TXT record.
+	RecordTypeTXT = "txt"
+	// RecordTypeTLSA looks the key up in a TLSA record.
+	RecordTypeTLSA = "tlsa"
+
+	// DNSSECOptional warns if the answer isn't DNSSEC authenticated.
+	DNSSECOptional = "optional"
+	// DNSSECRequire fails if the answer isn't DNSSEC authenticated.
+	DNSSECRequire = "require"
+
+	resolvConf = "/etc/resolv.conf"
+)
+
+// Reference is a parsed DNS key reference.
+type Reference struct {
+	Name       string
+	RecordType string
+	DNSSEC     string
+	// Server is the resolver queried, as host:port. If empty, the first
+	// resolver of /etc/resolv.conf is used.
+	Server string
+}
+
+// ParseReference parses a dns:// key reference.
+func ParseReference(ref string) (*Reference, error) {
+	if !strings.HasPrefix(ref, ReferenceScheme) {
+		return nil, fmt.Errorf("DNS key reference %q must start with %s", ref, ReferenceScheme)
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("parsing DNS key reference: %w", err)
+	}
+	if u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return nil, fmt.Errorf("DNS key reference %q must be of the form %s<name>", ref, ReferenceScheme)
+	}
+	q := u.Query()
+	r := &Reference{
+		Name:       dns.Fqdn(u.Host),
+		RecordType: strings.ToLower(q.Get("type")),
+		DNSSEC:     strings.ToLower(q.Get("dnssec")),
+		Server:     q.Get("server"),
+	}
+	switch r.RecordType {
+	case "":
+		r.RecordType = RecordTypeTXT
+	case RecordTypeTXT, RecordTypeTLSA:
+	default:
+		return nil, fmt.Errorf("unsupported record type %q, expected %s or %s", r.RecordType, RecordTypeTXT, RecordTypeTLSA)
+	}
+	switch r.DNSSEC {
+	case "":
+		r.DNSSEC = DNSSECOptional
+	case DNSSECOptional, DNSSECRequire:
+	default:
+		return nil, fmt.Errorf("unsupported dnssec %q, expected %s or %s", r.DNSSEC, DNSSECOptional, DNSSECRequire)
+	}
+	return r, nil
+}
+
+// GetPublicKey resolves the key reference and returns the public key it
+// holds. Exactly one key must be published.
+func GetPublicKey(ctx context.Context, ref string) (crypto.PublicKey, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	server := r.Server
+	if server == "" {
+		conf, err := dns.ClientConfigFromFile(resolvConf)
+		if err != nil {
+			return nil, fmt.Errorf("reading resolver configuration: %w", err)
+		}
+		if len(conf.Servers) == 0 {
+			return nil, fmt.Errorf("no resolver in %s", resolvConf)
+		}
+		server = net.JoinHostPort(conf.Servers[0], conf.Port)
+	}
+
+	qtype := dns.TypeTXT
+	if r.RecordType == RecordTypeTLSA {
+		qtype = dns.TypeTLSA
+	}
+	if err := netguard.Check(ctx, server); err != nil {
+		return nil, err
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(r.Name, qtype)
+	m.SetEdns0(4096, true)
+	m.AuthenticatedData = true
+	resp, _, err := new(dns.Client).ExchangeContext(ctx, m, server)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s %s: %w", r.RecordType, r.Name, err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("resolving %s %s: %s", r.RecordType, r.Name, dns.RcodeToString[resp.Rcode])
+	}
+	if !resp.AuthenticatedData {
+		if r.DNSSEC == DNSSECRequire {
+			return nil, fmt.Errorf("the %s record of %s is not DNSSEC authenticated", r.RecordType, r.Name)
+		}
+		ui.Warnf(ctx, "The %s record of %s is not DNSSEC authenticated", r.RecordType, r.Name)
+	}
+
+	var pubs []crypto.PublicKey
+	for _, rr := range resp.Answer {
+		var der []byte
+		switch rr := rr.(type) {
+		case *dns.TXT:
+			der, err = base64.StdEncoding.DecodeString(strings.Join(rr.Txt, ""))
+		case *dns.TLSA:
+			// Only full SubjectPublicKeyInfo records carry the key itself.
+			if rr.Selector != 1 || rr.MatchingType != 0 {
+				continue
+			}
+			der, err = hex.DecodeString(rr.Certificate)
+		default:
+			continue
+		}
+		if err != nil {
+			// Not every record of the name has to be a key.
+			continue
+		}
+		pub, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			continue
+		}
+		pubs = append(pubs, pub)
+	}
+	switch len(pubs) {
+	case 0:
+		return nil, fmt.Errorf("no public key found in the %s records of %s", r.RecordType, r.Name)
+	case 1:
+		return pubs[0], nil
+	default:
+		return nil, fmt.Errorf("more than one public key found in the %s records of %s", r.RecordType, r.Name)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnskey
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// serveDNS starts a resolver answering with the records of zone, in
+// presentation format, and returns its address.
+func serveDNS(t *testing.T, authenticated bool, zone ...string) string {
+	t.Helper()
+	var rrs []dns.RR
+	for _, z := range zone {
+		rr, err := dns.NewRR(z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rrs = append(rrs, rr)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.AuthenticatedData = authenticated
+		for _, rr := range rrs {
+			if rr.Header().Name == req.Question[0].Name && rr.Header().Rrtype == req.Question[0].Qtype {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+		if len(m.Answer) == 0 {
+			m.Rcode = dns.RcodeNameError
+		}
+		_ = w.WriteMsg(m)
+	})}
+	started := make(chan struct{})
+	srv.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = srv.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestGetPublicKey(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.StdEncoding.EncodeToString(der)
+	// TXT strings are at most 255 characters long.
+	txt := `signer.example.com. 300 IN TXT "` + b64[:60] + `" "` + b64[60:] + `"`
+	tlsa := "signer.example.com. 300 IN TLSA 3 1 0 " + hex.EncodeToString(der)
+
+	ctx := context.Background()
+	tests := []struct {
+		name          string
+		ref           string
+		authenticated bool
+		zone          []string
+		wantErr       string
+	}{{
+		name: "txt",
+		ref:  "dns://signer.example.com",
+		zone: []string{txt, `signer.example.com. 300 IN TXT "v=spf1 -all"`},
+	}, {
+		name: "tlsa",
+		ref:  "dns://signer.example.com?type=tlsa",
+		zone: []string{tlsa, "signer.example.com. 300 IN TLSA 3 1 1 " + strings.Repeat("ab", 32)},
+	}, {
+		name:          "dnssec required and authenticated",
+		ref:           "dns://signer.example.com?dnssec=require",
+		authenticated: true,
+		zone:          []string{txt},
+	}, {
+		name:    "dnssec required and not authenticated",
+		ref:     "dns://signer.example.com?dnssec=require",
+		zone:    []string{txt},
+		wantErr: "not DNSSEC authenticated",
+	}, {
+		name:    "no such name",
+		ref:     "dns://other.example.com",
+		zone:    []string{txt},
+		wantErr: "NXDOMAIN",
+	}, {
+		name:    "no key",
+		ref:     "dns://signer.example.com",
+		zone:    []string{`signer.example.com. 300 IN TXT "v=spf1 -all"`},
+		wantErr: "no public key found",
+	}, {
+		name:    "several keys",
+		ref:     "dns://signer.example.com",
+		zone:    []string{txt, `signer.example.com. 300 IN TXT "` + b64 + `"`},
+		wantErr: "more than one public key",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := serveDNS(t, test.authenticated, test.zone...)
+			sep := "?"
+			if strings.Contains(test.ref, "?") {
+				sep = "&"
+			}
+			pub, err := GetPublicKey(ctx, test.ref+sep+"server="+server)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("GetPublicKey() = %v, wanted %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPublicKey() = %v", err)
+			}
+			if !priv.PublicKey.Equal(pub) {
+				t.Error("GetPublicKey() returned the wrong key")
+			}
+		})
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	for ref, wantErr := range map[string]string{
+		"dns://signer.example.com":              "",
+		"dns://signer.example.com?type=TLSA":    "",
+		"dns://signer.example.com?type=a":       "unsupported record type",
+		"dns://signer.example.com?dnssec=maybe": "unsupported dnssec",
+		"dns://signer.example.com/key":          "must be of the form",
+		"dns://":                                "must be of the form",
+		"https://signer.example.com":            "must start with",
+	} {
+		_, err := ParseReference(ref)
+		if wantErr == "" && err != nil {
+			t.Errorf("ParseReference(%q) = %v", ref, err)
+		}
+		if wantErr != "" && (err == nil || !strings.Contains(err.Error(), wantErr)) {
+			t.Errorf("ParseReference(%q) = %v, wanted %q", ref, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fido2key verifies signatures made with a FIDO2 (WebAuthn)
+// credential.
+//
+// A key reference has the form
+//
+//	fido2://<path>
+//
+// where path is a file holding the credential public key as a CBOR encoded
+// COSE_Key (RFC 9052), as returned in the attested credential data at
+// registration. EC2 keys on P-256 (ES256) or P-384 (ES384) and OKP keys on
+// Ed25519 (EdDSA) are supported.
+//
+// An authenticator doesn't sign a message, but its authenticator data
+// followed by the SHA-256 digest of the client data. A signature is thus the
+// CBOR encoding of the map
+//
+//	{
+//	  "authenticatorData": bstr,
+//	  "clientDataJSON": bstr,
+//	  "signature": bstr,
+//	}
+//
+// holding the fields of the WebAuthn assertion response. The signature is
+// valid for a message if:
+//   - the client data type is "webauthn.get" and its challenge is the
+//     unpadded base64url encoding of the SHA-256 digest of the message, e.g.
+//     of the DSSE pre-authentication encoding of an envelope,
+//   - the authenticator data has the user present flag set,
+//   - the signature over the authenticator data and the SHA-256 digest of the
+//     client data validates with the key.
+//
+// The relying party ID and origin are not checked.
+package fido2key
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// ReferenceScheme is the prefix of FIDO2 key references.
+const ReferenceScheme = "fido2://"
+
+// COSE key types, curves and algorithms, see
+// https://www.iana.org/assignments/cose/cose.xhtml.
+const (
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+
+	coseCurveP256    = 1
+	coseCurveP384    = 2
+	coseCurveEd25519 = 6
+
+	coseAlgES256 = -7
+	coseAlgES384 = -35
+	coseAlgEdDSA = -8
+)
+
+// flagUserPresent is the user present (UP) bit of the authenticator data
+// flags.
+const flagUserPresent = 0x01
+
+// authenticatorDataMinSize is the size of the authenticator data without
+// attested credential data or extensions: the relying party ID hash, the
+// flags and the signature counter.
+const authenticatorDataMinSize = 37
+
+// coseKey holds the COSE_Key parameters of EC2 and OKP keys.
+type coseKey struct {
+	Kty int    `cbor:"1,keyasint"`
+	Alg int    `cbor:"3,keyasint,omitempty"`
+	Crv int    `cbor:"-1,keyasint"`
+	X   []byte `cbor:"-2,keyasint"`
+	Y   []byte `cbor:"-3,keyasint,omitempty"`
+}
+
+// Assertion holds the fields of a WebAuthn assertion response, the signature
+// format of a FIDO2 credential.
+type Assertion struct {
+	AuthenticatorData []byte `cbor:"authenticatorData"`
+	ClientDataJSON    []byte `cbor:"clientDataJSON"`
+	Signature         []byte `cbor:"signature"`
+}
+
+// clientData holds the checked fields of the WebAuthn client data.
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+}
+
+// Verifier verifies the WebAuthn assertions of a FIDO2 credential.
+type Verifier struct {
+	publicKey crypto.PublicKey
+	alg       int
+}
+
+var _ signature.Verifier = (*Verifier)(nil)
+
+// LoadVerifier returns the verifier of the COSE_Key file of a fido2:// key
+// reference.
+func LoadVerifier(ref string) (*Verifier, error) {
+	if !strings.HasPrefix(ref, ReferenceScheme) {
+		return nil, fmt.Errorf("FIDO2 key reference %q must start with %s", ref, ReferenceScheme)
+	}
+	path := strings.TrimPrefix(ref, ReferenceScheme)
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading FIDO2 key: %w", err)
+	}
+	return ParseCOSEKey(b)
+}
+
+// ParseCOSEKey returns the verifier of a CBOR encoded COSE_Key.
+func ParseCOSEKey(b []byte) (*Verifier, error) {
+	k := coseKey{}
+	if err := cbor.Unmarshal(b, &k); err != nil {
+		return nil, fmt.Errorf("decoding COSE key: %w", err)
+	}
+	switch k.Kty {
+	case coseKeyTypeEC2:
+		var curve elliptic.Curve
+		var alg int
+		switch k.Crv {
+		case coseCurveP256:
+			curve, alg = elliptic.P256(), coseAlgES256
+		case coseCurveP384:
+			curve, alg = elliptic.P384(), coseAlgES384
+		default:
+			return nil, fmt.Errorf("unsupported COSE EC2 curve %d", k.Crv)
+		}
+		if k.Alg != 0 && k.Alg != alg {
+			return nil, fmt.Errorf("COSE algorithm %d does not match the curve %d", k.Alg, k.Crv)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(k.X) != size || len(k.Y) != size {
+			return nil, errors.New("invalid COSE EC2 coordinates")
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(k.X), Y: new(big.Int).SetBytes(k.Y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("COSE EC2 point is not on the curve")
+		}
+		return &Verifier{publicKey: pub, alg: alg}, nil
+	case coseKeyTypeOKP:
+		if k.Crv != coseCurveEd25519 {
+			return nil, fmt.Errorf("unsupported COSE OKP curve %d", k.Crv)
+		}
+		if k.Alg != 0 && k.Alg != coseAlgEdDSA {
+			return nil, fmt.Errorf("COSE algorithm %d does not match the curve %d", k.Alg, k.Crv)
+		}
+		if len(k.X) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid COSE Ed25519 public key")
+		}
+		return &Verifier{publicKey: ed25519.PublicKey(k.X), alg: coseAlgEdDSA}, nil
+	default:
+		return nil, fmt.Errorf("unsupported COSE key type %d", k.Kty)
+	}
+}
+
+// PublicKey returns the public key of the credential.
+func (v *Verifier) PublicKey(_ ...signature.PublicKeyOption) (crypto.PublicKey, error) {
+	return v.publicKey, nil
+}
+
+// VerifySignature verifies that sig, a CBOR encoded Assertion, is a WebAuthn
+// assertion of the credential over message.
+func (v *Verifier) VerifySignature(sig, message io.Reader, _ ...signature.VerifyOption) error {
+	sigBytes, err := io.ReadAll(sig)
+	if err != nil {
+		return err
+	}
+	msg, err := io.ReadAll(message)
+	if err != nil {
+		return err
+	}
+	a := Assertion{}
+	if err := cbor.Unmarshal(sigBytes, &a); err != nil {
+		return fmt.Errorf("decoding WebAuthn assertion: %w", err)
+	}
+
+	cd := clientData{}
+	if err := json.Unmarshal(a.ClientDataJSON, &cd); err != nil {
+		return fmt.Errorf("decoding WebAuthn client data: %w", err)
+	}
+	if cd.Type != "webauthn.get" {
+		return fmt.Errorf("unexpected WebAuthn client data type %q", cd.Type)
+	}
+	challenge := sha256.Sum256(msg)
+	if cd.Challenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		return errors.New("WebAuthn challenge does not match the message")
+	}
+	if len(a.AuthenticatorData) < authenticatorDataMinSize {
+		return errors.New("WebAuthn authenticator data is too short")
+	}
+	if a.AuthenticatorData[32]&flagUserPresent == 0 {
+		return errors.New("WebAuthn authenticator data does not have the user present flag")
+	}
+
+	clientDataHash := sha256.Sum256(a.ClientDataJSON)
+	signed := bytes.Join([][]byte{a.AuthenticatorData, clientDataHash[:]}, nil)
+	switch v.alg {
+	case coseAlgES256:
+		digest := sha256.Sum256(signed)
+		if !ecdsa.VerifyASN1(v.publicKey.(*ecdsa.PublicKey), digest[:], a.Signature) {
+			return errors.New("invalid WebAuthn signature")
+		}
+	case coseAlgES384:
+		digest := sha512.Sum384(signed)
+		if !ecdsa.VerifyASN1(v.publicKey.(*ecdsa.PublicKey), digest[:], a.Signature) {
+			return errors.New("invalid WebAuthn signature")
+		}
+	case coseAlgEdDSA:
+		if !ed25519.Verify(v.publicKey.(ed25519.PublicKey), signed, a.Signature) {
+			return errors.New("invalid WebAuthn signature")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fido2key
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+)
+
+// TestVerifyTestVector verifies testdata/envelope.json, a DSSE envelope whose
+// signature is a WebAuthn assertion of the P-256 credential testdata/key.cose.
+func TestVerifyTestVector(t *testing.T) {
+	v, err := LoadVerifier(ReferenceScheme + "testdata/key.cose")
+	if err != nil {
+		t.Fatalf("LoadVerifier() = %v", err)
+	}
+	env, err := os.ReadFile("testdata/envelope.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dsse.WrapVerifier(v).VerifySignature(bytes.NewReader(env), nil); err != nil {
+		t.Fatalf("VerifySignature() = %v", err)
+	}
+
+	// Any change to the envelope invalidates it.
+	tampered := bytes.Replace(env, []byte(`"payloadType":"application/vnd.in-toto+json"`), []byte(`"payloadType":"application/json"`), 1)
+	if err := dsse.WrapVerifier(v).VerifySignature(bytes.NewReader(tampered), nil); err == nil {
+		t.Fatal("VerifySignature() of a tampered envelope expected an error")
+	}
+}
+
+// credential is a test FIDO2 credential.
+type credential struct {
+	coseKey []byte
+	sign    func(signed []byte) []byte
+}
+
+func newCredential(t *testing.T, alg int) credential {
+	t.Helper()
+	switch alg {
+	case coseAlgEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return credential{
+			coseKey: marshalCOSEKey(t, map[int]interface{}{1: coseKeyTypeOKP, 3: alg, -1: coseCurveEd25519, -2: []byte(pub)}),
+			sign:    func(signed []byte) []byte { return ed25519.Sign(priv, signed) },
+		}
+	default:
+		curve, crv, hash := elliptic.P256(), coseCurveP256, crypto.SHA256
+		if alg == coseAlgES384 {
+			curve, crv, hash = elliptic.P384(), coseCurveP384, crypto.SHA384
+		}
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		return credential{
+			coseKey: marshalCOSEKey(t, map[int]interface{}{
+				1: coseKeyTypeEC2, 3: alg, -1: crv,
+				-2: priv.X.FillBytes(make([]byte, size)), -3: priv.Y.FillBytes(make([]byte, size)),
+			}),
+			sign: func(signed []byte) []byte {
+				var digest []byte
+				if hash == crypto.SHA384 {
+					d := sha512.Sum384(signed)
+					digest = d[:]
+				} else {
+					d := sha256.Sum256(signed)
+					digest = d[:]
+				}
+				sig, err := ecdsa.SignASN1(rand.Reader, priv, digest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return sig
+			},
+		}
+	}
+}
+
+func marshalCOSEKey(t *testing.T, k map[int]interface{}) []byte {
+	t.Helper()
+	b, err := cbor.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// assert returns the CBOR encoded assertion of c over the client data, with
+// the given authenticator data flags.
+func (c credential) assert(t *testing.T, clientDataJSON []byte, flags byte) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte("example.com"))
+	authData := append(rpIDHash[:], flags, 0, 0, 0, 1)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	b, err := cbor.Marshal(Assertion{
+		AuthenticatorData: authData,
+		ClientDataJSON:    clientDataJSON,
+		Signature:         c.sign(append(append([]byte{}, authData...), clientDataHash[:]...)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func clientDataFor(t *testing.T, typ string, message []byte) []byte {
+	t.Helper()
+	challenge := sha256.Sum256(message)
+	b, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge[:]),
+		"origin":    "https://example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVerifySignature(t *testing.T) {
+	message := []byte("message")
+
+	for _, alg := range []int{coseAlgES256, coseAlgES384, coseAlgEdDSA} {
+		c := newCredential(t, alg)
+		v, err := ParseCOSEKey(c.coseKey)
+		if err != nil {
+			t.Fatalf("ParseCOSEKey(%d) = %v", alg, err)
+		}
+		other, err := ParseCOSEKey(newCredential(t, alg).coseKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		valid := c.assert(t, clientDataFor(t, "webauthn.get", message), flagUserPresent)
+		if err := v.VerifySignature(bytes.NewReader(valid), bytes.NewReader(message)); err != nil {
+			t.Errorf("VerifySignature(%d) = %v", alg, err)
+		}
+
+		invalid := map[string]struct {
+			verifier  *Verifier
+			signature []byte
+			message   []byte
+		}{
+			"other message":       {v, valid, []byte("other")},
+			"other credential":    {other, valid, message},
+			"registration":        {v, c.assert(t, clientDataFor(t, "webauthn.create", message), flagUserPresent), message},
+			"user not present":    {v, c.assert(t, clientDataFor(t, "webauthn.get", message), 0x04), message},
+			"raw signature":       {v, c.sign(message), message},
+			"short authenticator": {v, mustMarshal(t, Assertion{AuthenticatorData: []byte{1}, ClientDataJSON: clientDataFor(t, "webauthn.get", message)}), message},
+		}
+		for name, test := range invalid {
+			if err := test.verifier.VerifySignature(bytes.NewReader(test.signature), bytes.NewReader(test.message)); err == nil {
+				t.Errorf("VerifySignature(%d) of %s expected an error", alg, name)
+			}
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := cbor.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseCOSEKeyInvalid(t *testing.T) {
+	p256 := newCredential(t, coseAlgES256)
+	k := coseKey{}
+	if err := cbor.Unmarshal(p256.coseKey, &k); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"not cbor":           []byte("not cbor"),
+		"RSA key":            marshalCOSEKey(t, map[int]interface{}{1: 3, 3: -257, -1: []byte{1}, -2: []byte{1, 0, 1}}),
+		"unsupported curve":  marshalCOSEKey(t, map[int]interface{}{1: coseKeyTypeEC2, -1: 3, -2: k.X, -3: k.Y}),
+		"algorithm mismatch": marshalCOSEKey(t, map[int]interface{}{1: coseKeyTypeEC2, 3: coseAlgES384, -1: coseCurveP256, -2: k.X, -3: k.Y}),
+		"point off curve":    marshalCOSEKey(t, map[int]interface{}{1: coseKeyTypeEC2, -1: coseCurveP256, -2: k.X, -3: k.X}),
+		"short Ed25519 key":  marshalCOSEKey(t, map[int]interface{}{1: coseKeyTypeOKP, -1: coseCurveEd25519, -2: []byte{1}}),
+		"X25519 key":         marshalCOSEKey(t, map[int]interface{}{1: coseKeyTypeOKP, -1: 4, -2: make([]byte, 32)}),
+	}
+	for name, b := range tests {
+		if _, err := ParseCOSEKey(b); err == nil {
+			t.Errorf("ParseCOSEKey() of %s expected an error", name)
+		}
+	}
+	if _, err := LoadVerifier("testdata/key.cose"); err == nil {
+		t.Error("LoadVerifier() without the fido2:// scheme expected an error")
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jwkskey loads public keys published in a JSON Web Key Set (JWKS,
+// RFC 7517).
+//
+// A key reference has the form
+//
+//	jwks://<host>/<path>[#<kid>]
+//
+// where https://<host>/<path> serves the JWKS, e.g.
+// jwks://issuer.example.com/.well-known/jwks.json#key-1. With a kid, the key
+// of the set with this key ID is used. Without, the verifier is a KeySet
+// holding every key of the set, which callers knowing the key ID of a
+// signature narrow with KeySet.Key.
+//
+// A fetched JWKS is cached by the process for COSIGN_JWKS_CACHE_TTL, 5
+// minutes by default.
+package jwkskey
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/sigstore/sigstore/pkg/signature"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign/env"
+)
+
+const (
+	// ReferenceScheme is the prefix of JWKS key references.
+	ReferenceScheme = "jwks://"
+
+	// DefaultCacheTTL is how long a JWKS is cached if COSIGN_JWKS_CACHE_TTL
+	// isn't set.
+	DefaultCacheTTL = 5 * time.Minute
+
+	// maxSetSize bounds the size of a fetched JWKS.
+	maxSetSize = 1 << 20
+)
+
+// Reference is a parsed JWKS key reference.
+type Reference struct {
+	// URL is the https URL of the JWKS.
+	URL string
+	// KeyID is the kid of the key to use, if any.
+	KeyID string
+}
+
+// ParseReference parses a jwks:// key reference.
+func ParseReference(ref string) (*Reference, error) {
+	if !strings.HasPrefix(ref, ReferenceScheme) {
+		return nil, fmt.Errorf("JWKS key reference %q must start with %s", ref, ReferenceScheme)
+	}
+	u, err := url.Parse("https://" + strings.TrimPrefix(ref, ReferenceScheme))
+	if err != nil {
+		return nil, fmt.Errorf("parsing JWKS key reference: %w", err)
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("JWKS key reference %q must be of the form %s<host>/<path>[#<kid>]", ref, ReferenceScheme)
+	}
+	r := &Reference{KeyID: u.Fragment}
+	u.Fragment, u.RawFragment = "", ""
+	r.URL = u.String()
+	return r, nil
+}
+
+// Cache fetches JSON Web Key Sets, keeping each for a time-to-live.
+type Cache struct {
+	// Client fetches the sets. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// TTL is how long a set is kept. If zero, COSIGN_JWKS_CACHE_TTL or
+	// DefaultCacheTTL is used.
+	TTL time.Duration
+
+	mu   sync.Mutex
+	sets map[string]cachedSet
+}
+
+type cachedSet struct {
+	set     *jose.JSONWebKeySet
+	fetched time.Time
+}
+
+// DefaultCache is the cache of the process, used by LoadVerifier.
+var DefaultCache = &Cache{}
+
+func (c *Cache) ttl() (time.Duration, error) {
+	if c.TTL != 0 {
+		return c.TTL, nil
+	}
+	s := env.Getenv(env.VariableJWKSCacheTTL)
+	if s == "" {
+		return DefaultCacheTTL, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", env.VariableJWKSCacheTTL, err)
+	}
+	return d, nil
+}
+
+// Fetch returns the JWKS served at u, from the cache if it was fetched less
+// than the time-to-live ago.
+func (c *Cache) Fetch(ctx context.Context, u string) (*jose.JSONWebKeySet, error) {
+	ttl, err := c.ttl()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.sets[u]; ok && time.Since(cached.fetched) < ttl {
+		return cached.set, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS %s: %s", u, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxSetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching JWKS %s: %w", u, err)
+	}
+	if len(b) > maxSetSize {
+		return nil, fmt.Errorf("JWKS %s exceeds %d bytes", u, maxSetSize)
+	}
+	set := &jose.JSONWebKeySet{}
+	if err := json.Unmarshal(b, set); err != nil {
+		return nil, fmt.Errorf("parsing JWKS %s: %w", u, err)
+	}
+
+	if c.sets == nil {
+		c.sets = map[string]cachedSet{}
+	}
+	c.sets[u] = cachedSet{set: set, fetched: time.Now()}
+	return set, nil
+}
+
+// LoadVerifier returns the verifier of a jwks:// key reference: the key
+// with the referenced kid, or a KeySet if the reference has none.
+func LoadVerifier(ctx context.Context, ref string) (signature.Verifier, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	set, err := DefaultCache.Fetch(ctx, r.URL)
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeySet{url: r.URL, keys: set.Keys}
+	if r.KeyID != "" {
+		return ks.Key(r.KeyID)
+	}
+	return ks, nil
+}
+
+// KeySet verifies signatures with any key of a JWKS.
+type KeySet struct {
+	url  string
+	keys []jose.JSONWebKey
+}
+
+var _ signature.Verifier = (*KeySet)(nil)
+
+// Key returns the verifier of the key with ID kid.
+func (s *KeySet) Key(kid string) (signature.Verifier, error) {
+	var keys []jose.JSONWebKey
+	for _, k := range s.keys {
+		if k.KeyID == kid {
+			keys = append(keys, k)
+		}
+	}
+	switch len(keys) {
+	case 0:
+		return nil, fmt.Errorf("no key with kid %q in the JWKS %s", kid, s.url)
+	case 1:
+		return verifierFor(keys[0])
+	default:
+		return nil, fmt.Errorf("more than one key with kid %q in the JWKS %s", kid, s.url)
+	}
+}
+
+// PublicKey returns the public key of the set, which must hold exactly one.
+func (s *KeySet) PublicKey(_ ...signature.PublicKeyOption) (crypto.PublicKey, error) {
+	if len(s.keys) != 1 {
+		return nil, fmt.Errorf("the JWKS %s holds %d keys, select one with #<kid>", s.url, len(s.keys))
+	}
+	v, err := verifierFor(s.keys[0])
+	if err != nil {
+		return nil, err
+	}
+	return v.PublicKey()
+}
+
+// VerifySignature verifies that sig is a signature over message of a key of
+// the set.
+func (s *KeySet) VerifySignature(sig, message io.Reader, _ ...signature.VerifyOption) error {
+	sigBytes, err := io.ReadAll(sig)
+	if err != nil {
+		return err
+	}
+	msg, err := io.ReadAll(message)
+	if err != nil {
+		return err
+	}
+	for _, k := range s.keys {
+		v, err := verifierFor(k)
+		if err != nil {
+			continue
+		}
+		if v.VerifySignature(bytes.NewReader(sigBytes), bytes.NewReader(msg)) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no key of the JWKS %s validates the signature", s.url)
+}
+
+// algHashes are the digest algorithms of the JWS algorithms (RFC 7518).
+var algHashes = map[string]crypto.Hash{
+	"ES256": crypto.SHA256, "RS256": crypto.SHA256, "PS256": crypto.SHA256,
+	"ES384": crypto.SHA384, "RS384": crypto.SHA384, "PS384": crypto.SHA384,
+	"ES512": crypto.SHA512, "RS512": crypto.SHA512, "PS512": crypto.SHA512,
+	"EdDSA": crypto.SHA512,
+}
+
+// verifierFor returns the verifier of k, whose digest and padding follow its
+// alg, or the key size of ECDSA keys without alg.
+func verifierFor(k jose.JSONWebKey) (signature.Verifier, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return nil, fmt.Errorf("JWK %q is not a signing key (use %q)", k.KeyID, k.Use)
+	}
+	pub := k.Public().Key
+	if pub == nil {
+		return nil, fmt.Errorf("JWK %q is not an asymmetric key", k.KeyID)
+	}
+	hash := crypto.SHA256
+	if k.Algorithm != "" {
+		h, ok := algHashes[k.Algorithm]
+		if !ok {
+			return nil, fmt.Errorf("unsupported JWK algorithm %q", k.Algorithm)
+		}
+		hash = h
+	} else if ec, ok := pub.(*ecdsa.PublicKey); ok {
+		switch ec.Curve {
+		case elliptic.P384():
+			hash = crypto.SHA384
+		case elliptic.P521():
+			hash = crypto.SHA512
+		}
+	}
+	if strings.HasPrefix(k.Algorithm, "PS") {
+		rsaPub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("JWK algorithm %s requires an RSA key", k.Algorithm)
+		}
+		return signature.LoadRSAPSSVerifier(rsaPub, hash, nil)
+	}
+	return signature.LoadVerifier(pub, hash)
+}
@@ -0,0 +1,226 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwkskey
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+func TestParseReference(t *testing.T) {
+	r, err := ParseReference("jwks://issuer.example.com/.well-known/jwks.json#key-1")
+	if err != nil {
+		t.Fatalf("ParseReference() = %v", err)
+	}
+	if r.URL != "https://issuer.example.com/.well-known/jwks.json" || r.KeyID != "key-1" {
+		t.Errorf("ParseReference() = %+v", r)
+	}
+	r, err = ParseReference("jwks://issuer.example.com/jwks.json")
+	if err != nil {
+		t.Fatalf("ParseReference() = %v", err)
+	}
+	if r.KeyID != "" {
+		t.Errorf("ParseReference() without a kid = %+v", r)
+	}
+
+	for _, ref := range []string{
+		"https://issuer.example.com/jwks.json",
+		"jwks://issuer.example.com",
+		"jwks:///jwks.json",
+	} {
+		if _, err := ParseReference(ref); err == nil {
+			t.Errorf("ParseReference(%q) expected an error", ref)
+		}
+	}
+}
+
+// jwksServer serves a JWKS of the given keys, counting the requests.
+func jwksServer(t *testing.T, keys ...jose.JSONWebKey) (*httptest.Server, *int32) {
+	t.Helper()
+	b, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/jwks.json" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		w.Write(b) //nolint:errcheck
+	}))
+	t.Cleanup(s.Close)
+	return s, &requests
+}
+
+func newECDSAKey(t *testing.T, kid string) (signature.Signer, jose.JSONWebKey) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sv, jose.JSONWebKey{Key: priv.Public(), KeyID: kid, Algorithm: "ES256", Use: "sig"}
+}
+
+func TestCacheFetch(t *testing.T) {
+	ctx := context.Background()
+	_, k := newECDSAKey(t, "a")
+	s, requests := jwksServer(t, k)
+	u := s.URL + "/.well-known/jwks.json"
+
+	c := &Cache{Client: s.Client(), TTL: time.Hour}
+	for i := 0; i < 2; i++ {
+		set, err := c.Fetch(ctx, u)
+		if err != nil {
+			t.Fatalf("Fetch() = %v", err)
+		}
+		if len(set.Keys) != 1 || set.Keys[0].KeyID != "a" {
+			t.Fatalf("Fetch() = %+v", set)
+		}
+	}
+	if *requests != 1 {
+		t.Errorf("the JWKS was fetched %d times, expected once", *requests)
+	}
+
+	// An expired set is fetched again.
+	c = &Cache{Client: s.Client(), TTL: time.Nanosecond}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Fetch(ctx, u); err != nil {
+			t.Fatalf("Fetch() = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if *requests != 3 {
+		t.Errorf("the JWKS was fetched %d times, expected 3", *requests)
+	}
+
+	t.Setenv("COSIGN_JWKS_CACHE_TTL", "not a duration")
+	if _, err := (&Cache{Client: s.Client()}).Fetch(ctx, u); err == nil {
+		t.Error("Fetch() with an invalid COSIGN_JWKS_CACHE_TTL expected an error")
+	}
+	if _, err := c.Fetch(ctx, s.URL+"/missing.json"); err == nil {
+		t.Error("Fetch() of a missing JWKS expected an error")
+	}
+}
+
+func TestLoadVerifier(t *testing.T) {
+	ctx := context.Background()
+	signerA, keyA := newECDSAKey(t, "a")
+	signerB, keyB := newECDSAKey(t, "b")
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pss, err := signature.LoadRSAPSSSignerVerifier(rsaPriv, crypto.SHA384, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := signature.LoadED25519SignerVerifier(edPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := jwksServer(t, keyA, keyB,
+		jose.JSONWebKey{Key: &rsaPriv.PublicKey, KeyID: "pss", Algorithm: "PS384"},
+		jose.JSONWebKey{Key: edPub, KeyID: "ed", Algorithm: "EdDSA"},
+		jose.JSONWebKey{Key: keyA.Key, KeyID: "enc", Use: "enc"},
+	)
+	defer func(c *Cache) { DefaultCache = c }(DefaultCache)
+	DefaultCache = &Cache{Client: s.Client()}
+	ref := ReferenceScheme + strings.TrimPrefix(s.URL, "https://") + "/.well-known/jwks.json"
+
+	message := []byte("message")
+	sign := func(s signature.Signer) []byte {
+		sig, err := s.SignMessage(bytes.NewReader(message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+	sigA, sigB := sign(signerA), sign(signerB)
+
+	tests := []struct {
+		kid     string
+		sig     []byte
+		wantErr bool
+	}{
+		{kid: "a", sig: sigA},
+		{kid: "b", sig: sigB},
+		{kid: "a", sig: sigB, wantErr: true},
+		{kid: "pss", sig: sign(pss)},
+		{kid: "ed", sig: sign(ed)},
+	}
+	for _, test := range tests {
+		v, err := LoadVerifier(ctx, ref+"#"+test.kid)
+		if err != nil {
+			t.Fatalf("LoadVerifier(#%s) = %v", test.kid, err)
+		}
+		err = v.VerifySignature(bytes.NewReader(test.sig), bytes.NewReader(message))
+		if (err != nil) != test.wantErr {
+			t.Errorf("VerifySignature() with #%s = %v, wantErr %t", test.kid, err, test.wantErr)
+		}
+	}
+
+	for _, kid := range []string{"missing", "enc"} {
+		if _, err := LoadVerifier(ctx, ref+"#"+kid); err == nil {
+			t.Errorf("LoadVerifier(#%s) expected an error", kid)
+		}
+	}
+
+	// Without a kid, any key of the set verifies.
+	v, err := LoadVerifier(ctx, ref)
+	if err != nil {
+		t.Fatalf("LoadVerifier() = %v", err)
+	}
+	ks, ok := v.(*KeySet)
+	if !ok {
+		t.Fatalf("LoadVerifier() without a kid = %T, expected a *KeySet", v)
+	}
+	for _, sig := range [][]byte{sigA, sigB} {
+		if err := ks.VerifySignature(bytes.NewReader(sig), bytes.NewReader(message)); err != nil {
+			t.Errorf("VerifySignature() = %v", err)
+		}
+	}
+	if err := ks.VerifySignature(bytes.NewReader(sigA), bytes.NewReader([]byte("other"))); err == nil {
+		t.Error("VerifySignature() of another message expected an error")
+	}
+	if _, err := ks.PublicKey(); err == nil {
+		t.Error("PublicKey() of a set of several keys expected an error")
+	}
+}
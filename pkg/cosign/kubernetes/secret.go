@@ -31,6 +31,8 @@ import (
 
 const (
 	KeyReference = "k8s://"
+	// PublicKeyField is the secret data field holding the PEM encoded public key.
+	PublicKeyField = "cosign.pub"
 )
 
 func GetKeyPairSecret(ctx context.Context, k8sRef string) (*v1.Secret, error) {
@@ -106,7 +108,7 @@ func secret(keys *cosign.KeysBytes, namespace, name string, data map[string][]by
 		data = map[string][]byte{}
 	}
 	data["cosign.key"] = keys.PrivateBytes
-	data["cosign.pub"] = keys.PublicBytes
+	data[PublicKeyField] = keys.PublicBytes
 	data["cosign.password"] = keys.Password()
 
 	obj := metav1.ObjectMeta{
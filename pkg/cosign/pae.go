@@ -0,0 +1,80 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// StandardPAE names the DSSE pre-authentication encoding of the
+// specification, the default and the only one registered unless a build adds
+// others with RegisterPAE.
+const StandardPAE = "standard"
+
+// PAEFunc computes the message a DSSE envelope signature is over from the
+// payload type and the decoded payload of the envelope.
+type PAEFunc func(payloadType string, payload []byte) []byte
+
+var (
+	paeMu       sync.RWMutex
+	paeRegistry = map[string]PAEFunc{}
+)
+
+// RegisterPAE registers a custom DSSE pre-authentication encoding under name,
+// for CheckOpts.DSSEPAE to select it. It is meant to be called from the init
+// function of a file added to a custom build, to interoperate with a signer
+// that doesn't follow the DSSE specification. It panics if name is already
+// registered, or is StandardPAE.
+//
+// A custom PAE weakens verification, and is never used unless selected. The
+// standard PAE encodes the payload type and the payload unambiguously, with
+// their lengths, so that a signature binds both. A PAE which doesn't, e.g. one
+// joining them with a separator that may appear in the payload type, lets a
+// signature over one envelope verify a different one.
+func RegisterPAE(name string, pae PAEFunc) {
+	paeMu.Lock()
+	defer paeMu.Unlock()
+	if pae == nil {
+		panic("cosign: RegisterPAE with a nil PAE")
+	}
+	if _, dup := paeRegistry[name]; dup || name == StandardPAE {
+		panic("cosign: RegisterPAE called twice for " + name)
+	}
+	paeRegistry[name] = pae
+}
+
+// LookupPAE returns the PAE registered under name, the standard one if name
+// is empty or StandardPAE.
+func LookupPAE(name string) (PAEFunc, error) {
+	if name == "" || name == StandardPAE {
+		return ssldsse.PAE, nil
+	}
+	paeMu.RLock()
+	defer paeMu.RUnlock()
+	if pae, ok := paeRegistry[name]; ok {
+		return pae, nil
+	}
+	names := []string{StandardPAE}
+	for n := range paeRegistry {
+		names = append(names, n)
+	}
+	sort.Strings(names[1:])
+	return nil, fmt.Errorf("unknown DSSE PAE %q, expected one of %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"testing"
+
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+func TestRegisterPAE(t *testing.T) {
+	reversePAE := func(payloadType string, payload []byte) []byte {
+		return ssldsse.PAE(string(payload), []byte(payloadType))
+	}
+	if _, err := LookupPAE("test-reverse"); err != nil {
+		RegisterPAE("test-reverse", reversePAE)
+	}
+
+	for _, name := range []string{"", StandardPAE} {
+		pae, err := LookupPAE(name)
+		if err != nil {
+			t.Fatalf("LookupPAE(%q) = %v", name, err)
+		}
+		if got := pae("t", []byte("p")); !bytes.Equal(got, ssldsse.PAE("t", []byte("p"))) {
+			t.Errorf("LookupPAE(%q) isn't the standard PAE", name)
+		}
+	}
+	pae, err := LookupPAE("test-reverse")
+	if err != nil {
+		t.Fatalf("LookupPAE() = %v", err)
+	}
+	if got := pae("t", []byte("p")); !bytes.Equal(got, reversePAE("t", []byte("p"))) {
+		t.Error("LookupPAE() isn't the registered PAE")
+	}
+	if _, err := LookupPAE("legacy"); err == nil {
+		t.Error("LookupPAE() of an unregistered PAE expected an error")
+	}
+
+	for _, name := range []string{StandardPAE, "test-reverse"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterPAE(%q) didn't panic", name)
+				}
+			}()
+			RegisterPAE(name, reversePAE)
+		}()
+	}
+}
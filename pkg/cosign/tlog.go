@@ -19,9 +19,11 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -47,7 +49,10 @@ import (
 	hashedrekord_v001 "github.com/sigstore/rekor/pkg/types/hashedrekord/v0.0.1"
 	"github.com/sigstore/rekor/pkg/types/intoto"
 	intoto_v001 "github.com/sigstore/rekor/pkg/types/intoto/v0.0.1"
+	"github.com/sigstore/rekor/pkg/util"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/options"
 	"github.com/sigstore/sigstore/pkg/tuf"
 )
 
@@ -499,6 +504,118 @@ func VerifyTLogEntryOffline(ctx context.Context, e *models.LogEntryAnon, rekorPu
 	return nil
 }
 
+// VerifyCheckpointWitnesses verifies that the checkpoint of the entry's
+// inclusion proof commits to the proof's root hash, is signed by the log, and
+// is co-signed by at least one of the witnesses.
+func VerifyCheckpointWitnesses(e *models.LogEntryAnon, rekorPubKeys *TrustedTransparencyLogPubKeys, witnesses []signature.Verifier) error {
+	sc, err := verifiedCheckpoint(e, rekorPubKeys)
+	if err != nil {
+		return err
+	}
+	for _, w := range witnesses {
+		if noteSignedBy(sc.SignedNote, w) {
+			return nil
+		}
+	}
+	return errors.New("checkpoint is not co-signed by any of the rekor witness keys")
+}
+
+// VerifyCheckpointTreeID verifies that the checkpoint of the entry's
+// inclusion proof commits to the proof's root hash, is signed by the log, and
+// is for the log tree treeID. A Rekor checkpoint's origin is
+// "<hostname> - <tree ID>".
+func VerifyCheckpointTreeID(e *models.LogEntryAnon, rekorPubKeys *TrustedTransparencyLogPubKeys, treeID int64) error {
+	sc, err := verifiedCheckpoint(e, rekorPubKeys)
+	if err != nil {
+		return err
+	}
+	_, id, ok := strings.Cut(sc.Origin, " - ")
+	if !ok {
+		return fmt.Errorf("checkpoint origin %q does not name a tree ID", sc.Origin)
+	}
+	got, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("checkpoint origin %q does not name a tree ID", sc.Origin)
+	}
+	if got != treeID {
+		return fmt.Errorf("tlog entry belongs to the tree %d, expected %d", got, treeID)
+	}
+	return nil
+}
+
+// verifiedCheckpoint returns the checkpoint of the entry's inclusion proof,
+// once verified to commit to the proof's root hash and to be signed by the
+// log.
+func verifiedCheckpoint(e *models.LogEntryAnon, rekorPubKeys *TrustedTransparencyLogPubKeys) (*util.SignedCheckpoint, error) {
+	if e.Verification == nil || e.Verification.InclusionProof == nil || e.Verification.InclusionProof.Checkpoint == nil {
+		return nil, errors.New("inclusion proof checkpoint not provided")
+	}
+	ip := e.Verification.InclusionProof
+
+	sc := &util.SignedCheckpoint{}
+	if err := sc.UnmarshalText([]byte(*ip.Checkpoint)); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint: %w", err)
+	}
+	rootHash, err := hex.DecodeString(swag.StringValue(ip.RootHash))
+	if err != nil {
+		return nil, fmt.Errorf("decoding inclusion proof root hash: %w", err)
+	}
+	if !bytes.Equal(sc.Hash, rootHash) || sc.Size != uint64(swag.Int64Value(ip.TreeSize)) {
+		return nil, errors.New("checkpoint does not match the inclusion proof")
+	}
+
+	if rekorPubKeys == nil || e.LogID == nil {
+		return nil, errors.New("no rekor public key to verify the checkpoint with")
+	}
+	logKey, ok := rekorPubKeys.Keys[*e.LogID]
+	if !ok {
+		return nil, errors.New("rekor log public key not found for checkpoint")
+	}
+	logVerifier, err := signature.LoadVerifier(logKey.PubKey, crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	if !noteSignedBy(sc.SignedNote, logVerifier) {
+		return nil, errors.New("checkpoint is not signed by the log")
+	}
+	return sc, nil
+}
+
+// noteSignedBy returns whether one of the note's signatures hinting at the
+// verifier's key is valid.
+func noteSignedBy(n util.SignedNote, v signature.Verifier) bool {
+	pub, err := v.PublicKey()
+	if err != nil {
+		return false
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return false
+	}
+	keyHash := sha256.Sum256(der)
+	hint := binary.BigEndian.Uint32(keyHash[:])
+
+	msg := []byte(n.Note)
+	var opts []signature.VerifyOption
+	if _, ok := pub.(ed25519.PublicKey); !ok {
+		digest := sha256.Sum256(msg)
+		opts = append(opts, options.WithDigest(digest[:]))
+	}
+	for _, s := range n.Signatures {
+		if s.Hash != hint {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(s.Base64)
+		if err != nil {
+			continue
+		}
+		if err := v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(msg), opts...); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func NewTrustedTransparencyLogPubKeys() TrustedTransparencyLogPubKeys {
 	return TrustedTransparencyLogPubKeys{Keys: make(map[string]TransparencyLogPubKey, 0)}
 }
@@ -0,0 +1,110 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sigstore/rekor/pkg/util"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// PinnedCheckpoint is a known-good signed checkpoint of the log, which tlog
+// entries must have been logged after.
+type PinnedCheckpoint struct {
+	checkpoint util.SignedCheckpoint
+
+	// The checkpoint signature is only verified once per log key.
+	mu       sync.Mutex
+	verified map[string]bool
+}
+
+// LoadPinnedCheckpoint reads the signed checkpoint at path, as served by
+// Rekor's /api/v1/log endpoint.
+func LoadPinnedCheckpoint(path string) (*PinnedCheckpoint, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	p := &PinnedCheckpoint{verified: map[string]bool{}}
+	if err := p.checkpoint.UnmarshalText(b); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint: %w", err)
+	}
+	return p, nil
+}
+
+// Size is the tree size of the checkpoint, i.e. the index of the first entry
+// logged after it.
+func (p *PinnedCheckpoint) Size() uint64 {
+	return p.checkpoint.Size
+}
+
+// Timestamp is when the log signed the checkpoint, if it says.
+func (p *PinnedCheckpoint) Timestamp() (time.Time, bool) {
+	ts := p.checkpoint.GetTimestamp()
+	if ts == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(ts)), true
+}
+
+// VerifyAfter verifies that the tlog entry at logIndex, integrated at
+// integratedTime, was logged after the checkpoint, and that the checkpoint
+// is signed by the log of logID. The entry's index must be at least the
+// checkpoint tree size and, if the checkpoint has a timestamp, it must not
+// be integrated before the second of the timestamp, less skew.
+func (p *PinnedCheckpoint) VerifyAfter(logID string, logIndex int64, integratedTime time.Time, skew time.Duration, rekorPubKeys *TrustedTransparencyLogPubKeys) error {
+	if err := p.verifySignature(logID, rekorPubKeys); err != nil {
+		return err
+	}
+	if logIndex < 0 || uint64(logIndex) < p.Size() {
+		return fmt.Errorf("tlog entry %d is not after the checkpoint of tree size %d", logIndex, p.Size())
+	}
+	if ts, ok := p.Timestamp(); ok && integratedTime.Add(skew).Before(ts.Truncate(time.Second)) {
+		return fmt.Errorf("tlog entry integrated at %s, before the checkpoint at %s", integratedTime.UTC().Format(time.RFC3339), ts.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (p *PinnedCheckpoint) verifySignature(logID string, rekorPubKeys *TrustedTransparencyLogPubKeys) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.verified[logID] {
+		return nil
+	}
+
+	if rekorPubKeys == nil {
+		return errors.New("no trusted rekor public keys provided")
+	}
+	logKey, ok := rekorPubKeys.Keys[logID]
+	if !ok {
+		return errors.New("rekor log public key not found for the pinned checkpoint")
+	}
+	v, err := signature.LoadVerifier(logKey.PubKey, crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	if !noteSignedBy(p.checkpoint.SignedNote, v) {
+		return errors.New("pinned checkpoint is not signed by the log of the tlog entry")
+	}
+	p.verified[logID] = true
+	return nil
+}
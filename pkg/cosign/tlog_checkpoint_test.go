@@ -0,0 +1,171 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sigstore/rekor/pkg/util"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+	"github.com/sigstore/sigstore/pkg/tuf"
+)
+
+func TestPinnedCheckpointVerifyAfter(t *testing.T) {
+	newSigner := func() signature.SignerVerifier {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sv
+	}
+	logSigner := newSigner()
+	logPub, err := logSigner.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPEM, err := cryptoutils.MarshalPublicKeyToPEM(logPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorPubKeys := NewTrustedTransparencyLogPubKeys()
+	if err := rekorPubKeys.AddTransparencyLogPubKey(logPEM, tuf.Active); err != nil {
+		t.Fatal(err)
+	}
+	logID, err := GetTransparencyLogID(logPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpointTime := time.Date(2023, 7, 1, 12, 0, 0, 500, time.UTC)
+	writeCheckpoint := func(t *testing.T, signer signature.Signer, timestamp time.Time) string {
+		t.Helper()
+		sc, err := util.CreateSignedCheckpoint(util.Checkpoint{Origin: "rekor.example.com - 1", Size: 100, Hash: make([]byte, 32)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !timestamp.IsZero() {
+			sc.SetTimestamp(uint64(timestamp.UnixNano()))
+		}
+		if _, err := sc.Sign("rekor.example.com", signer, options.WithCryptoSignerOpts(crypto.SHA256)); err != nil {
+			t.Fatal(err)
+		}
+		cp, err := sc.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "checkpoint")
+		if err := os.WriteFile(path, cp, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		description string
+		signer      signature.Signer
+		timestamp   time.Time
+		index       int64
+		integrated  time.Time
+		skew        time.Duration
+		wantErr     string
+	}{
+		{
+			description: "entry after the checkpoint",
+			signer:      logSigner,
+			timestamp:   checkpointTime,
+			index:       100,
+			integrated:  checkpointTime.Add(time.Hour),
+		}, {
+			description: "entry in the second of the checkpoint",
+			signer:      logSigner,
+			timestamp:   checkpointTime,
+			index:       100,
+			integrated:  checkpointTime.Truncate(time.Second),
+		}, {
+			description: "entry in the checkpoint tree",
+			signer:      logSigner,
+			timestamp:   checkpointTime,
+			index:       99,
+			integrated:  checkpointTime.Add(time.Hour),
+			wantErr:     "tlog entry 99 is not after the checkpoint of tree size 100",
+		}, {
+			description: "entry integrated before the checkpoint",
+			signer:      logSigner,
+			timestamp:   checkpointTime,
+			index:       100,
+			integrated:  checkpointTime.Add(-time.Hour),
+			wantErr:     "before the checkpoint",
+		}, {
+			description: "entry integrated before the checkpoint, within the skew",
+			signer:      logSigner,
+			timestamp:   checkpointTime,
+			index:       100,
+			integrated:  checkpointTime.Add(-10 * time.Second),
+			skew:        10 * time.Second,
+		}, {
+			description: "entry integrated before the checkpoint, beyond the skew",
+			signer:      logSigner,
+			timestamp:   checkpointTime,
+			index:       100,
+			integrated:  checkpointTime.Add(-11 * time.Second),
+			skew:        10 * time.Second,
+			wantErr:     "before the checkpoint",
+		}, {
+			description: "checkpoint without a timestamp",
+			signer:      logSigner,
+			index:       100,
+			integrated:  checkpointTime.Add(-time.Hour),
+		}, {
+			description: "checkpoint not signed by the log",
+			signer:      newSigner(),
+			timestamp:   checkpointTime,
+			index:       100,
+			integrated:  checkpointTime.Add(time.Hour),
+			wantErr:     "not signed by the log",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			p, err := LoadPinnedCheckpoint(writeCheckpoint(t, test.signer, test.timestamp))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.VerifyAfter(logID, test.index, test.integrated, test.skew, &rekorPubKeys)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyAfter() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("VerifyAfter() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
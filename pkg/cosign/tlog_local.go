@@ -0,0 +1,163 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sigstore/rekor/pkg/util"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/transparency-dev/merkle/rfc6962"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+)
+
+const (
+	// LocalTreeCheckpointFile is the file of a local tree holding the signed
+	// checkpoint of the mirrored tree state.
+	LocalTreeCheckpointFile = "checkpoint"
+	// LocalTreeLeavesFile is the file of a local tree holding the hex encoded
+	// leaf hashes of the tree, one per line in log index order.
+	LocalTreeLeavesFile = "leaves"
+)
+
+// LocalTree is a locally mirrored Rekor merkle tree, used to verify the
+// inclusion of log entries without querying Rekor.
+type LocalTree struct {
+	checkpoint util.SignedCheckpoint
+	leaves     [][]byte
+
+	// The tree head is only verified once per log key.
+	mu       sync.Mutex
+	verified map[string]bool
+}
+
+// LoadLocalTree loads a tree mirrored in dir, see LocalTreeCheckpointFile and
+// LocalTreeLeavesFile for its layout.
+func LoadLocalTree(dir string) (*LocalTree, error) {
+	cp, err := os.ReadFile(filepath.Join(dir, LocalTreeCheckpointFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading local tree checkpoint: %w", err)
+	}
+	t := &LocalTree{verified: map[string]bool{}}
+	if err := t.checkpoint.UnmarshalText(cp); err != nil {
+		return nil, fmt.Errorf("parsing local tree checkpoint: %w", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, LocalTreeLeavesFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading local tree leaves: %w", err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		leaf, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("decoding leaf %d of local tree: %w", len(t.leaves), err)
+		}
+		t.leaves = append(t.leaves, leaf)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading local tree leaves: %w", err)
+	}
+	if uint64(len(t.leaves)) < t.checkpoint.Size {
+		return nil, fmt.Errorf("local tree has %d leaves, checkpoint is for %d", len(t.leaves), t.checkpoint.Size)
+	}
+	// Leaves past the checkpoint aren't committed to by the log.
+	t.leaves = t.leaves[:t.checkpoint.Size]
+	return t, nil
+}
+
+// VerifyInclusion verifies that the tlog entry of the bundle payload is
+// included in the local tree, and that the tree head is signed by the log the
+// entry belongs to.
+func (t *LocalTree) VerifyInclusion(payload bundle.RekorPayload, rekorPubKeys *TrustedTransparencyLogPubKeys) error {
+	if err := t.verifyTreeHead(payload.LogID, rekorPubKeys); err != nil {
+		return err
+	}
+	body, ok := payload.Body.(string)
+	if !ok {
+		return errors.New("tlog entry body is not a string")
+	}
+	entryBytes, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return err
+	}
+	if payload.LogIndex < 0 || payload.LogIndex >= int64(len(t.leaves)) {
+		return fmt.Errorf("tlog entry %d is not in the local tree of size %d", payload.LogIndex, len(t.leaves))
+	}
+	if !bytes.Equal(t.leaves[payload.LogIndex], rfc6962.DefaultHasher.HashLeaf(entryBytes)) {
+		return fmt.Errorf("tlog entry %d does not match the local tree", payload.LogIndex)
+	}
+	return nil
+}
+
+func (t *LocalTree) verifyTreeHead(logID string, rekorPubKeys *TrustedTransparencyLogPubKeys) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.verified[logID] {
+		return nil
+	}
+
+	if rekorPubKeys == nil {
+		return errors.New("no trusted rekor public keys provided")
+	}
+	logKey, ok := rekorPubKeys.Keys[logID]
+	if !ok {
+		return errors.New("rekor log public key not found for the local tree")
+	}
+	v, err := signature.LoadVerifier(logKey.PubKey, crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	if !noteSignedBy(t.checkpoint.SignedNote, v) {
+		return errors.New("local tree checkpoint is not signed by the log")
+	}
+	if !bytes.Equal(merkleRoot(t.leaves), t.checkpoint.Hash) {
+		return errors.New("local tree does not match its checkpoint")
+	}
+	t.verified[logID] = true
+	return nil
+}
+
+// merkleRoot computes the RFC 6962 root hash of the leaf hashes.
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		return rfc6962.DefaultHasher.EmptyRoot()
+	case 1:
+		return leaves[0]
+	}
+	// Split at the largest power of two smaller than the number of leaves.
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	return rfc6962.DefaultHasher.HashChildren(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/rekor/pkg/util"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+	"github.com/sigstore/sigstore/pkg/tuf"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	for n := 1; n <= 17; n++ {
+		var leaves [][]byte
+		r := rf.NewEmptyRange(0)
+		for i := 0; i < n; i++ {
+			leaf := rfc6962.DefaultHasher.HashLeaf([]byte(fmt.Sprint(i)))
+			leaves = append(leaves, leaf)
+			if err := r.Append(leaf, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := r.GetRootHash(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := merkleRoot(leaves); !bytes.Equal(got, want) {
+			t.Errorf("merkleRoot() of %d leaves = %x, want %x", n, got, want)
+		}
+	}
+}
+
+func TestLocalTreeVerifyInclusion(t *testing.T) {
+	newSigner := func() signature.SignerVerifier {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sv
+	}
+	logSigner := newSigner()
+	logPub, err := logSigner.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPEM, err := cryptoutils.MarshalPublicKeyToPEM(logPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorPubKeys := NewTrustedTransparencyLogPubKeys()
+	if err := rekorPubKeys.AddTransparencyLogPubKey(logPEM, tuf.Active); err != nil {
+		t.Fatal(err)
+	}
+	logID, err := GetTransparencyLogID(logPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bodies []string
+	var leaves [][]byte
+	for i := 0; i < 5; i++ {
+		body := []byte(fmt.Sprintf(`{"entry":%d}`, i))
+		bodies = append(bodies, base64.StdEncoding.EncodeToString(body))
+		leaves = append(leaves, rfc6962.DefaultHasher.HashLeaf(body))
+	}
+
+	writeTree := func(t *testing.T, signer signature.Signer, leaves [][]byte) string {
+		t.Helper()
+		dir := t.TempDir()
+		sc, err := util.CreateSignedCheckpoint(util.Checkpoint{Origin: "rekor.example.com - 1", Size: uint64(len(leaves)), Hash: merkleRoot(leaves)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sc.Sign("rekor.example.com", signer, options.WithCryptoSignerOpts(crypto.SHA256)); err != nil {
+			t.Fatal(err)
+		}
+		cp, err := sc.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, LocalTreeCheckpointFile), cp, 0600); err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, l := range leaves {
+			lines = append(lines, hex.EncodeToString(l))
+		}
+		if err := os.WriteFile(filepath.Join(dir, LocalTreeLeavesFile), []byte(strings.Join(lines, "\n")), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	tests := []struct {
+		description string
+		signer      signature.Signer
+		body        string
+		index       int64
+		wantErr     string
+	}{
+		{
+			description: "entry included",
+			signer:      logSigner,
+			body:        bodies[3],
+			index:       3,
+		}, {
+			description: "entry at another index",
+			signer:      logSigner,
+			body:        bodies[3],
+			index:       2,
+			wantErr:     "does not match the local tree",
+		}, {
+			description: "entry past the tree",
+			signer:      logSigner,
+			body:        bodies[3],
+			index:       5,
+			wantErr:     "is not in the local tree",
+		}, {
+			description: "checkpoint not signed by the log",
+			signer:      newSigner(),
+			body:        bodies[3],
+			index:       3,
+			wantErr:     "not signed by the log",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tree, err := LoadLocalTree(writeTree(t, test.signer, leaves))
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload := bundle.RekorPayload{Body: test.body, LogIndex: test.index, LogID: logID}
+			err = tree.VerifyInclusion(payload, &rekorPubKeys)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyInclusion() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("VerifyInclusion() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+
+	t.Run("tampered leaves", func(t *testing.T) {
+		dir := writeTree(t, logSigner, leaves)
+		tampered := append([][]byte{}, leaves...)
+		tampered[1] = rfc6962.DefaultHasher.HashLeaf([]byte("tampered"))
+		var lines []string
+		for _, l := range tampered {
+			lines = append(lines, hex.EncodeToString(l))
+		}
+		if err := os.WriteFile(filepath.Join(dir, LocalTreeLeavesFile), []byte(strings.Join(lines, "\n")), 0600); err != nil {
+			t.Fatal(err)
+		}
+		tree, err := LoadLocalTree(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tree.VerifyInclusion(bundle.RekorPayload{Body: bodies[3], LogIndex: 3, LogID: logID}, &rekorPubKeys)
+		if err == nil || !strings.Contains(err.Error(), "does not match its checkpoint") {
+			t.Fatalf("VerifyInclusion() = %v, expected root mismatch", err)
+		}
+	})
+}
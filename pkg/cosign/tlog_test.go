@@ -17,17 +17,24 @@ package cosign
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
 	"strings"
 	"testing"
 
+	"github.com/go-openapi/swag"
 	ttestdata "github.com/google/certificate-transparency-go/trillian/testdata"
 	"github.com/sigstore/rekor/pkg/generated/models"
+	"github.com/sigstore/rekor/pkg/util"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/options"
 	"github.com/sigstore/sigstore/pkg/tuf"
 )
 
@@ -181,3 +188,171 @@ func TestVerifyTLogEntryOfflineFailsWithInvalidPublicKey(t *testing.T) {
 		t.Fatalf("Did not get expected error message, wanted 'is not type ecdsa.PublicKey' got: %v", err)
 	}
 }
+
+func TestVerifyCheckpointWitnesses(t *testing.T) {
+	newSigner := func() signature.SignerVerifier {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sv
+	}
+	logSigner, witness, otherWitness := newSigner(), newSigner(), newSigner()
+
+	logPub, err := logSigner.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPEM, err := cryptoutils.MarshalPublicKeyToPEM(logPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorPubKeys := NewTrustedTransparencyLogPubKeys()
+	if err := rekorPubKeys.AddTransparencyLogPubKey(logPEM, tuf.Active); err != nil {
+		t.Fatal(err)
+	}
+	logID, err := GetTransparencyLogID(logPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := func(t *testing.T, signers ...signature.Signer) *models.LogEntryAnon {
+		return checkpointEntry(t, logID, "rekor.example.com - 1", signers...)
+	}
+
+	tests := []struct {
+		description string
+		signers     []signature.Signer
+		wantErr     string
+	}{
+		{
+			description: "co-signed by a witness",
+			signers:     []signature.Signer{logSigner, otherWitness, witness},
+		}, {
+			description: "not co-signed by a witness",
+			signers:     []signature.Signer{logSigner, otherWitness},
+			wantErr:     "not co-signed",
+		}, {
+			description: "not signed by the log",
+			signers:     []signature.Signer{witness},
+			wantErr:     "not signed by the log",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := VerifyCheckpointWitnesses(entry(t, test.signers...), &rekorPubKeys, []signature.Verifier{witness})
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyCheckpointWitnesses() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("VerifyCheckpointWitnesses() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// checkpointEntry returns a log entry whose inclusion proof has a checkpoint
+// with the given origin, signed by signers.
+func checkpointEntry(t *testing.T, logID, origin string, signers ...signature.Signer) *models.LogEntryAnon {
+	t.Helper()
+	rootHash := sha256.Sum256([]byte("root"))
+	sc, err := util.CreateSignedCheckpoint(util.Checkpoint{Origin: origin, Size: 10, Hash: rootHash[:]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range signers {
+		if _, err := sc.Sign("signer", s, options.WithCryptoSignerOpts(crypto.SHA256)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkpoint, err := sc.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.LogEntryAnon{
+		LogID: swag.String(logID),
+		Verification: &models.LogEntryAnonVerification{
+			InclusionProof: &models.InclusionProof{
+				Checkpoint: swag.String(string(checkpoint)),
+				RootHash:   swag.String(hex.EncodeToString(rootHash[:])),
+				TreeSize:   swag.Int64(10),
+			},
+		},
+	}
+}
+
+func TestVerifyCheckpointTreeID(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logSigner, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPEM, err := cryptoutils.MarshalPublicKeyToPEM(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorPubKeys := NewTrustedTransparencyLogPubKeys()
+	if err := rekorPubKeys.AddTransparencyLogPubKey(logPEM, tuf.Active); err != nil {
+		t.Fatal(err)
+	}
+	logID, err := GetTransparencyLogID(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := signature.LoadECDSASignerVerifier(otherPriv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const treeID = 1193050959916656506
+	tests := []struct {
+		description string
+		entry       *models.LogEntryAnon
+		wantErr     string
+	}{
+		{
+			description: "expected tree",
+			entry:       checkpointEntry(t, logID, "rekor.sigstore.dev - 1193050959916656506", logSigner),
+		}, {
+			description: "other tree",
+			entry:       checkpointEntry(t, logID, "rekor.sigstore.dev - 2605736670972794746", logSigner),
+			wantErr:     "belongs to the tree 2605736670972794746",
+		}, {
+			description: "no tree ID",
+			entry:       checkpointEntry(t, logID, "rekor.sigstore.dev", logSigner),
+			wantErr:     "does not name a tree ID",
+		}, {
+			description: "not signed by the log",
+			entry:       checkpointEntry(t, logID, "rekor.sigstore.dev - 1193050959916656506", otherSigner),
+			wantErr:     "not signed by the log",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := VerifyCheckpointTreeID(test.entry, &rekorPubKeys, treeID)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyCheckpointTreeID() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("VerifyCheckpointTreeID() = %v, wanted %q", err, test.wantErr)
+			}
+		})
+	}
+}
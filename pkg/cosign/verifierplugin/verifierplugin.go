@@ -0,0 +1,170 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verifierplugin lets cosign delegate signature verification to an
+// external program.
+//
+// A plugin is an executable that cosign invokes with one of the following
+// subcommands appended to its arguments:
+//
+//	verify
+//	    Reads a JSON encoded VerifyRequest from stdin. The plugin must exit
+//	    with status 0 if the signature is valid for the message, and with a
+//	    non-zero status otherwise. Anything written to stderr is included in
+//	    the verification error.
+//
+//	public-key
+//	    Writes the PEM encoded public key of the verifier to stdout. This is
+//	    only needed when the key has to be compared against a tlog entry.
+package verifierplugin
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+const (
+	// VerifyCommand is the subcommand used to verify a signature.
+	VerifyCommand = "verify"
+	// PublicKeyCommand is the subcommand used to retrieve the public key.
+	PublicKeyCommand = "public-key"
+)
+
+// VerifyRequest is sent to the plugin's verify command.
+type VerifyRequest struct {
+	// Signature holds the raw signature bytes.
+	Signature []byte `json:"signature"`
+	// Message holds the signed message. For DSSE envelopes this is the PAE
+	// encoding of the payload.
+	Message []byte `json:"message"`
+}
+
+// ExecVerifier is a signature.Verifier backed by a plugin executable.
+type ExecVerifier struct {
+	path string
+	args []string
+}
+
+var _ signature.Verifier = (*ExecVerifier)(nil)
+
+// NewExecVerifier returns a verifier running the given command line. The
+// command is split on whitespace, the first field being the executable.
+func NewExecVerifier(command string) (*ExecVerifier, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("empty verifier plugin command")
+	}
+	return &ExecVerifier{path: fields[0], args: fields[1:]}, nil
+}
+
+// VerifySignature sends the signature and message to the plugin.
+func (v *ExecVerifier) VerifySignature(sig, message io.Reader, opts ...signature.VerifyOption) error {
+	ctx := context.Background()
+	for _, opt := range opts {
+		opt.ApplyContext(&ctx)
+	}
+
+	req := VerifyRequest{}
+	var err error
+	if req.Signature, err = io.ReadAll(sig); err != nil {
+		return fmt.Errorf("reading signature: %w", err)
+	}
+	if req.Message, err = io.ReadAll(message); err != nil {
+		return fmt.Errorf("reading message: %w", err)
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := v.run(ctx, VerifyCommand, bytes.NewReader(b)); err != nil {
+		return fmt.Errorf("verifier plugin: %w", err)
+	}
+	return nil
+}
+
+// PublicKey asks the plugin for its PEM encoded public key.
+func (v *ExecVerifier) PublicKey(opts ...signature.PublicKeyOption) (crypto.PublicKey, error) {
+	ctx := context.Background()
+	for _, opt := range opts {
+		opt.ApplyContext(&ctx)
+	}
+	out, err := v.run(ctx, PublicKeyCommand, nil)
+	if err != nil {
+		return nil, fmt.Errorf("verifier plugin: %w", err)
+	}
+	return cryptoutils.UnmarshalPEMToPublicKey(out)
+}
+
+func (v *ExecVerifier) run(ctx context.Context, subcommand string, stdin io.Reader) ([]byte, error) {
+	args := append(append([]string{}, v.args...), subcommand)
+	cmd := exec.CommandContext(ctx, v.path, args...) // #nosec G204
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%s failed: %s", subcommand, strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// Serve implements the plugin side of the protocol on top of v, and is the
+// reference implementation of a plugin. A plugin's main function can be as
+// simple as:
+//
+//	if err := verifierplugin.Serve(verifier, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//		os.Exit(1)
+//	}
+func Serve(v signature.Verifier, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected %s or %s subcommand", VerifyCommand, PublicKeyCommand)
+	}
+	switch args[len(args)-1] {
+	case VerifyCommand:
+		req := VerifyRequest{}
+		if err := json.NewDecoder(stdin).Decode(&req); err != nil {
+			return fmt.Errorf("decoding verify request: %w", err)
+		}
+		return v.VerifySignature(bytes.NewReader(req.Signature), bytes.NewReader(req.Message))
+	case PublicKeyCommand:
+		pub, err := v.PublicKey()
+		if err != nil {
+			return err
+		}
+		pem, err := cryptoutils.MarshalPublicKeyToPEM(pub)
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(pem)
+		return err
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[len(args)-1])
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifierplugin
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// testPluginKeyEnv makes the test binary act as a verifier plugin for the
+// PEM encoded public key at the given path.
+const testPluginKeyEnv = "COSIGN_TEST_VERIFIER_PLUGIN_KEY"
+
+func TestMain(m *testing.M) {
+	if keyPath := os.Getenv(testPluginKeyEnv); keyPath != "" {
+		if err := servePublicKey(keyPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func servePublicKey(keyPath string) error {
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(pemBytes)
+	if err != nil {
+		return err
+	}
+	v, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	return Serve(v, os.Args[1:], os.Stdin, os.Stdout)
+}
+
+func TestExecVerifier(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := cryptoutils.MarshalPublicKeyToPEM(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	if err := os.WriteFile(keyPath, pemBytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testPluginKeyEnv, keyPath)
+
+	v, err := NewExecVerifier(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("some-payload")
+	sig, err := signer.SignMessage(bytes.NewReader(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(message)); err != nil {
+		t.Fatalf("VerifySignature() expected no error, got %v", err)
+	}
+	if err := v.VerifySignature(bytes.NewReader(sig), bytes.NewReader([]byte("another-payload"))); err == nil {
+		t.Fatal("VerifySignature() expected an error for a different message")
+	}
+
+	pub, err := v.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cryptoutils.EqualKeys(pub, priv.Public()); err != nil {
+		t.Fatalf("PublicKey() returned an unexpected key: %v", err)
+	}
+}
+
+func TestNewExecVerifierEmpty(t *testing.T) {
+	if _, err := NewExecVerifier("  "); err == nil {
+		t.Fatal("expected an error for an empty command")
+	}
+}
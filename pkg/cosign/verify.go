@@ -37,7 +37,9 @@ import (
 
 	"github.com/digitorus/timestamp"
 	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/swag"
 	"github.com/nozzle/throttler"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 
 	"github.com/sigstore/cosign/v2/internal/pkg/cosign"
 	"github.com/sigstore/cosign/v2/pkg/blob"
@@ -52,6 +54,7 @@ import (
 
 	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
 	ociexperimental "github.com/sigstore/cosign/v2/internal/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/internal/pkg/tracing"
 	"github.com/sigstore/cosign/v2/internal/ui"
 	"github.com/sigstore/cosign/v2/pkg/oci"
 	"github.com/sigstore/cosign/v2/pkg/oci/layout"
@@ -101,6 +104,23 @@ type CheckOpts struct {
 	// Note that even though the type is of crypto.PublicKey, Rekor only allows
 	// for ecdsa.PublicKey: https://github.com/sigstore/cosign/issues/2540
 	RekorPubKeys *TrustedTransparencyLogPubKeys
+	// RekorWitnessKeys, if set, requires the checkpoint of the log entry's
+	// inclusion proof to be co-signed by at least one of these witnesses, in
+	// addition to the log. Only online tlog lookups return a checkpoint.
+	RekorWitnessKeys []signature.Verifier
+	// RekorLocalTree, if set, is a local mirror of the log the inclusion of
+	// bundled tlog entries is verified against.
+	RekorLocalTree *LocalTree
+	// TlogEntryKind, if set, is the kind the tlog entry must be of, optionally
+	// followed by :<apiVersion>, e.g. dsse or intoto:0.0.2.
+	TlogEntryKind string
+	// RekorTreeID, if set, is the ID of the log tree, i.e. the Rekor shard,
+	// the checkpoint of the log entry's inclusion proof must be for. Only
+	// online tlog lookups return a checkpoint.
+	RekorTreeID int64
+	// RekorAfterCheckpoint, if set, requires the log entry to have been
+	// logged after this checkpoint.
+	RekorAfterCheckpoint *PinnedCheckpoint
 
 	// SigVerifier is used to verify signatures.
 	SigVerifier signature.Verifier
@@ -123,6 +143,27 @@ type CheckOpts struct {
 	// CertGithubWorkflowRef is the GitHub Workflow Ref expected for a certificate to be valid. The empty string means any certificate can be valid.
 	CertGithubWorkflowRef string
 
+	// CertSPKIPins are SHA-256 digests of a certificate's SubjectPublicKeyInfo. If set, the certificate's public key must match one of them.
+	CertSPKIPins [][]byte
+	// CertPolicyOID is a certificate policy OID, in dotted form, the certificate must carry. The empty string means any certificate can be valid.
+	CertPolicyOID string
+	// CertSPIFFEID is the SPIFFE ID, in the canonical form of NormalizeSPIFFEID, a URI SAN of the certificate must be.
+	// The empty string means any certificate can be valid.
+	CertSPIFFEID string
+	// IntermediateSKI is the Subject Key Identifier of an intermediate CA a chain built for the certificate must go through.
+	// Nil means any chain can be valid.
+	IntermediateSKI []byte
+	// MaxCertLifetime is the longest validity period, NotAfter minus NotBefore, the certificate may have. Zero means any lifetime is accepted.
+	MaxCertLifetime time.Duration
+	// ClockSkew widens the time windows by this amount on both ends, to tolerate clocks that are slightly out of
+	// sync: the certificate validity period when checking it against the signing time, and the time of the
+	// RekorAfterCheckpoint checkpoint when checking the integrated time of the tlog entry against it. Zero means
+	// the windows are used as is.
+	ClockSkew time.Duration
+	// RequireSigningTime requires a certificate's validity to be checked against a trusted signing time, the tlog
+	// integrated time or an RFC3161 timestamp, rather than the current time.
+	RequireSigningTime bool
+
 	// IgnoreSCT requires that a certificate contain an embedded SCT during verification. An SCT is proof of inclusion in a
 	// certificate transparency log.
 	IgnoreSCT bool
@@ -162,6 +203,15 @@ type CheckOpts struct {
 	// Should the experimental OCI 1.1 behaviour be enabled or not.
 	// Defaults to false.
 	ExperimentalOCI11 bool
+
+	// DSSEPAE names the pre-authentication encoding the DSSE envelope signatures of blob attestations are over,
+	// one registered with RegisterPAE. Empty, the default, means StandardPAE. See RegisterPAE for how a custom
+	// PAE weakens verification.
+	DSSEPAE string
+
+	// DecodeDSSESignature decodes the base64 signatures of DSSE envelopes. Nil, the default, means the standard
+	// base64 encoding. The envelope is left as is, so that its tlog entry or bundle still verifies.
+	DecodeDSSESignature func(sig string) ([]byte, error)
 }
 
 // This is a substitutable signature verification function that can be used for verifying
@@ -177,6 +227,13 @@ type payloader interface {
 }
 
 func verifyOCIAttestation(ctx context.Context, verifier signature.Verifier, att payloader) error {
+	return verifyDSSEEnvelope(ctx, verifier, att, types.IntotoPayloadType, nil)
+}
+
+// verifyDSSEEnvelope verifies the DSSE envelope att, whose payload must be of
+// payloadType unless it is empty, with the PAE and signature decoding of co,
+// the standard ones if co is nil.
+func verifyDSSEEnvelope(ctx context.Context, verifier signature.Verifier, att payloader, payloadType string, co *CheckOpts) error {
 	payload, err := att.Payload()
 	if err != nil {
 		return err
@@ -187,17 +244,59 @@ func verifyOCIAttestation(ctx context.Context, verifier signature.Verifier, att
 		return err
 	}
 
-	if env.PayloadType != types.IntotoPayloadType {
+	if payloadType != "" && env.PayloadType != payloadType {
 		return &VerificationFailure{
-			fmt.Errorf("invalid payloadType %s on envelope. Expected %s", env.PayloadType, types.IntotoPayloadType),
+			fmt.Errorf("invalid payloadType %s on envelope. Expected %s", env.PayloadType, payloadType),
 		}
 	}
-	dssev, err := ssldsse.NewEnvelopeVerifier(&dsse.VerifierAdapter{SignatureVerifier: verifier})
-	if err != nil {
+	return VerifyDSSEEnvelopeSignatures(ctx, verifier, &env, co)
+}
+
+// VerifyDSSEEnvelopeSignatures verifies that a signature of the DSSE envelope
+// env verifies with verifier, over the message computed by the PAE named by
+// co.DSSEPAE, once decoded with co.DecodeDSSESignature. co may be nil, for the
+// standard PAE and encoding.
+func VerifyDSSEEnvelopeSignatures(ctx context.Context, verifier signature.Verifier, env *ssldsse.Envelope, co *CheckOpts) error {
+	pae, decode := ssldsse.PAE, base64.StdEncoding.DecodeString
+	custom := false
+	if co != nil && co.DSSEPAE != "" && co.DSSEPAE != StandardPAE {
+		var err error
+		if pae, err = LookupPAE(co.DSSEPAE); err != nil {
+			return err
+		}
+		custom = true
+	}
+	if co != nil && co.DecodeDSSESignature != nil {
+		decode = co.DecodeDSSESignature
+	} else if !custom {
+		dssev, err := ssldsse.NewEnvelopeVerifier(&dsse.VerifierAdapter{SignatureVerifier: verifier})
+		if err != nil {
+			return err
+		}
+		_, err = dssev.Verify(ctx, env)
 		return err
 	}
-	_, err = dssev.Verify(ctx, &env)
-	return err
+
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return fmt.Errorf("decoding DSSE payload: %w", err)
+	}
+	message := pae(env.PayloadType, payload)
+	for _, s := range env.Signatures {
+		sig, err := decode(s.Sig)
+		if err != nil {
+			continue
+		}
+		if err := verifier.VerifySignature(bytes.NewReader(sig), bytes.NewReader(message), options.WithContext(ctx)); err == nil {
+			return nil
+		}
+	}
+	if custom {
+		return &VerificationFailure{
+			errors.New("no signature of the envelope verifies over the message of the custom PAE"),
+		}
+	}
+	return &VerificationFailure{errors.New("no signature of the envelope verifies")}
 }
 
 func verifyOCISignature(ctx context.Context, verifier signature.Verifier, sig payloader) error {
@@ -219,6 +318,12 @@ func verifyOCISignature(ctx context.Context, verifier signature.Verifier, sig pa
 // ValidateAndUnpackCert creates a Verifier from a certificate. Veries that the certificate
 // chains up to a trusted root. Optionally verifies the subject and issuer of the certificate.
 func ValidateAndUnpackCert(cert *x509.Certificate, co *CheckOpts) (signature.Verifier, error) {
+	return validateAndUnpackCert(context.Background(), cert, co)
+}
+
+// validateAndUnpackCert is ValidateAndUnpackCert, tracing the SCT
+// verification in ctx.
+func validateAndUnpackCert(ctx context.Context, cert *x509.Certificate, co *CheckOpts) (signature.Verifier, error) {
 	verifier, err := signature.LoadVerifier(cert.PublicKey, crypto.SHA256)
 	if err != nil {
 		return nil, fmt.Errorf("invalid certificate found on signature: %w", err)
@@ -243,6 +348,9 @@ func ValidateAndUnpackCert(cert *x509.Certificate, co *CheckOpts) (signature.Ver
 	if err != nil {
 		return nil, err
 	}
+	if err := checkIntermediateSKI(chains, co.IntermediateSKI); err != nil {
+		return nil, err
+	}
 
 	err = CheckCertificatePolicy(cert, co)
 	if err != nil {
@@ -253,12 +361,25 @@ func ValidateAndUnpackCert(cert *x509.Certificate, co *CheckOpts) (signature.Ver
 	if co.IgnoreSCT {
 		return verifier, nil
 	}
-	contains, err := ContainsSCT(cert.Raw)
+	sctCtx, span := tracing.Start(ctx, "sct")
+	err = verifyCertSCT(sctCtx, cert, chains, co)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
+
+	return verifier, nil
+}
+
+// verifyCertSCT verifies the embedded SCT of cert, or else the detached
+// co.SCT, over the first of its verified chains.
+func verifyCertSCT(ctx context.Context, cert *x509.Certificate, chains [][]*x509.Certificate, co *CheckOpts) error {
+	contains, err := ContainsSCT(cert.Raw)
+	if err != nil {
+		return err
+	}
 	if !contains && len(co.SCT) == 0 {
-		return nil, &VerificationFailure{
+		return &VerificationFailure{
 			fmt.Errorf("certificate does not include required embedded SCT and no detached SCT was set"),
 		}
 	}
@@ -267,28 +388,21 @@ func ValidateAndUnpackCert(cert *x509.Certificate, co *CheckOpts) (signature.Ver
 		fmt.Fprintf(os.Stderr, "**Info** Multiple valid certificate chains found. Selecting the first to verify the SCT.\n")
 	}
 	if contains {
-		if err := VerifyEmbeddedSCT(context.Background(), chains[0], co.CTLogPubKeys); err != nil {
-			return nil, err
-		}
-	} else {
-		chain := chains[0]
-		if len(chain) < 2 {
-			return nil, errors.New("certificate chain must contain at least a certificate and its issuer")
-		}
-		certPEM, err := cryptoutils.MarshalCertificateToPEM(chain[0])
-		if err != nil {
-			return nil, err
-		}
-		chainPEM, err := cryptoutils.MarshalCertificatesToPEM(chain[1:])
-		if err != nil {
-			return nil, err
-		}
-		if err := VerifySCT(context.Background(), certPEM, chainPEM, co.SCT, co.CTLogPubKeys); err != nil {
-			return nil, err
-		}
+		return VerifyEmbeddedSCT(ctx, chains[0], co.CTLogPubKeys)
 	}
-
-	return verifier, nil
+	chain := chains[0]
+	if len(chain) < 2 {
+		return errors.New("certificate chain must contain at least a certificate and its issuer")
+	}
+	certPEM, err := cryptoutils.MarshalCertificateToPEM(chain[0])
+	if err != nil {
+		return err
+	}
+	chainPEM, err := cryptoutils.MarshalCertificatesToPEM(chain[1:])
+	if err != nil {
+		return err
+	}
+	return VerifySCT(ctx, certPEM, chainPEM, co.SCT, co.CTLogPubKeys)
 }
 
 // CheckCertificatePolicy checks that the certificate subject and issuer match
@@ -299,6 +413,18 @@ func CheckCertificatePolicy(cert *x509.Certificate, co *CheckOpts) error {
 	if err := validateCertExtensions(ce, co); err != nil {
 		return err
 	}
+	if err := checkSPKIPins(cert, co.CertSPKIPins); err != nil {
+		return err
+	}
+	if err := checkCertPolicyOID(cert, co.CertPolicyOID); err != nil {
+		return err
+	}
+	if err := checkCertSPIFFEID(cert, co.CertSPIFFEID); err != nil {
+		return err
+	}
+	if err := checkCertLifetime(cert, co.MaxCertLifetime); err != nil {
+		return err
+	}
 	oidcIssuer := ce.GetIssuer()
 	sans := cryptoutils.GetSubjectAlternateNames(cert)
 	// If there are identities given, go through them and if one of them
@@ -403,6 +529,116 @@ func validateCertExtensions(ce CertExtensions, co *CheckOpts) error {
 	return nil
 }
 
+// checkSPKIPins verifies that the SHA-256 digest of the certificate's
+// SubjectPublicKeyInfo matches one of the pinned digests, if any are given.
+func checkSPKIPins(cert *x509.Certificate, pins [][]byte) error {
+	if len(pins) == 0 {
+		return nil
+	}
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if bytes.Equal(h[:], pin) {
+			return nil
+		}
+	}
+	return &VerificationFailure{
+		fmt.Errorf("certificate public key does not match any pinned SPKI hash, got %s", base64.StdEncoding.EncodeToString(h[:])),
+	}
+}
+
+// checkCertPolicyOID verifies that the certificate carries the certificate
+// policy, if one is given.
+func checkCertPolicyOID(cert *x509.Certificate, oid string) error {
+	if oid == "" {
+		return nil
+	}
+	for _, p := range cert.PolicyIdentifiers {
+		if p.String() == oid {
+			return nil
+		}
+	}
+	return &VerificationFailure{
+		fmt.Errorf("certificate does not carry the certificate policy %s", oid),
+	}
+}
+
+// spiffeScheme is the scheme of SPIFFE IDs.
+const spiffeScheme = "spiffe://"
+
+// NormalizeSPIFFEID returns the canonical form of the SPIFFE ID id, with its
+// scheme and trust domain lowercased and no trailing slash, e.g.
+// spiffe://example.org/ci for SPIFFE://Example.org/ci/. It fails if id isn't
+// a valid SPIFFE ID.
+func NormalizeSPIFFEID(id string) (string, error) {
+	if len(id) < len(spiffeScheme) || !strings.EqualFold(id[:len(spiffeScheme)], spiffeScheme) {
+		return "", fmt.Errorf("invalid SPIFFE ID %q: expected the %s scheme", id, spiffeScheme)
+	}
+	td, path, _ := strings.Cut(id[len(spiffeScheme):], "/")
+	normalized := spiffeScheme + strings.ToLower(td)
+	if path = strings.TrimRight(path, "/"); path != "" {
+		normalized += "/" + path
+	}
+	spiffeID, err := spiffeid.FromString(normalized)
+	if err != nil {
+		return "", fmt.Errorf("invalid SPIFFE ID %q: %w", id, err)
+	}
+	return spiffeID.String(), nil
+}
+
+// checkCertSPIFFEID verifies that a URI SAN of the certificate is the SPIFFE
+// ID, if one is given, once both are normalized.
+func checkCertSPIFFEID(cert *x509.Certificate, id string) error {
+	if id == "" {
+		return nil
+	}
+	uris := make([]string, 0, len(cert.URIs))
+	for _, u := range cert.URIs {
+		if san, err := NormalizeSPIFFEID(u.String()); err == nil && san == id {
+			return nil
+		}
+		uris = append(uris, u.String())
+	}
+	return &VerificationFailure{
+		fmt.Errorf("certificate URI SAN is not the SPIFFE ID %s, got [%s]", id, strings.Join(uris, ", ")),
+	}
+}
+
+// checkIntermediateSKI verifies that one of the chains built for the
+// certificate goes through an intermediate CA of Subject Key Identifier ski,
+// if one is given. The leaf and the root of the chains aren't intermediates.
+func checkIntermediateSKI(chains [][]*x509.Certificate, ski []byte) error {
+	if len(ski) == 0 {
+		return nil
+	}
+	for _, chain := range chains {
+		if len(chain) < 3 {
+			continue
+		}
+		for _, c := range chain[1 : len(chain)-1] {
+			if bytes.Equal(c.SubjectKeyId, ski) {
+				return nil
+			}
+		}
+	}
+	return &VerificationFailure{
+		fmt.Errorf("certificate chain does not go through the intermediate CA of subject key identifier %s", hex.EncodeToString(ski)),
+	}
+}
+
+// checkCertLifetime verifies that the certificate is not valid for longer
+// than max, if a limit is given.
+func checkCertLifetime(cert *x509.Certificate, max time.Duration) error {
+	if max <= 0 {
+		return nil
+	}
+	if lifetime := cert.NotAfter.Sub(cert.NotBefore); lifetime > max {
+		return &VerificationFailure{
+			fmt.Errorf("certificate lifetime %s exceeds the maximum of %s", lifetime, max),
+		}
+	}
+	return nil
+}
+
 // ValidateAndUnpackCertWithChain creates a Verifier from a certificate. Verifies that the certificate
 // chains up to the provided root. Chain should start with the parent of the certificate and end with the root.
 // Optionally verifies the subject and issuer of the certificate.
@@ -463,6 +699,20 @@ func tlogValidateEntry(ctx context.Context, client *client.Rekor, rekorPubKeys *
 	return &earliestLogEntry, nil
 }
 
+// FindVerifiedTlogEntry looks up the tlog entries for sig using
+// co.RekorClient, and returns the earliest one that verifies against
+// co.RekorPubKeys.
+func FindVerifiedTlogEntry(ctx context.Context, sig oci.Signature, co *CheckOpts) (*models.LogEntryAnon, error) {
+	if co.RekorClient == nil {
+		return nil, fmt.Errorf("rekor client not provided for online lookup")
+	}
+	pemBytes, err := keyBytes(sig, co)
+	if err != nil {
+		return nil, err
+	}
+	return tlogValidateEntry(ctx, co.RekorClient, co.RekorPubKeys, sig, pemBytes)
+}
+
 type fakeOCISignatures struct {
 	oci.Signatures
 	signatures []oci.Signature
@@ -665,44 +915,13 @@ func verifyInternal(ctx context.Context, sig oci.Signature, h v1.Hash,
 	}
 
 	if !co.IgnoreTlog {
-		bundleVerified, err = VerifyBundle(sig, co)
+		tlogCtx, span := tracing.Start(ctx, "tlog")
+		bundleVerified, acceptableRekorBundleTime, err = verifyTlog(tlogCtx, sig, co)
+		tracing.End(span, err)
 		if err != nil {
-			return false, fmt.Errorf("error verifying bundle: %w", err)
-		}
-
-		if bundleVerified {
-			// Update with the verified bundle's integrated time.
-			t, err := getBundleIntegratedTime(sig)
-			if err != nil {
-				return false, fmt.Errorf("error getting bundle integrated time: %w", err)
-			}
-			acceptableRekorBundleTime = &t
-		} else {
-			// If the --offline flag was specified, fail here. bundleVerified returns false with
-			// no error when there was no bundle provided.
-			if co.Offline {
-				return false, fmt.Errorf("offline verification failed")
-			}
-
-			// no Rekor client provided for an online lookup
-			if co.RekorClient == nil {
-				return false, fmt.Errorf("rekor client not provided for online verification")
-			}
-
-			pemBytes, err := keyBytes(sig, co)
-			if err != nil {
-				return false, err
-			}
-
-			e, err := tlogValidateEntry(ctx, co.RekorClient, co.RekorPubKeys, sig, pemBytes)
-			if err != nil {
-				return false, err
-			}
-			t := time.Unix(*e.IntegratedTime, 0)
-			acceptableRekorBundleTime = &t
+			return false, err
 		}
 	}
-
 	verifier := co.SigVerifier
 	if verifier == nil {
 		// If we don't have a public key to check against, we can try a root cert.
@@ -733,14 +952,17 @@ func verifyInternal(ctx context.Context, sig oci.Signature, h v1.Hash,
 				co.IntermediateCerts = pool
 			}
 		}
-		verifier, err = ValidateAndUnpackCert(cert, co)
+		verifier, err = validateAndUnpackCert(ctx, cert, co)
 		if err != nil {
 			return false, err
 		}
 	}
 
 	// 1. Perform cryptographic verification of the signature using the certificate's public key.
-	if err := verifyFn(ctx, verifier, sig); err != nil {
+	sigCtx, span := tracing.Start(ctx, "signature")
+	err = verifyFn(sigCtx, verifier, sig)
+	tracing.End(span, err)
+	if err != nil {
 		return false, err
 	}
 
@@ -762,22 +984,28 @@ func verifyInternal(ctx context.Context, sig oci.Signature, h v1.Hash,
 
 		if acceptableRFC3161Time != nil {
 			// Verify the cert against the timestamp time.
-			if err := CheckExpiry(cert, *acceptableRFC3161Time); err != nil {
+			if err := checkExpiry(cert, *acceptableRFC3161Time, co.ClockSkew); err != nil {
 				return false, fmt.Errorf("checking expiry on certificate with timestamp: %w", err)
 			}
 			expirationChecked = true
 		}
 
 		if acceptableRekorBundleTime != nil {
-			if err := CheckExpiry(cert, *acceptableRekorBundleTime); err != nil {
+			if err := checkExpiry(cert, *acceptableRekorBundleTime, co.ClockSkew); err != nil {
 				return false, fmt.Errorf("checking expiry on certificate with bundle: %w", err)
 			}
 			expirationChecked = true
 		}
 
+		if !expirationChecked && co.RequireSigningTime {
+			return false, &VerificationFailure{
+				fmt.Errorf("no tlog entry or signed timestamp to check the certificate validity against"),
+			}
+		}
+
 		// if no timestamp has been provided, use the current time
 		if !expirationChecked {
-			if err := CheckExpiry(cert, time.Now()); err != nil {
+			if err := checkExpiry(cert, time.Now(), co.ClockSkew); err != nil {
 				// If certificate is expired and not signed timestamp was provided then error the following message. Otherwise throw an expiration error.
 				if co.IgnoreTlog && acceptableRFC3161Time == nil {
 					return false, &VerificationFailure{
@@ -792,6 +1020,109 @@ func verifyInternal(ctx context.Context, sig oci.Signature, h v1.Hash,
 	return bundleVerified, nil
 }
 
+// verifyTlog verifies the tlog entry of the signature, from its bundle or
+// online, and returns its integrated time.
+func verifyTlog(ctx context.Context, sig oci.Signature, co *CheckOpts) (bundleVerified bool, integratedTime *time.Time, err error) {
+	bundleVerified, err = VerifyBundle(sig, co)
+	if err != nil {
+		return false, nil, fmt.Errorf("error verifying bundle: %w", err)
+	}
+
+	if bundleVerified && len(co.RekorWitnessKeys) > 0 {
+		return false, nil, fmt.Errorf("rekor witness co-signatures can't be verified from a bundle, the bundle doesn't include a checkpoint")
+	}
+	if bundleVerified && co.RekorTreeID != 0 {
+		return false, nil, fmt.Errorf("the rekor tree ID can't be verified from a bundle, the bundle doesn't include a checkpoint")
+	}
+	if co.RekorLocalTree != nil {
+		if !bundleVerified {
+			return false, nil, fmt.Errorf("verifying against a local rekor tree requires a bundle with the tlog entry")
+		}
+		b, err := sig.Bundle()
+		if err != nil {
+			return false, nil, err
+		}
+		if err := co.RekorLocalTree.VerifyInclusion(b.Payload, co.RekorPubKeys); err != nil {
+			return false, nil, fmt.Errorf("verifying inclusion in local rekor tree: %w", err)
+		}
+	}
+	if bundleVerified && co.TlogEntryKind != "" {
+		b, err := sig.Bundle()
+		if err != nil {
+			return false, nil, err
+		}
+		if err := checkTlogEntryKind(b.Payload.Body, co.TlogEntryKind); err != nil {
+			return false, nil, err
+		}
+	}
+	if bundleVerified {
+		// Update with the verified bundle's integrated time.
+		t, err := getBundleIntegratedTime(sig)
+		if err != nil {
+			return false, nil, fmt.Errorf("error getting bundle integrated time: %w", err)
+		}
+		integratedTime = &t
+		if co.RekorAfterCheckpoint != nil {
+			b, err := sig.Bundle()
+			if err != nil {
+				return false, nil, err
+			}
+			if err := co.RekorAfterCheckpoint.VerifyAfter(b.Payload.LogID, b.Payload.LogIndex, t, co.ClockSkew, co.RekorPubKeys); err != nil {
+				return false, nil, err
+			}
+		}
+	} else {
+		// If the --offline flag was specified, fail here. bundleVerified returns false with
+		// no error when there was no bundle provided.
+		if co.Offline {
+			return false, nil, fmt.Errorf("offline verification failed")
+		}
+
+		// no Rekor client provided for an online lookup
+		if co.RekorClient == nil {
+			return false, nil, fmt.Errorf("rekor client not provided for online verification")
+		}
+
+		pemBytes, err := keyBytes(sig, co)
+		if err != nil {
+			return false, nil, err
+		}
+
+		e, err := tlogValidateEntry(ctx, co.RekorClient, co.RekorPubKeys, sig, pemBytes)
+		if err != nil {
+			return false, nil, err
+		}
+		if len(co.RekorWitnessKeys) > 0 {
+			if err := VerifyCheckpointWitnesses(e, co.RekorPubKeys, co.RekorWitnessKeys); err != nil {
+				return false, nil, err
+			}
+		}
+		if co.RekorTreeID != 0 {
+			if err := VerifyCheckpointTreeID(e, co.RekorPubKeys, co.RekorTreeID); err != nil {
+				return false, nil, err
+			}
+		}
+		if co.TlogEntryKind != "" {
+			if err := checkTlogEntryKind(e.Body, co.TlogEntryKind); err != nil {
+				return false, nil, err
+			}
+		}
+		t := time.Unix(*e.IntegratedTime, 0)
+		integratedTime = &t
+		if co.RekorAfterCheckpoint != nil {
+			// The index within the tree, which the checkpoint is of.
+			logIndex := swag.Int64Value(e.LogIndex)
+			if e.Verification != nil && e.Verification.InclusionProof != nil {
+				logIndex = swag.Int64Value(e.Verification.InclusionProof.LogIndex)
+			}
+			if err := co.RekorAfterCheckpoint.VerifyAfter(swag.StringValue(e.LogID), logIndex, t, co.ClockSkew, co.RekorPubKeys); err != nil {
+				return false, nil, err
+			}
+		}
+	}
+	return bundleVerified, integratedTime, nil
+}
+
 func keyBytes(sig oci.Signature, co *CheckOpts) ([]byte, error) {
 	cert, err := sig.Cert()
 	if err != nil {
@@ -942,7 +1273,18 @@ func VerifyLocalImageAttestations(ctx context.Context, path string, co *CheckOpt
 
 func VerifyBlobAttestation(ctx context.Context, att oci.Signature, h v1.Hash, co *CheckOpts) (
 	bool, error) {
-	return verifyInternal(ctx, att, h, verifyOCIAttestation, co)
+	return verifyInternal(ctx, att, h, func(ctx context.Context, verifier signature.Verifier, att payloader) error {
+		return verifyDSSEEnvelope(ctx, verifier, att, types.IntotoPayloadType, co)
+	}, co)
+}
+
+// VerifyBlobDSSEEnvelope verifies a DSSE envelope like VerifyBlobAttestation,
+// but accepts a payload of any type rather than an in-toto statement.
+func VerifyBlobDSSEEnvelope(ctx context.Context, att oci.Signature, h v1.Hash, co *CheckOpts) (
+	bool, error) {
+	return verifyInternal(ctx, att, h, func(ctx context.Context, verifier signature.Verifier, att payloader) error {
+		return verifyDSSEEnvelope(ctx, verifier, att, "", co)
+	}, co)
 }
 
 func VerifyImageAttestation(ctx context.Context, atts oci.Signatures, h v1.Hash, co *CheckOpts) (checkedAttestations []oci.Signature, bundleVerified bool, err error) {
@@ -1009,16 +1351,22 @@ func VerifyImageAttestation(ctx context.Context, atts oci.Signatures, h v1.Hash,
 
 // CheckExpiry confirms the time provided is within the valid period of the cert
 func CheckExpiry(cert *x509.Certificate, it time.Time) error {
+	return checkExpiry(cert, it, 0)
+}
+
+// checkExpiry confirms the time provided is within the valid period of the
+// cert, widened by skew on both ends.
+func checkExpiry(cert *x509.Certificate, it time.Time, skew time.Duration) error {
 	ft := func(t time.Time) string {
 		return t.Format(time.RFC3339)
 	}
-	if cert.NotAfter.Before(it) {
+	if cert.NotAfter.Add(skew).Before(it) {
 		return &VerificationFailure{
 			fmt.Errorf("certificate expired before signatures were entered in log: %s is before %s",
 				ft(cert.NotAfter), ft(it)),
 		}
 	}
-	if cert.NotBefore.After(it) {
+	if cert.NotBefore.Add(-skew).After(it) {
 		return &VerificationFailure{
 			fmt.Errorf("certificate was issued after signatures were entered in log: %s is after %s",
 				ft(cert.NotAfter), ft(it)),
@@ -1218,6 +1566,31 @@ func extractEntryImpl(bundleBody string) (rekor_types.EntryImpl, error) {
 	return rekor_types.UnmarshalEntry(pe)
 }
 
+// checkTlogEntryKind verifies that the tlog entry body is of the kind, given as
+// <kind>[:<apiVersion>].
+func checkTlogEntryKind(body interface{}, kind string) error {
+	bodyStr, ok := body.(string)
+	if !ok {
+		return errors.New("tlog entry body is not a string")
+	}
+	pe, err := models.UnmarshalProposedEntry(base64.NewDecoder(base64.StdEncoding, strings.NewReader(bodyStr)), runtime.JSONConsumer())
+	if err != nil {
+		return fmt.Errorf("decoding tlog entry: %w", err)
+	}
+	ei, err := rekor_types.UnmarshalEntry(pe)
+	if err != nil {
+		return fmt.Errorf("decoding tlog entry: %w", err)
+	}
+	wantKind, wantVersion, _ := strings.Cut(kind, ":")
+	gotKind, gotVersion := pe.Kind(), ei.APIVersion()
+	if gotKind != wantKind || (wantVersion != "" && gotVersion != wantVersion) {
+		return &VerificationFailure{
+			fmt.Errorf("tlog entry is of kind %s:%s, expected %s", gotKind, gotVersion, kind),
+		}
+	}
+	return nil
+}
+
 func bundleHash(bundleBody, _ string) (string, string, error) {
 	ei, err := extractEntryImpl(bundleBody)
 	if err != nil {
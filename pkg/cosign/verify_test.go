@@ -18,17 +18,20 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/url"
@@ -133,6 +136,54 @@ func Test_verifyOCIAttestation(t *testing.T) {
 	if err := verifyOCIAttestation(context.TODO(), &mockVerifier{shouldErr: true}, &mockAttestation{payload: valid}); err == nil {
 		t.Error("verifyOCIAttestation() expected invalid payload type error, got nil")
 	}
+
+	// Should Verify without a payload type
+	if err := verifyDSSEEnvelope(context.TODO(), &mockVerifier{}, &mockAttestation{payload: invalid}, "", nil); err != nil {
+		t.Errorf("verifyDSSEEnvelope() error = %v", err)
+	}
+}
+
+func TestVerifyDSSEEnvelopeSignaturesPAE(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	require.NoError(t, err)
+	// customPAE separates the fields with newlines rather than spaces.
+	customPAE := func(payloadType string, payload []byte) []byte {
+		return []byte(fmt.Sprintf("DSSEv1\n%d\n%s\n%d\n%s", len(payloadType), payloadType, len(payload), payload))
+	}
+	if _, err := LookupPAE("test-custom"); err != nil {
+		RegisterPAE("test-custom", customPAE)
+	}
+	sign := func(pae PAEFunc) *dsse.Envelope {
+		payload := []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`)
+		sig, err := sv.SignMessage(bytes.NewReader(pae(types.IntotoPayloadType, payload)))
+		require.NoError(t, err)
+		return &dsse.Envelope{
+			PayloadType: types.IntotoPayloadType,
+			Payload:     base64.StdEncoding.EncodeToString(payload),
+			Signatures:  []dsse.Signature{{Sig: base64.StdEncoding.EncodeToString(sig)}},
+		}
+	}
+	ctx := context.Background()
+
+	require.NoError(t, VerifyDSSEEnvelopeSignatures(ctx, sv, sign(customPAE), &CheckOpts{DSSEPAE: "test-custom"}))
+	require.NoError(t, VerifyDSSEEnvelopeSignatures(ctx, sv, sign(dsse.PAE), nil))
+	// The standard PAE remains the default.
+	require.Error(t, VerifyDSSEEnvelopeSignatures(ctx, sv, sign(customPAE), nil))
+	err = VerifyDSSEEnvelopeSignatures(ctx, sv, sign(dsse.PAE), &CheckOpts{DSSEPAE: "test-custom"})
+	require.ErrorContains(t, err, "no signature of the envelope verifies over the message of the custom PAE")
+	// Only registered PAEs are used.
+	err = VerifyDSSEEnvelopeSignatures(ctx, sv, sign(dsse.PAE), &CheckOpts{DSSEPAE: "legacy"})
+	require.ErrorContains(t, err, `unknown DSSE PAE "legacy"`)
+
+	// A signature in another encoding is verified with a matching decoder.
+	env := sign(dsse.PAE)
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	require.NoError(t, err)
+	env.Signatures[0].Sig = hex.EncodeToString(sig)
+	require.NoError(t, VerifyDSSEEnvelopeSignatures(ctx, sv, env, &CheckOpts{DecodeDSSESignature: hex.DecodeString}))
+	require.Error(t, VerifyDSSEEnvelopeSignatures(ctx, sv, env, nil))
 }
 
 func TestVerifyImageSignature(t *testing.T) {
@@ -1039,6 +1090,68 @@ func TestValidateAndUnpackCertInvalidGithubWorkflowRef(t *testing.T) {
 	require.Contains(t, err.Error(), "expected GitHub Workflow Ref not found in certificate")
 }
 
+func TestValidateAndUnpackCertSPKIPins(t *testing.T) {
+	subject := "email@email"
+	oidcIssuer := "https://accounts.google.com"
+
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	leafCert, _, _ := test.GenerateLeafCert(subject, oidcIssuer, rootCert, rootKey)
+	otherCert, _, _ := test.GenerateLeafCert(subject, oidcIssuer, rootCert, rootKey)
+
+	rootPool := x509.NewCertPool()
+	rootPool.AddCert(rootCert)
+
+	leafPin := sha256.Sum256(leafCert.RawSubjectPublicKeyInfo)
+	otherPin := sha256.Sum256(otherCert.RawSubjectPublicKeyInfo)
+
+	co := &CheckOpts{
+		RootCerts:    rootPool,
+		IgnoreSCT:    true,
+		CertSPKIPins: [][]byte{otherPin[:], leafPin[:]},
+	}
+	if _, err := ValidateAndUnpackCert(leafCert, co); err != nil {
+		t.Errorf("ValidateAndUnpackCert expected no error, got err = %v", err)
+	}
+
+	co.CertSPKIPins = [][]byte{otherPin[:]}
+	_, err := ValidateAndUnpackCert(leafCert, co)
+	require.Contains(t, err.Error(), "certificate public key does not match any pinned SPKI hash")
+	err = CheckCertificatePolicy(leafCert, co)
+	require.Contains(t, err.Error(), "certificate public key does not match any pinned SPKI hash")
+}
+
+func TestValidateAndUnpackCertPolicyOID(t *testing.T) {
+	subject := "email@email"
+	oidcIssuer := "https://accounts.google.com"
+
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	policies, err := asn1.Marshal([]struct {
+		Policy asn1.ObjectIdentifier
+	}{{Policy: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}}})
+	require.NoError(t, err)
+	leafCert, _, _ := test.GenerateLeafCert(subject, oidcIssuer, rootCert, rootKey, pkix.Extension{
+		// OID for the certificate policies extension
+		Id:    asn1.ObjectIdentifier{2, 5, 29, 32},
+		Value: policies,
+	})
+
+	rootPool := x509.NewCertPool()
+	rootPool.AddCert(rootCert)
+
+	co := &CheckOpts{
+		RootCerts:     rootPool,
+		IgnoreSCT:     true,
+		CertPolicyOID: "1.3.6.1.4.1.99999.1",
+	}
+	if _, err := ValidateAndUnpackCert(leafCert, co); err != nil {
+		t.Errorf("ValidateAndUnpackCert expected no error, got err = %v", err)
+	}
+
+	co.CertPolicyOID = "1.3.6.1.4.1.99999.2"
+	_, err = ValidateAndUnpackCert(leafCert, co)
+	require.Contains(t, err.Error(), "certificate does not carry the certificate policy 1.3.6.1.4.1.99999.2")
+}
+
 func TestValidateAndUnpackCertWithChainSuccess(t *testing.T) {
 	subject := "email@email"
 	oidcIssuer := "https://accounts.google.com"
@@ -1265,6 +1378,35 @@ func TestCompareSigs(t *testing.T) {
 	}
 }
 
+func TestCheckTlogEntryKind(t *testing.T) {
+	// A hashedrekord:0.0.1 entry.
+	body := `eyJhcGlWZXJzaW9uIjoiMC4wLjEiLCJraW5kIjoiaGFzaGVkcmVrb3JkIiwic3BlYyI6eyJkYXRhIjp7Imhhc2giOnsiYWxnb3JpdGhtIjoic2hhMjU2IiwidmFsdWUiOiIzODE1MmQxZGQzMjZhZjQwNWY4OTlkYmNjMmNlMzUwYjVmMTZkNDVkZjdmMjNjNDg4ZjQ4NTBhZmExY2Q4NmQxIn19LCJzaWduYXR1cmUiOnsiY29udGVudCI6Ik1FUUNJRE8zWEhiTG92UFdLK2JrOEl0Q2lnMmN3bHIvOE1YYkx2ejNVRnp4TUdJTUFpQTFscWRNOUlxcVV2Q1Vxek9qdWZUcTNzS1UzcVNuN1I1dFBxUHowZGROd1E9PSIsInB1YmxpY0tleSI6eyJjb250ZW50IjoiTFMwdExTMUNSVWRKVGlCUVZVSk1TVU1nUzBWWkxTMHRMUzBLVFVacmQwVjNXVWhMYjFwSmVtb3dRMEZSV1VsTGIxcEplbW93UkVGUlkwUlJaMEZGVUN0RVIyb3ZXWFV4VG5vd01XVjVSV2hVZDNRMlQya3hXV3BGWXdwSloxRldjRlZTTjB0bUwwSm1hVk16Y1ZReFVHd3dkbGh3ZUZwNVMyWkpSMHMyZWxoQ04ybE5aV3RFVTA1M1dHWldPSEpKYUdaMmRrOW5QVDBLTFMwdExTMUZUa1FnVUZWQ1RFbERJRXRGV1MwdExTMHRDZz09In19fX0=`
+	tests := []struct {
+		kind      string
+		shouldErr bool
+	}{
+		{kind: "hashedrekord"},
+		{kind: "hashedrekord:0.0.1"},
+		{kind: "hashedrekord:0.0.2", shouldErr: true},
+		{kind: "dsse", shouldErr: true},
+		{kind: "intoto", shouldErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.kind, func(t *testing.T) {
+			err := checkTlogEntryKind(body, test.kind)
+			if err == nil && test.shouldErr {
+				t.Fatal("test should have errored")
+			}
+			if err != nil && !test.shouldErr {
+				t.Fatal(err)
+			}
+			if err != nil {
+				require.Contains(t, err.Error(), "tlog entry is of kind hashedrekord:0.0.1")
+			}
+		})
+	}
+}
+
 func TestTrustedCertSuccess(t *testing.T) {
 	rootCert, rootKey, _ := test.GenerateRootCa()
 	subCert, subKey, _ := test.GenerateSubordinateCa(rootCert, rootKey)
@@ -1430,3 +1572,162 @@ func TestVerifyRFC3161Timestamp(t *testing.T) {
 		t.Fatalf("expected error verifying without a root certificate, got: %v", err)
 	}
 }
+
+func TestValidateAndUnpackCertMaxLifetime(t *testing.T) {
+	subject := "email@email"
+	oidcIssuer := "https://accounts.google.com"
+
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	// Valid for 10 minutes, like a Fulcio certificate.
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafCert, _ := test.GenerateLeafCertWithExpiration(subject, oidcIssuer, time.Now().Add(-time.Minute), leafKey, rootCert, rootKey)
+
+	rootPool := x509.NewCertPool()
+	rootPool.AddCert(rootCert)
+
+	co := &CheckOpts{
+		RootCerts:       rootPool,
+		IgnoreSCT:       true,
+		MaxCertLifetime: 20 * time.Minute,
+	}
+	if _, err := ValidateAndUnpackCert(leafCert, co); err != nil {
+		t.Errorf("ValidateAndUnpackCert expected no error, got err = %v", err)
+	}
+
+	co.MaxCertLifetime = 5 * time.Minute
+	_, err := ValidateAndUnpackCert(leafCert, co)
+	require.ErrorContains(t, err, "certificate lifetime 10m0s exceeds the maximum of 5m0s")
+	var vf *VerificationFailure
+	require.ErrorAs(t, err, &vf)
+}
+
+func TestNormalizeSPIFFEID(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{id: "spiffe://example.org/ci/build", want: "spiffe://example.org/ci/build"},
+		{id: "SPIFFE://Example.ORG/ci/build/", want: "spiffe://example.org/ci/build"},
+		{id: "spiffe://example.org/", want: "spiffe://example.org"},
+		{id: "spiffe://example.org/CI", want: "spiffe://example.org/CI"},
+		{id: "https://example.org/ci", wantErr: true},
+		{id: "spiffe:///ci", wantErr: true},
+		{id: "spiffe://example.org/ci//build", wantErr: true},
+		{id: "spiffe://example.org/ci/../build", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.id, func(t *testing.T) {
+			got, err := NormalizeSPIFFEID(tc.id)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NormalizeSPIFFEID() = %v, wantErr %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("NormalizeSPIFFEID() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateAndUnpackCertSPIFFEID(t *testing.T) {
+	oidcIssuer := "https://spire.example.org"
+
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	spiffeID, err := url.Parse("spiffe://Example.org/ci/build/")
+	require.NoError(t, err)
+	leafCert, _, _ := test.GenerateLeafCertWithSubjectAlternateNames(nil, nil, nil, []*url.URL{spiffeID}, oidcIssuer, rootCert, rootKey)
+
+	rootPool := x509.NewCertPool()
+	rootPool.AddCert(rootCert)
+
+	co := &CheckOpts{
+		RootCerts:    rootPool,
+		IgnoreSCT:    true,
+		Identities:   []Identity{{Issuer: oidcIssuer}},
+		CertSPIFFEID: "spiffe://example.org/ci/build",
+	}
+	if _, err := ValidateAndUnpackCert(leafCert, co); err != nil {
+		t.Errorf("ValidateAndUnpackCert expected no error, got err = %v", err)
+	}
+
+	co.CertSPIFFEID = "spiffe://example.org/ci"
+	_, err = ValidateAndUnpackCert(leafCert, co)
+	require.ErrorContains(t, err, "certificate URI SAN is not the SPIFFE ID spiffe://example.org/ci")
+	var vf *VerificationFailure
+	require.ErrorAs(t, err, &vf)
+}
+
+func TestValidateAndUnpackCertIntermediateSKI(t *testing.T) {
+	subject := "email@email"
+	oidcIssuer := "https://accounts.google.com"
+
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	subCert, subKey, _ := test.GenerateSubordinateCa(rootCert, rootKey)
+	otherSubCert, _, _ := test.GenerateSubordinateCa(rootCert, rootKey)
+	leafCert, _, _ := test.GenerateLeafCert(subject, oidcIssuer, subCert, subKey)
+
+	co := &CheckOpts{
+		Identities:      []Identity{{Subject: subject, Issuer: oidcIssuer}},
+		IgnoreSCT:       true,
+		IntermediateSKI: subCert.SubjectKeyId,
+	}
+	if _, err := ValidateAndUnpackCertWithChain(leafCert, []*x509.Certificate{subCert, rootCert}, co); err != nil {
+		t.Errorf("ValidateAndUnpackCertWithChain expected no error, got err = %v", err)
+	}
+
+	co.IntermediateSKI = otherSubCert.SubjectKeyId
+	_, err := ValidateAndUnpackCertWithChain(leafCert, []*x509.Certificate{subCert, rootCert}, co)
+	require.ErrorContains(t, err, "certificate chain does not go through the intermediate CA of subject key identifier")
+	var vf *VerificationFailure
+	require.ErrorAs(t, err, &vf)
+
+	// The root isn't an intermediate.
+	co.IntermediateSKI = rootCert.SubjectKeyId
+	_, err = ValidateAndUnpackCertWithChain(leafCert, []*x509.Certificate{subCert, rootCert}, co)
+	require.ErrorContains(t, err, "certificate chain does not go through the intermediate CA")
+}
+
+func TestCheckExpiryClockSkew(t *testing.T) {
+	notBefore := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(10 * time.Minute),
+	}
+
+	tests := []struct {
+		description string
+		at          time.Time
+		skew        time.Duration
+		shouldErr   bool
+	}{
+		{
+			description: "within validity",
+			at:          notBefore.Add(time.Minute),
+		}, {
+			description: "before validity",
+			at:          notBefore.Add(-5 * time.Second),
+			shouldErr:   true,
+		}, {
+			description: "before validity within skew",
+			at:          notBefore.Add(-5 * time.Second),
+			skew:        10 * time.Second,
+		}, {
+			description: "after validity within skew",
+			at:          cert.NotAfter.Add(5 * time.Second),
+			skew:        10 * time.Second,
+		}, {
+			description: "after validity beyond skew",
+			at:          cert.NotAfter.Add(20 * time.Second),
+			skew:        10 * time.Second,
+			shouldErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := checkExpiry(cert, test.at, test.skew)
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("checkExpiry() = %v, expected shouldErr=%t", err, test.shouldErr)
+			}
+		})
+	}
+}
@@ -24,14 +24,18 @@ import (
 
 	"github.com/sigstore/cosign/v2/pkg/blob"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/dnskey"
+	"github.com/sigstore/cosign/v2/pkg/cosign/fido2key"
 	"github.com/sigstore/cosign/v2/pkg/cosign/git"
 	"github.com/sigstore/cosign/v2/pkg/cosign/git/gitlab"
+	"github.com/sigstore/cosign/v2/pkg/cosign/jwkskey"
 	"github.com/sigstore/cosign/v2/pkg/cosign/kubernetes"
 	"github.com/sigstore/cosign/v2/pkg/cosign/pkcs11key"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/sigstore/sigstore/pkg/signature"
 
 	"github.com/sigstore/sigstore/pkg/signature/kms"
+	v1 "k8s.io/api/core/v1"
 )
 
 // LoadPublicKey is a wrapper for VerifierForKeyRef, hardcoding SHA256 as the hash algorithm
@@ -174,15 +178,28 @@ func PublicKeyFromKeyRef(ctx context.Context, keyRef string) (signature.Verifier
 }
 
 func PublicKeyFromKeyRefWithHashAlgo(ctx context.Context, keyRef string, hashAlgorithm crypto.Hash) (signature.Verifier, error) {
+	if strings.HasPrefix(keyRef, dnskey.ReferenceScheme) {
+		pub, err := dnskey.GetPublicKey(ctx, keyRef)
+		if err != nil {
+			return nil, fmt.Errorf("loading key from DNS: %w", err)
+		}
+		return signature.LoadVerifier(pub, hashAlgorithm)
+	}
+
+	if strings.HasPrefix(keyRef, fido2key.ReferenceScheme) {
+		return fido2key.LoadVerifier(keyRef)
+	}
+
+	if strings.HasPrefix(keyRef, jwkskey.ReferenceScheme) {
+		return jwkskey.LoadVerifier(ctx, keyRef)
+	}
+
 	if strings.HasPrefix(keyRef, kubernetes.KeyReference) {
 		s, err := kubernetes.GetKeyPairSecret(ctx, keyRef)
 		if err != nil {
 			return nil, err
 		}
-
-		if len(s.Data) > 0 {
-			return LoadPublicKeyRaw(s.Data["cosign.pub"], hashAlgorithm)
-		}
+		return publicKeyFromSecret(s, keyRef, hashAlgorithm)
 	}
 
 	if strings.HasPrefix(keyRef, pkcs11key.ReferenceScheme) {
@@ -227,6 +244,16 @@ func PublicKeyFromKeyRefWithHashAlgo(ctx context.Context, keyRef string, hashAlg
 	return VerifierForKeyRef(ctx, keyRef, hashAlgorithm)
 }
 
+// publicKeyFromSecret loads the public key of a cosign key pair stored in a
+// Kubernetes secret.
+func publicKeyFromSecret(s *v1.Secret, keyRef string, hashAlgorithm crypto.Hash) (signature.Verifier, error) {
+	pub, ok := s.Data[kubernetes.PublicKeyField]
+	if !ok || len(pub) == 0 {
+		return nil, fmt.Errorf("secret %s does not contain a public key in the %q field", keyRef, kubernetes.PublicKeyField)
+	}
+	return LoadPublicKeyRaw(pub, hashAlgorithm)
+}
+
 func PublicKeyPem(key signature.PublicKeyProvider, pkOpts ...signature.PublicKeyOption) ([]byte, error) {
 	pub, err := key.PublicKey(pkOpts...)
 	if err != nil {
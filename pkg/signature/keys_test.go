@@ -22,14 +22,17 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/sigstore/cosign/v2/pkg/blob"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/kubernetes"
 	"github.com/sigstore/cosign/v2/test"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	sigsignature "github.com/sigstore/sigstore/pkg/signature"
 	"github.com/sigstore/sigstore/pkg/signature/kms"
+	v1 "k8s.io/api/core/v1"
 )
 
 func generateKeyFile(t *testing.T, tmpDir string, pf cosign.PassFunc) (privFile, pubFile string) {
@@ -130,6 +133,24 @@ func TestPublicKeyFromEnvVar(t *testing.T) {
 	}
 }
 
+func TestPublicKeyFromSecret(t *testing.T) {
+	keys, err := cosign.GenerateKeyPair(pass("whatever"))
+	if err != nil {
+		t.Fatalf("failed to generate keypair: %v", err)
+	}
+
+	s := &v1.Secret{Data: map[string][]byte{kubernetes.PublicKeyField: keys.PublicBytes}}
+	if _, err := publicKeyFromSecret(s, "k8s://ns/name", crypto.SHA256); err != nil {
+		t.Fatalf("publicKeyFromSecret returned error: %v", err)
+	}
+
+	s = &v1.Secret{Data: map[string][]byte{"cosign.key": keys.PrivateBytes}}
+	_, err = publicKeyFromSecret(s, "k8s://ns/name", crypto.SHA256)
+	if err == nil || !strings.Contains(err.Error(), "does not contain a public key") {
+		t.Fatalf("publicKeyFromSecret should have failed on a secret without a public key, got: %v", err)
+	}
+}
+
 func TestSignerVerifierFromEnvVar(t *testing.T) {
 	passFunc := pass("whatever")
 	keys, err := cosign.GenerateKeyPair(passFunc)
@@ -29,11 +29,13 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -69,6 +71,7 @@ import (
 	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
 	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
 	sigs "github.com/sigstore/cosign/v2/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/sigstore/sigstore/pkg/signature/payload"
 	tsaclient "github.com/sigstore/timestamp-authority/pkg/client"
 	"github.com/sigstore/timestamp-authority/pkg/server"
@@ -1544,6 +1547,89 @@ func TestSignBlobRFC3161TimestampBundle(t *testing.T) {
 	must(verifyBlobCmd.Exec(ctx, bp), t)
 }
 
+func TestAttestBlobRFC3161TimestampVerify(t *testing.T) {
+	// TSA server needed to create timestamp, served over TLS to exercise
+	// --timestamp-client-cacert
+	viper.Set("timestamp-signer", "memory")
+	viper.Set("timestamp-signer-hash", "sha256")
+	apiServer := server.NewRestAPIServer("localhost", 0, []string{"http"}, false, 10*time.Second, 10*time.Second)
+	server := httptest.NewTLSServer(apiServer.GetHandler())
+	t.Cleanup(server.Close)
+
+	td := t.TempDir()
+	caPath := filepath.Join(td, "tsa-ca.pem")
+	caPEM, err := cryptoutils.MarshalCertificateToPEM(server.Certificate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(caPath, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Client().Get(server.URL + "/api/v1/timestamp/certchain")
+	if err != nil {
+		t.Fatalf("unexpected error getting timestamp chain: %v", err)
+	}
+	defer resp.Body.Close()
+	chain, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainPath := filepath.Join(td, "tsa-chain.pem")
+	if err := os.WriteFile(chainPath, chain, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	blob := "someblob"
+	bp := filepath.Join(td, "blob")
+	if err := os.WriteFile(bp, []byte(blob), 0600); err != nil {
+		t.Fatal(err)
+	}
+	predicatePath := filepath.Join(td, "predicate")
+	if err := os.WriteFile(predicatePath, []byte(`{ "buildType": "x", "builder": { "id": "2" }, "recipe": {} }`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sigPath := filepath.Join(td, "attestation.sig")
+	tsPath := filepath.Join(td, "attestation.timestamp.json")
+
+	_, privKeyPath, pubKeyPath := keypair(t, td)
+	ctx := context.Background()
+
+	attestBlobCmd := attest.AttestBlobCommand{
+		KeyOpts: options.KeyOpts{
+			KeyRef:               privKeyPath,
+			PassFunc:             passFunc,
+			TSAServerURL:         server.URL + "/api/v1/timestamp",
+			TSAClientCACert:      caPath,
+			RFC3161TimestampPath: tsPath,
+		},
+		PredicatePath:   predicatePath,
+		PredicateType:   "slsaprovenance",
+		OutputSignature: sigPath,
+	}
+	must(attestBlobCmd.Exec(ctx, bp), t)
+
+	verifyBlobAttestationCmd := cliverify.VerifyBlobAttestationCommand{
+		KeyOpts: options.KeyOpts{
+			KeyRef:               pubKeyPath,
+			RFC3161TimestampPath: tsPath,
+			TSACertChainPath:     chainPath,
+		},
+		SignaturePath: sigPath,
+		PredicateType: "slsaprovenance",
+		IgnoreTlog:    true,
+		CheckClaims:   true,
+	}
+	must(verifyBlobAttestationCmd.Exec(ctx, bp), t)
+
+	// The timestamp must be over the attestation signature.
+	otherSigPath := filepath.Join(td, "other.sig")
+	attestBlobCmd.RFC3161TimestampPath = filepath.Join(td, "other.timestamp.json")
+	attestBlobCmd.OutputSignature = otherSigPath
+	must(attestBlobCmd.Exec(ctx, bp), t)
+	verifyBlobAttestationCmd.SignaturePath = otherSigPath
+	mustErr(verifyBlobAttestationCmd.Exec(ctx, bp), t)
+}
+
 func TestGenerate(t *testing.T) {
 	repo, stop := reg(t)
 	defer stop()
@@ -2439,6 +2525,91 @@ func TestAttestBlobSignVerify(t *testing.T) {
 	mustErr(blobVerifyAttestationCmd.Exec(ctx, anotherBlob), t)
 }
 
+func TestAttestImageVerifyBlobAttestation(t *testing.T) {
+	repo, stop := reg(t)
+	defer stop()
+	td := t.TempDir()
+
+	imgName := path.Join(repo, "cosign-attest-verify-blob-e2e")
+	ref, desc, cleanup := mkimage(t, imgName)
+	defer cleanup()
+
+	_, privKeyPath, pubKeyPath := keypair(t, td)
+	ctx := context.Background()
+
+	predicatePath := filepath.Join(td, "predicate")
+	if err := os.WriteFile(predicatePath, []byte(`{ "buildType": "x", "builder": { "id": "2" }, "recipe": {} }`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	attestCmd := attest.AttestCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: privKeyPath, PassFunc: passFunc},
+		PredicatePath: predicatePath,
+		PredicateType: "slsaprovenance",
+		Timeout:       30 * time.Second,
+	}
+	must(attestCmd.Exec(ctx, imgName), t)
+
+	// Save the envelope attached to the image, as downloaded with
+	// `cosign download attestation`, and the manifest it is about.
+	attestations, err := cosign.FetchAttestationsForReference(ctx, ref, "", ociremote.WithRemoteOptions(registryClientOpts(ctx)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attestations) != 1 {
+		t.Fatalf("expected 1 attestation, got %d", len(attestations))
+	}
+	envelope, err := json.Marshal(attestations[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelopePath := filepath.Join(td, "envelope.json")
+	if err := os.WriteFile(envelopePath, envelope, 0600); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(td, "manifest.json")
+	if err := os.WriteFile(manifestPath, desc.Manifest, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The image attestation verifies as a blob attestation of the manifest.
+	blobVerifyAttestationCmd := cliverify.VerifyBlobAttestationCommand{
+		KeyOpts:       options.KeyOpts{KeyRef: pubKeyPath},
+		SignaturePath: envelopePath,
+		PredicateType: "slsaprovenance",
+		IgnoreTlog:    true,
+		CheckClaims:   true,
+	}
+	must(blobVerifyAttestationCmd.Exec(ctx, manifestPath), t)
+
+	// The subject of an image attestation is the manifest, so a layer of the
+	// image, although covered by the manifest, fails the claim check.
+	img, err := desc.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := layers[0].Compressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	layer, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layerPath := filepath.Join(td, "layer")
+	if err := os.WriteFile(layerPath, layer, 0600); err != nil {
+		t.Fatal(err)
+	}
+	err = blobVerifyAttestationCmd.Exec(ctx, layerPath)
+	if err == nil || !strings.Contains(err.Error(), "no matching subject digest found") {
+		t.Fatalf("verifying the layer = %v, expected the claim check to fail", err)
+	}
+}
+
 func TestOffline(t *testing.T) {
 	regName, stop := reg(t)
 	defer stop()